@@ -0,0 +1,249 @@
+// Package config loads the configuration of helio, imageserver and webServer. Every setting has a default, which a
+// YAML or TOML file overrides, which the environment overrides, which flags override:
+//
+//	defaults < file(-config or <NAME>_CONFIG) < environment < flags
+//
+// A service describes its configuration as a struct, see field for the tags, and checks it by implementing Validator.
+// The effective configuration is printed with secrets redacted, and settings tagged reload are reloaded on SIGHUP.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// Source is where the value of a setting was taken from.
+type Source string
+
+const (
+	Default Source = "default"
+	File    Source = "file"
+	Env     Source = "env"
+	Flag    Source = "flag"
+)
+
+const redacted = "[redacted]"
+
+// Validator is implemented by configurations checking their settings once loaded.
+type Validator interface {
+	Validate() error
+}
+
+// Loader loads a configuration of type T, a struct.
+type Loader[T any] struct {
+	name     string
+	env      string // environment variable holding the path of the file
+	defaults func() T
+	fields   []field
+
+	path      string            // -config
+	printOnly bool              // -print-config
+	flags     map[string]string // flags given, by key
+
+	mu      sync.Mutex
+	loaded  string            // file of the last load
+	sources map[string]Source // of the last load, by key
+}
+
+// NewLoader constructs a Loader of the configuration of the service name, defaults returns a configuration holding
+// the default of every setting. The file is read from <NAME>_CONFIG unless -config is given.
+func NewLoader[T any](name string, defaults func() T) (*Loader[T], error) {
+	fields, err := fieldsOf(reflect.TypeOf(defaults()), "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Loader[T]{
+		name:     name,
+		env:      strings.ToUpper(name) + "_CONFIG",
+		defaults: defaults,
+		fields:   fields,
+		flags:    map[string]string{},
+		sources:  map[string]Source{},
+	}, nil
+}
+
+// settingFlag is the flag of a setting, it only records what it is given so flags are applied last.
+type settingFlag struct {
+	field    field
+	fallback string
+	given    map[string]string
+	boolean  bool
+}
+
+func (f *settingFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.fallback
+}
+
+func (f *settingFlag) Set(value string) error {
+	f.given[f.field.key] = value
+	return nil
+}
+
+func (f *settingFlag) IsBoolFlag() bool {
+	return f.boolean
+}
+
+// Parse parses the command line flags, args excluding the program name. -h prints every setting and exits.
+func (l *Loader[T]) Parse(args []string) {
+	fs := flag.NewFlagSet(l.name, flag.ExitOnError)
+	fs.StringVar(&l.path, "config", "", fmt.Sprintf("YAML or TOML configuration file(%s)", l.env))
+	fs.BoolVar(&l.printOnly, "print-config", false, "print the effective configuration and exit")
+	defaults := reflect.ValueOf(l.defaults())
+	for _, f := range l.fields {
+		usage := f.usage
+		if f.env != "" {
+			usage += "(" + f.env + ")"
+		}
+		fallback := format(defaults.FieldByIndex(f.index))
+		if f.secret {
+			fallback = ""
+		}
+		fs.Var(&settingFlag{field: f, fallback: fallback, given: l.flags, boolean: defaults.FieldByIndex(f.index).Kind() == reflect.Bool},
+			f.flag, usage)
+	}
+	_ = fs.Parse(args)
+}
+
+// PrintOnly reports whether -print-config was given.
+func (l *Loader[T]) PrintOnly() bool {
+	return l.printOnly
+}
+
+// Load loads the configuration from its defaults, file, environment and flags, then validates it.
+func (l *Loader[T]) Load() (T, error) {
+	cfg := l.defaults()
+	v := reflect.ValueOf(&cfg).Elem()
+	sources := map[string]Source{}
+	byKey := make(map[string]field, len(l.fields))
+	for _, f := range l.fields {
+		byKey[f.key] = f
+		sources[f.key] = Default
+	}
+
+	path := l.path
+	if path == "" {
+		path = os.Getenv(l.env)
+	}
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return cfg, err
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			f, ok := byKey[key]
+			if !ok {
+				return cfg, fmt.Errorf("%s: unknown setting %q", path, key)
+			}
+			if err := setRaw(v.FieldByIndex(f.index), values[key]); err != nil {
+				return cfg, fmt.Errorf("%s: setting %s: %w", path, key, err)
+			}
+			sources[key] = File
+		}
+	}
+	for _, f := range l.fields {
+		if value := os.Getenv(f.env); f.env != "" && value != "" {
+			if err := setString(v.FieldByIndex(f.index), value); err != nil {
+				return cfg, fmt.Errorf("%s: %w", f.env, err)
+			}
+			sources[f.key] = Env
+		}
+	}
+	for _, f := range l.fields {
+		if value, ok := l.flags[f.key]; ok {
+			if err := setString(v.FieldByIndex(f.index), value); err != nil {
+				return cfg, fmt.Errorf("-%s: %w", f.flag, err)
+			}
+			sources[f.key] = Flag
+		}
+	}
+
+	if validator, ok := any(&cfg).(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return cfg, fmt.Errorf("invalid %s configuration: %w", l.name, err)
+		}
+	}
+	l.mu.Lock()
+	l.loaded, l.sources = path, sources
+	l.mu.Unlock()
+	return cfg, nil
+}
+
+// readFile reads a YAML(.yaml, .yml) or TOML(.toml) file into its settings by key, sections are flattened.
+func readFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading configuration: %w", err)
+	}
+	document := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	case ".toml":
+		err = toml.Unmarshal(data, &document)
+	default:
+		return nil, fmt.Errorf("%s: configuration files must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	values := map[string]any{}
+	flatten(document, "", values)
+	return values, nil
+}
+
+func flatten(section map[string]any, prefix string, values map[string]any) {
+	for key, value := range section {
+		if inner, ok := value.(map[string]any); ok {
+			flatten(inner, prefix+key+".", values)
+			continue
+		}
+		values[prefix+key] = value
+	}
+}
+
+// Print writes every setting of cfg along with where it was taken from, secrets are redacted.
+func (l *Loader[T]) Print(w io.Writer, cfg T) {
+	l.mu.Lock()
+	loaded, sources := l.loaded, l.sources
+	l.mu.Unlock()
+	if loaded == "" {
+		loaded = "none"
+	}
+	_, _ = fmt.Fprintf(w, "%s configuration, file %s\n", l.name, loaded)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	v := reflect.ValueOf(cfg)
+	for _, f := range l.fields {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\t%s\n", f.key, l.value(f, v), sources[f.key])
+	}
+	_ = tw.Flush()
+}
+
+// value returns the printed value of a setting, a secret that is set is redacted.
+func (l *Loader[T]) value(f field, v reflect.Value) string {
+	value := format(v.FieldByIndex(f.index))
+	if f.secret && value != "" {
+		return redacted
+	}
+	if value == "" {
+		return `""`
+	}
+	return value
+}
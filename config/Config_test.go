@@ -0,0 +1,178 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testConfig is the configuration of a service named test.
+type testConfig struct {
+	Port    int      `config:"port" env:"TEST_PORT" usage:"HTTP port"`
+	Level   Level    `config:"log_level" env:"TEST_LOG_LEVEL" reload:"true" usage:"debug, info, warn or error"`
+	Origins []string `config:"origins" env:"TEST_ORIGINS" reload:"true" usage:"origins allowed"`
+	Secret  string   `config:"secret" env:"TEST_SECRET" secret:"true" usage:"a secret"`
+	Ignored string
+
+	Server struct {
+		Timeout time.Duration `config:"timeout" env:"TEST_TIMEOUT" usage:"request timeout"`
+		Debug   bool          `config:"debug" usage:"serve pprof"`
+	} `config:"server"`
+}
+
+func defaultTestConfig() testConfig {
+	cfg := testConfig{Port: 8000, Level: Info, Origins: []string{"*"}}
+	cfg.Server.Timeout = 30 * time.Second
+	return cfg
+}
+
+func (c *testConfig) Validate() error {
+	var problems Problems
+	problems.Port("port", c.Port)
+	problems.Positive("server.timeout", int64(c.Server.Timeout))
+	return problems.Err()
+}
+
+// writeFile writes a configuration file named name, returning its path.
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestLoader(t *testing.T, args ...string) *Loader[testConfig] {
+	l, err := NewLoader("test", defaultTestConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Parse(args)
+	return l
+}
+
+func TestLoad(t *testing.T) {
+	yamlFile := writeFile(t, "test.yaml", "port: 8100\nlog_level: warn\norigins: [https://a.example, https://b.example]\nserver:\n  timeout: 1m\n")
+	tomlFile := writeFile(t, "test.toml", "port = 8200\n[server]\ntimeout = \"45s\"\ndebug = true\n")
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		wantPort    int
+		wantLevel   Level
+		wantOrigins string
+		wantTimeout time.Duration
+		wantDebug   bool
+		wantSources map[string]Source
+	}{
+		{"defaults", nil, nil, 8000, Info, "*", 30 * time.Second, false,
+			map[string]Source{"port": Default, "server.timeout": Default}},
+		{"yaml file", []string{"-config", yamlFile}, nil, 8100, Warn, "https://a.example,https://b.example", time.Minute, false,
+			map[string]Source{"port": File, "log_level": File, "server.timeout": File, "server.debug": Default}},
+		{"toml file from the environment", nil, map[string]string{"TEST_CONFIG": tomlFile}, 8200, Info, "*", 45 * time.Second, true,
+			map[string]Source{"port": File, "server.debug": File}},
+		{"environment over the file", []string{"-config", yamlFile}, map[string]string{"TEST_PORT": "8300", "TEST_ORIGINS": "https://c.example, "},
+			8300, Warn, "https://c.example", time.Minute, false, map[string]Source{"port": Env, "origins": Env, "log_level": File}},
+		{"flags over the environment", []string{"-config", yamlFile, "-port", "8400", "-server-debug", "-server-timeout", "5s"},
+			map[string]string{"TEST_PORT": "8300"}, 8400, Warn, "https://a.example,https://b.example", 5 * time.Second, true,
+			map[string]Source{"port": Flag, "server.timeout": Flag, "server.debug": Flag}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			l := newTestLoader(t, tt.args...)
+			cfg, err := l.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Port != tt.wantPort || cfg.Level != tt.wantLevel || strings.Join(cfg.Origins, ",") != tt.wantOrigins ||
+				cfg.Server.Timeout != tt.wantTimeout || cfg.Server.Debug != tt.wantDebug {
+				t.Errorf("Load() = %+v, want port %d, level %s, origins %s, timeout %v, debug %v", cfg, tt.wantPort, tt.wantLevel,
+					tt.wantOrigins, tt.wantTimeout, tt.wantDebug)
+			}
+			for key, want := range tt.wantSources {
+				if got := l.sources[key]; got != want {
+					t.Errorf("source of %s = %s, want %s", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string // name and content split by a newline
+		env     map[string]string
+		wantErr string
+	}{
+		{"unknown setting", "test.yaml\nport: 8100\nhost: localhost\n", nil, `unknown setting "host"`},
+		{"section given a value", "test.yaml\nserver: 30s\n", nil, `unknown setting "server"`},
+		{"value given a section", "test.yaml\nport:\n  http: 8100\n", nil, `unknown setting "port.http"`},
+		{"list given to a value", "test.yaml\nport: [8100]\n", nil, "setting port: expected a value, not a list"},
+		{"not a duration", "test.toml\n[server]\ntimeout = \"soon\"\n", nil, `setting server.timeout: "soon" is not a duration, i.e. 30s`},
+		{"unknown log level", "test.yaml\nlog_level: verbose\n", nil, `"verbose" is not a log level`},
+		{"malformed file", "test.yaml\nport: [8100\n", nil, "test.yaml"},
+		{"unknown extension", "test.json\n{}\n", nil, "configuration files must be .yaml, .yml or .toml"},
+		{"environment not an integer", "", map[string]string{"TEST_PORT": "http"}, `TEST_PORT: "http" is not an integer`},
+		{"every problem validated", "", map[string]string{"TEST_PORT": "0", "TEST_TIMEOUT": "0s"},
+			"invalid test configuration: port must be a port between 1 and 65535, not 0; server.timeout must be above 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			var args []string
+			if tt.file != "" {
+				name, content, _ := strings.Cut(tt.file, "\n")
+				args = []string{"-config", writeFile(t, name, content)}
+			}
+			if _, err := newTestLoader(t, args...).Load(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrint(t *testing.T) {
+	t.Setenv("TEST_SECRET", "hunter2")
+	l := newTestLoader(t, "-port", "8100")
+	cfg, err := l.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	l.Print(&buf, cfg)
+	printed := buf.String()
+	if strings.Contains(printed, "hunter2") {
+		t.Errorf("Print() = %q, want the secret redacted", printed)
+	}
+	for _, want := range []string{"test configuration, file none", "port", "8100", "flag", "[redacted]", "env", "30s", "default"} {
+		if !strings.Contains(printed, want) {
+			t.Errorf("Print() = %q, want it to hold %q", printed, want)
+		}
+	}
+}
+
+func TestNewLoaderInvalid(t *testing.T) {
+	type unsupported struct {
+		Ports map[string]int `config:"ports"`
+	}
+	if _, err := NewLoader("test", func() unsupported { return unsupported{} }); err == nil ||
+		!strings.Contains(err.Error(), "setting ports has unsupported type") {
+		t.Errorf("NewLoader() error = %v, want the unsupported setting refused", err)
+	}
+	type unexported struct {
+		port int `config:"port"`
+	}
+	if _, err := NewLoader("test", func() unexported { return unexported{port: 1} }); err == nil ||
+		!strings.Contains(err.Error(), "setting port must be exported") {
+		t.Errorf("NewLoader() error = %v, want the unexported setting refused", err)
+	}
+}
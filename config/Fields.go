@@ -0,0 +1,169 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// field is a setting of a configuration struct, taken from its tags:
+//
+//	Port int `config:"port" env:"PORT" usage:"port served" reload:"true"`
+//
+// Nested structs are sections, their settings are keyed by the path through them, i.e. auth.issuer. Flags are named
+// after the key with dashes, i.e. -auth-issuer. Fields without a config tag are left alone.
+type field struct {
+	key    string // dotted path within a file
+	env    string // environment variable, optional
+	flag   string
+	usage  string
+	secret bool // never printed
+	reload bool // safe to change while running
+	index  []int
+}
+
+// fieldsOf returns the settings of the struct type t, in the order they are declared.
+func fieldsOf(t reflect.Type, prefix string, index []int) ([]field, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("configuration must be a struct, not %s", t)
+	}
+	fields := []field{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key, ok := sf.Tag.Lookup("config")
+		if !ok || key == "-" {
+			continue
+		}
+		if !sf.IsExported() {
+			return nil, fmt.Errorf("setting %s%s must be exported", prefix, key)
+		}
+		at := append(append([]int{}, index...), i)
+		if sf.Type.Kind() == reflect.Struct {
+			section, err := fieldsOf(sf.Type, prefix+key+".", at)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, section...)
+			continue
+		}
+		if !settable(sf.Type) {
+			return nil, fmt.Errorf("setting %s%s has unsupported type %s", prefix, key, sf.Type)
+		}
+		fields = append(fields, field{
+			key:    prefix + key,
+			env:    sf.Tag.Get("env"),
+			flag:   strings.NewReplacer(".", "-", "_", "-").Replace(prefix + key),
+			usage:  sf.Tag.Get("usage"),
+			secret: sf.Tag.Get("secret") == "true",
+			reload: sf.Tag.Get("reload") == "true",
+			index:  at,
+		})
+	}
+	return fields, nil
+}
+
+// settable reports whether a setting of type t can be read from a string.
+func settable(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+// setString sets v from its string form, lists are comma separated.
+func setString(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration, i.e. 30s", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		list := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = reflect.Append(list, reflect.ValueOf(item).Convert(v.Type().Elem()))
+			}
+		}
+		v.Set(list)
+	}
+	return nil
+}
+
+// setRaw sets v from a value decoded from a file.
+func setRaw(v reflect.Value, raw any) error {
+	switch raw := raw.(type) {
+	case map[string]any:
+		return fmt.Errorf("expected a value, not a section")
+	case []any:
+		if v.Kind() != reflect.Slice {
+			return fmt.Errorf("expected a value, not a list")
+		}
+		list := reflect.MakeSlice(v.Type(), 0, len(raw))
+		for _, item := range raw {
+			list = reflect.Append(list, reflect.ValueOf(fmt.Sprint(item)).Convert(v.Type().Elem()))
+		}
+		v.Set(list)
+		return nil
+	case nil:
+		return nil
+	default:
+		return setString(v, fmt.Sprint(raw))
+	}
+}
+
+// format returns the string form of v, as read by setString.
+func format(v reflect.Value) string {
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, _ := m.MarshalText()
+		return string(text)
+	}
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	if v.Kind() == reflect.Slice {
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = v.Index(i).String()
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
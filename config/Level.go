@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+)
+
+// Level is how much a service logs, from debug(everything) to error(only failures).
+type Level string
+
+const (
+	Debug Level = "debug"
+	Info  Level = "info"
+	Warn  Level = "warn"
+	Error Level = "error"
+)
+
+var levelRanks = map[Level]int32{Debug: 0, Info: 1, Warn: 2, Error: 3}
+
+var slogLevels = []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError} // by rank
+
+// UnmarshalText reads a Level, refusing anything but debug, info, warn or error.
+func (l *Level) UnmarshalText(text []byte) error {
+	level := Level(text)
+	if _, ok := levelRanks[level]; !ok {
+		return fmt.Errorf("%q is not a log level, one of debug, info, warn or error", text)
+	}
+	*l = level
+	return nil
+}
+
+// LevelVar holds the Level in use, which may change on reload while it is being read. It is the slog.Leveler of the
+// logger of a service, so a reload changes what is logged right away.
+type LevelVar struct {
+	rank atomic.Int32
+}
+
+// NewLevelVar constructs a LevelVar set to level.
+func NewLevelVar(level Level) *LevelVar {
+	l := &LevelVar{}
+	l.Set(level)
+	return l
+}
+
+// Set changes the Level in use.
+func (l *LevelVar) Set(level Level) {
+	l.rank.Store(levelRanks[level])
+}
+
+// Enabled reports whether messages of the level are logged.
+func (l *LevelVar) Enabled(level Level) bool {
+	return levelRanks[level] >= l.rank.Load()
+}
+
+// Level returns the slog.Level in use, see slog.Leveler.
+func (l *LevelVar) Level() slog.Level {
+	return slogLevels[l.rank.Load()]
+}
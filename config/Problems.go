@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Problems collects everything wrong with a configuration, so all of it is reported at once by Validate.
+type Problems []string
+
+// Add records a problem.
+func (p *Problems) Add(format string, args ...any) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+// Port records a problem unless port is a TCP port.
+func (p *Problems) Port(key string, port int) {
+	if port < 1 || port > 65535 {
+		p.Add("%s must be a port between 1 and 65535, not %d", key, port)
+	}
+}
+
+// Positive records a problem unless n is above 0.
+func (p *Problems) Positive(key string, n int64) {
+	if n < 1 {
+		p.Add("%s must be above 0", key)
+	}
+}
+
+// File records a problem if path is set and is not a readable file.
+func (p *Problems) File(key string, path string) {
+	if path == "" {
+		return
+	}
+	if info, err := os.Stat(path); err != nil {
+		p.Add("%s: %s", key, err.Error())
+	} else if info.IsDir() {
+		p.Add("%s: %s is a directory", key, path)
+	}
+}
+
+// Dir records a problem unless path is a directory.
+func (p *Problems) Dir(key string, path string) {
+	if info, err := os.Stat(path); err != nil {
+		p.Add("%s: %s", key, err.Error())
+	} else if !info.IsDir() {
+		p.Add("%s: %s is not a directory", key, path)
+	}
+}
+
+// Err returns the problems as one error, nil if there are none.
+func (p Problems) Err() error {
+	if len(p) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(p, "; "))
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+)
+
+// Reload loads the configuration again and returns current with the settings tagged reload taken from it, along with
+// the keys of those that changed. Any other setting that changed is logged and left as it is until a restart.
+func (l *Loader[T]) Reload(current T) (T, []string, error) {
+	next, err := l.Load()
+	if err != nil {
+		return current, nil, err
+	}
+	merged := current
+	cv, nv, mv := reflect.ValueOf(current), reflect.ValueOf(next), reflect.ValueOf(&merged).Elem()
+	changed := []string{}
+	for _, f := range l.fields {
+		if reflect.DeepEqual(cv.FieldByIndex(f.index).Interface(), nv.FieldByIndex(f.index).Interface()) {
+			continue
+		}
+		if !f.reload {
+			slog.Warn("Setting changed, restart to apply it", "setting", f.key, "service", l.name)
+			continue
+		}
+		mv.FieldByIndex(f.index).Set(nv.FieldByIndex(f.index))
+		changed = append(changed, f.key)
+	}
+	return merged, changed, nil
+}
+
+// Watch reloads the configuration on SIGHUP until ctx is done, handing it to apply whenever a setting tagged reload
+// changed. A configuration failing to load or validate is logged and the one in use is kept.
+func (l *Loader[T]) Watch(ctx context.Context, current T, apply func(cfg T)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+			}
+			next, changed, err := l.Reload(current)
+			if err != nil {
+				slog.Error("Error reloading configuration, keeping the current one", "error", err)
+				continue
+			}
+			if len(changed) == 0 {
+				slog.Info("Reloaded configuration, nothing changed", "service", l.name)
+				continue
+			}
+			current = next
+			apply(current)
+			slog.Info("Reloaded configuration", "service", l.name, "changed", changed)
+		}
+	}()
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"context"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	tests := []struct {
+		name        string
+		next        string // file loaded on reload
+		wantChanged []string
+		wantPort    int
+		wantLevel   Level
+		wantOrigins string
+		wantErr     bool
+	}{
+		{"nothing changed", "port: 8100\nlog_level: info\n", []string{}, 8100, Info, "*", false},
+		{"reloaded settings changed", "port: 8100\nlog_level: debug\norigins: [https://a.example]\n",
+			[]string{"log_level", "origins"}, 8100, Debug, "https://a.example", false},
+		// the port needs a restart, the level is taken all the same
+		{"restart needed", "port: 8200\nlog_level: error\n", []string{"log_level"}, 8100, Error, "*", false},
+		{"invalid kept", "port: 0\nlog_level: debug\n", nil, 8100, Info, "*", true},
+		{"malformed kept", "port: [\n", nil, 8100, Info, "*", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, "test.yaml", "port: 8100\nlog_level: info\n")
+			l := newTestLoader(t, "-config", path)
+			current, err := l.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = os.WriteFile(path, []byte(tt.next), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, changed, err := l.Reload(current)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Reload() error = %v, want an error %v", err, tt.wantErr)
+			}
+			if strings.Join(changed, ",") != strings.Join(tt.wantChanged, ",") {
+				t.Errorf("Reload() changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if got.Port != tt.wantPort || got.Level != tt.wantLevel || strings.Join(got.Origins, ",") != tt.wantOrigins {
+				t.Errorf("Reload() = %+v, want port %d, level %s, origins %s", got, tt.wantPort, tt.wantLevel, tt.wantOrigins)
+			}
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := writeFile(t, "test.yaml", "log_level: info\n")
+	l := newTestLoader(t, "-config", path)
+	current, err := l.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied := make(chan testConfig, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l.Watch(ctx, current, func(cfg testConfig) { applied <- cfg })
+
+	if err = os.WriteFile(path, []byte("log_level: debug\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case cfg := <-applied:
+		if cfg.Level != Debug {
+			t.Errorf("applied log_level = %s, want debug", cfg.Level)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("nothing applied on SIGHUP")
+	}
+}
+
+func TestLevelVar(t *testing.T) {
+	l := NewLevelVar(Warn)
+	tests := []struct {
+		level Level
+		want  bool
+	}{
+		{Debug, false},
+		{Info, false},
+		{Warn, true},
+		{Error, true},
+	}
+	for _, tt := range tests {
+		if got := l.Enabled(tt.level); got != tt.want {
+			t.Errorf("Enabled(%s) at warn = %v, want %v", tt.level, got, tt.want)
+		}
+	}
+	l.Set(Debug)
+	if !l.Enabled(Debug) || l.Level().String() != "DEBUG" {
+		t.Errorf("Level() once set to debug = %s, want DEBUG", l.Level())
+	}
+}
@@ -0,0 +1,8 @@
+module mbcarruthers/config
+
+go 1.21
+
+require (
+	github.com/pelletier/go-toml/v2 v2.0.6
+	gopkg.in/yaml.v3 v3.0.1
+)
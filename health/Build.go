@@ -0,0 +1,50 @@
+package health
+
+import (
+	"runtime/debug"
+)
+
+var (
+	// Version of the service, set when building with -ldflags "-X mbcarruthers/health.Version=v1.2.0", taken from the
+	// build info otherwise.
+	Version = ""
+	// Commit the service was built from, set like Version or taken from the build info(vcs.revision).
+	Commit = ""
+)
+
+// Build returns the version and commit of the running binary, "dev" and "unknown" when neither is known.
+func Build() (version string, commit string) {
+	version, commit = Version, Commit
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if version == "" && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+		if commit == "" {
+			commit = revision(info.Settings)
+		}
+	}
+	if version == "" {
+		version = "dev"
+	}
+	if commit == "" {
+		commit = "unknown"
+	}
+	return version, commit
+}
+
+// revision returns the commit recorded by go build, suffixed with -dirty if the tree had uncommitted changes.
+func revision(settings []debug.BuildSetting) string {
+	commit, modified := "", false
+	for _, setting := range settings {
+		switch setting.Key {
+		case "vcs.revision":
+			commit = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if commit != "" && modified {
+		commit += "-dirty"
+	}
+	return commit
+}
@@ -0,0 +1,165 @@
+// Package health tells whether helio, imageserver and webServer are alive and ready to serve, and how their
+// dependencies are doing:
+//
+//	/healthz  liveness, 200 as long as the process serves HTTP
+//	/readyz   readiness, 200 once every dependency answers its check, 503 otherwise
+//	/status   version, commit, uptime and the latency of every dependency, as json or as a page
+//
+// A service constructs a Checker and adds a Check for every dependency, i.e. a database ping.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	Up   = "up"
+	Down = "down"
+
+	Ready       = "ready"
+	Unavailable = "unavailable"
+)
+
+// Check returns an error unless a dependency is usable, answering before ctx is done.
+type Check func(ctx context.Context) error
+
+// Dependency is the outcome of a Check.
+type Dependency struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Status of a service, as reported by /status.
+type Status struct {
+	Service       string       `json:"service"`
+	Status        string       `json:"status"`
+	Version       string       `json:"version"`
+	Commit        string       `json:"commit"`
+	GoVersion     string       `json:"go_version"`
+	Started       time.Time    `json:"started"`
+	Uptime        string       `json:"uptime"`
+	UptimeSeconds int64        `json:"uptime_seconds"`
+	Dependencies  []Dependency `json:"dependencies"`
+}
+
+// Checker checks the dependencies of a service, every Check being given at most Timeout.
+type Checker struct {
+	Service string
+	Timeout time.Duration
+
+	started time.Time
+	names   []string
+	checks  []Check
+}
+
+// NewChecker constructs a Checker of service, started now.
+func NewChecker(service string, timeout time.Duration) *Checker {
+	return &Checker{
+		Service: service,
+		Timeout: timeout,
+		started: time.Now(),
+	}
+}
+
+// Add adds the check of the dependency name. Checks are added before the Checker serves.
+func (h *Checker) Add(name string, check Check) {
+	h.names = append(h.names, name)
+	h.checks = append(h.checks, check)
+}
+
+// Check runs every check at once and returns their outcomes, in the order they were added.
+func (h *Checker) Check(ctx context.Context) []Dependency {
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
+	defer cancel()
+	dependencies := make([]Dependency, len(h.checks))
+	var wg sync.WaitGroup
+	for i := range h.checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			start := time.Now()
+			err := h.checks[i](ctx)
+			dependencies[i] = Dependency{
+				Name:      h.names[i],
+				Status:    Up,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				dependencies[i].Status, dependencies[i].Error = Down, err.Error()
+			}
+		}(i)
+	}
+	wg.Wait()
+	return dependencies
+}
+
+// Readiness returns Ready when every dependency is up, Unavailable otherwise.
+func Readiness(dependencies []Dependency) string {
+	for _, dependency := range dependencies {
+		if dependency.Status != Up {
+			return Unavailable
+		}
+	}
+	return Ready
+}
+
+// Status checks every dependency and returns the status of the service.
+func (h *Checker) Status(ctx context.Context) Status {
+	dependencies := h.Check(ctx)
+	version, commit := Build()
+	uptime := time.Since(h.started).Truncate(time.Second)
+	return Status{
+		Service:       h.Service,
+		Status:        Readiness(dependencies),
+		Version:       version,
+		Commit:        commit,
+		GoVersion:     runtime.Version(),
+		Started:       h.started.UTC(),
+		Uptime:        uptime.String(),
+		UptimeSeconds: int64(uptime.Seconds()),
+		Dependencies:  dependencies,
+	}
+}
+
+// HealthzHandler GET /healthz
+// Liveness, helio, imageserver and webServer answer as long as they serve HTTP.
+// Responses: 200 - alive
+func (h *Checker) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "alive"})
+}
+
+// ReadyzHandler GET /readyz
+// Readiness, every dependency is checked.
+// Responses: 200 - ready
+// 503 - a dependency is down
+func (h *Checker) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	dependencies := h.Check(r.Context())
+	status := Readiness(dependencies)
+	code := http.StatusOK
+	if status != Ready {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]any{"status": status, "dependencies": dependencies})
+}
+
+// StatusHandler GET /status
+// The status of the service, as a page when asked for text/html and as json otherwise.
+// Responses: 200 - the status, whether or not the service is ready
+func (h *Checker) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	WriteStatus(w, r, h.Status(r.Context()))
+}
+
+// writeJSON writes v as the json body of a response.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,164 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime/debug"
+	"strings"
+	"testing"
+	"time"
+)
+
+// up and down are checks of a dependency that is usable or not.
+func up(ctx context.Context) error { return nil }
+
+func down(ctx context.Context) error { return errors.New("err unreachable") }
+
+// hanging is the check of a dependency that never answers.
+func hanging(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []Check
+		want   string
+	}{
+		{"no dependencies", nil, Ready},
+		{"every dependency up", []Check{up, up}, Ready},
+		{"one down", []Check{up, down, up}, Unavailable},
+		{"every dependency down", []Check{down, down}, Unavailable},
+		{"one timed out", []Check{up, hanging}, Unavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker("helio", 20*time.Millisecond)
+			for i, check := range tt.checks {
+				checker.Add(string(rune('a'+i)), check)
+			}
+			dependencies := checker.Check(context.Background())
+			if got := Readiness(dependencies); got != tt.want {
+				t.Errorf("Readiness(%+v) = %s, want %s", dependencies, got, tt.want)
+			}
+
+			recorder := httptest.NewRecorder()
+			checker.ReadyzHandler(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			wantCode := http.StatusOK
+			if tt.want != Ready {
+				wantCode = http.StatusServiceUnavailable
+			}
+			var body struct {
+				Status       string       `json:"status"`
+				Dependencies []Dependency `json:"dependencies"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if recorder.Code != wantCode || body.Status != tt.want || len(body.Dependencies) != len(tt.checks) {
+				t.Errorf("GET /readyz = %d %s, want %d %s", recorder.Code, recorder.Body.String(), wantCode, tt.want)
+			}
+			if cacheControl := recorder.Header().Get("Cache-Control"); cacheControl != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", cacheControl)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	checker := NewChecker("helio", 50*time.Millisecond)
+	checker.Add("database", up)
+	checker.Add("cache", down)
+	checker.Add("search", hanging)
+	checker.Add("tiles", hanging)
+
+	start := time.Now()
+	dependencies := checker.Check(context.Background())
+	// the hanging checks run at once, both given the same timeout
+	if elapsed := time.Since(start); elapsed > 90*time.Millisecond {
+		t.Errorf("Check() took %v, want the checks run at once within the timeout", elapsed)
+	}
+	want := []Dependency{
+		{Name: "database", Status: Up},
+		{Name: "cache", Status: Down, Error: "err unreachable"},
+		{Name: "search", Status: Down, Error: context.DeadlineExceeded.Error()},
+		{Name: "tiles", Status: Down, Error: context.DeadlineExceeded.Error()},
+	}
+	if len(dependencies) != len(want) {
+		t.Fatalf("Check() = %+v, want %+v", dependencies, want)
+	}
+	for i := range want {
+		got := dependencies[i]
+		if got.Name != want[i].Name || got.Status != want[i].Status || got.Error != want[i].Error {
+			t.Errorf("Check()[%d] = %+v, want %+v", i, got, want[i])
+		}
+	}
+	if dependencies[2].LatencyMs < 50 {
+		t.Errorf("latency of a timed out check = %vms, want the timeout at least", dependencies[2].LatencyMs)
+	}
+}
+
+func TestStatusHandler(t *testing.T) {
+	checker := NewChecker("helio", time.Second)
+	checker.Add("database", up)
+	checker.Add("cache", down)
+	tests := []struct {
+		name     string
+		accept   string
+		wantHTML bool
+	}{
+		{"no accept", "", false},
+		{"json", "application/json", false},
+		{"browser", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", true},
+		{"json ahead of html", "application/json, text/html", false},
+		{"html ahead of json", "text/html;q=0.9, application/json", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/status", nil)
+			request.Header.Set("Accept", tt.accept)
+			recorder := httptest.NewRecorder()
+			checker.StatusHandler(recorder, request)
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("GET /status = %d, want 200 whether or not ready", recorder.Code)
+			}
+			if tt.wantHTML {
+				body := recorder.Body.String()
+				if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/html") ||
+					!strings.Contains(body, `<span class="unavailable">unavailable</span>`) || !strings.Contains(body, "err unreachable") {
+					t.Errorf("GET /status = %s, want the page of an unavailable service", body)
+				}
+				return
+			}
+			var status Status
+			if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+				t.Fatal(err)
+			}
+			if status.Service != "helio" || status.Status != Unavailable || len(status.Dependencies) != 2 || status.Version == "" {
+				t.Errorf("GET /status = %+v, want helio unavailable with both dependencies", status)
+			}
+		})
+	}
+}
+
+func TestRevision(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings []debug.BuildSetting
+		want     string
+	}{
+		{"none", nil, ""},
+		{"clean", []debug.BuildSetting{{Key: "vcs.revision", Value: "446fc5a"}, {Key: "vcs.modified", Value: "false"}}, "446fc5a"},
+		{"dirty", []debug.BuildSetting{{Key: "vcs.modified", Value: "true"}, {Key: "vcs.revision", Value: "446fc5a"}}, "446fc5a-dirty"},
+		{"modified without a revision", []debug.BuildSetting{{Key: "vcs.modified", Value: "true"}}, ""},
+	}
+	for _, tt := range tests {
+		if got := revision(tt.settings); got != tt.want {
+			t.Errorf("revision() of %s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
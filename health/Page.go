@@ -0,0 +1,68 @@
+package health
+
+import (
+	"html/template"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// page renders a Status for people, /status is json to anything not asking for text/html.
+var page = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Service}} status</title>
+<style>
+body { font-family: sans-serif; margin: 2rem; }
+table { border-collapse: collapse; margin-bottom: 1.5rem; }
+th, td { border: 1px solid #ccc; padding: .3rem .8rem; text-align: left; }
+.up, .ready { color: #1a7f37; }
+.down, .unavailable, .draining { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>{{.Service}} <span class="{{.Status}}">{{.Status}}</span></h1>
+<table>
+<tr><th>Version</th><td>{{.Version}}</td></tr>
+<tr><th>Commit</th><td>{{.Commit}}</td></tr>
+<tr><th>Go</th><td>{{.GoVersion}}</td></tr>
+<tr><th>Started</th><td>{{.Started.Format "2006-01-02 15:04:05 MST"}}</td></tr>
+<tr><th>Uptime</th><td>{{.Uptime}}</td></tr>
+</table>
+<table>
+<tr><th>Dependency</th><th>Status</th><th>Latency(ms)</th><th>Error</th></tr>
+{{range .Dependencies}}<tr><td>{{.Name}}</td><td class="{{.Status}}">{{.Status}}</td><td>{{printf "%.2f" .LatencyMs}}</td><td>{{.Error}}</td></tr>
+{{else}}<tr><td colspan="4">none</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteStatus writes status as a page when r asks for text/html ahead of json, as json otherwise.
+func WriteStatus(w http.ResponseWriter, r *http.Request, status Status) {
+	if !wantsHTML(r.Header.Get("Accept")) {
+		writeJSON(w, http.StatusOK, status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = page.Execute(w, status)
+}
+
+// wantsHTML reports whether the Accept header lists text/html before application/json, as browsers do.
+func wantsHTML(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/html":
+			return true
+		case "application/json":
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,3 @@
+module mbcarruthers/health
+
+go 1.19
@@ -0,0 +1,40 @@
+// Package apikey provides API keys for partners along with per-key token bucket rate limits and daily quotas.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/google/uuid"
+	"mbcarruthers/helio/model"
+	"time"
+)
+
+const (
+	keyPrefix    = "helio_"
+	prefixLength = len(keyPrefix) + 6 // characters of a key kept in the clear to tell keys apart
+)
+
+// Store is where keys and their usage are kept, implemented by db.DataStore.
+type Store interface {
+	GetApiKeyByHash(hash []byte, ctx context.Context) (model.ApiKey, error)
+	AddApiKeyUsage(id uuid.UUID, day time.Time, requests int64, ctx context.Context) error
+	GetApiKeyUsageOn(id uuid.UUID, day time.Time, ctx context.Context) (int64, error)
+}
+
+// Generate returns a new random key, its hash and the prefix stored along with it.
+func Generate() (key string, hash []byte, prefix string, err error) {
+	random := make([]byte, 32)
+	if _, err = rand.Read(random); err != nil {
+		return "", nil, "", err
+	}
+	key = keyPrefix + base64.RawURLEncoding.EncodeToString(random)
+	return key, Hash(key), key[:prefixLength], nil
+}
+
+// Hash returns the hash a key is stored and looked up by. Keys are random and long, so a plain SHA-256 will do.
+func Hash(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
@@ -0,0 +1,264 @@
+package apikey
+
+import (
+	"context"
+	"golang.org/x/sync/singleflight"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	loadBackoff = 30 * time.Second // wait after a failed read of a key's usage before it is read again
+)
+
+// Limits of a single caller.
+type Limits struct {
+	RatePerSecond float64
+	Burst         int
+	DailyQuota    int64 // 0 is unlimited
+}
+
+// bucket is a token bucket refilled at RatePerSecond up to Burst tokens.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // when the bucket is full again, from then on it is the same as a new bucket
+}
+
+// take refills the bucket and takes a token if there is one. It returns whether a token was taken, the tokens left
+// and how long until the bucket is full again.
+func (b *bucket) take(limits Limits, now time.Time) (bool, int, time.Duration) {
+	b.tokens = math.Min(float64(limits.Burst), b.tokens+now.Sub(b.updated).Seconds()*limits.RatePerSecond)
+	b.updated = now
+	taken := b.tokens >= 1
+	if taken {
+		b.tokens--
+	}
+	full := time.Duration((float64(limits.Burst) - b.tokens) / limits.RatePerSecond * float64(time.Second))
+	b.full = now.Add(full)
+	return taken, int(b.tokens), full
+}
+
+// quota counts the requests of a single key on the current day.
+type quota struct {
+	day     time.Time
+	used    int64     // requests on day, persisted or not
+	pending int64     // requests on day not yet persisted
+	loaded  bool      // used was read from the Store
+	retry   time.Time // when used is read again after a failed read
+}
+
+// usage is a number of requests of a key on a day, to be added to the Store.
+type usage struct {
+	caller   string
+	day      time.Time
+	requests int64
+}
+
+// Decision is the outcome of Limiter.Allow, describing the most restrictive of the caller's limits.
+type Decision struct {
+	Allowed   bool
+	Limit     int64         // RateLimit-Limit
+	Remaining int64         // RateLimit-Remaining
+	Reset     time.Duration // RateLimit-Reset
+	Policy    string        // RateLimit-Policy
+	Quota     bool          // the daily quota, rather than the rate, is the limit described
+}
+
+// Limiter keeps a token bucket for every caller and the daily quota of every key.
+// Usage is counted in memory and written to the Store by Flush. With several replicas every replica keeps its own
+// buckets, and quotas are only as exact as the last Flush of each replica.
+// The Store is never used while l.mu is held, so a slow database holds up the keys waiting on it and no other caller.
+type Limiter struct {
+	store Store
+	now   func() time.Time
+	loads singleflight.Group // reads of a key's usage, one at a time per key
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	quotas  map[string]*quota
+}
+
+// NewLimiter constructs a Limiter persisting usage to the store.
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{
+		store:   store,
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+		quotas:  map[string]*quota{},
+	}
+}
+
+// Allow decides whether the caller may make a request. Callers with a daily quota are counted towards it,
+// a request refused by the rate limit does not count.
+func (l *Limiter) Allow(caller string, limits Limits, ctx context.Context) Decision {
+	now := l.now().UTC()
+	if limits.DailyQuota > 0 {
+		l.load(caller, now, ctx)
+	}
+	l.mu.Lock()
+	decision, rolled := l.allow(caller, limits, now)
+	l.mu.Unlock()
+	if rolled.requests != 0 {
+		l.persist(rolled, ctx) // the day rolled over before the last Flush
+	}
+	return decision
+}
+
+// allow is Allow once the caller's usage is loaded, along with the usage of the previous day left to persist when the
+// day rolled over. The caller holds l.mu.
+func (l *Limiter) allow(caller string, limits Limits, now time.Time) (Decision, usage) {
+	b, ok := l.buckets[caller]
+	if !ok {
+		b = &bucket{tokens: float64(limits.Burst), updated: now}
+		l.buckets[caller] = b
+	}
+	taken, tokens, full := b.take(limits, now)
+	decision := Decision{
+		Allowed:   taken,
+		Limit:     int64(limits.Burst),
+		Remaining: int64(tokens),
+		Reset:     full,
+		Policy:    policy(limits),
+	}
+	if !taken {
+		decision.Reset = time.Duration((1 - math.Mod(float64(tokens), 1)) / limits.RatePerSecond * float64(time.Second))
+		return decision, usage{}
+	}
+	if limits.DailyQuota <= 0 {
+		return decision, usage{}
+	}
+
+	q, rolled := l.quota(caller, now)
+	remaining := limits.DailyQuota - q.used
+	if remaining <= 0 {
+		b.tokens++ // give back the token, the request is refused anyway
+		return Decision{Allowed: false, Limit: limits.DailyQuota, Remaining: 0, Reset: q.day.Add(24 * time.Hour).Sub(now), Policy: decision.Policy, Quota: true}, rolled
+	}
+	q.used++
+	q.pending++
+	if remaining-1 < decision.Remaining {
+		decision = Decision{Allowed: true, Limit: limits.DailyQuota, Remaining: remaining - 1, Reset: q.day.Add(24 * time.Hour).Sub(now), Policy: decision.Policy, Quota: true}
+	}
+	return decision, rolled
+}
+
+// quota returns the caller's quota of the current day, along with the pending usage of the previous day when the day
+// rolled over. The caller holds l.mu.
+func (l *Limiter) quota(caller string, now time.Time) (*quota, usage) {
+	day := now.Truncate(24 * time.Hour)
+	q, ok := l.quotas[caller]
+	if ok && q.day.Equal(day) {
+		return q, usage{}
+	}
+	var rolled usage
+	if ok {
+		rolled = usage{caller: caller, day: q.day, requests: q.pending}
+	}
+	q = &quota{day: day}
+	l.quotas[caller] = q
+	return q, rolled
+}
+
+// load reads the caller's usage of the current day from the Store, unless it was read already. Concurrent requests of
+// a key share a single read, a failed read is tried again once loadBackoff passed and meanwhile the quota counts the
+// requests made since.
+func (l *Limiter) load(caller string, now time.Time, ctx context.Context) {
+	l.mu.Lock()
+	q, rolled := l.quota(caller, now)
+	day, wanted := q.day, !q.loaded && !now.Before(q.retry)
+	l.mu.Unlock()
+	if rolled.requests != 0 {
+		l.persist(rolled, ctx)
+	}
+	if !wanted {
+		return
+	}
+	// Note: The read is not cancelled along with the request starting it, other requests of the key wait on it too.
+	_, _, _ = l.loads.Do(caller+"@"+day.Format(time.DateOnly), func() (any, error) {
+		used, err := l.read(caller, day, context.WithoutCancel(ctx))
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		q, ok := l.quotas[caller]
+		if !ok || !q.day.Equal(day) || q.loaded {
+			return nil, nil
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Error reading usage of api key", "caller", caller, "error", err)
+			q.retry = l.now().UTC().Add(loadBackoff)
+			return nil, err
+		}
+		q.used += used
+		q.loaded = true
+		return nil, nil
+	})
+}
+
+// read returns the usage of the caller's key on day, callers other than keys have none.
+func (l *Limiter) read(caller string, day time.Time, ctx context.Context) (int64, error) {
+	id, err := parseId(caller)
+	if err != nil {
+		return 0, nil
+	}
+	return l.store.GetApiKeyUsageOn(id, day, ctx)
+}
+
+// Flush writes every pending usage to the Store and forgets the buckets that are full again.
+// Usage that fails to be written is pending again for the next Flush, unless its day is over.
+func (l *Limiter) Flush(ctx context.Context) {
+	l.mu.Lock()
+	pending := make([]usage, 0)
+	for caller, q := range l.quotas {
+		if q.pending != 0 {
+			pending = append(pending, usage{caller: caller, day: q.day, requests: q.pending})
+			q.pending = 0
+		}
+	}
+	now := l.now().UTC()
+	for caller, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, caller)
+		}
+	}
+	l.mu.Unlock()
+
+	for _, u := range pending {
+		if err := l.persist(u, ctx); err != nil {
+			l.mu.Lock()
+			if q, ok := l.quotas[u.caller]; ok && q.day.Equal(u.day) {
+				q.pending += u.requests
+			}
+			l.mu.Unlock()
+		}
+	}
+}
+
+// persist adds usage to the Store.
+func (l *Limiter) persist(u usage, ctx context.Context) error {
+	id, err := parseId(u.caller)
+	if err != nil {
+		return nil
+	}
+	if err = l.store.AddApiKeyUsage(id, u.day, u.requests, ctx); err != nil {
+		slog.ErrorContext(ctx, "Error persisting usage of api key", "caller", u.caller, "error", err)
+		return err
+	}
+	return nil
+}
+
+// Run flushes usage every interval until ctx is done, then flushes one last time.
+func (l *Limiter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.Flush(ctx)
+		case <-ctx.Done():
+			l.Flush(context.Background())
+			return
+		}
+	}
+}
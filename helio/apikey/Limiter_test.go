@@ -0,0 +1,140 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"mbcarruthers/helio/model"
+	"sync"
+	"testing"
+	"time"
+)
+
+// usageStore keeps the usage of keys by day, its writes failing while failing is set.
+type usageStore struct {
+	mu      sync.Mutex
+	usage   map[string]int64 // by day
+	failing bool
+}
+
+func (s *usageStore) GetApiKeyByHash(hash []byte, ctx context.Context) (model.ApiKey, error) {
+	return model.ApiKey{}, errors.New("err not found")
+}
+
+func (s *usageStore) AddApiKeyUsage(id uuid.UUID, day time.Time, requests int64, ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failing {
+		return errors.New("err execute")
+	}
+	s.usage[day.Format(time.DateOnly)] += requests
+	return nil
+}
+
+func (s *usageStore) GetApiKeyUsageOn(id uuid.UUID, day time.Time, ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage[day.Format(time.DateOnly)], nil
+}
+
+func TestLimiterDayRollover(t *testing.T) {
+	at := func(day int, clock string) time.Time {
+		hms, err := time.Parse(time.TimeOnly, clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return time.Date(2023, 5, day, hms.Hour(), hms.Minute(), hms.Second(), 0, time.UTC)
+	}
+	// step is a request at a time, or a Flush, made while the writes of the Store fail or not
+	type step struct {
+		at            time.Time
+		flush         bool
+		failing       bool
+		wantAllowed   bool
+		wantRemaining int64
+	}
+	tests := []struct {
+		name   string
+		stored map[string]int64
+		steps  []step
+		want   map[string]int64 // usage stored in the end
+	}{
+		{"previous day persisted on rollover", map[string]int64{"2023-05-01": 1}, []step{
+			{at: at(1, "23:59:00"), wantAllowed: true, wantRemaining: 1},
+			{at: at(1, "23:59:30"), wantAllowed: true, wantRemaining: 0},
+			{at: at(1, "23:59:50"), wantAllowed: false, wantRemaining: 0},
+			{at: at(2, "00:00:10"), wantAllowed: true, wantRemaining: 2},
+			{at: at(2, "00:01:00"), flush: true},
+		}, map[string]int64{"2023-05-01": 3, "2023-05-02": 1}},
+		{"flushed before rollover", nil, []step{
+			{at: at(1, "23:59:00"), wantAllowed: true, wantRemaining: 2},
+			{at: at(1, "23:59:30"), flush: true},
+			{at: at(2, "00:00:10"), wantAllowed: true, wantRemaining: 2},
+			{at: at(2, "00:01:00"), flush: true},
+		}, map[string]int64{"2023-05-01": 1, "2023-05-02": 1}},
+		{"usage of the new day read", map[string]int64{"2023-05-02": 2}, []step{
+			{at: at(1, "23:59:00"), wantAllowed: true, wantRemaining: 2},
+			{at: at(2, "00:00:10"), wantAllowed: true, wantRemaining: 0},
+			{at: at(2, "00:00:20"), wantAllowed: false, wantRemaining: 0},
+			{at: at(2, "00:01:00"), flush: true},
+		}, map[string]int64{"2023-05-01": 1, "2023-05-02": 3}},
+		{"quota exhausted until the next day", map[string]int64{"2023-05-01": 3}, []step{
+			{at: at(1, "12:00:00"), wantAllowed: false, wantRemaining: 0},
+			{at: at(1, "23:59:59"), wantAllowed: false, wantRemaining: 0},
+			{at: at(2, "00:00:00"), wantAllowed: true, wantRemaining: 2},
+			{at: at(2, "00:01:00"), flush: true},
+		}, map[string]int64{"2023-05-01": 3, "2023-05-02": 1}},
+		{"failed flush pending again", nil, []step{
+			{at: at(1, "12:00:00"), wantAllowed: true, wantRemaining: 2},
+			{at: at(1, "12:01:00"), flush: true, failing: true},
+			{at: at(1, "12:02:00"), wantAllowed: true, wantRemaining: 1},
+			{at: at(1, "12:03:00"), flush: true},
+		}, map[string]int64{"2023-05-01": 2}},
+		{"failed usage of a day over dropped", nil, []step{
+			{at: at(1, "23:59:00"), wantAllowed: true, wantRemaining: 2},
+			{at: at(1, "23:59:30"), flush: true, failing: true},
+			{at: at(2, "00:00:10"), failing: true, wantAllowed: true, wantRemaining: 2},
+			{at: at(2, "00:01:00"), flush: true},
+		}, map[string]int64{"2023-05-02": 1}},
+	}
+	limits := Limits{RatePerSecond: 100, Burst: 100, DailyQuota: 3}
+	caller := uuid.NewString()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &usageStore{usage: map[string]int64{}}
+			for day, requests := range tt.stored {
+				store.usage[day] = requests
+			}
+			l := NewLimiter(store)
+			ctx := context.Background()
+			for i, step := range tt.steps {
+				store.mu.Lock()
+				store.failing = step.failing
+				store.mu.Unlock()
+				l.now = func() time.Time { return step.at }
+				if step.flush {
+					l.Flush(ctx)
+					continue
+				}
+				decision := l.Allow(caller, limits, ctx)
+				if decision.Allowed != step.wantAllowed || decision.Remaining != step.wantRemaining {
+					t.Errorf("step %d Allow() = %v with %d remaining, want %v with %d",
+						i, decision.Allowed, decision.Remaining, step.wantAllowed, step.wantRemaining)
+				}
+				wantReset := step.at.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(step.at)
+				if !decision.Quota || decision.Limit != limits.DailyQuota || decision.Reset != wantReset {
+					t.Errorf("step %d Allow() = %+v, want the quota resetting in %v", i, decision, wantReset)
+				}
+			}
+			if len(store.usage) != len(tt.want) {
+				t.Fatalf("usage stored = %v, want %v", store.usage, tt.want)
+			}
+			for day, requests := range tt.want {
+				if store.usage[day] != requests {
+					t.Errorf("usage stored = %v, want %v", store.usage, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
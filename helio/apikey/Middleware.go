@@ -0,0 +1,160 @@
+package apikey
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"mbcarruthers/helio/auth"
+	"mbcarruthers/helio/model"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Header carrying the API key of a request.
+	Header = "X-API-Key"
+	// ContextKey is the gin.Context key of the model.ApiKey a request was made with.
+	ContextKey = "apikey.key"
+
+	cacheTTL = time.Minute // how long a looked up key is trusted before it is looked up again, i.e. revoked
+)
+
+// DefaultAnonymousLimits are the limits of callers without a key, counted by client ip.
+func DefaultAnonymousLimits() Limits {
+	return Limits{RatePerSecond: 5, Burst: 20}
+}
+
+type cachedKey struct {
+	key     model.ApiKey
+	expires time.Time
+}
+
+// Middleware enforces the limits of API keys in front of a group of routes.
+// Requests with a key are limited by the key's limits, requests with a bearer token are not limited and any other
+// request is limited by the anonymous limits of its client ip.
+type Middleware struct {
+	store     Store
+	limiter   *Limiter
+	anonymous Limits
+	notFound  error // the Store's not found error
+
+	mu    sync.Mutex
+	cache map[string]cachedKey
+}
+
+// NewMiddleware constructs a Middleware. notFound is the error the Store returns for unknown keys.
+func NewMiddleware(store Store, limiter *Limiter, anonymous Limits, notFound error) *Middleware {
+	return &Middleware{
+		store:     store,
+		limiter:   limiter,
+		anonymous: anonymous,
+		notFound:  notFound,
+		cache:     map[string]cachedKey{},
+	}
+}
+
+// Handler returns the gin middleware.
+func (m *Middleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller, limits := "ip:"+c.ClientIP(), m.anonymous
+		if raw := c.GetHeader(Header); raw != "" {
+			key, err := m.lookup(c, raw)
+			if errors.Is(err, m.notFound) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"error":   "err unauthorized",
+					"message": "invalid api key",
+				})
+				return
+			} else if err != nil {
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+					"error":   err.Error(),
+					"message": "could not check api key",
+				})
+				return
+			}
+			c.Set(ContextKey, key)
+			caller, limits = key.Id.String(), Limits{RatePerSecond: key.RatePerSecond, Burst: key.Burst, DailyQuota: key.DailyQuota}
+		} else if _, ok := auth.GetIdentity(c); ok {
+			c.Next()
+			return
+		}
+
+		decision := m.limiter.Allow(caller, limits, c.Request.Context())
+		c.Header("RateLimit-Limit", strconv.FormatInt(decision.Limit, 10))
+		c.Header("RateLimit-Remaining", strconv.FormatInt(decision.Remaining, 10))
+		c.Header("RateLimit-Reset", strconv.Itoa(seconds(decision.Reset)))
+		c.Header("RateLimit-Policy", decision.Policy)
+		if !decision.Allowed {
+			c.Header("Retry-After", strconv.Itoa(seconds(decision.Reset)))
+			message := "rate limit exceeded"
+			if decision.Quota {
+				message = "daily quota exceeded"
+			}
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":   "err too many requests",
+				"message": message,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// lookup returns the key, from the cache when it was looked up less than cacheTTL ago.
+func (m *Middleware) lookup(c *gin.Context, raw string) (model.ApiKey, error) {
+	hash := Hash(raw)
+	m.mu.Lock()
+	cached, ok := m.cache[string(hash)]
+	m.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.key, nil
+	}
+	key, err := m.store.GetApiKeyByHash(hash, c.Request.Context())
+	if err != nil {
+		return model.ApiKey{}, err
+	}
+	m.mu.Lock()
+	m.cache[string(hash)] = cachedKey{key: key, expires: time.Now().Add(cacheTTL)}
+	m.mu.Unlock()
+	return key, nil
+}
+
+// Forget drops a key from the cache, i.e. once it is revoked.
+func (m *Middleware) Forget(id uuid.UUID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for hash, cached := range m.cache {
+		if cached.key.Id == id {
+			delete(m.cache, hash)
+		}
+	}
+}
+
+// policy describes limits as a RateLimit-Policy header, i.e. 20;w=4, 1000;w=86400
+func policy(limits Limits) string {
+	window := int(float64(limits.Burst)/limits.RatePerSecond + 0.5)
+	if window < 1 {
+		window = 1
+	}
+	p := fmt.Sprintf("%d;w=%d", limits.Burst, window)
+	if limits.DailyQuota > 0 {
+		p += fmt.Sprintf(", %d;w=86400", limits.DailyQuota)
+	}
+	return p
+}
+
+func parseId(caller string) (uuid.UUID, error) {
+	return uuid.Parse(caller)
+}
+
+// seconds rounds a duration up to whole seconds.
+func seconds(d time.Duration) int {
+	s := int((d + time.Second - 1) / time.Second)
+	if s < 0 {
+		return 0
+	}
+	return s
+}
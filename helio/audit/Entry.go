@@ -0,0 +1,117 @@
+// Package audit records every mutation of an observation(Entity) along with who made it and what changed.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/helio/auth"
+	"mbcarruthers/helio/model"
+	"mbcarruthers/helio/requestid"
+	"sort"
+	"time"
+)
+
+const (
+	// SystemActor is the actor of mutations helio makes on its own, i.e. the import of data/monarch.json
+	SystemActor = "system"
+	// Anonymous is the actor of requests without an identity
+	Anonymous = "anonymous"
+)
+
+// Caller is whoever made a mutation, over REST or gRPC.
+type Caller struct {
+	Actor     string
+	RequestId string
+	ClientIp  string
+}
+
+// CallerOf returns the Caller of the request. Its ClientIp is the address the request came from, or the one named by
+// X-Forwarded-For when it came through one of the engine's trusted proxies(gin.Engine.SetTrustedProxies).
+func CallerOf(c *gin.Context) Caller {
+	return Caller{Actor: Actor(c), RequestId: requestid.Get(c), ClientIp: c.ClientIP()}
+}
+
+// Entry returns the entry of a mutation made by the caller, before or after are nil when the Entity did not exist.
+func (c Caller) Entry(action string, entityId int, before *model.Entity, after *model.Entity) model.AuditEntry {
+	return model.AuditEntry{
+		At:        time.Now().UTC(),
+		Action:    action,
+		EntityId:  entityId,
+		Actor:     c.Actor,
+		RequestId: c.RequestId,
+		ClientIp:  c.ClientIp,
+		Changes:   Diff(before, after),
+		Before:    before,
+		After:     after,
+	}
+}
+
+// Entry returns the entry of a mutation made by the request.
+func Entry(c *gin.Context, action string, entityId int, before *model.Entity, after *model.Entity) model.AuditEntry {
+	return CallerOf(c).Entry(action, entityId, before, after)
+}
+
+// System returns the entry of a mutation helio made on its own.
+func System(action string, entityId int, before *model.Entity, after *model.Entity) model.AuditEntry {
+	return Caller{Actor: SystemActor}.Entry(action, entityId, before, after)
+}
+
+// Actor names the caller of the request, i.e. "curator:1234 (Jane Doe)", "apikey:helio_AbCdEf" or "anonymous"
+func Actor(c *gin.Context) string {
+	if identity, ok := auth.GetIdentity(c); ok {
+		return IdentityActor(identity)
+	}
+	return Anonymous
+}
+
+// IdentityActor names an authenticated caller, i.e. "curator:1234 (Jane Doe)"
+func IdentityActor(identity auth.Identity) string {
+	actor := string(identity.Role) + ":" + identity.Subject
+	if identity.Name != "" {
+		actor += " (" + identity.Name + ")"
+	}
+	return actor
+}
+
+// Diff returns every field whose json value differs between before and after, either may be nil.
+func Diff(before *model.Entity, after *model.Entity) []model.FieldChange {
+	b, a := fields(before), fields(after)
+	names := make(map[string]bool, len(b)+len(a))
+	for name := range b {
+		names[name] = true
+	}
+	for name := range a {
+		names[name] = true
+	}
+	changes := []model.FieldChange{}
+	for name := range names {
+		if !bytes.Equal(b[name], a[name]) {
+			changes = append(changes, model.FieldChange{Field: name, Before: orNull(b[name]), After: orNull(a[name])})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// fields returns the json value of every field of the entity.
+func fields(entity *model.Entity) map[string]json.RawMessage {
+	result := map[string]json.RawMessage{}
+	if entity == nil {
+		return result
+	}
+	encoded, err := json.Marshal(entity)
+	if err != nil {
+		return result
+	}
+	_ = json.Unmarshal(encoded, &result)
+	delete(result, "coordinates_obscured") // a property of a response, never of the stored entity
+	return result
+}
+
+func orNull(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+	return value
+}
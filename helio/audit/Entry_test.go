@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCallerOfClientIp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name      string
+		trusted   []string
+		forwarded string
+		want      string
+	}{
+		{"no proxy trusted", nil, "203.0.113.7", "10.0.0.2"},
+		{"trusted proxy", []string{"10.0.0.0/8"}, "203.0.113.7", "203.0.113.7"},
+		{"trusted proxy without X-Forwarded-For", []string{"10.0.0.2"}, "", "10.0.0.2"},
+		{"another proxy trusted", []string{"192.168.0.1"}, "203.0.113.7", "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			if err := r.SetTrustedProxies(tt.trusted); err != nil {
+				t.Fatal(err)
+			}
+			var caller Caller
+			r.POST("/entities/", func(c *gin.Context) {
+				caller = CallerOf(c)
+				c.Status(http.StatusOK)
+			})
+			request := httptest.NewRequest(http.MethodPost, "/entities/", nil)
+			request.RemoteAddr = "10.0.0.2:41234"
+			if tt.forwarded != "" {
+				request.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), request)
+			if caller.ClientIp != tt.want {
+				t.Errorf("ClientIp = %q, want %q", caller.ClientIp, tt.want)
+			}
+			if caller.Actor != Anonymous {
+				t.Errorf("Actor = %q, want %q", caller.Actor, Anonymous)
+			}
+		})
+	}
+}
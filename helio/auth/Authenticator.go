@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"strings"
+)
+
+const (
+	identityKey = "auth.identity" // gin.Context key of the caller's Identity
+)
+
+// validMethods are the signing algorithms accepted, anything symmetric or unsigned is refused.
+var validMethods = []string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Claims are the claims of a helio token. Roles holds any of viewer, curator or admin.
+type Claims struct {
+	Roles []string `json:"roles"`
+	Name  string   `json:"name,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	Subject string `json:"subject"`
+	Name    string `json:"name,omitempty"`
+	Role    Role   `json:"role"`
+}
+
+// Authenticator validates bearer tokens against a KeySource, and session cookies once UseSessions is called.
+type Authenticator struct {
+	keys     KeySource
+	parser   *jwt.Parser
+	sessions *Sessions
+}
+
+// NewAuthenticator constructs an Authenticator. Empty issuer or audience are not checked.
+// A nil KeySource refuses every token.
+func NewAuthenticator(keys KeySource, issuer string, audience string) *Authenticator {
+	options := []jwt.ParserOption{jwt.WithValidMethods(validMethods), jwt.WithExpirationRequired()}
+	if issuer != "" {
+		options = append(options, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		options = append(options, jwt.WithAudience(audience))
+	}
+	return &Authenticator{
+		keys:   keys,
+		parser: jwt.NewParser(options...),
+	}
+}
+
+// UseSessions lets requests without a bearer token authenticate through their session cookie.
+func (a *Authenticator) UseSessions(sessions *Sessions) {
+	a.sessions = sessions
+}
+
+// Verify parses and validates a token, returning the Identity it carries.
+func (a *Authenticator) Verify(token string) (Identity, error) {
+	var claims Claims
+	keyfunc := func(t *jwt.Token) (any, error) {
+		if a.keys == nil {
+			return nil, jwt.ErrTokenUnverifiable
+		}
+		return a.keys.Key(t)
+	}
+	if _, err := a.parser.ParseWithClaims(token, &claims, keyfunc); err != nil {
+		return Identity{}, err
+	}
+	return Identity{
+		Subject: claims.Subject,
+		Name:    claims.Name,
+		Role:    highest(claims.Roles),
+	}, nil
+}
+
+// Authenticate is middleware verifying the bearer token of a request, if there is one, or else its session.
+// Requests without either continue anonymously, requests with an invalid token are refused with 401.
+func (a *Authenticator) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			if a.sessions != nil {
+				if identity, ok := a.sessions.Identity(c); ok {
+					SetIdentity(c, identity)
+				}
+			}
+			c.Next()
+			return
+		}
+		scheme, token, _ := strings.Cut(header, " ")
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "err unauthorized",
+				"message": "authorization must be a bearer token",
+			})
+			return
+		}
+		identity, err := a.Verify(strings.TrimSpace(token))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "err unauthorized",
+				"message": "invalid token",
+			})
+			return
+		}
+		SetIdentity(c, identity)
+		c.Next()
+	}
+}
+
+// SetIdentity stores the caller's Identity on the request.
+func SetIdentity(c *gin.Context, identity Identity) {
+	c.Set(identityKey, identity)
+}
+
+// GetIdentity returns the caller's Identity and false for anonymous callers.
+func GetIdentity(c *gin.Context) (Identity, bool) {
+	value, ok := c.Get(identityKey)
+	if !ok {
+		return Identity{}, false
+	}
+	identity, ok := value.(Identity)
+	return identity, ok
+}
+
+// Policy maps routes to the Role required to use them. Routes are written as "METHOD /full/path" the same way
+// they are registered with gin, i.e. "DELETE /entities/:id". Routes not found within a Policy are public.
+type Policy map[string]Role
+
+// Authorize is middleware enforcing a Policy. Anonymous callers of a protected route are refused with 401,
+// callers without the required Role with 403.
+func Authorize(policy Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		required, ok := policy[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			c.Next()
+			return
+		}
+		Require(required)(c)
+	}
+}
+
+// Require is middleware refusing any caller without the required Role.
+func Require(required Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := GetIdentity(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "err unauthorized",
+				"message": "authentication required",
+			})
+			return
+		}
+		if !identity.Role.Allows(required) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "err forbidden",
+				"message": "requires the " + string(required) + " role",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Grant is middleware setting key to true on the requests of callers with the given Role,
+// i.e. Grant(geoprivacy.TrustedKey, Curator) lets curators see true coordinates.
+func Grant(key string, role Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if identity, ok := GetIdentity(c); ok && identity.Role.Allows(role) {
+			c.Set(key, true)
+		}
+		c.Next()
+	}
+}
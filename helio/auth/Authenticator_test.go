@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// claims returns the claims Signer.Mint would, valid for ttl.
+func claims(issuer string, audience string, ttl time.Duration, roles ...string) Claims {
+	now := time.Now()
+	return Claims{
+		Roles: roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "tester",
+			Issuer:    issuer,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+}
+
+func TestVerify(t *testing.T) {
+	signer, err := NewSigner("helio", "helio-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewSigner("helio", "helio-api") // same issuer and audience, another key
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := NewAuthenticator(signer.Verifier(), "helio", "helio-api")
+
+	sign := func(signer *Signer, claims Claims) string {
+		unsigned := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+		unsigned.Header["kid"] = signer.kid
+		token, err := unsigned.SignedString(signer.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	hmac, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims("helio", "helio-api", time.Hour, "admin")).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims("helio", "helio-api", time.Hour, "admin")).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutExpiry := claims("helio", "helio-api", time.Hour, "admin")
+	withoutExpiry.ExpiresAt = nil
+
+	tests := []struct {
+		name     string
+		token    string
+		wantErr  error // nil when the token is valid
+		wantRole Role
+	}{
+		{"valid", sign(signer, claims("helio", "helio-api", time.Hour, "curator")), nil, Curator},
+		{"highest of several roles", sign(signer, claims("helio", "helio-api", time.Hour, "viewer", "admin", "curator")), nil, Admin},
+		{"unknown role", sign(signer, claims("helio", "helio-api", time.Hour, "owner")), nil, ""},
+		{"expired", sign(signer, claims("helio", "helio-api", -time.Minute, "admin")), jwt.ErrTokenExpired, ""},
+		{"without expiry", sign(signer, withoutExpiry), jwt.ErrTokenRequiredClaimMissing, ""},
+		{"wrong issuer", sign(signer, claims("someone-else", "helio-api", time.Hour, "admin")), jwt.ErrTokenInvalidIssuer, ""},
+		{"wrong audience", sign(signer, claims("helio", "another-api", time.Hour, "admin")), jwt.ErrTokenInvalidAudience, ""},
+		{"another key", sign(other, claims("helio", "helio-api", time.Hour, "admin")), jwt.ErrTokenSignatureInvalid, ""},
+		{"hmac", hmac, jwt.ErrTokenSignatureInvalid, ""},
+		{"alg none", none, jwt.ErrTokenSignatureInvalid, ""},
+		{"malformed", "not.a.token", jwt.ErrTokenMalformed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := authenticator.Verify(tt.token)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Verify() error = %v, want nil", err)
+				}
+				if identity.Subject != "tester" || identity.Role != tt.wantRole {
+					t.Errorf("Verify() = %+v, want subject tester and role %q", identity, tt.wantRole)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyWithoutKeys(t *testing.T) {
+	signer, err := NewSigner("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := signer.Mint("tester", time.Hour, Admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewAuthenticator(nil, "", "").Verify(token); err == nil {
+		t.Error("Verify() without a KeySource accepted a token")
+	}
+}
+
+// policy is the role gate of the mutating entity routes, the same as helio's routePolicy.
+var policy = Policy{
+	"POST /entities/":            Curator,
+	"PUT /entities/:id":          Curator,
+	"DELETE /entities/:id":       Admin,
+	"POST /entities/:id/restore": Admin,
+}
+
+func TestAuthorize(t *testing.T) {
+	signer, err := NewSigner("helio", "helio-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := NewAuthenticator(signer.Verifier(), "helio", "helio-api")
+	r := gin.New()
+	r.Use(authenticator.Authenticate(), Authorize(policy))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/entities/:id", ok)
+	r.POST("/entities/", ok)
+	r.PUT("/entities/:id", ok)
+	r.DELETE("/entities/:id", ok)
+	r.POST("/entities/:id/restore", ok)
+
+	tokens := map[Role]string{}
+	for _, role := range []Role{Viewer, Curator, Admin} {
+		if tokens[role], err = signer.Mint("tester", time.Hour, role); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		method, path string
+		role         Role // "" is anonymous
+		want         int
+	}{
+		{http.MethodGet, "/entities/1", "", http.StatusOK},
+		{http.MethodPost, "/entities/", "", http.StatusUnauthorized},
+		{http.MethodPost, "/entities/", Viewer, http.StatusForbidden},
+		{http.MethodPost, "/entities/", Curator, http.StatusOK},
+		{http.MethodPost, "/entities/", Admin, http.StatusOK},
+		{http.MethodPut, "/entities/1", "", http.StatusUnauthorized},
+		{http.MethodPut, "/entities/1", Viewer, http.StatusForbidden},
+		{http.MethodPut, "/entities/1", Curator, http.StatusOK},
+		{http.MethodDelete, "/entities/1", "", http.StatusUnauthorized},
+		{http.MethodDelete, "/entities/1", Curator, http.StatusForbidden},
+		{http.MethodDelete, "/entities/1", Admin, http.StatusOK},
+		{http.MethodPost, "/entities/1/restore", Curator, http.StatusForbidden},
+		{http.MethodPost, "/entities/1/restore", Admin, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path+" as "+string(tt.role), func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.role != "" {
+				request.Header.Set("Authorization", "Bearer "+tokens[tt.role])
+			}
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, request)
+			if recorder.Code != tt.want {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid token", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/entities/1", nil)
+		request.Header.Set("Authorization", "Bearer not.a.token")
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want %d even on a public route", recorder.Code, http.StatusUnauthorized)
+		}
+	})
+}
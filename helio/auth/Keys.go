@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// KeySource looks up the key verifying a token's signature.
+type KeySource interface {
+	Key(token *jwt.Token) (any, error)
+}
+
+// StaticKey is a single public key verifying every token, i.e. read from a local key file.
+type StaticKey struct {
+	PublicKey crypto.PublicKey
+}
+
+// Key returns the StaticKey's public key.
+func (s StaticKey) Key(*jwt.Token) (any, error) {
+	return s.PublicKey, nil
+}
+
+// LoadKeyFile reads a PEM encoded public key, or the public half of a private key, from path.
+func LoadKeyFile(path string) (StaticKey, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return StaticKey{}, fmt.Errorf("reading key file: %w", err)
+	}
+	block, _ := pem.Decode(file)
+	if block == nil {
+		return StaticKey{}, fmt.Errorf("key file %s is not PEM encoded", path)
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return StaticKey{}, fmt.Errorf("parsing public key: %w", err)
+		}
+		return StaticKey{PublicKey: key}, nil
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return StaticKey{}, fmt.Errorf("parsing public key: %w", err)
+		}
+		return StaticKey{PublicKey: key}, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return StaticKey{}, fmt.Errorf("parsing private key: %w", err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return StaticKey{}, fmt.Errorf("unsupported private key %T", key)
+		}
+		return StaticKey{PublicKey: signer.Public()}, nil
+	default:
+		return StaticKey{}, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// JWKS is a remote JSON Web Key Set. Keys are fetched when first needed and again once they are older than the
+// refresh interval, or when a token names a key that is not known yet(at most once a minute).
+type JWKS struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// NewJWKS constructs a JWKS for the key set found at url.
+func NewJWKS(url string, refresh time.Duration) *JWKS {
+	return &JWKS{
+		url:     url,
+		refresh: refresh,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		keys:    map[string]crypto.PublicKey{},
+	}
+}
+
+// Key returns the key named by the token's kid header.
+func (j *JWKS) Key(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	key, ok := j.keys[kid]
+	stale := time.Since(j.fetched) > j.refresh
+	if !ok && time.Since(j.fetched) > time.Minute {
+		stale = true
+	}
+	if stale {
+		if err := j.fetch(); err != nil {
+			slog.Error("Error fetching JWKS", "url", j.url, "error", err)
+			if !ok {
+				return nil, fmt.Errorf("signing key unavailable")
+			}
+		}
+		key, ok = j.keys[kid]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// jwk is a single key of a JSON Web Key Set, only the members needed for RSA, EC and OKP keys are kept.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetch replaces the keys with the ones currently found at the url. The caller holds j.mu.
+func (j *JWKS) fetch() error {
+	res, err := j.client.Get(j.url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			slog.Warn("Skipping JWKS key", "kid", k.Kid, "error", err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	j.keys, j.fetched = keys, time.Now()
+	return nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBig(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBig(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBig(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBig(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBig(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
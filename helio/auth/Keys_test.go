@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// keyServer serves the JWKS of a signer, which can be swapped to rotate the key, and counts the fetches.
+type keyServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	signer  *Signer
+	fetches int
+}
+
+func newKeyServer(t *testing.T, signer *Signer) *keyServer {
+	s := &keyServer{signer: signer}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.fetches++
+		jwks, err := jwksOf(s.signer)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(jwks)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *keyServer) rotate(signer *Signer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signer = signer
+}
+
+func (s *keyServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+// jwksOf returns the public key of signer as a JSON Web Key Set.
+func jwksOf(signer *Signer) ([]byte, error) {
+	size := (signer.key.Curve.Params().BitSize + 7) / 8
+	return json.Marshal(map[string][]jwk{"keys": {{
+		Kty: "EC",
+		Kid: signer.kid,
+		Use: "sig",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(signer.key.X.FillBytes(make([]byte, size))),
+		Y:   base64.RawURLEncoding.EncodeToString(signer.key.Y.FillBytes(make([]byte, size))),
+	}}})
+}
+
+// signerWithKid returns a Signer naming its key kid.
+func signerWithKid(t *testing.T, kid string) *Signer {
+	signer, err := NewSigner("helio", "helio-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer.kid = kid
+	return signer
+}
+
+func TestJWKSUnknownKid(t *testing.T) {
+	first, rotated := signerWithKid(t, "first"), signerWithKid(t, "rotated")
+	server := newKeyServer(t, first)
+	jwks := NewJWKS(server.URL, time.Hour)
+	authenticator := NewAuthenticator(jwks, "helio", "helio-api")
+	verify := func(signer *Signer) error {
+		token, err := signer.Mint("tester", time.Hour, Viewer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = authenticator.Verify(token)
+		return err
+	}
+
+	if err := verify(first); err != nil {
+		t.Fatalf("Verify() of the first key = %v, want nil", err)
+	}
+	if err := verify(first); err != nil || server.count() != 1 {
+		t.Fatalf("Verify() again = %v after %d fetches, want nil after a single fetch", err, server.count())
+	}
+
+	server.rotate(rotated)
+	if err := verify(rotated); err == nil || server.count() != 1 {
+		t.Fatalf("Verify() of an unknown kid within a minute = %v after %d fetches, want an error without a refetch", err, server.count())
+	}
+
+	jwks.mu.Lock()
+	jwks.fetched = jwks.fetched.Add(-2 * time.Minute) // the last fetch was over a minute ago
+	jwks.mu.Unlock()
+	if err := verify(rotated); err != nil || server.count() != 2 {
+		t.Fatalf("Verify() of an unknown kid = %v after %d fetches, want nil after a refetch", err, server.count())
+	}
+	if err := verify(first); err == nil {
+		t.Error("Verify() of a key rotated out = nil, want an error")
+	}
+}
+
+func TestJWKSRefresh(t *testing.T) {
+	signer := signerWithKid(t, "local")
+	server := newKeyServer(t, signer)
+	jwks := NewJWKS(server.URL, time.Hour)
+	authenticator := NewAuthenticator(jwks, "helio", "helio-api")
+	token, err := signer.Mint("tester", time.Hour, Viewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = authenticator.Verify(token); err != nil {
+		t.Fatal(err)
+	}
+
+	jwks.mu.Lock()
+	jwks.fetched = jwks.fetched.Add(-2 * time.Hour) // older than the refresh interval
+	jwks.mu.Unlock()
+	server.Close() // a failed refresh keeps the keys known
+	if _, err = authenticator.Verify(token); err != nil {
+		t.Errorf("Verify() after a failed refresh = %v, want nil", err)
+	}
+}
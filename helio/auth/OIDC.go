@@ -0,0 +1,435 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	loginCookie = "helio_login" // holds the state, nonce and PKCE verifier of a login in flight
+	loginTTL    = 10 * time.Minute
+
+	discoveryBackoff    = 5 * time.Second // wait after the first failed discovery, doubled after every other
+	discoveryMaxBackoff = 5 * time.Minute
+)
+
+// OIDCConfig configures the login flow against an OpenID Connect provider.
+type OIDCConfig struct {
+	Issuer       string          // discovered through Issuer/.well-known/openid-configuration
+	ClientId     string          //
+	ClientSecret string          // optional, public clients rely on PKCE alone
+	RedirectURL  string          // the callback route, i.e. http://localhost:8000/auth/callback
+	PostLoginURL string          // where the browser is sent once logged in
+	Scopes       []string        // openid is always asked for
+	RolesClaim   string          // ID token claim holding the caller's roles or groups, i.e. roles
+	RoleMap      map[string]Role // maps values of RolesClaim to roles, any value left out grants nothing
+}
+
+// discovery is the part of the provider's metadata the login flow needs.
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// OIDC logs callers in through an OpenID Connect provider using the authorization code flow with PKCE,
+// and keeps them logged in through Sessions. The provider's endpoints are discovered by the first login, or by
+// Discover, and a provider that cannot be reached is tried again by a later login.
+type OIDC struct {
+	config   OIDCConfig
+	parser   *jwt.Parser
+	sessions *Sessions
+	client   *http.Client
+	flight   singleflight.Group // the discovery in flight, shared by the logins waiting on it
+
+	mu       sync.Mutex
+	provider *discovery // nil until discovered
+	keys     KeySource
+	err      error         // of the last failed discovery
+	backoff  time.Duration // wait after the last failed discovery, doubled by every other
+	retry    time.Time     // when discovery is tried again
+}
+
+// NewOIDC constructs an OIDC, nothing is asked of the provider until Discover or the first login.
+func NewOIDC(config OIDCConfig, sessions *Sessions) *OIDC {
+	if config.RolesClaim == "" {
+		config.RolesClaim = "roles"
+	}
+	if config.PostLoginURL == "" {
+		config.PostLoginURL = "/"
+	}
+	return &OIDC{
+		config: config,
+		parser: jwt.NewParser(jwt.WithValidMethods(validMethods), jwt.WithIssuer(config.Issuer),
+			jwt.WithAudience(config.ClientId), jwt.WithExpirationRequired()),
+		sessions: sessions,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Discover looks up the provider's endpoints unless they are known already. A failed discovery is not tried again
+// for discoveryBackoff, doubled after every other failure up to discoveryMaxBackoff, and returns the last error meanwhile.
+func (o *OIDC) Discover(ctx context.Context) error {
+	_, _, err := o.discovered(ctx)
+	return err
+}
+
+// discovered returns the provider's metadata and keys, discovering them first if need be. The provider is asked
+// without holding o.mu, and logins waiting on a discovery in flight wait for it rather than asking the provider
+// themselves.
+func (o *OIDC) discovered(ctx context.Context) (discovery, KeySource, error) {
+	if provider, keys, known, err := o.known(); known {
+		return provider, keys, err
+	}
+	// Note: The discovery is not cancelled along with the login starting it, other logins may be waiting on it.
+	flight := o.flight.DoChan("discover", func() (any, error) {
+		if _, _, known, err := o.known(); known {
+			return nil, err // discovered by the flight before this one
+		}
+		provider, err := o.discover(context.WithoutCancel(ctx))
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if err != nil {
+			o.err, o.backoff = err, min(max(2*o.backoff, discoveryBackoff), discoveryMaxBackoff)
+			o.retry = time.Now().Add(o.backoff)
+			return nil, err
+		}
+		o.provider, o.keys = &provider, NewJWKS(provider.JwksURI, 15*time.Minute)
+		o.err, o.backoff, o.retry = nil, 0, time.Time{}
+		return nil, nil
+	})
+	select {
+	case <-ctx.Done():
+		return discovery{}, nil, ctx.Err()
+	case result := <-flight:
+		if result.Err != nil {
+			return discovery{}, nil, result.Err
+		}
+	}
+	provider, keys, _, err := o.known()
+	return provider, keys, err
+}
+
+// known returns the provider once discovered, or the error of the last discovery until it is tried again. It is
+// false when the provider is to be discovered.
+func (o *OIDC) known() (discovery, KeySource, bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.provider != nil {
+		return *o.provider, o.keys, true, nil
+	}
+	if time.Now().Before(o.retry) {
+		return discovery{}, nil, true, o.err
+	}
+	return discovery{}, nil, false, nil
+}
+
+// discover reads the provider's metadata from Issuer/.well-known/openid-configuration.
+func (o *OIDC) discover(ctx context.Context) (discovery, error) {
+	wellKnown := strings.TrimSuffix(o.config.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return discovery{}, err
+	}
+	res, err := o.client.Do(req)
+	if err != nil {
+		return discovery{}, fmt.Errorf("discovering %s: %w", o.config.Issuer, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return discovery{}, fmt.Errorf("discovering %s: unexpected status %d", o.config.Issuer, res.StatusCode)
+	}
+	var provider discovery
+	if err = json.NewDecoder(res.Body).Decode(&provider); err != nil {
+		return discovery{}, fmt.Errorf("discovering %s: %w", o.config.Issuer, err)
+	}
+	if provider.Issuer != o.config.Issuer {
+		return discovery{}, fmt.Errorf("discovered issuer %q does not match %q", provider.Issuer, o.config.Issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JwksURI == "" {
+		return discovery{}, fmt.Errorf("discovering %s: incomplete provider metadata", o.config.Issuer)
+	}
+	return provider, nil
+}
+
+// unavailable answers a login while the provider cannot be discovered.
+func unavailable(c *gin.Context, err error) {
+	slog.WarnContext(c.Request.Context(), "Identity provider unavailable", "error", err)
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"error":   "err identity provider unavailable",
+		"message": "logging in is not possible right now, try again later",
+	})
+}
+
+// loginState is kept within the login cookie between LoginHandler and CallbackHandler.
+type loginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// LoginHandler GET /auth/login
+// Redirects the browser to the provider to log in.
+// Responses:
+// 302 - Redirect to the provider
+// 500 - Login could not be started
+// 503 - The provider could not be discovered
+func (o *OIDC) LoginHandler(c *gin.Context) {
+	provider, _, err := o.discovered(c.Request.Context())
+	if err != nil {
+		unavailable(c, err)
+		return
+	}
+	state := loginState{State: randomString(), Nonce: randomString(), Verifier: randomString() + randomString()}
+	state.ExpiresAt = jwt.NewNumericDate(time.Now().Add(loginTTL))
+	cookie, err := o.sessions.signer.Sign(state)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "err starting login",
+		})
+		return
+	}
+	o.sessions.setCookie(c, loginCookie, cookie, loginTTL)
+
+	challenge := sha256.Sum256([]byte(state.Verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.config.ClientId},
+		"redirect_uri":          {o.config.RedirectURL},
+		"scope":                 {strings.Join(o.scopes(), " ")},
+		"state":                 {state.State},
+		"nonce":                 {state.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	c.Redirect(http.StatusFound, provider.AuthorizationEndpoint+"?"+query.Encode())
+}
+
+// CallbackHandler GET /auth/callback?code=...&state=...
+// Exchanges the provider's code for an ID token and starts a session carrying the caller's role.
+// Responses:
+// 302 - Logged in, redirect to the post login url
+// 400 - Missing or mismatched state, or the provider returned an error
+// 401 - The provider's tokens could not be verified
+// 503 - The provider could not be discovered
+func (o *OIDC) CallbackHandler(c *gin.Context) {
+	if providerErr := c.Query("error"); providerErr != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   providerErr,
+			"message": c.Query("error_description"),
+		})
+		return
+	}
+	cookie, err := c.Cookie(loginCookie)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "err no login in progress",
+		})
+		return
+	}
+	o.sessions.setCookie(c, loginCookie, "", -1)
+	var state loginState
+	_, err = jwt.ParseWithClaims(cookie, &state, func(*jwt.Token) (any, error) { return o.sessions.signer.Public(), nil },
+		jwt.WithValidMethods([]string{"ES256"}), jwt.WithExpirationRequired())
+	if err != nil || state.State == "" || state.State != c.Query("state") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "err state mismatch",
+		})
+		return
+	}
+
+	provider, keys, err := o.discovered(c.Request.Context())
+	if err != nil {
+		unavailable(c, err)
+		return
+	}
+	rawIdToken, err := o.exchange(c.Request.Context(), provider.TokenEndpoint, c.Query("code"), state.Verifier)
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "Error exchanging authorization code", "error", err)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "err exchanging code",
+		})
+		return
+	}
+	identity, err := o.verify(keys, rawIdToken, state.Nonce)
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "Error verifying ID token", "error", err)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "err invalid id token",
+		})
+		return
+	}
+	if err = o.sessions.Issue(c, identity); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "err starting session",
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, o.config.PostLoginURL)
+}
+
+// LogoutHandler POST /auth/logout
+// Ends the caller's session, the provider's session is left alone.
+// Responses:
+// 200 - Logged out
+func (o *OIDC) LogoutHandler(c *gin.Context) {
+	o.sessions.Clear(c)
+	provider, _, _ := o.discovered(c.Request.Context()) // without a provider there is no session of it to end
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "logged out",
+		"end_session":  provider.EndSessionEndpoint,
+		"redirect_url": o.config.PostLoginURL,
+	})
+}
+
+// MeHandler GET /auth/me
+// Returns the Identity of the caller.
+// Responses:
+// 200 - Successful operation
+// 401 - Not logged in
+func MeHandler(c *gin.Context) {
+	identity, ok := GetIdentity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "err unauthorized",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, identity)
+}
+
+// exchange trades the authorization code for the provider's ID token.
+func (o *OIDC) exchange(ctx context.Context, tokenEndpoint string, code string, verifier string) (string, error) {
+	if code == "" {
+		return "", fmt.Errorf("missing code")
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.config.RedirectURL},
+		"client_id":     {o.config.ClientId},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.config.ClientId), url.QueryEscape(o.config.ClientSecret))
+	}
+	res, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	var tokens struct {
+		IdToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&tokens); err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK || tokens.IdToken == "" {
+		return "", fmt.Errorf("token endpoint returned %d %s", res.StatusCode, tokens.Error)
+	}
+	return tokens.IdToken, nil
+}
+
+// verify validates the ID token against the provider's keys and maps its claims to an Identity.
+func (o *OIDC) verify(keys KeySource, rawIdToken string, nonce string) (Identity, error) {
+	claims := jwt.MapClaims{}
+	if _, err := o.parser.ParseWithClaims(rawIdToken, claims, keys.Key); err != nil {
+		return Identity{}, err
+	}
+	if claims["nonce"] != nonce {
+		return Identity{}, fmt.Errorf("nonce mismatch")
+	}
+	subject, _ := claims.GetSubject()
+	name, _ := claims["name"].(string)
+	if name == "" {
+		name, _ = claims["email"].(string)
+	}
+	return Identity{
+		Subject: subject,
+		Name:    name,
+		Role:    o.role(claims[o.config.RolesClaim]),
+	}, nil
+}
+
+// role maps the value of the roles claim, a string or a list of strings, to the highest Role the RoleMap grants it.
+// Values left out of the RoleMap grant nothing, not even a role of the same name, and a caller granted nothing has no
+// role: logged in, but allowed no more than an anonymous caller.
+func (o *OIDC) role(claim any) Role {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = strings.Fields(v)
+	case []any:
+		for _, value := range v {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	roles := make([]string, 0, len(values))
+	for _, value := range values {
+		if mapped, ok := o.config.RoleMap[value]; ok {
+			roles = append(roles, string(mapped))
+		}
+	}
+	return highest(roles)
+}
+
+func (o *OIDC) scopes() []string {
+	scopes := []string{"openid"}
+	for _, scope := range o.config.Scopes {
+		if scope != "openid" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// ParseRoleMap parses a comma separated list of value=role pairs, i.e. helio-curators=curator,helio-admins=admin
+func ParseRoleMap(roleMap string) (map[string]Role, error) {
+	mapped := map[string]Role{}
+	for _, pair := range strings.Split(roleMap, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		value, role, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid role mapping %q", pair)
+		}
+		r, err := ParseRole(role)
+		if err != nil {
+			return nil, err
+		}
+		mapped[strings.TrimSpace(value)] = r
+	}
+	return mapped, nil
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOIDCDiscoveryRetry(t *testing.T) {
+	var requests, down atomic.Int64
+	down.Store(1)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if down.Load() == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(discovery{
+			Issuer:                server.URL,
+			AuthorizationEndpoint: server.URL + "/authorize",
+			TokenEndpoint:         server.URL + "/token",
+			JwksURI:               server.URL + "/jwks",
+		})
+	}))
+	defer server.Close()
+	o := NewOIDC(OIDCConfig{Issuer: server.URL, ClientId: "helio"}, nil)
+	ctx := context.Background()
+
+	if err := o.Discover(ctx); err == nil || requests.Load() != 1 {
+		t.Fatalf("Discover() = %v after %d requests, want an error after one", err, requests.Load())
+	}
+	down.Store(0)
+	if err := o.Discover(ctx); err == nil || requests.Load() != 1 {
+		t.Fatalf("Discover() within the backoff = %v after %d requests, want the last error without a request", err, requests.Load())
+	}
+	if o.backoff != discoveryBackoff {
+		t.Errorf("backoff = %v, want %v", o.backoff, discoveryBackoff)
+	}
+
+	o.retry = time.Now().Add(-time.Second) // the backoff passed
+	if err := o.Discover(ctx); err != nil || requests.Load() != 2 {
+		t.Fatalf("Discover() = %v after %d requests, want nil after two", err, requests.Load())
+	}
+	if err := o.Discover(ctx); err != nil || requests.Load() != 2 {
+		t.Errorf("Discover() once discovered = %v after %d requests, want nil without a request", err, requests.Load())
+	}
+}
+
+func TestOIDCDiscoveryCoalesced(t *testing.T) {
+	var requests atomic.Int64
+	release := make(chan struct{})
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		_ = json.NewEncoder(w).Encode(discovery{
+			Issuer:                server.URL,
+			AuthorizationEndpoint: server.URL + "/authorize",
+			TokenEndpoint:         server.URL + "/token",
+			JwksURI:               server.URL + "/jwks",
+		})
+	}))
+	defer server.Close()
+	o := NewOIDC(OIDCConfig{Issuer: server.URL, ClientId: "helio"}, nil)
+
+	const logins = 10
+	var done sync.WaitGroup
+	errs := make([]error, logins)
+	for i := 0; i < logins; i++ {
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			errs[i] = o.Discover(context.Background())
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond) // every login waits on the first's discovery
+
+	// a login giving up while the provider is slow is not held up by the discovery
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := o.Discover(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Discover() while discovering = %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(release)
+	done.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Discover() of login %d = %v, want nil", i, err)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Errorf("requests = %d, want 1", requests.Load())
+	}
+}
+
+func TestOIDCDiscoveryBackoff(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	o := NewOIDC(OIDCConfig{Issuer: server.URL}, nil)
+	want := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 40 * time.Second, 80 * time.Second,
+		160 * time.Second, discoveryMaxBackoff, discoveryMaxBackoff}
+	for i, backoff := range want {
+		o.retry = time.Time{}
+		if err := o.Discover(context.Background()); err == nil {
+			t.Fatal("Discover() = nil, want an error")
+		}
+		if o.backoff != backoff {
+			t.Errorf("backoff after %d failures = %v, want %v", i+1, o.backoff, backoff)
+		}
+	}
+}
+
+func TestOIDCRole(t *testing.T) {
+	o := NewOIDC(OIDCConfig{RoleMap: map[string]Role{"editors": Curator, "owners": Admin}}, nil)
+	tests := []struct {
+		name  string
+		claim any
+		want  Role
+	}{
+		{"mapped string", "editors", Curator},
+		{"space separated", "editors owners", Admin},
+		{"list", []any{"owners", "editors"}, Admin},
+		{"unmapped role name", []any{"admin"}, ""},
+		{"unmapped", "superusers", ""},
+		{"missing", nil, ""},
+		{"not a string", []any{42}, ""},
+	}
+	for _, tt := range tests {
+		if got := o.role(tt.claim); got != tt.want {
+			t.Errorf("%s: role(%v) = %q, want %q", tt.name, tt.claim, got, tt.want)
+		}
+	}
+}
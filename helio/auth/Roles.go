@@ -0,0 +1,47 @@
+// Package auth provides JWT authentication and role based authorization for the helio routes.
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Role of an authenticated caller. Every role is allowed whatever the roles below it are allowed.
+type Role string
+
+const (
+	Viewer  Role = "viewer"  // may read
+	Curator Role = "curator" // may create, update, review and merge entities
+	Admin   Role = "admin"   // may do anything, i.e. delete entities
+)
+
+var rank = map[Role]int{
+	Viewer:  1,
+	Curator: 2,
+	Admin:   3,
+}
+
+// ParseRole turns a string into a Role.
+func ParseRole(role string) (Role, error) {
+	r := Role(strings.ToLower(strings.TrimSpace(role)))
+	if _, ok := rank[r]; !ok {
+		return "", fmt.Errorf("unknown role %q", role)
+	}
+	return r, nil
+}
+
+// Allows reports whether the role is allowed what the required role is allowed.
+func (r Role) Allows(required Role) bool {
+	return rank[r] != 0 && rank[r] >= rank[required]
+}
+
+// highest returns the highest known role of roles, or an empty Role if none are known.
+func highest(roles []string) Role {
+	var best Role
+	for _, role := range roles {
+		if r, err := ParseRole(role); err == nil && rank[r] > rank[best] {
+			best = r
+		}
+	}
+	return best
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+const (
+	SessionCookie = "helio_session"
+	SessionIssuer = "helio-session" // issuer and audience of session tokens, keeping them apart from any other token
+)
+
+// Sessions keeps the Identity of a logged in caller within a signed cookie, so a browser needs no bearer token.
+type Sessions struct {
+	signer   *Signer
+	verifier *Authenticator
+	ttl      time.Duration
+	secure   bool // only send the cookie over https
+}
+
+// NewSessions constructs Sessions signed by signer. The signer should not be used for anything but sessions
+// and needs SessionIssuer as its issuer and audience, i.e. NewSigner(SessionIssuer, SessionIssuer).
+func NewSessions(signer *Signer, ttl time.Duration, secure bool) *Sessions {
+	return &Sessions{
+		signer:   signer,
+		verifier: NewAuthenticator(signer.Verifier(), signer.issuer, signer.audience),
+		ttl:      ttl,
+		secure:   secure,
+	}
+}
+
+// Issue starts a session for the identity by setting the session cookie.
+func (s *Sessions) Issue(c *gin.Context, identity Identity) error {
+	token, err := s.signer.MintIdentity(identity, s.ttl)
+	if err != nil {
+		return err
+	}
+	s.setCookie(c, SessionCookie, token, s.ttl)
+	return nil
+}
+
+// Identity returns the Identity of the request's session, and false if there is no valid session.
+func (s *Sessions) Identity(c *gin.Context) (Identity, bool) {
+	token, err := c.Cookie(SessionCookie)
+	if err != nil || token == "" {
+		return Identity{}, false
+	}
+	identity, err := s.verifier.Verify(token)
+	if err != nil {
+		return Identity{}, false
+	}
+	return identity, true
+}
+
+// Clear ends the request's session.
+func (s *Sessions) Clear(c *gin.Context) {
+	s.setCookie(c, SessionCookie, "", -1)
+}
+
+func (s *Sessions) setCookie(c *gin.Context, name string, value string, ttl time.Duration) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(name, value, int(ttl.Seconds()), "/", "", s.secure, true)
+}
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"os"
+	"time"
+)
+
+// Signer mints helio tokens with a local signing key. It is meant for tests and local development, where a
+// Signer's Verifier() replaces the JWKS or key file of production.
+type Signer struct {
+	key      *ecdsa.PrivateKey
+	kid      string
+	issuer   string
+	audience string
+}
+
+// NewSigner generates a fresh ES256 signing key. Tokens carry the given issuer and audience when not empty.
+func NewSigner(issuer string, audience string) (*Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating signing key: %w", err)
+	}
+	return &Signer{key: key, kid: "local", issuer: issuer, audience: audience}, nil
+}
+
+// LoadSigner reads a PEM encoded P-256 private key(PKCS8 or EC) from path.
+func LoadSigner(path string, issuer string, audience string) (*Signer, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+	block, _ := pem.Decode(file)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s is not PEM encoded", path)
+	}
+	var parsed any
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing signing key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok || key.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("signing key must be an ECDSA P-256 key")
+	}
+	return &Signer{key: key, kid: "local", issuer: issuer, audience: audience}, nil
+}
+
+// Mint returns a signed token for the subject with the given roles, valid for ttl.
+func (s *Signer) Mint(subject string, ttl time.Duration, roles ...Role) (string, error) {
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, string(role))
+	}
+	now := time.Now()
+	claims := Claims{
+		Roles: names,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Issuer:    s.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	if s.audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.audience}
+	}
+	return s.Sign(claims)
+}
+
+// MintIdentity returns a signed token carrying the identity, valid for ttl.
+func (s *Signer) MintIdentity(identity Identity, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		Name: identity.Name,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   identity.Subject,
+			Issuer:    s.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	if identity.Role != "" {
+		claims.Roles = []string{string(identity.Role)}
+	}
+	if s.audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.audience}
+	}
+	return s.Sign(claims)
+}
+
+// Sign signs any claims with the Signer's key.
+func (s *Signer) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = s.kid
+	return token.SignedString(s.key)
+}
+
+// Verifier returns the KeySource validating the Signer's tokens.
+func (s *Signer) Verifier() KeySource {
+	return StaticKey{PublicKey: s.Public()}
+}
+
+// Public returns the Signer's public key.
+func (s *Signer) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+// PublicKeyPEM returns the Signer's public key PEM encoded, as read by LoadKeyFile.
+func (s *Signer) PublicKeyPEM() ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(s.key.Public())
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// JWKS returns the Signer's public key as a JSON Web Key Set, as read by NewJWKS.
+func (s *Signer) JWKS() ([]byte, error) {
+	size := (s.key.Curve.Params().BitSize + 7) / 8
+	return json.Marshal(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "EC",
+			"kid": s.kid,
+			"use": "sig",
+			"alg": "ES256",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(s.key.X.FillBytes(make([]byte, size))),
+			"y":   base64.RawURLEncoding.EncodeToString(s.key.Y.FillBytes(make([]byte, size))),
+		}},
+	})
+}
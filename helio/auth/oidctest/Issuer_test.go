@@ -0,0 +1,218 @@
+package oidctest
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/helio/auth"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const (
+	clientId    = "helio"
+	redirectURL = "http://helio.test/auth/callback"
+)
+
+// login is a login against helio's routes, r, the way a browser would run it.
+type login struct {
+	t         *testing.T
+	r         *gin.Engine
+	authorize func(u *url.URL)          // changes the authorization url before the browser follows it
+	state     func(state string) string // changes the state returned to the callback
+}
+
+func newRouter(t *testing.T, issuer *Issuer, roleMap map[string]auth.Role) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	signer, err := auth.NewSigner(auth.SessionIssuer, auth.SessionIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := auth.NewSessions(signer, time.Hour, false)
+	oidc := auth.NewOIDC(auth.OIDCConfig{
+		Issuer:       issuer.URL,
+		ClientId:     clientId,
+		RedirectURL:  redirectURL,
+		PostLoginURL: "http://helio.test/",
+		RoleMap:      roleMap,
+	}, sessions)
+	authenticator := auth.NewAuthenticator(nil, "", "")
+	authenticator.UseSessions(sessions)
+	r := gin.New()
+	r.Use(authenticator.Authenticate())
+	r.GET("/auth/login", oidc.LoginHandler)
+	r.GET("/auth/callback", oidc.CallbackHandler)
+	r.GET("/auth/me", auth.MeHandler)
+	return r
+}
+
+func newIssuer(t *testing.T, user User) *Issuer {
+	issuer, err := NewIssuer(clientId, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+// run logs in and returns the response to the callback.
+func (l login) run() *httptest.ResponseRecorder {
+	t := l.t
+	recorder := httptest.NewRecorder()
+	l.r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("GET /auth/login = %d, want %d", recorder.Code, http.StatusFound)
+	}
+	cookies := recorder.Result().Cookies()
+	authorization, err := url.Parse(recorder.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.authorize != nil {
+		l.authorize(authorization)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	res, err := client.Get(authorization.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("GET %s = %d, want %d", authorization.Path, res.StatusCode, http.StatusFound)
+	}
+	callback, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.state != nil {
+		query := callback.Query()
+		query.Set("state", l.state(query.Get("state")))
+		callback.RawQuery = query.Encode()
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/auth/callback?"+callback.RawQuery, nil)
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	recorder = httptest.NewRecorder()
+	l.r.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// me returns the identity of the session a callback started.
+func me(t *testing.T, r *gin.Engine, callback *httptest.ResponseRecorder) auth.Identity {
+	request := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
+	for _, cookie := range callback.Result().Cookies() {
+		if cookie.Name == auth.SessionCookie {
+			request.AddCookie(cookie)
+		}
+	}
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET /auth/me = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var identity auth.Identity
+	if err := json.Unmarshal(recorder.Body.Bytes(), &identity); err != nil {
+		t.Fatal(err)
+	}
+	return identity
+}
+
+func TestLogin(t *testing.T) {
+	issuer := newIssuer(t, User{Subject: "1234", Name: "Jane Doe", Roles: []string{"editors"}})
+	r := newRouter(t, issuer, map[string]auth.Role{"editors": auth.Curator})
+
+	var challenge url.Values
+	callback := login{t: t, r: r, authorize: func(u *url.URL) { challenge = u.Query() }}.run()
+	if callback.Code != http.StatusFound || callback.Header().Get("Location") != "http://helio.test/" {
+		t.Fatalf("GET /auth/callback = %d to %q, want %d to the post login url", callback.Code, callback.Header().Get("Location"), http.StatusFound)
+	}
+	if challenge.Get("code_challenge_method") != "S256" || challenge.Get("code_challenge") == "" || challenge.Get("nonce") == "" {
+		t.Errorf("authorization url %v, want a S256 code challenge and a nonce", challenge)
+	}
+	if identity := me(t, r, callback); identity != (auth.Identity{Subject: "1234", Name: "Jane Doe", Role: auth.Curator}) {
+		t.Errorf("GET /auth/me = %+v, want Jane Doe as a curator", identity)
+	}
+}
+
+func TestLoginRefused(t *testing.T) {
+	issuer := newIssuer(t, User{Subject: "1234", Roles: []string{"editors"}})
+	r := newRouter(t, issuer, map[string]auth.Role{"editors": auth.Curator})
+	set := func(key string, value string) func(u *url.URL) {
+		return func(u *url.URL) {
+			query := u.Query()
+			query.Set(key, value)
+			u.RawQuery = query.Encode()
+		}
+	}
+	tests := []struct {
+		name string
+		l    login
+		want int
+	}{
+		{"state mismatch", login{state: func(string) string { return "forged" }}, http.StatusBadRequest},
+		{"no state", login{state: func(string) string { return "" }}, http.StatusBadRequest},
+		// the issuer puts the nonce it was given into the ID token, which no longer matches the login's
+		{"nonce mismatch", login{authorize: set("nonce", "forged")}, http.StatusUnauthorized},
+		// the code verifier sent by helio no longer matches the challenge the issuer was given
+		{"pkce mismatch", login{authorize: set("code_challenge", "forged")}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.l.t, tt.l.r = t, r
+			callback := tt.l.run()
+			if callback.Code != tt.want {
+				t.Errorf("GET /auth/callback = %d, want %d", callback.Code, tt.want)
+			}
+			for _, cookie := range callback.Result().Cookies() {
+				if cookie.Name == auth.SessionCookie && cookie.Value != "" {
+					t.Error("GET /auth/callback started a session")
+				}
+			}
+		})
+	}
+}
+
+func TestLoginRoleMap(t *testing.T) {
+	roleMap := map[string]auth.Role{"readers": auth.Viewer, "editors": auth.Curator, "owners": auth.Admin}
+	tests := []struct {
+		name  string
+		roles []string
+		want  auth.Role
+	}{
+		{"mapped", []string{"editors"}, auth.Curator},
+		{"highest of several", []string{"readers", "owners", "editors"}, auth.Admin},
+		{"unmapped along with mapped", []string{"editors", "superusers"}, auth.Curator},
+		{"unmapped role name", []string{"admin"}, ""},
+		{"unmapped", []string{"superusers"}, ""},
+		{"none", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newIssuer(t, User{Subject: "1234", Roles: tt.roles})
+			r := newRouter(t, issuer, roleMap)
+			callback := login{t: t, r: r}.run()
+			if callback.Code != http.StatusFound {
+				t.Fatalf("GET /auth/callback = %d, want %d", callback.Code, http.StatusFound)
+			}
+			if identity := me(t, r, callback); identity.Role != tt.want {
+				t.Errorf("role of %v = %q, want %q", tt.roles, identity.Role, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginProviderUnavailable(t *testing.T) {
+	issuer := newIssuer(t, User{Subject: "1234"})
+	r := newRouter(t, issuer, nil)
+	issuer.Close()
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /auth/login = %d, want %d", recorder.Code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,190 @@
+// Package cache keeps the results of reads of the database for a while, so the same searches made by every map load
+// run once rather than every time. Results are kept as json by a Backend, in memory or in redis when replicas share
+// them, and identical reads running at the same time wait for one of them rather than each running its own.
+//
+// Every mutation invalidates every result: data rarely changes, and a search may hold any entity. Results are kept
+// under the generation current when the read started, so that a read finishing after a mutation is never served.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+const (
+	None   = "none"
+	Memory = "memory"
+	Redis  = "redis"
+)
+
+// Backend keeps results as json, under keys prefixed by a generation.
+type Backend interface {
+	// Generation returns the current generation, results of the ones before are never read again.
+	Generation(ctx context.Context) (uint64, error)
+	// Get returns the result kept under key, false when there is none or it expired.
+	Get(key string, ctx context.Context) ([]byte, bool, error)
+	// Set keeps value under key for ttl.
+	Set(key string, value []byte, ttl time.Duration, ctx context.Context) error
+	// Invalidate starts a new generation.
+	Invalidate(ctx context.Context) error
+}
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "helio",
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "Reads through the cache, by method and result: hit, miss, coalesced(waited for an identical read) or error(of the backend).",
+	}, []string{"method", "result"})
+	invalidations = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "helio",
+		Subsystem: "cache",
+		Name:      "invalidations_total",
+		Help:      "Mutations invalidating every result.",
+	})
+	entries = prometheus.NewDesc("helio_cache_entries", "Results kept in memory, by the memory backend.", nil, nil)
+)
+
+// Cache reads through its backend, a nil *Cache reads straight from the database.
+type Cache struct {
+	backend Backend
+	ttl     time.Duration
+	group   singleflight.Group
+}
+
+// New returns the cache keeping results in backend for ttl.
+func New(backend Backend, ttl time.Duration) *Cache {
+	return &Cache{backend: backend, ttl: ttl}
+}
+
+// Open returns the cache of the backend named, nil for none. maxEntries bounds the memory backend, redisURL is the
+// redis://… url of the redis one.
+func Open(backend string, ttl time.Duration, maxEntries int, redisURL string) (*Cache, error) {
+	switch backend {
+	case None:
+		return nil, nil
+	case Memory:
+		return New(NewMemory(maxEntries), ttl), nil
+	case Redis:
+		shared, err := NewRedis(redisURL)
+		if err != nil {
+			return nil, err
+		}
+		return New(shared, ttl), nil
+	}
+	return nil, fmt.Errorf("backend must be none, memory or redis, not %q", backend)
+}
+
+// Get returns the result of the read method with args, loading it when it is not kept. Identical reads wait for the
+// one loading it, each until its own ctx is done. The load runs under the deadline of the first but is not cancelled
+// along with it, as others may be waiting. Errors are not kept, and a backend failing leaves reads to the database.
+func Get[T any](c *Cache, method string, args any, load func(ctx context.Context) (T, error), ctx context.Context) (T, error) {
+	var value T
+	if c == nil {
+		return load(ctx)
+	}
+	generation, err := c.backend.Generation(ctx)
+	if err != nil {
+		c.failed(method, err, ctx)
+		return load(ctx)
+	}
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return load(ctx)
+	}
+	key := method + ":" + strconv.FormatUint(generation, 10) + ":" + string(encoded)
+	if data, ok, err := c.backend.Get(key, ctx); err != nil {
+		c.failed(method, err, ctx)
+	} else if ok && json.Unmarshal(data, &value) == nil {
+		requests.WithLabelValues(method, "hit").Inc()
+		return value, nil
+	}
+
+	result := c.group.DoChan(key, func() (any, error) {
+		loadCtx := context.WithoutCancel(ctx)
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			loadCtx, cancel = context.WithDeadline(loadCtx, deadline)
+			defer cancel()
+		}
+		loaded, err := load(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(loaded)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.backend.Set(key, data, c.ttl, loadCtx); err != nil {
+			c.failed(method, err, ctx)
+		}
+		return data, nil
+	})
+	select {
+	case <-ctx.Done():
+		return value, ctx.Err()
+	case r := <-result:
+		if r.Shared {
+			requests.WithLabelValues(method, "coalesced").Inc()
+		} else {
+			requests.WithLabelValues(method, "miss").Inc()
+		}
+		if r.Err != nil {
+			return value, r.Err
+		}
+		// every caller decodes its own copy, so that none changes the result of another
+		err = json.Unmarshal(r.Val.([]byte), &value)
+		return value, err
+	}
+}
+
+// Invalidate drops every result, once an entity was inserted, changed or deleted. A nil *Cache does nothing.
+func (c *Cache) Invalidate(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	invalidations.Inc()
+	// Note: the mutation is done by now, the results must go even though the request went away.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := c.backend.Invalidate(ctx); err != nil {
+		slog.ErrorContext(ctx, "Error invalidating the cache, results may be stale until they expire", "error", err)
+	}
+}
+
+// failed counts and logs a failure of the backend.
+func (c *Cache) failed(method string, err error, ctx context.Context) {
+	requests.WithLabelValues(method, "error").Inc()
+	slog.WarnContext(ctx, "Cache backend failed, reading from the database", "method", method, "error", err)
+}
+
+// collector collects the metrics of a Cache.
+type collector struct {
+	cache *Cache
+}
+
+// Collector returns the metrics of the cache: hits, misses and coalesced reads by method, invalidations and the
+// results kept in memory.
+func (c *Cache) Collector() prometheus.Collector {
+	return collector{cache: c}
+}
+
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	requests.Describe(ch)
+	invalidations.Describe(ch)
+	ch <- entries
+}
+
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	requests.Collect(ch)
+	invalidations.Collect(ch)
+	if memory, ok := c.cache.backend.(*MemoryBackend); ok {
+		ch <- prometheus.MustNewConstMetric(entries, prometheus.GaugeValue, float64(memory.Len()))
+	}
+}
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errLoad = errors.New("err execute")
+
+// database is what the reads of the tests load, its version moving on with every mutation.
+type database struct {
+	version atomic.Int64
+	loads   atomic.Int64
+}
+
+func (d *database) load(ctx context.Context) ([]int64, error) {
+	d.loads.Add(1)
+	return []int64{d.version.Load()}, nil
+}
+
+// failingBackend is a backend that cannot be reached.
+type failingBackend struct{}
+
+func (failingBackend) Generation(ctx context.Context) (uint64, error) {
+	return 0, errors.New("err redis unreachable")
+}
+
+func (failingBackend) Get(key string, ctx context.Context) ([]byte, bool, error) {
+	return nil, false, errors.New("err redis unreachable")
+}
+
+func (failingBackend) Set(key string, value []byte, ttl time.Duration, ctx context.Context) error {
+	return errors.New("err redis unreachable")
+}
+
+func (failingBackend) Invalidate(ctx context.Context) error {
+	return errors.New("err redis unreachable")
+}
+
+func TestGet(t *testing.T) {
+	// ops, each a read but for mutate
+	const (
+		read            = "read"
+		readOther       = "read other" // a read with other args
+		mutate          = "mutate"
+		readWhileMutate = "read while mutate" // a read loading the version of before a mutation finishing along with it
+		readFailing     = "read failing"
+	)
+	tests := []struct {
+		name      string
+		cache     func() *Cache
+		ops       []string
+		wantLoads int64
+	}{
+		{"hit", func() *Cache { return New(NewMemory(10), time.Minute) }, []string{read, read, read}, 1},
+		{"other args", func() *Cache { return New(NewMemory(10), time.Minute) }, []string{read, readOther, read, readOther}, 2},
+		{"invalidated", func() *Cache { return New(NewMemory(10), time.Minute) }, []string{read, mutate, read, read}, 2},
+		{"every result invalidated", func() *Cache { return New(NewMemory(10), time.Minute) }, []string{read, readOther, mutate, read, readOther}, 4},
+		{"read finishing after a mutation", func() *Cache { return New(NewMemory(10), time.Minute) }, []string{readWhileMutate, read, read}, 2},
+		{"errors not kept", func() *Cache { return New(NewMemory(10), time.Minute) }, []string{readFailing, read, read}, 2},
+		{"expired", func() *Cache { return New(NewMemory(10), time.Nanosecond) }, []string{read, read}, 2},
+		{"evicted", func() *Cache { return New(NewMemory(1), time.Minute) }, []string{read, readOther, read}, 3},
+		{"nil cache", func() *Cache { return nil }, []string{read, read}, 2},
+		{"backend failing", func() *Cache { return New(failingBackend{}, time.Minute) }, []string{read, mutate, read}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, db := tt.cache(), &database{}
+			ctx := context.Background()
+			for i, op := range tt.ops {
+				if op == mutate {
+					db.version.Add(1)
+					c.Invalidate(ctx)
+					continue
+				}
+				args, load := "monarch", db.load
+				switch op {
+				case readOther:
+					args = "queen"
+				case readWhileMutate:
+					load = func(ctx context.Context) ([]int64, error) {
+						result, err := db.load(ctx)
+						db.version.Add(1)
+						c.Invalidate(ctx)
+						return result, err
+					}
+				case readFailing:
+					load = func(ctx context.Context) ([]int64, error) {
+						db.loads.Add(1)
+						return nil, errLoad
+					}
+				}
+				want := db.version.Load()
+				got, err := Get(c, "Search", args, load, ctx)
+				if op == readFailing {
+					if !errors.Is(err, errLoad) {
+						t.Errorf("op %d %s error = %v, want %v", i, op, err, errLoad)
+					}
+					continue
+				}
+				if err != nil || len(got) != 1 || got[0] != want {
+					t.Errorf("op %d %s = %v, %v, want version %d", i, op, got, err, want)
+				}
+			}
+			if loads := db.loads.Load(); loads != tt.wantLoads {
+				t.Errorf("loads = %d, want %d", loads, tt.wantLoads)
+			}
+		})
+	}
+}
+
+func TestGetCoalesced(t *testing.T) {
+	c, db := New(NewMemory(10), time.Minute), &database{}
+	db.version.Store(7)
+	release := make(chan struct{})
+	load := func(ctx context.Context) ([]int64, error) {
+		<-release
+		return db.load(ctx)
+	}
+
+	const readers = 10
+	var started, done sync.WaitGroup
+	results := make([][]int64, readers)
+	errs := make([]error, readers)
+	for i := 0; i < readers; i++ {
+		started.Add(1)
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			started.Done()
+			results[i], errs[i] = Get(c, "Search", "monarch", load, context.Background())
+		}(i)
+	}
+	started.Wait()
+	time.Sleep(20 * time.Millisecond) // every reader waits on the first's load
+	close(release)
+	done.Wait()
+
+	if loads := db.loads.Load(); loads != 1 {
+		t.Errorf("loads = %d, want 1", loads)
+	}
+	for i := range results {
+		if errs[i] != nil || len(results[i]) != 1 || results[i][0] != 7 {
+			t.Fatalf("reader %d = %v, %v, want version 7", i, results[i], errs[i])
+		}
+	}
+	results[0][0] = 0 // a reader changing its result changes no other
+	if results[1][0] != 7 {
+		t.Errorf("reader 1 = %v once reader 0 changed its own, want version 7", results[1])
+	}
+}
+
+func TestGetCancelled(t *testing.T) {
+	c, db := New(NewMemory(10), time.Minute), &database{}
+	release := make(chan struct{})
+	load := func(ctx context.Context) ([]int64, error) {
+		<-release
+		return db.load(ctx)
+	}
+	first := make(chan error)
+	go func() {
+		_, err := Get(c, "Search", "monarch", load, context.Background())
+		first <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := Get(c, "Search", "monarch", load, ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() of a waiting reader error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(release)
+	if err := <-first; err != nil {
+		t.Errorf("Get() of the loading reader error = %v, want nil", err)
+	}
+	if _, err := Get(c, "Search", "monarch", load, context.Background()); err != nil || db.loads.Load() != 1 {
+		t.Errorf("Get() after = %v after %d loads, want the result kept by the first", err, db.loads.Load())
+	}
+}
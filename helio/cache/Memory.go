@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryBackend keeps at most maxEntries results in memory, dropping the least recently used first. Replicas each keep
+// their own, and do not see the mutations made through the others.
+type MemoryBackend struct {
+	mu         sync.Mutex
+	maxEntries int
+	generation uint64
+	order      *list.List // most recently used first
+	entries    map[string]*list.Element
+}
+
+// entry is a result kept until expires.
+type entry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemory returns the backend keeping at most maxEntries results.
+func NewMemory(maxEntries int) *MemoryBackend {
+	return &MemoryBackend{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+func (m *MemoryBackend) Generation(ctx context.Context) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.generation, nil
+}
+
+func (m *MemoryBackend) Get(key string, ctx context.Context) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if e := element.Value.(*entry); time.Now().Before(e.expires) {
+		m.order.MoveToFront(element)
+		return e.value, true, nil
+	}
+	m.remove(element)
+	return nil, false, nil
+}
+
+func (m *MemoryBackend) Set(key string, value []byte, ttl time.Duration, ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if element, ok := m.entries[key]; ok {
+		m.remove(element)
+	}
+	m.entries[key] = m.order.PushFront(&entry{key: key, value: value, expires: time.Now().Add(ttl)})
+	for m.order.Len() > m.maxEntries {
+		m.remove(m.order.Back())
+	}
+	return nil
+}
+
+// Invalidate drops every result right away, as no result of an older generation is read again.
+func (m *MemoryBackend) Invalidate(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.generation++
+	m.order.Init()
+	m.entries = map[string]*list.Element{}
+	return nil
+}
+
+// Len returns the number of results kept, expired ones included until they are dropped.
+func (m *MemoryBackend) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+func (m *MemoryBackend) remove(element *list.Element) {
+	m.order.Remove(element)
+	delete(m.entries, element.Value.(*entry).key)
+}
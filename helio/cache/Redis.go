@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+const (
+	redisPrefix     = "helio:cache:"
+	redisGeneration = redisPrefix + "generation"
+)
+
+// RedisBackend keeps results in redis, shared by every replica: a mutation made through one invalidates the results of
+// all. Results of older generations are left to expire.
+type RedisBackend struct {
+	client *redis.Client
+}
+
+// NewRedis returns the backend of the redis at url, i.e. redis://:password@redis:6379/0. Nothing is connected to until
+// the first read.
+func NewRedis(url string) (*RedisBackend, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url")
+	}
+	return &RedisBackend{client: redis.NewClient(options)}, nil
+}
+
+func (r *RedisBackend) Generation(ctx context.Context) (uint64, error) {
+	generation, err := r.client.Get(ctx, redisGeneration).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return generation, err
+}
+
+func (r *RedisBackend) Get(key string, ctx context.Context) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, redisPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *RedisBackend) Set(key string, value []byte, ttl time.Duration, ctx context.Context) error {
+	return r.client.Set(ctx, redisPrefix+key, value, ttl).Err()
+}
+
+func (r *RedisBackend) Invalidate(ctx context.Context) error {
+	return r.client.Incr(ctx, redisGeneration).Err()
+}
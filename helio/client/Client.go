@@ -0,0 +1,212 @@
+// Package client is a typed Go client of the helio API sharing its model. Requests failing with a network error or a
+// 429, 502, 503 or 504 are retried with exponential backoff, responses refused by helio are returned as *Error.
+//
+//	c, err := client.New("http://localhost:8000", client.Config{Token: token})
+//	entity, err := c.Get(105781710, ctx)
+//	if errors.Is(err, client.ErrNotFound) { ... }
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"math/rand"
+	"mbcarruthers/helio/validation"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxErrorBody = 1 << 20 // largest error body read
+)
+
+// Retry is how requests are retried.
+type Retry struct {
+	Max      int           // retries after the first attempt, 0 never retries
+	Base     time.Duration // delay before the first retry, doubled every retry
+	MaxDelay time.Duration // longest delay, requests asked to wait longer by Retry-After are not retried
+}
+
+// DefaultRetry retries 3 times, waiting about 100ms, 200ms then 400ms.
+func DefaultRetry() Retry {
+	return Retry{Max: 3, Base: 100 * time.Millisecond, MaxDelay: 5 * time.Second}
+}
+
+// delay returns how long to wait before the retry following attempt(0 being the first), a random half of it
+// spreading out clients retrying together.
+func (r Retry) delay(attempt int) time.Duration {
+	delay := r.Base << attempt
+	if delay > r.MaxDelay || delay <= 0 {
+		delay = r.MaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// Config configures a Client, every field is optional.
+type Config struct {
+	Token      string       // bearer token, required by curator and admin operations
+	APIKey     string       // partner key sent as X-API-Key
+	HTTPClient *http.Client // http.DefaultClient when nil
+	Retry      *Retry       // DefaultRetry when nil
+	UserAgent  string
+}
+
+// Client calls the helio API. It is safe for concurrent use.
+type Client struct {
+	base   *url.URL
+	config Config
+	retry  Retry
+}
+
+// New returns a Client of the helio API at baseURL, i.e. http://localhost:8000
+func New(baseURL string, config Config) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing base url: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("base url %q must be http or https", baseURL)
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	retry := DefaultRetry()
+	if config.Retry != nil {
+		retry = *config.Retry
+	}
+	return &Client{base: base, config: config, retry: retry}, nil
+}
+
+// retryable reports whether a request is retried after the response's status. Requests that are not idempotent are
+// only retried once helio refused them without running them.
+func retryable(method string, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return method != http.MethodPost
+	}
+	return false
+}
+
+// streamDecoder reads a streamed response as it arrives, in place of out being decoded at once.
+type streamDecoder func(decoder *json.Decoder) error
+
+// do sends a request with the body encoded as json, retrying it, and decodes the response into out unless it is nil.
+// out may be a streamDecoder. It returns the response's headers.
+func (c *Client) do(method string, path string, query url.Values, body any, out any, ctx context.Context) (http.Header, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("encoding request: %w", err)
+		}
+	}
+	target := *c.base
+	target.Path += path
+	target.RawQuery = query.Encode()
+	requestId := uuid.NewString() // the same across retries, so helio's logs show them as one request
+
+	for attempt := 0; ; attempt++ {
+		request, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Accept", "application/json")
+		request.Header.Set("X-Request-Id", requestId)
+		if payload != nil {
+			request.Header.Set("Content-Type", "application/json")
+		}
+		if c.config.Token != "" {
+			request.Header.Set("Authorization", "Bearer "+c.config.Token)
+		}
+		if c.config.APIKey != "" {
+			request.Header.Set("X-API-Key", c.config.APIKey)
+		}
+		if c.config.UserAgent != "" {
+			request.Header.Set("User-Agent", c.config.UserAgent)
+		}
+
+		response, err := c.config.HTTPClient.Do(request)
+		var wait time.Duration
+		if err != nil {
+			// requests that are not idempotent may have run before the connection failed
+			if ctx.Err() != nil || method == http.MethodPost || attempt >= c.retry.Max {
+				return nil, err
+			}
+			wait = c.retry.delay(attempt)
+		} else if response.StatusCode >= 400 {
+			failure := decodeError(request, response)
+			if !retryable(method, response.StatusCode) || attempt >= c.retry.Max || failure.RetryAfter > c.retry.MaxDelay {
+				return response.Header, failure
+			}
+			wait = c.retry.delay(attempt)
+			if failure.RetryAfter > wait {
+				wait = failure.RetryAfter
+			}
+		} else {
+			defer response.Body.Close()
+			if out == nil {
+				_, _ = io.Copy(io.Discard, response.Body)
+				return response.Header, nil
+			}
+			if stream, ok := out.(streamDecoder); ok {
+				return response.Header, stream(json.NewDecoder(response.Body))
+			}
+			if err = json.NewDecoder(response.Body).Decode(out); err != nil {
+				return response.Header, fmt.Errorf("decoding response of %s %s: %w", method, path, err)
+			}
+			return response.Header, nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// decodeError reads a response helio refused or failed with and closes its body.
+func decodeError(request *http.Request, response *http.Response) *Error {
+	defer response.Body.Close()
+	failure := &Error{
+		StatusCode: response.StatusCode,
+		Method:     request.Method,
+		Path:       request.URL.Path,
+		RequestId:  response.Header.Get("X-Request-Id"),
+	}
+	body, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBody))
+	var detail struct {
+		Error      string                 `json:"error"`
+		Err        string                 `json:"err"` // the search route names the error err
+		Message    string                 `json:"message"`
+		Violations []validation.Violation `json:"violations"`
+		Problems   []string               `json:"problems"`
+	}
+	if json.Unmarshal(body, &detail) == nil {
+		failure.Err, failure.Message = detail.Error, detail.Message
+		failure.Violations, failure.Problems = detail.Violations, detail.Problems
+		if failure.Err == "" {
+			failure.Err = detail.Err
+		}
+	}
+	if failure.Err == "" {
+		failure.Err = strings.TrimSpace(string(body))
+	}
+	if failure.Err == "" {
+		failure.Err = http.StatusText(response.StatusCode)
+	}
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		failure.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return failure
+}
@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// script answers the requests it gets in turn with its handlers, the last one answering every request after.
+type script struct {
+	mu         sync.Mutex
+	handlers   []http.HandlerFunc
+	requestIds []string
+}
+
+func (s *script) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	handler := s.handlers[len(s.handlers)-1] // the last one answers every later request
+	if len(s.requestIds) < len(s.handlers) {
+		handler = s.handlers[len(s.requestIds)]
+	}
+	s.requestIds = append(s.requestIds, r.Header.Get("X-Request-Id"))
+	s.mu.Unlock()
+	handler(w, r)
+}
+
+func (s *script) attempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requestIds)
+}
+
+// status answers with the status and body, along with the headers given as name, value pairs.
+func status(code int, body string, headers ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i+1 < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+		w.WriteHeader(code)
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+// hangUp closes the connection without an answer.
+func hangUp(w http.ResponseWriter, r *http.Request) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err == nil {
+		_ = conn.Close()
+	}
+}
+
+var ok = status(http.StatusOK, `{"id": 1, "species_guess": "Monarch"}`)
+
+// newScripted returns a Client of a server answering with handlers, retrying quickly.
+func newScripted(t *testing.T, handlers ...http.HandlerFunc) (*Client, *script) {
+	s := &script{handlers: handlers}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	c, err := New(server.URL, Config{Retry: &Retry{Max: 3, Base: time.Millisecond, MaxDelay: 50 * time.Millisecond}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, s
+}
+
+func TestRetry(t *testing.T) {
+	unavailable := status(http.StatusServiceUnavailable, `{"error": "err database unavailable"}`)
+	tests := []struct {
+		name         string
+		create       bool // POST rather than GET
+		handlers     []http.HandlerFunc
+		wantAttempts int
+		wantErr      error // nil when it succeeds
+	}{
+		{"success", false, []http.HandlerFunc{ok}, 1, nil},
+		{"503 then success", false, []http.HandlerFunc{unavailable, unavailable, ok}, 3, nil},
+		{"429 then success", false, []http.HandlerFunc{status(http.StatusTooManyRequests, "", "Retry-After", "0"), ok}, 2, nil},
+		{"502 then success", false, []http.HandlerFunc{status(http.StatusBadGateway, ""), ok}, 2, nil},
+		{"504 then success", false, []http.HandlerFunc{status(http.StatusGatewayTimeout, ""), ok}, 2, nil},
+		{"hang up then success", false, []http.HandlerFunc{hangUp, ok}, 2, nil},
+		{"retries exhausted", false, []http.HandlerFunc{unavailable}, 4, ErrUnavailable},
+		{"retry after too long", false, []http.HandlerFunc{status(http.StatusServiceUnavailable, "", "Retry-After", "60"), ok}, 1, ErrUnavailable},
+		{"not retried 500", false, []http.HandlerFunc{status(http.StatusInternalServerError, ""), ok}, 1, ErrServer},
+		{"not retried 404", false, []http.HandlerFunc{status(http.StatusNotFound, ""), ok}, 1, ErrNotFound},
+		// a create is only retried once helio refused it without running it
+		{"create 503 then success", true, []http.HandlerFunc{unavailable, ok}, 2, nil},
+		{"create 429 then success", true, []http.HandlerFunc{status(http.StatusTooManyRequests, ""), ok}, 2, nil},
+		{"create not retried 502", true, []http.HandlerFunc{status(http.StatusBadGateway, ""), ok}, 1, ErrUnavailable},
+		{"create not retried 504", true, []http.HandlerFunc{status(http.StatusGatewayTimeout, ""), ok}, 1, ErrUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, s := newScripted(t, tt.handlers...)
+			var err error
+			if tt.create {
+				_, err = c.Create(entity(0), context.Background())
+			} else {
+				_, err = c.Get(1, context.Background())
+			}
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if s.attempts() != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", s.attempts(), tt.wantAttempts)
+			}
+			for _, requestId := range s.requestIds {
+				if requestId == "" || requestId != s.requestIds[0] {
+					t.Errorf("X-Request-Id of the attempts = %v, want the same one", s.requestIds)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestCreateHangUpNotRetried(t *testing.T) {
+	c, s := newScripted(t, hangUp, ok)
+	if _, err := c.Create(entity(0), context.Background()); err == nil {
+		t.Error("Create() = nil, want the connection's error")
+	}
+	if s.attempts() != 1 {
+		t.Errorf("attempts = %d, want 1, the create may have run", s.attempts())
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	s := &script{handlers: []http.HandlerFunc{status(http.StatusServiceUnavailable, "", "Retry-After", "1")}}
+	server := httptest.NewServer(s)
+	defer server.Close()
+	c, err := New(server.URL, Config{Retry: &Retry{Max: 3, Base: time.Millisecond, MaxDelay: time.Minute}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = c.Get(1, ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() error = %v, want the context's error while waiting to retry", err)
+	}
+	if s.attempts() != 1 {
+		t.Errorf("attempts = %d, want 1", s.attempts())
+	}
+}
+
+func TestErrorDecoding(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		want        error
+		wantErr     string
+		wantMessage string
+		check       func(t *testing.T, failure *Error)
+	}{
+		{"json error", status(http.StatusNotFound, `{"error": "err not found"}`), ErrNotFound, "err not found", "", nil},
+		{"json error and message", status(http.StatusConflict, `{"error": "err exists", "message": "entity exists"}`),
+			ErrConflict, "err exists", "entity exists", nil},
+		{"err of the search route", status(http.StatusBadRequest, `{"err": "invalid date1"}`), ErrInvalid, "invalid date1", "", nil},
+		{"violations", status(http.StatusBadRequest,
+			`{"error": "validation failed", "message": "entity failed validation", "violations": [{"field": "species_guess", "flag": "missing_species_guess", "message": "species_guess must not be empty"}]}`),
+			ErrInvalid, "validation failed", "entity failed validation", func(t *testing.T, failure *Error) {
+				if len(failure.Violations) != 1 || failure.Violations[0].Field != "species_guess" || failure.Violations[0].Flag != "missing_species_guess" {
+					t.Errorf("Violations = %+v, want species_guess", failure.Violations)
+				}
+			}},
+		{"problems", status(http.StatusBadRequest, `{"error": "err invalid request", "problems": ["query parameter limit: must be at most 1000"]}`),
+			ErrInvalid, "err invalid request", "", func(t *testing.T, failure *Error) {
+				if len(failure.Problems) != 1 {
+					t.Errorf("Problems = %v, want one", failure.Problems)
+				}
+			}},
+		{"plain text", status(http.StatusUnauthorized, "  unauthorized\n"), ErrUnauthorized, "unauthorized", "", nil},
+		{"empty body", status(http.StatusForbidden, ""), ErrForbidden, "Forbidden", "", nil},
+		{"server error", status(http.StatusInternalServerError, `{"error": "err execute"}`), ErrServer, "err execute", "", nil},
+		{"not implemented", status(http.StatusNotImplemented, ""), ErrServer, "Not Implemented", "", nil},
+		{"request id", status(http.StatusNotFound, "", "X-Request-Id", "abc-123"), ErrNotFound, "Not Found", "", func(t *testing.T, failure *Error) {
+			if failure.RequestId != "abc-123" {
+				t.Errorf("RequestId = %q, want abc-123", failure.RequestId)
+			}
+		}},
+		{"retry after", status(http.StatusServiceUnavailable, "", "Retry-After", "120"), ErrUnavailable, "Service Unavailable", "", func(t *testing.T, failure *Error) {
+			if failure.RetryAfter != 2*time.Minute {
+				t.Errorf("RetryAfter = %v, want 2m", failure.RetryAfter)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newScripted(t, tt.handler)
+			c.retry.Max = 0
+			_, err := c.Get(1, context.Background())
+			var failure *Error
+			if !errors.As(err, &failure) {
+				t.Fatalf("error = %v, want an *Error", err)
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want it to match %v", err, tt.want)
+			}
+			for _, other := range []error{ErrInvalid, ErrUnauthorized, ErrForbidden, ErrNotFound, ErrConflict, ErrRateLimited, ErrUnavailable, ErrServer} {
+				if other != tt.want && errors.Is(err, other) {
+					t.Errorf("error = %v also matches %v", err, other)
+				}
+			}
+			if failure.Err != tt.wantErr || failure.Message != tt.wantMessage {
+				t.Errorf("Err, Message = %q, %q, want %q, %q", failure.Err, failure.Message, tt.wantErr, tt.wantMessage)
+			}
+			if failure.Method != http.MethodGet || failure.Path != "/entities/1" {
+				t.Errorf("Method, Path = %s %s, want GET /entities/1", failure.Method, failure.Path)
+			}
+			if tt.check != nil {
+				tt.check(t, failure)
+			}
+		})
+	}
+}
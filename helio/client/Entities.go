@@ -0,0 +1,216 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mbcarruthers/helio/model"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultPageSize is the number of entities an Iterator fetches at a time unless told otherwise.
+const DefaultPageSize = 100
+
+// nextLink finds the url of the next page within a Link header.
+var nextLink = regexp.MustCompile(`<([^>]*)>\s*;\s*rel="?next"?`)
+
+// Page is a page of a search.
+type Page struct {
+	Entities []model.Entity
+	After    string // cursor of the next page, empty on the last one
+}
+
+// searchValues encodes a search the way /entities/search binds it, dates are quoted.
+func searchValues(query model.SearchQuery) url.Values {
+	values := url.Values{}
+	if query.TaxonId != 0 {
+		values.Set("taxon_id", strconv.Itoa(query.TaxonId))
+	}
+	if query.Date1.Valid {
+		values.Set("date1", `"`+query.Date1.Time.Format("2006-01-02")+`"`)
+	}
+	if query.Date2.Valid {
+		values.Set("date2", `"`+query.Date2.Time.Format("2006-01-02")+`"`)
+	}
+	if len(query.QualityGrade) != 0 {
+		values.Set("quality_grade", strings.Join(query.QualityGrade, ","))
+	}
+	if query.QualityFlag != "" {
+		values.Set("quality_flag", query.QualityFlag)
+	}
+	if query.Geoprivacy != "" {
+		values.Set("geoprivacy", query.Geoprivacy)
+	}
+	if query.Merged {
+		values.Set("merged", "true")
+	}
+	return values
+}
+
+// entityPath returns the path of an entity.
+func entityPath(id int, suffix string) string {
+	return "/entities/" + strconv.Itoa(id) + suffix
+}
+
+// Get returns the entity by id.
+func (c *Client) Get(id int, ctx context.Context) (model.Entity, error) {
+	var entity model.Entity
+	_, err := c.do(http.MethodGet, entityPath(id, ""), nil, nil, &entity, ctx)
+	return entity, err
+}
+
+// List returns the published entities, rejected entities and entities merged into another are left out.
+func (c *Client) List(ctx context.Context) ([]model.Entity, error) {
+	var entities []model.Entity
+	_, err := c.do(http.MethodGet, "/entities/", nil, nil, &entities, ctx)
+	return entities, err
+}
+
+// Search returns every entity matching the query, see Iterate for large searches.
+func (c *Client) Search(query model.SearchQuery, ctx context.Context) ([]model.Entity, error) {
+	var entities []model.Entity
+	_, err := c.do(http.MethodGet, "/entities/search", searchValues(query), nil, &entities, ctx)
+	return entities, err
+}
+
+// Stream calls each with every entity as it arrives, without holding them, a nil query streaming the published entities
+// like List and any other the entities matching it like Search. An error of each stops the stream and is returned. A
+// stream helio fails once it started returns an *Error matching ErrServer.
+func (c *Client) Stream(query *model.SearchQuery, each func(model.Entity) error, ctx context.Context) error {
+	path, values := "/entities/", url.Values{}
+	if query != nil {
+		path, values = "/entities/search", searchValues(*query)
+	}
+	values.Set("stream", "true")
+	_, err := c.do(http.MethodGet, path, values, nil, streamDecoder(func(decoder *json.Decoder) error {
+		for {
+			var line struct {
+				model.Entity
+				Error     string `json:"error"` // only held by the line ending a failed stream
+				RequestId string `json:"request_id"`
+			}
+			if err := decoder.Decode(&line); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("decoding stream of %s: %w", path, err)
+			}
+			if line.Error != "" {
+				return &Error{StatusCode: http.StatusInternalServerError, Method: http.MethodGet, Path: path, Err: line.Error,
+					Message: "the stream failed once started", RequestId: line.RequestId}
+			}
+			if err := each(line.Entity); err != nil {
+				return err
+			}
+		}
+	}), ctx)
+	return err
+}
+
+// SearchPage returns a page of the entities matching the query. A page's After asks for the next page.
+func (c *Client) SearchPage(query model.SearchQuery, page model.PageQuery, ctx context.Context) (Page, error) {
+	values := searchValues(query)
+	if page.Limit != 0 {
+		values.Set("limit", strconv.Itoa(page.Limit))
+	}
+	if page.After != "" {
+		values.Set("after", page.After)
+	}
+	var result Page
+	header, err := c.do(http.MethodGet, "/entities/search", values, nil, &result.Entities, ctx)
+	if err != nil {
+		return Page{}, err
+	}
+	if match := nextLink.FindStringSubmatch(header.Get("Link")); match != nil {
+		if next, err := url.Parse(match[1]); err == nil {
+			result.After = next.Query().Get("after")
+		}
+	}
+	return result, nil
+}
+
+// Create creates an entity, which requires the curator role. Returns the entity as stored.
+func (c *Client) Create(entity model.Entity, ctx context.Context) (model.Entity, error) {
+	var created model.Entity
+	_, err := c.do(http.MethodPost, "/entities/", nil, entity, &created, ctx)
+	return created, err
+}
+
+// Update updates an existing entity, which requires the curator role. Its taxon_id is never updated.
+func (c *Client) Update(id int, entity model.Entity, ctx context.Context) error {
+	entity.Id = id
+	_, err := c.do(http.MethodPut, entityPath(id, ""), nil, entity, nil, ctx)
+	return err
+}
+
+// Delete deletes an entity, which requires the admin role. A retried delete may find the entity deleted by the
+// attempt before it and return ErrNotFound.
+func (c *Client) Delete(id int, ctx context.Context) error {
+	_, err := c.do(http.MethodDelete, entityPath(id, ""), nil, nil, nil, ctx)
+	return err
+}
+
+// Restore restores a deleted entity, which requires the admin role. Returns ErrConflict if it exists.
+func (c *Client) Restore(id int, ctx context.Context) (model.Entity, error) {
+	var restored model.Entity
+	_, err := c.do(http.MethodPost, entityPath(id, "/restore"), nil, nil, &restored, ctx)
+	return restored, err
+}
+
+// Iterator walks through every entity of a search a page at a time.
+//
+//	it := c.Iterate(query, 500)
+//	for it.Next(ctx) {
+//		entity := it.Entity()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type Iterator struct {
+	client   *Client
+	query    model.SearchQuery
+	page     model.PageQuery
+	entities []model.Entity
+	current  model.Entity
+	last     bool // the page held by entities is the last one
+	err      error
+}
+
+// Iterate returns an Iterator over the entities matching the query, fetching pageSize of them at a time(at most 1000,
+// DefaultPageSize when 0).
+func (c *Client) Iterate(query model.SearchQuery, pageSize int) *Iterator {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return &Iterator{client: c, query: query, page: model.PageQuery{Limit: pageSize}}
+}
+
+// Next advances to the next entity, fetching the next page once needed. It returns false once every entity was
+// walked through or fetching failed, see Err.
+func (it *Iterator) Next(ctx context.Context) bool {
+	for len(it.entities) == 0 {
+		if it.last || it.err != nil {
+			return false
+		}
+		page, err := it.client.SearchPage(it.query, it.page, ctx)
+		if err != nil {
+			it.err = fmt.Errorf("fetching page: %w", err)
+			return false
+		}
+		it.entities, it.page.After, it.last = page.Entities, page.After, page.After == ""
+	}
+	it.current, it.entities = it.entities[0], it.entities[1:]
+	return true
+}
+
+// Entity returns the current entity.
+func (it *Iterator) Entity() model.Entity {
+	return it.current
+}
+
+// Err returns the error that stopped the Iterator, nil once every entity was walked through.
+func (it *Iterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,221 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"mbcarruthers/helio/auth"
+	"mbcarruthers/helio/client/heliotest"
+	"mbcarruthers/helio/model"
+	"mbcarruthers/helio/validation"
+	"sort"
+	"testing"
+	"time"
+)
+
+// entity returns an entity with the id breaking none of the default rules.
+func entity(id int) model.Entity {
+	return model.Entity{
+		Id:           id,
+		TaxonId:      48662,
+		Uuid:         uuid.New(),
+		PlaceGuess:   "Gainesville, FL, USA",
+		SpeciesGuess: "Monarch",
+		Latitude:     "29.6516",
+		Longitude:    "-82.3248",
+		ObservedOn:   pgtype.Date{Time: time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, id%300), Valid: true},
+		TimeZone:     "Eastern Time (US & Canada)",
+	}
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func newServer(t *testing.T, mode validation.Mode, entities ...model.Entity) *heliotest.Server {
+	server, err := heliotest.NewServer(mode, entities...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newClient returns a Client of the server calling as role, anonymously when role is empty.
+func newClient(t *testing.T, server *heliotest.Server, role auth.Role) *Client {
+	var config Config
+	if role != "" {
+		token, err := server.Token(role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.Token = token
+	}
+	c, err := New(server.URL, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestGet(t *testing.T) {
+	server := newServer(t, validation.Warn, entity(1), entity(2))
+	c := newClient(t, server, "")
+	ctx := context.Background()
+
+	got, err := c.Get(2, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Id != 2 || got.SpeciesGuess != "Monarch" || got.QualityGrade != model.GradeClean {
+		t.Errorf("Get(2) = %+v, want the clean entity 2", got)
+	}
+	if _, err = c.Get(3, ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(3) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCreateUpdateDelete(t *testing.T) {
+	server := newServer(t, validation.Warn, entity(1))
+	ctx := context.Background()
+	anonymous, viewer := newClient(t, server, ""), newClient(t, server, auth.Viewer)
+	curator, admin := newClient(t, server, auth.Curator), newClient(t, server, auth.Admin)
+
+	if _, err := anonymous.Create(entity(0), ctx); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("anonymous Create() error = %v, want ErrUnauthorized", err)
+	}
+	if _, err := viewer.Create(entity(0), ctx); !errors.Is(err, ErrForbidden) {
+		t.Errorf("viewer Create() error = %v, want ErrForbidden", err)
+	}
+	created, err := curator.Create(entity(0), ctx)
+	if err != nil {
+		t.Fatalf("curator Create() error = %v", err)
+	}
+	if stored, err := curator.Get(created.Id, ctx); err != nil || stored.SpeciesGuess != "Monarch" {
+		t.Errorf("Get(%d) = %+v, %v, want the created entity", created.Id, stored, err)
+	}
+
+	update := entity(1)
+	update.SpeciesGuess = "Queen"
+	if err = viewer.Update(1, update, ctx); !errors.Is(err, ErrForbidden) {
+		t.Errorf("viewer Update() error = %v, want ErrForbidden", err)
+	}
+	if err = curator.Update(1, update, ctx); err != nil {
+		t.Fatalf("curator Update() error = %v", err)
+	}
+	if updated, err := curator.Get(1, ctx); err != nil || updated.SpeciesGuess != "Queen" {
+		t.Errorf("Get(1) = %+v, %v, want species_guess Queen", updated, err)
+	}
+	if err = curator.Update(404, update, ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update(404) error = %v, want ErrNotFound", err)
+	}
+
+	if err = curator.Delete(1, ctx); !errors.Is(err, ErrForbidden) {
+		t.Errorf("curator Delete() error = %v, want ErrForbidden", err)
+	}
+	if err = admin.Delete(1, ctx); err != nil {
+		t.Fatalf("admin Delete() error = %v", err)
+	}
+	if _, err = admin.Get(1, ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(1) once deleted error = %v, want ErrNotFound", err)
+	}
+	if err = admin.Delete(1, ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete(1) once deleted error = %v, want ErrNotFound", err)
+	}
+
+	restored, err := admin.Restore(1, ctx)
+	if err != nil || restored.SpeciesGuess != "Queen" {
+		t.Fatalf("Restore(1) = %+v, %v, want the entity as it was deleted", restored, err)
+	}
+	if _, err = admin.Restore(1, ctx); !errors.Is(err, ErrConflict) {
+		t.Errorf("Restore(1) of an existing entity error = %v, want ErrConflict", err)
+	}
+
+	actions := []string{}
+	for _, entry := range server.Store.AuditEntries() {
+		actions = append(actions, entry.Action)
+	}
+	want := []string{model.AuditImport, model.AuditCreate, model.AuditUpdate, model.AuditDelete, model.AuditRestore}
+	if len(actions) != len(want) {
+		t.Fatalf("audit log = %v, want %v", actions, want)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Errorf("audit log = %v, want %v", actions, want)
+			break
+		}
+	}
+}
+
+func TestCreateInvalid(t *testing.T) {
+	server := newServer(t, validation.Strict)
+	c := newClient(t, server, auth.Curator)
+	invalid := entity(0)
+	invalid.SpeciesGuess = ""
+	_, err := c.Create(invalid, context.Background())
+	var failure *Error
+	if !errors.Is(err, ErrInvalid) || !errors.As(err, &failure) {
+		t.Fatalf("Create() error = %v, want an *Error matching ErrInvalid", err)
+	}
+	if len(failure.Violations) != 1 || failure.Violations[0].Flag != "missing_species_guess" {
+		t.Errorf("Violations = %+v, want missing_species_guess", failure.Violations)
+	}
+	if failure.RequestId == "" {
+		t.Error("RequestId is empty, want the X-Request-Id of the request")
+	}
+}
+
+func TestSearchPaging(t *testing.T) {
+	entities := make([]model.Entity, 0, 25)
+	for id := 1; id <= 25; id++ {
+		entities = append(entities, entity(id))
+	}
+	server := newServer(t, validation.Warn, entities...)
+	c := newClient(t, server, "")
+	ctx := context.Background()
+
+	page, err := c.SearchPage(model.SearchQuery{}, model.PageQuery{Limit: 10}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Entities) != 10 || page.After == "" {
+		t.Fatalf("SearchPage() = %d entities, after %q, want 10 and a next page", len(page.Entities), page.After)
+	}
+
+	for _, pageSize := range []int{1, 7, 10, 25, 100} {
+		it := c.Iterate(model.SearchQuery{}, pageSize)
+		seen := map[int]bool{}
+		ids := []int{}
+		for it.Next(ctx) {
+			id := it.Entity().Id
+			if seen[id] {
+				t.Fatalf("Iterate(%d) walked through %d twice", pageSize, id)
+			}
+			seen[id] = true
+			ids = append(ids, id)
+		}
+		if err = it.Err(); err != nil {
+			t.Fatalf("Iterate(%d) error = %v", pageSize, err)
+		}
+		sort.Ints(ids)
+		if len(ids) != 25 || ids[0] != 1 || ids[24] != 25 {
+			t.Errorf("Iterate(%d) walked through %v, want 1 to 25", pageSize, ids)
+		}
+	}
+
+	all, err := c.Search(model.SearchQuery{}, ctx)
+	if err != nil || len(all) != 25 {
+		t.Errorf("Search() = %d entities, %v, want 25", len(all), err)
+	}
+}
+
+func TestSearchPageInvalid(t *testing.T) {
+	server := newServer(t, validation.Warn, entity(1))
+	c := newClient(t, server, "")
+	_, err := c.SearchPage(model.SearchQuery{}, model.PageQuery{After: "not a cursor"}, context.Background())
+	if !errors.Is(err, ErrInvalid) {
+		t.Errorf("SearchPage() of a malformed cursor error = %v, want ErrInvalid", err)
+	}
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"mbcarruthers/helio/validation"
+	"net/http"
+	"time"
+)
+
+// Errors an *Error matches with errors.Is, by the status of the response.
+var (
+	ErrInvalid      = errors.New("err invalid request")         // 400, validation violations are within the *Error
+	ErrUnauthorized = errors.New("err authentication required") // 401
+	ErrForbidden    = errors.New("err role not allowed")        // 403
+	ErrNotFound     = errors.New("err not found")               // 404
+	ErrConflict     = errors.New("err conflict")                // 409
+	ErrRateLimited  = errors.New("err rate limited")            // 429
+	ErrUnavailable  = errors.New("err unavailable")             // 502, 503 and 504
+	ErrServer       = errors.New("err server")                  // any other 5xx
+)
+
+// Error is a response helio refused or failed with.
+type Error struct {
+	StatusCode int
+	Method     string
+	Path       string
+	Err        string // the error, as helio words it
+	Message    string // what went wrong, if helio says
+	Violations []validation.Violation
+	Problems   []string      // where the request did not conform to the OpenAPI document
+	RequestId  string        // X-Request-Id of the request, to look up in helio's logs and audit log
+	RetryAfter time.Duration // how long to wait before retrying, if helio says
+}
+
+func (e *Error) Error() string {
+	detail := e.Err
+	if e.Message != "" {
+		detail += ": " + e.Message
+	}
+	return fmt.Sprintf("helio %s %s: %d %s", e.Method, e.Path, e.StatusCode, detail)
+}
+
+// Is matches the error of the response's status.
+func (e *Error) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return target == ErrInvalid
+	case http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case http.StatusForbidden:
+		return target == ErrForbidden
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusConflict:
+		return target == ErrConflict
+	case http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return target == ErrUnavailable
+	}
+	return e.StatusCode >= 500 && target == ErrServer
+}
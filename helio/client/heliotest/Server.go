@@ -0,0 +1,77 @@
+// Package heliotest serves the helio entity routes from an in-memory store(memory.Store) on an httptest.Server, so
+// clients can be tested against the real handlers, authorization and OpenAPI validation without a database.
+package heliotest
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/helio/auth"
+	"mbcarruthers/helio/dataservice/memory"
+	"mbcarruthers/helio/geoprivacy"
+	"mbcarruthers/helio/model"
+	"mbcarruthers/helio/openapi"
+	"mbcarruthers/helio/requestid"
+	"mbcarruthers/helio/routes"
+	"mbcarruthers/helio/service"
+	"mbcarruthers/helio/validation"
+	"net/http/httptest"
+	"time"
+)
+
+// Policy is the role required by each protected entity route, the same as helio's routePolicy.
+var Policy = auth.Policy{
+	"POST /entities/":            auth.Curator,
+	"PUT /entities/:id":          auth.Curator,
+	"DELETE /entities/:id":       auth.Admin,
+	"POST /entities/:id/restore": auth.Admin,
+}
+
+// Server serves the entity routes. Close it once done.
+type Server struct {
+	*httptest.Server
+	Store  *memory.Store
+	signer *auth.Signer
+}
+
+// NewServer starts a Server holding the entities, validated in the given mode like an import. Entities are published
+// according to the default geoprivacy policy.
+func NewServer(mode validation.Mode, entities ...model.Entity) (*Server, error) {
+	signer, err := auth.NewSigner("heliotest", "heliotest")
+	if err != nil {
+		return nil, err
+	}
+	validator, err := validation.NewValidator(validation.DefaultRules(), mode)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := openapi.Load()
+	if err != nil {
+		return nil, err
+	}
+	store := memory.NewStore()
+	entityService := service.NewEntities(store, validator)
+	entityService.Import(entities, context.Background())
+	handler := routes.NewEntityRoutes(entityService, geoprivacy.DefaultPolicy())
+
+	authenticator := auth.NewAuthenticator(signer.Verifier(), "heliotest", "heliotest")
+	r := gin.New()
+	_ = r.SetTrustedProxies(nil) // the same as helio without trusted_proxies
+	r.Use(requestid.Middleware(), authenticator.Authenticate(), auth.Authorize(Policy), auth.Grant(geoprivacy.TrustedKey, auth.Curator))
+	r.Use(spec.Validate())
+	group := r.Group("/entities")
+	{
+		group.POST("/", handler.NewEntityHandler)
+		group.GET("/:id", handler.GetEntityById)
+		group.GET("/", handler.ListEntityHandler)
+		group.PUT("/:id", handler.UpdateEntityHandler)
+		group.DELETE("/:id", handler.DeleteEntityHandler)
+		group.POST("/:id/restore", handler.RestoreEntityHandler)
+		group.GET("/search", handler.SearchEntitiesWithinDateRange)
+	}
+	return &Server{Server: httptest.NewServer(r), Store: store, signer: signer}, nil
+}
+
+// Token returns a bearer token of the role accepted by the Server for an hour.
+func (s *Server) Token(role auth.Role) (string, error) {
+	return s.signer.Mint("heliotest", time.Hour, role)
+}
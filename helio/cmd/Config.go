@@ -0,0 +1,285 @@
+package main
+
+import (
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"mbcarruthers/config"
+	"mbcarruthers/helio/apikey"
+	"mbcarruthers/helio/auth"
+	"mbcarruthers/helio/cache"
+	"mbcarruthers/helio/dataservice/db"
+	"mbcarruthers/helio/graph"
+	"mbcarruthers/helio/requestid"
+	"mbcarruthers/helio/routes"
+	"mbcarruthers/helio/validation"
+	"mbcarruthers/logging"
+	"mbcarruthers/tracing"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// Config is the configuration of helio, loaded from HELIO_CONFIG(or -config), the environment and flags.
+// cors_origins and log_level are reloaded on SIGHUP.
+type Config struct {
+	Port             int          `config:"port" env:"PORT" usage:"HTTP port"`
+	GrpcPort         int          `config:"grpc_port" env:"GRPC_PORT" usage:"gRPC port"`
+	DSN              string       `config:"dsn" env:"DSN" secret:"true" usage:"CockroachDB connection string"`
+	DataFile         string       `config:"data_file" env:"DATA_FILE" usage:"json array of entities imported into a new database"`
+	CorsOrigins      []string     `config:"cors_origins" env:"CORS_ORIGINS" reload:"true" usage:"origins allowed by CORS, * allows any"`
+	LogLevel         config.Level `config:"log_level" env:"LOG_LEVEL" reload:"true" usage:"debug, info, warn or error, requests are logged at debug and info"`
+	GeoprivacyPolicy string       `config:"geoprivacy_policy" env:"GEOPRIVACY_POLICY" usage:"json file setting the geoprivacy of sensitive taxa"`
+	TrustedProxies   []string     `config:"trusted_proxies" env:"TRUSTED_PROXIES" usage:"ips or CIDRs of the proxies in front of helio whose X-Forwarded-For names the client, empty trusts none"`
+
+	Database struct {
+		ConnectBackoff     time.Duration `config:"connect_backoff" env:"DB_CONNECT_BACKOFF" usage:"wait after the first failed connection attempt, doubled after every other"`
+		ConnectMaxBackoff  time.Duration `config:"connect_max_backoff" env:"DB_CONNECT_MAX_BACKOFF" usage:"longest wait between connection attempts"`
+		BreakerThreshold   int           `config:"breaker_threshold" env:"DB_BREAKER_THRESHOLD" usage:"failures to reach the database in a row opening the circuit breaker"`
+		BreakerCooldown    time.Duration `config:"breaker_cooldown" env:"DB_BREAKER_COOLDOWN" usage:"time the circuit breaker stays open, doubled every time it opens again"`
+		BreakerMaxCooldown time.Duration `config:"breaker_max_cooldown" env:"DB_BREAKER_MAX_COOLDOWN" usage:"longest time the circuit breaker stays open"`
+		StatementTimeout   time.Duration `config:"statement_timeout" env:"DB_STATEMENT_TIMEOUT" usage:"time the database gives any statement before cancelling it, 0 leaves it to the database"`
+	} `config:"database"`
+
+	Cache struct {
+		Backend    string        `config:"backend" env:"CACHE_BACKEND" usage:"none, memory or redis(shared by replicas) keeps the results of reads"`
+		TTL        time.Duration `config:"ttl" env:"CACHE_TTL" usage:"time a result is kept, mutations drop every result right away"`
+		MaxEntries int           `config:"max_entries" env:"CACHE_MAX_ENTRIES" usage:"results kept by the memory backend, the least recently used dropped first"`
+		RedisURL   string        `config:"redis_url" env:"CACHE_REDIS_URL" secret:"true" usage:"redis://… url of the redis backend"`
+		MaxAge     time.Duration `config:"max_age" env:"CACHE_MAX_AGE" usage:"time browsers and CDNs may keep lists, searches and aggregates answered to anonymous callers, 0 revalidates every time"`
+	} `config:"cache"`
+
+	Compression struct {
+		Encodings []string `config:"encodings" env:"COMPRESSION_ENCODINGS" usage:"zstd, br and gzip compress responses, preferred in the order given when a request accepts several, empty compresses nothing"`
+		MinSize   int      `config:"min_size" env:"COMPRESSION_MIN_SIZE" usage:"bytes a response holds before it is compressed"`
+	} `config:"compression"`
+
+	Timeouts struct {
+		Default time.Duration `config:"default" env:"REQUEST_TIMEOUT" usage:"time a request or unary rpc has before its statements are cancelled"`
+		Routes  []string      `config:"routes" env:"ROUTE_TIMEOUTS" usage:"timeouts of single routes, i.e. GET /audit/export=2m"`
+	} `config:"timeouts"`
+
+	Shutdown struct {
+		DrainDelay time.Duration `config:"drain_delay" env:"SHUTDOWN_DRAIN_DELAY" usage:"time readiness fails before the listeners close on SIGINT or SIGTERM"`
+		Timeout    time.Duration `config:"timeout" env:"SHUTDOWN_TIMEOUT" usage:"time given to requests in flight before they are cancelled"`
+	} `config:"shutdown"`
+
+	Validation struct {
+		Mode  string `config:"mode" env:"VALIDATION_MODE" usage:"strict refuses entities breaking a rule, warn flags them"`
+		Rules string `config:"rules" env:"VALIDATION_RULES" usage:"json file of validation rules"`
+	} `config:"validation"`
+
+	GraphQL struct {
+		MaxComplexity int `config:"max_complexity" env:"GRAPHQL_MAX_COMPLEXITY" usage:"highest estimated cost of a query"`
+		MaxDepth      int `config:"max_depth" env:"GRAPHQL_MAX_DEPTH" usage:"deepest selection of a query"`
+	} `config:"graphql"`
+
+	Auth struct {
+		JWKSURL  string `config:"jwks_url" env:"AUTH_JWKS_URL" usage:"JWKS validating bearer tokens"`
+		KeyFile  string `config:"key_file" env:"AUTH_KEY_FILE" usage:"PEM public key validating bearer tokens, if there is no jwks_url"`
+		Issuer   string `config:"issuer" env:"AUTH_ISSUER" usage:"required iss of bearer tokens"`
+		Audience string `config:"audience" env:"AUTH_AUDIENCE" usage:"required aud of bearer tokens"`
+	} `config:"auth"`
+
+	OIDC struct {
+		Issuer         string `config:"issuer" env:"OIDC_ISSUER" usage:"OpenID Connect provider, enables logging in"`
+		ClientId       string `config:"client_id" env:"OIDC_CLIENT_ID" usage:"client id at the provider"`
+		ClientSecret   string `config:"client_secret" env:"OIDC_CLIENT_SECRET" secret:"true" usage:"client secret at the provider"`
+		RedirectURL    string `config:"redirect_url" env:"OIDC_REDIRECT_URL" usage:"url of /auth/callback"`
+		PostLoginURL   string `config:"post_login_url" env:"OIDC_POST_LOGIN_URL" usage:"url sent to once logged in"`
+		Scopes         string `config:"scopes" env:"OIDC_SCOPES" usage:"space separated scopes requested"`
+		RolesClaim     string `config:"roles_claim" env:"OIDC_ROLES_CLAIM" usage:"claim holding the roles of the user"`
+		RoleMap        string `config:"role_map" env:"OIDC_ROLE_MAP" usage:"provider roles to helio roles, i.e. editors=curator,owners=admin"`
+		SessionKeyFile string `config:"session_key_file" env:"SESSION_KEY_FILE" usage:"PEM P-256 private key signing sessions, generated at startup if not set"`
+	} `config:"oidc"`
+
+	Tracing tracing.Settings `config:"tracing"`
+}
+
+// defaultConfig is the configuration helio runs with when nothing is set.
+func defaultConfig() Config {
+	cfg := Config{
+		Port:        8000,
+		GrpcPort:    9000,
+		DataFile:    "data/monarch.json",
+		CorsOrigins: []string{"https://*", "http://", "*"}, // Todo: narrow down once the front end has a fixed origin
+		LogLevel:    config.Info,
+		Tracing:     tracing.Defaults(),
+	}
+	cfg.Database.ConnectBackoff, cfg.Database.ConnectMaxBackoff = 500*time.Millisecond, 30*time.Second
+	cfg.Database.StatementTimeout = 2 * time.Minute
+	cfg.Cache.Backend, cfg.Cache.TTL, cfg.Cache.MaxEntries = cache.Memory, time.Minute, 1000
+	cfg.Cache.MaxAge = time.Minute
+	cfg.Compression.Encodings, cfg.Compression.MinSize = routes.Encodings, 1024
+	cfg.Timeouts.Default = 30 * time.Second
+	cfg.Timeouts.Routes = []string{"GET /audit/export=2m", "POST /review/analyze=2m"}
+	cfg.Shutdown.Timeout = 15 * time.Second
+	breaker := db.DefaultBreaker()
+	cfg.Database.BreakerThreshold, cfg.Database.BreakerCooldown, cfg.Database.BreakerMaxCooldown = breaker.Threshold, breaker.Cooldown, breaker.MaxCooldown
+	cfg.Validation.Mode = string(validation.Warn)
+	cfg.GraphQL.MaxComplexity = graph.DefaultMaxComplexity
+	cfg.GraphQL.MaxDepth = graph.DefaultMaxDepth
+	return cfg
+}
+
+// Validate checks every setting that can be checked before anything starts.
+func (c *Config) Validate() error {
+	var problems config.Problems
+	problems.Port("port", c.Port)
+	problems.Port("grpc_port", c.GrpcPort)
+	if c.Port == c.GrpcPort {
+		problems.Add("port and grpc_port must differ")
+	}
+	problems.File("data_file", c.DataFile)
+	problems.Positive("database.connect_backoff", int64(c.Database.ConnectBackoff))
+	if c.Database.ConnectMaxBackoff < c.Database.ConnectBackoff {
+		problems.Add("database.connect_max_backoff must be at least database.connect_backoff")
+	}
+	if c.Database.StatementTimeout < 0 {
+		problems.Add("database.statement_timeout must not be negative")
+	}
+	switch c.Cache.Backend {
+	case cache.None:
+	case cache.Memory:
+		problems.Positive("cache.max_entries", int64(c.Cache.MaxEntries))
+	case cache.Redis:
+		if c.Cache.RedisURL == "" {
+			problems.Add("cache.redis_url is required by the redis backend")
+		} else if _, err := cache.NewRedis(c.Cache.RedisURL); err != nil {
+			problems.Add("cache.redis_url: %s", err.Error())
+		}
+	default:
+		problems.Add("cache.backend must be none, memory or redis, not %q", c.Cache.Backend)
+	}
+	if c.Cache.Backend != cache.None {
+		problems.Positive("cache.ttl", int64(c.Cache.TTL))
+	}
+	if c.Cache.MaxAge < 0 {
+		problems.Add("cache.max_age must not be negative")
+	}
+	if _, err := routes.NewCompression(c.Compression.Encodings, c.Compression.MinSize); err != nil {
+		problems.Add("compression: %s", err.Error())
+	}
+	if timeouts, err := c.routeTimeouts(); err != nil {
+		problems.Add("timeouts: %s", err.Error())
+	} else if limit := c.Database.StatementTimeout; limit > 0 {
+		for route, timeout := range timeouts.Routes {
+			if timeout > limit {
+				problems.Add("timeouts.routes: %s is longer than database.statement_timeout", route)
+			}
+		}
+		if timeouts.Default > limit {
+			problems.Add("timeouts.default is longer than database.statement_timeout")
+		}
+	}
+	if c.Shutdown.DrainDelay < 0 {
+		problems.Add("shutdown.drain_delay must not be negative")
+	}
+	problems.Positive("shutdown.timeout", int64(c.Shutdown.Timeout))
+	problems.Positive("database.breaker_threshold", int64(c.Database.BreakerThreshold))
+	problems.Positive("database.breaker_cooldown", int64(c.Database.BreakerCooldown))
+	if c.Database.BreakerMaxCooldown < c.Database.BreakerCooldown {
+		problems.Add("database.breaker_max_cooldown must be at least database.breaker_cooldown")
+	}
+	problems.File("geoprivacy_policy", c.GeoprivacyPolicy)
+	for _, proxy := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			problems.Add("trusted_proxies: %q is neither an ip nor a CIDR", proxy)
+		}
+	}
+	if err := corsConfig(c.CorsOrigins).Validate(); err != nil {
+		problems.Add("cors_origins: %s", err.Error())
+	}
+	if _, err := validation.ParseMode(c.Validation.Mode); err != nil {
+		problems.Add("validation.mode: %s", err.Error())
+	}
+	problems.File("validation.rules", c.Validation.Rules)
+	problems.Positive("graphql.max_complexity", int64(c.GraphQL.MaxComplexity))
+	problems.Positive("graphql.max_depth", int64(c.GraphQL.MaxDepth))
+	problems.File("auth.key_file", c.Auth.KeyFile)
+	if c.OIDC.Issuer != "" {
+		if c.OIDC.ClientId == "" || c.OIDC.RedirectURL == "" {
+			problems.Add("oidc.client_id and oidc.redirect_url are required along with oidc.issuer")
+		}
+		if _, err := auth.ParseRoleMap(c.OIDC.RoleMap); err != nil {
+			problems.Add("oidc.role_map: %s", err.Error())
+		}
+		problems.File("oidc.session_key_file", c.OIDC.SessionKeyFile)
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		problems.Add("tracing: %s", err.Error())
+	}
+	return problems.Err()
+}
+
+// routeTimeouts returns the timeout of every route.
+func (c *Config) routeTimeouts() (routes.Timeouts, error) {
+	return routes.ParseTimeouts(c.Timeouts.Default, c.Timeouts.Routes)
+}
+
+// corsConfig allows origins to call helio, with credentials.
+func corsConfig(origins []string) cors.Config {
+	return cors.Config{
+		AllowOrigins:     origins,
+		AllowWildcard:    true,
+		AllowMethods:     []string{"GET", "PUT", "POST", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Accept", "Authorization", "Content-type", "X-CSRF-Token", "If-None-Match", "If-Modified-Since", apikey.Header, requestid.Header},
+		ExposeHeaders:    []string{"Content-Length", "Link", "ETag", "Last-Modified", requestid.Header, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
+		AllowCredentials: true,
+		MaxAge:           300,
+	}
+}
+
+// Cors serves CORS for the origins in use, which change on reload.
+type Cors struct {
+	handler atomic.Value // gin.HandlerFunc
+}
+
+// NewCors constructs Cors allowing origins.
+func NewCors(origins []string) *Cors {
+	c := &Cors{}
+	c.SetOrigins(origins)
+	return c
+}
+
+// SetOrigins changes the origins allowed, they must have passed Config.Validate.
+func (c *Cors) SetOrigins(origins []string) {
+	c.handler.Store(cors.New(corsConfig(origins)))
+}
+
+// Handler returns the middleware.
+func (c *Cors) Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c.handler.Load().(gin.HandlerFunc)(ctx)
+	}
+}
+
+// requestLogger logs every request once served, at info, and at error when it failed with a server error. The line
+// carries the request id, set by requestid.Middleware further down the chain.
+func requestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		logging.Request(c.Request.Context(), c.Request, c.FullPath(), c.Writer.Status(), c.Writer.Size(), start)
+	}
+}
+
+// recovery answers 500 to a request whose handler panicked, logging the panic and its stack.
+func recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, err any) {
+		slog.ErrorContext(c.Request.Context(), "Panic serving request", "panic", err, "stack", string(debug.Stack()))
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": "err internal",
+		})
+	})
+}
+
+// noRoute answers requests matching no route.
+func noRoute(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error":   "err not found",
+		"message": "no route matches the request",
+	})
+}
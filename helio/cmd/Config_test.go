@@ -0,0 +1,122 @@
+package main
+
+import (
+	"mbcarruthers/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig returns the defaults, the data file taken from the module root.
+func validConfig() Config {
+	cfg := defaultConfig()
+	cfg.DataFile = "../data/monarch.json"
+	return cfg
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		change       func(cfg *Config)
+		wantProblems []string // none when the configuration is valid
+	}{
+		{"defaults", func(cfg *Config) {}, nil},
+		{"same ports", func(cfg *Config) { cfg.GrpcPort = cfg.Port }, []string{"port and grpc_port must differ"}},
+		{"port out of range", func(cfg *Config) { cfg.Port = 70000 }, []string{"port must be a port between 1 and 65535, not 70000"}},
+		{"data file missing", func(cfg *Config) { cfg.DataFile = "missing.json" }, []string{"data_file: stat missing.json"}},
+		{"max backoff under backoff", func(cfg *Config) { cfg.Database.ConnectMaxBackoff = time.Millisecond },
+			[]string{"database.connect_max_backoff must be at least database.connect_backoff"}},
+		{"unknown cache backend", func(cfg *Config) { cfg.Cache.Backend = "memcached" },
+			[]string{`cache.backend must be none, memory or redis, not "memcached"`}},
+		{"redis without url", func(cfg *Config) { cfg.Cache.Backend = "redis" }, []string{"cache.redis_url is required by the redis backend"}},
+		{"no cache needs no ttl", func(cfg *Config) { cfg.Cache.Backend, cfg.Cache.TTL = "none", 0 }, nil},
+		{"memory cache without entries", func(cfg *Config) { cfg.Cache.MaxEntries = 0 }, []string{"cache.max_entries must be above 0"}},
+		{"unknown encoding", func(cfg *Config) { cfg.Compression.Encodings = []string{"lzma"} }, []string{"compression: "}},
+		{"malformed route timeout", func(cfg *Config) { cfg.Timeouts.Routes = []string{"/audit/export=2m"} },
+			[]string{`timeouts: err route timeout "/audit/export=2m"`}},
+		{"route timeout over the statement timeout", func(cfg *Config) { cfg.Timeouts.Routes = []string{"GET /audit/export=5m"} },
+			[]string{"timeouts.routes: GET /audit/export is longer than database.statement_timeout"}},
+		{"default timeout over the statement timeout", func(cfg *Config) { cfg.Timeouts.Default = 3 * time.Minute },
+			[]string{"timeouts.default is longer than database.statement_timeout"}},
+		{"no statement timeout", func(cfg *Config) {
+			cfg.Database.StatementTimeout = 0
+			cfg.Timeouts.Routes = []string{"GET /audit/export=5m"}
+		}, nil},
+		{"invalid proxy", func(cfg *Config) { cfg.TrustedProxies = []string{"10.0.0.0/8", "proxy.local"} },
+			[]string{`trusted_proxies: "proxy.local" is neither an ip nor a CIDR`}},
+		{"invalid cors origin", func(cfg *Config) { cfg.CorsOrigins = []string{"example.com"} }, []string{"cors_origins: "}},
+		{"unknown validation mode", func(cfg *Config) { cfg.Validation.Mode = "lenient" }, []string{"validation.mode: "}},
+		{"oidc incomplete", func(cfg *Config) { cfg.OIDC.Issuer, cfg.OIDC.RoleMap = "https://id.example", "editors" },
+			[]string{"oidc.client_id and oidc.redirect_url are required along with oidc.issuer", "oidc.role_map: "}},
+		{"every problem listed", func(cfg *Config) {
+			cfg.Shutdown.Timeout, cfg.GraphQL.MaxDepth, cfg.Database.BreakerThreshold = 0, 0, 0
+		}, []string{"shutdown.timeout must be above 0", "database.breaker_threshold must be above 0", "graphql.max_depth must be above 0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.change(&cfg)
+			err := cfg.Validate()
+			if tt.wantProblems == nil {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() = nil, want %q", tt.wantProblems)
+			}
+			problems := strings.Split(err.Error(), "; ")
+			if len(problems) != len(tt.wantProblems) {
+				t.Fatalf("Validate() = %q, want %q", problems, tt.wantProblems)
+			}
+			for _, want := range tt.wantProblems {
+				found := false
+				for _, problem := range problems {
+					found = found || strings.HasPrefix(problem, want)
+				}
+				if !found {
+					t.Errorf("Validate() = %q, want %q among them", problems, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "helio.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("data_file: ../data/monarch.json\ncors_origins: [https://helio.example]\n")
+	loader, err := config.NewLoader("helio", defaultConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader.Parse([]string{"-config", path})
+	current, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write("data_file: ../data/monarch.json\ncors_origins: [https://helio.example, https://maps.example]\nlog_level: debug\nport: 8100\n")
+	next, changed, err := loader.Reload(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(changed, ",") != "cors_origins,log_level" {
+		t.Errorf("Reload() changed = %v, want cors_origins and log_level", changed)
+	}
+	if next.LogLevel != config.Debug || len(next.CorsOrigins) != 2 || next.Port != 8000 {
+		t.Errorf("Reload() = %+v, want the origins and level taken, the port left for a restart", next)
+	}
+
+	write("data_file: ../data/monarch.json\ncors_origins: [example.com]\n")
+	if kept, _, err := loader.Reload(next); err == nil || len(kept.CorsOrigins) != 2 {
+		t.Errorf("Reload() of invalid origins = %v, %v, want an error keeping the current ones", kept.CorsOrigins, err)
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/metrics"
+)
+
+// requestMetrics records every request by method, route and status. Put it first, so that requests refused by any
+// other middleware are recorded as well.
+func requestMetrics(m *metrics.HTTP) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		done := m.Start()
+		c.Next()
+		done(c.Request.Method, c.FullPath(), c.Writer.Status())
+	}
+}
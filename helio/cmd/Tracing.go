@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"mbcarruthers/helio/requestid"
+	"mbcarruthers/tracing"
+)
+
+// requestTracing traces every request by method and route, continuing the trace of its traceparent header so the
+// statements it runs are part of it. Put it first, along with requestMetrics, so that requests refused by any other
+// middleware are traced as well.
+func requestTracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := tracing.StartServer(c.Request, c.FullPath())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		span.SetAttributes(attribute.String("http.request_id", c.Writer.Header().Get(requestid.Header)))
+		tracing.EndServer(span, c.Writer.Status())
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mbcarruthers/helio/dataservice/db"
+	"mbcarruthers/helio/model"
+	"mbcarruthers/helio/service"
+	"os"
+)
+
+// closeStore closes the database, logging rather than failing the command.
+func closeStore(store *db.DataStore) {
+	if err := store.Close(background); err != nil {
+		fmt.Fprintf(os.Stderr, "database didnt close properly:%s \n", err.Error())
+	}
+}
+
+// importCommand validates and imports entities from a file(- reads stdin) into an existing database. Entities whose
+// id or uuid is stored already are skipped, every entity imported is recorded to the audit log.
+func importCommand(args []string) error {
+	var database dbFlags
+	var format string
+	var dryRun bool
+	fs := newFlagSet("import", "import [flags] <file>")
+	database.register(fs)
+	fs.StringVar(&format, "format", "", "json, ndjson or csv, taken from the file extension by default")
+	fs.BoolVar(&dryRun, "dry-run", false, "validate the file without importing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected a single file")
+	}
+	path := fs.Arg(0)
+	if format == "" {
+		format = formatOf(path, formatJSON)
+	}
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	entities, err := readEntities(in, format)
+	if err != nil {
+		return err
+	}
+
+	validator, err := newValidator()
+	if err != nil {
+		return err
+	}
+	valid := make([]model.Entity, 0, len(entities))
+	for _, entity := range entities {
+		if !model.ValidGeoprivacy(entity.Geoprivacy) {
+			fmt.Fprintf(os.Stderr, "Skipping %d, invalid geoprivacy %q\n", entity.Id, entity.Geoprivacy)
+			continue
+		}
+		if err := validator.Apply(&entity); err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping %d, %s\n", entity.Id, err.Error())
+			continue
+		}
+		valid = append(valid, entity)
+	}
+	if dryRun {
+		fmt.Printf("%d of %d entities are valid\n", len(valid), len(entities))
+		return nil
+	}
+
+	store, err := database.open()
+	if err != nil {
+		return err
+	}
+	defer closeStore(store)
+	inserted, err := store.InsertEntities(valid, func(inserted []model.Entity) []model.AuditEntry {
+		entries := make([]model.AuditEntry, 0, len(inserted))
+		for i := range inserted {
+			entries = append(entries, caller.Entry(model.AuditImport, inserted[i].Id, nil, &inserted[i]))
+		}
+		return entries
+	}, background)
+	if err != nil {
+		return fmt.Errorf("%w, the database is created by helioctl seed", err)
+	}
+	fmt.Printf("Imported %d entities, %d were stored already, %d invalid\n",
+		len(inserted), len(valid)-len(inserted), len(entities)-len(valid))
+	return nil
+}
+
+// exportCommand writes the entities matching a search with their true coordinates.
+func exportCommand(args []string) error {
+	var database dbFlags
+	var search searchFlags
+	var format, out string
+	fs := newFlagSet("export", "export [flags]")
+	database.register(fs)
+	search.register(fs)
+	fs.StringVar(&format, "format", "", "json, ndjson or csv, taken from the --out extension by default(json)")
+	fs.StringVar(&out, "out", "-", "file written, - writes to stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if format == "" {
+		format = formatOf(out, formatJSON)
+	}
+	if err := checkFormat(format, formatJSON, formatNDJSON, formatCSV); err != nil {
+		return err
+	}
+	query, err := search.query()
+	if err != nil {
+		return err
+	}
+	store, err := database.open()
+	if err != nil {
+		return err
+	}
+	defer closeStore(store)
+	entities, err := store.SearchEntities(query, background)
+	if err != nil {
+		return err
+	}
+
+	if out == "-" {
+		return writeEntities(os.Stdout, format, entities)
+	}
+	file, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err = writeEntities(file, format, entities); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d entities to %s\n", len(entities), out)
+	return nil
+}
+
+// migrateCommand brings the database schema up to date.
+func migrateCommand(args []string) error {
+	var database dbFlags
+	fs := newFlagSet("migrate", "migrate [flags]")
+	database.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	store, err := database.open()
+	if err != nil {
+		return err
+	}
+	defer closeStore(store)
+	if err = store.Migrate(background); err != nil {
+		return err
+	}
+	fmt.Println("Database is up to date")
+	return nil
+}
+
+// seedCommand creates the database from a json file the same way the server does on startup, doing nothing if it
+// exists.
+func seedCommand(args []string) error {
+	var database dbFlags
+	var path string
+	fs := newFlagSet("seed", "seed [flags]")
+	database.register(fs)
+	fs.StringVar(&path, "file", "data/monarch.json", "json array of entities")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	observations := make([]model.Entity, 0)
+	if err = json.Unmarshal(file, &observations); err != nil {
+		return fmt.Errorf("decoding %s: %w", path, err)
+	}
+	validator, err := newValidator()
+	if err != nil {
+		return err
+	}
+	store, err := database.open()
+	if err != nil {
+		return err
+	}
+	defer closeStore(store)
+	service.NewEntities(store, validator).Import(observations, background)
+	count, err := store.CountEntities(model.SearchQuery{Merged: true, QualityGrade: []string{model.GradeClean, model.GradeFlagged, model.GradeAccepted, model.GradeRejected}}, background)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Database holds %d entities\n", count)
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"mbcarruthers/helio/model"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportDryRun(t *testing.T) {
+	t.Setenv("VALIDATION_MODE", "strict")
+	t.Setenv("VALIDATION_RULES", "")
+	noSpecies, hidden := entity(2), entity(3)
+	noSpecies.SpeciesGuess = ""
+	hidden.Geoprivacy = "hidden"
+
+	dir := t.TempDir()
+	for _, format := range []string{formatJSON, formatNDJSON, formatCSV} {
+		var buf bytes.Buffer
+		if err := writeEntities(&buf, format, []model.Entity{entity(1), noSpecies, hidden}); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "observations."+format), buf.Bytes(), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{"json", []string{"--dry-run", filepath.Join(dir, "observations.json")}, "1 of 3 entities are valid", ""},
+		{"ndjson", []string{"--dry-run", filepath.Join(dir, "observations.ndjson")}, "1 of 3 entities are valid", ""},
+		{"csv", []string{"--dry-run", filepath.Join(dir, "observations.csv")}, "1 of 3 entities are valid", ""},
+		{"format flag over the extension", []string{"--dry-run", "--format", "csv", filepath.Join(dir, "observations.json")},
+			"", "reading csv"},
+		{"no file", []string{"--dry-run"}, "", "expected a single file"},
+		{"missing file", []string{"--dry-run", filepath.Join(dir, "missing.json")}, "", "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			printed, err := run(t, importCommand, tt.args...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("import %v error = %v, want %q", tt.args, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || strings.TrimSpace(printed) != tt.want {
+				t.Errorf("import %v = %q, %v, want %q", tt.args, printed, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"mbcarruthers/helio/quality"
+	"mbcarruthers/helio/service"
+	"os"
+	"strconv"
+)
+
+// dedupeCommand lists the likely duplicate pairs of observations, the same as /entities/duplicates. With --merge the
+// duplicate of every pair is merged into the other, recording the merges to the audit log.
+func dedupeCommand(args []string) error {
+	var database dbFlags
+	var format string
+	var merge bool
+	detector := quality.DefaultDuplicateDetector()
+	fs := newFlagSet("dedupe", "dedupe [flags]")
+	database.register(fs)
+	fs.StringVar(&format, "o", formatTable, "output format: table, json or csv")
+	fs.Float64Var(&detector.MinScore, "min-score", detector.MinScore, "pairs scoring lower, 0 to 1, are left out")
+	fs.BoolVar(&merge, "merge", false, "merge every pair listed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(format, formatTable, formatJSON, formatCSV); err != nil {
+		return err
+	}
+	if detector.MinScore < 0 || detector.MinScore > 1 {
+		return fmt.Errorf("min-score must be a number between 0 and 1")
+	}
+	store, err := database.open()
+	if err != nil {
+		return err
+	}
+	defer closeStore(store)
+	entities, err := store.ListAllEntities(background)
+	if err != nil {
+		return err
+	}
+	pairs := detector.Candidates(entities)
+	if pairs == nil {
+		pairs = []quality.DuplicatePair{}
+	}
+
+	if format == formatJSON {
+		err = writeJSON(os.Stdout, pairs)
+	} else {
+		rows := make([][]string, 0, len(pairs))
+		for _, pair := range pairs {
+			rows = append(rows, []string{strconv.Itoa(pair.Id), strconv.Itoa(pair.DuplicateId), strconv.FormatFloat(pair.Score, 'f', 3, 64),
+				strconv.FormatFloat(pair.Distance, 'f', 1, 64), strconv.Itoa(pair.DaysApart), strconv.FormatBool(pair.SameTaxon)})
+		}
+		err = writeRows(os.Stdout, format, []string{"ID", "DUPLICATE ID", "SCORE", "DISTANCE", "DAYS APART", "SAME TAXON"}, rows)
+	}
+	if err != nil || !merge {
+		return err
+	}
+
+	// pairs are merged highest score first, an observation merged by an earlier pair is left alone
+	merged, entityService := map[int]bool{}, service.NewEntities(store, nil) // merges are not validated
+	for _, pair := range pairs {
+		if merged[pair.Id] || merged[pair.DuplicateId] {
+			continue
+		}
+		if _, err := entityService.Merge(pair.Id, []int{pair.DuplicateId}, caller, background); err != nil {
+			return fmt.Errorf("merging %d into %d: %w", pair.DuplicateId, pair.Id, err)
+		}
+		merged[pair.DuplicateId] = true
+	}
+	fmt.Fprintf(os.Stderr, "Merged %d observations\n", len(merged))
+	return nil
+}
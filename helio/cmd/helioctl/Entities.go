@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"mbcarruthers/helio/model"
+	"os"
+	"strconv"
+)
+
+// entitiesCommand reads entities through the API: entities get <id>..., entities list and entities search.
+func entitiesCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected get, list or search")
+	}
+	var api apiFlags
+	var search searchFlags
+	var format string
+	var limit, pageSize int
+	fs := newFlagSet("entities "+args[0], "entities "+args[0]+" [flags]")
+	api.register(fs)
+	fs.StringVar(&format, "o", formatTable, "output format: table, json, ndjson or csv")
+	switch args[0] {
+	case "get", "list":
+	case "search":
+		search.register(fs)
+		fs.IntVar(&limit, "limit", 0, "print at most limit entities, 0 prints every one")
+		fs.IntVar(&pageSize, "page-size", 500, "entities fetched at a time, at most 1000")
+	default:
+		return fmt.Errorf("unknown entities command %q, expected get, list or search", args[0])
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := checkFormat(format, formatTable, formatJSON, formatNDJSON, formatCSV); err != nil {
+		return err
+	}
+	c, err := api.client()
+	if err != nil {
+		return err
+	}
+
+	entities := []model.Entity{}
+	switch args[0] {
+	case "get":
+		if fs.NArg() == 0 {
+			return fmt.Errorf("expected the id of an entity")
+		}
+		for _, arg := range fs.Args() {
+			id, err := strconv.Atoi(arg)
+			if err != nil {
+				return fmt.Errorf("invalid id %q", arg)
+			}
+			entity, err := c.Get(id, background)
+			if err != nil {
+				return err
+			}
+			entities = append(entities, entity)
+		}
+	case "list":
+		if entities, err = c.List(background); err != nil {
+			return err
+		}
+	case "search":
+		query, err := search.query()
+		if err != nil {
+			return err
+		}
+		if limit > 0 && limit < pageSize {
+			pageSize = limit
+		}
+		it := c.Iterate(query, pageSize)
+		for (limit <= 0 || len(entities) < limit) && it.Next(background) {
+			entities = append(entities, it.Entity())
+		}
+		if err = it.Err(); err != nil {
+			return err
+		}
+	}
+	return writeEntities(os.Stdout, format, entities)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"github.com/gin-gonic/gin"
+	"io"
+	"mbcarruthers/helio/auth"
+	"mbcarruthers/helio/client/heliotest"
+	"mbcarruthers/helio/model"
+	"mbcarruthers/helio/validation"
+	"os"
+	"strings"
+	"testing"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// run runs a command, returning what it printed.
+func run(t *testing.T, command func(args []string) error, args ...string) (string, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	printed := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		_, _ = io.Copy(&buf, r)
+		printed <- buf.String()
+	}()
+	err = command(args)
+	_ = w.Close()
+	return <-printed, err
+}
+
+func TestEntitiesCommand(t *testing.T) {
+	entities := make([]model.Entity, 0, 12)
+	for id := 1; id <= 12; id++ {
+		entities = append(entities, entity(id))
+	}
+	server, err := heliotest.NewServer(validation.Warn, entities...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	token, err := server.Token(auth.Viewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantIds []int
+		wantErr string
+	}{
+		{"get", []string{"get", "--url", server.URL, "-o", "ndjson", "3", "5"}, []int{3, 5}, ""},
+		{"get with a token", []string{"get", "--url", server.URL, "--token", token, "-o", "ndjson", "3"}, []int{3}, ""},
+		{"get missing", []string{"get", "--url", server.URL, "-o", "ndjson", "99"}, nil, "404"},
+		{"get no id", []string{"get", "--url", server.URL}, nil, "expected the id of an entity"},
+		{"get invalid id", []string{"get", "--url", server.URL, "three"}, nil, `invalid id "three"`},
+		{"list", []string{"list", "--url", server.URL, "-o", "ndjson"}, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}, ""},
+		{"search paged", []string{"search", "--url", server.URL, "-o", "ndjson", "--page-size", "5", "--date2", "2021-10-11"},
+			[]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, ""},
+		{"search limited", []string{"search", "--url", server.URL, "-o", "ndjson", "--limit", "3"}, []int{1, 2, 3}, ""},
+		{"search invalid date", []string{"search", "--url", server.URL, "--date1", "2021"}, nil, `date "2021" must be yyyy-mm-dd`},
+		{"search invalid grade", []string{"search", "--url", server.URL, "--quality-grade", "clean,spam"}, nil,
+			`unknown quality grade "spam"`},
+		{"invalid format", []string{"list", "--url", server.URL, "-o", "xml"}, nil, `format "xml" must be one of`},
+		{"unknown command", []string{"delete", "1"}, nil, `unknown entities command "delete"`},
+		{"no command", nil, nil, "expected get, list or search"},
+		{"bad token", []string{"list", "--url", server.URL, "--token", "not.a.token"}, nil, "401"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			printed, err := run(t, entitiesCommand, tt.args...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("entities %v error = %v, want %q", tt.args, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("entities %v error = %v", tt.args, err)
+			}
+			got, err := readEntities(strings.NewReader(printed), formatNDJSON)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.wantIds) {
+				t.Fatalf("entities %v printed %d entities, want %v", tt.args, len(got), tt.wantIds)
+			}
+			for i, entity := range got {
+				if entity.Id != tt.wantIds[i] {
+					t.Errorf("entities %v printed %d at %d, want %v", tt.args, entity.Id, i, tt.wantIds)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestSearchFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    model.SearchQuery
+		wantErr string
+	}{
+		{"none", nil, model.SearchQuery{}, ""},
+		{"every filter", []string{"--taxon-id", "48662", "--quality-grade", "clean, accepted", "--quality-flag", "date_in_future",
+			"--geoprivacy", "obscured", "--merged"}, model.SearchQuery{TaxonId: 48662,
+			QualityGrade: []string{model.GradeClean, model.GradeAccepted}, QualityFlag: "date_in_future",
+			Geoprivacy: model.GeoprivacyObscured, Merged: true}, ""},
+		{"invalid geoprivacy", []string{"--geoprivacy", "hidden"}, model.SearchQuery{}, "geoprivacy must be open, obscured or private"},
+		{"invalid date2", []string{"--date2", "2021-13-01"}, model.SearchQuery{}, `date "2021-13-01" must be yyyy-mm-dd`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var search searchFlags
+			fs := flag.NewFlagSet("search", flag.ContinueOnError)
+			search.register(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			got, err := search.query()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("query() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got.TaxonId != tt.want.TaxonId || strings.Join(got.QualityGrade, ",") != strings.Join(tt.want.QualityGrade, ",") ||
+				got.QualityFlag != tt.want.QualityFlag || got.Geoprivacy != tt.want.Geoprivacy || got.Merged != tt.want.Merged {
+				t.Errorf("query() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
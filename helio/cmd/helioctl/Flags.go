@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"mbcarruthers/helio/audit"
+	"mbcarruthers/helio/client"
+	"mbcarruthers/helio/dataservice/db"
+	"mbcarruthers/helio/model"
+	"mbcarruthers/helio/validation"
+	"os"
+	"strings"
+	"time"
+)
+
+// newFlagSet returns the flags of a command, errors are returned rather than exiting.
+func newFlagSet(name string, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: helioctl %s\n\nFlags:\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// envOr returns the environment variable, fallback when it is not set.
+func envOr(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// apiFlags reach the helio API.
+type apiFlags struct {
+	url, token, apiKey string
+}
+
+func (a *apiFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&a.url, "url", envOr("HELIO_URL", "http://localhost:8000"), "helio API url(HELIO_URL)")
+	fs.StringVar(&a.token, "token", os.Getenv("HELIO_TOKEN"), "bearer token, trusted callers see true coordinates(HELIO_TOKEN)")
+	fs.StringVar(&a.apiKey, "api-key", os.Getenv("HELIO_API_KEY"), "partner API key(HELIO_API_KEY)")
+}
+
+func (a *apiFlags) client() (*client.Client, error) {
+	return client.New(a.url, client.Config{Token: a.token, APIKey: a.apiKey, UserAgent: "helioctl"})
+}
+
+// dbFlags reach the database.
+type dbFlags struct {
+	dsn string
+}
+
+func (d *dbFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&d.dsn, "dsn", db.Defaultdb, "CockroachDB connection string(DSN)")
+}
+
+// open connects to the database, retrying for up to 15 seconds, close it once done.
+func (d *dbFlags) open() (*db.DataStore, error) {
+	if d.dsn == "" {
+		return nil, fmt.Errorf("set --dsn or DSN to reach the database")
+	}
+	store := db.NewDataStore(d.dsn, 0, db.DefaultBreaker())
+	ctx, cancel := context.WithTimeout(background, 15*time.Second)
+	defer cancel()
+	if err := store.Connect(500*time.Millisecond, 5*time.Second, ctx); err != nil {
+		closeStore(store)
+		return nil, fmt.Errorf("database unreachable: %w", err)
+	}
+	return store, nil
+}
+
+// searchFlags hold a search, the same filters as /entities/search.
+type searchFlags struct {
+	taxonId      int
+	date1, date2 string
+	qualityGrade string
+	qualityFlag  string
+	geoprivacy   string
+	merged       bool
+}
+
+func (s *searchFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&s.taxonId, "taxon-id", 0, "iNaturalist taxon id")
+	fs.StringVar(&s.date1, "date1", "", "observed on or after, yyyy-mm-dd")
+	fs.StringVar(&s.date2, "date2", "", "observed on or before, yyyy-mm-dd")
+	fs.StringVar(&s.qualityGrade, "quality-grade", "", "comma separated quality grades, rejected observations are left out unless asked for")
+	fs.StringVar(&s.qualityFlag, "quality-flag", "", "only observations carrying the quality flag")
+	fs.StringVar(&s.geoprivacy, "geoprivacy", "", "only observations with the geoprivacy")
+	fs.BoolVar(&s.merged, "merged", false, "include observations merged into another")
+}
+
+func (s *searchFlags) query() (model.SearchQuery, error) {
+	query := model.SearchQuery{TaxonId: s.taxonId, QualityFlag: s.qualityFlag, Geoprivacy: s.geoprivacy, Merged: s.merged}
+	var err error
+	if query.Date1, err = parseDate(s.date1); err != nil {
+		return query, err
+	}
+	if query.Date2, err = parseDate(s.date2); err != nil {
+		return query, err
+	}
+	if s.qualityGrade != "" {
+		for _, grade := range strings.Split(s.qualityGrade, ",") {
+			if grade = strings.TrimSpace(grade); !model.ValidGrade(grade) {
+				return query, fmt.Errorf("unknown quality grade %q", grade)
+			}
+			query.QualityGrade = append(query.QualityGrade, grade)
+		}
+	}
+	if !model.ValidGeoprivacy(query.Geoprivacy) {
+		return query, fmt.Errorf("geoprivacy must be open, obscured or private")
+	}
+	return query, nil
+}
+
+// parseDate parses a yyyy-mm-dd date, empty is no date.
+func parseDate(value string) (pgtype.Date, error) {
+	if value == "" {
+		return pgtype.Date{}, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return pgtype.Date{}, fmt.Errorf("date %q must be yyyy-mm-dd", value)
+	}
+	return pgtype.Date{Time: date, Valid: true}, nil
+}
+
+// newValidator returns the validator the server uses, configured by VALIDATION_MODE and VALIDATION_RULES.
+func newValidator() (*validation.Validator, error) {
+	mode, err := validation.ParseMode(os.Getenv("VALIDATION_MODE"))
+	if err != nil {
+		return nil, err
+	}
+	rules, err := validation.LoadRules(os.Getenv("VALIDATION_RULES"))
+	if err != nil {
+		return nil, err
+	}
+	return validation.NewValidator(rules, mode)
+}
+
+// caller is who helioctl records changes to the audit log as, a single request id ties together a run's changes.
+var caller = audit.Caller{
+	Actor:     "helioctl:" + envOr("USER", "unknown"),
+	RequestId: uuid.NewString(),
+}
+
+// background is the context of every command.
+var background = context.Background()
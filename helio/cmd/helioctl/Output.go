@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"mbcarruthers/helio/model"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats, tables are meant for people and are never read back.
+const (
+	formatTable  = "table"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatCSV    = "csv"
+)
+
+// entityHeader names the csv columns of an entity, in the order of entityRecord.
+var entityHeader = []string{"id", "taxon_id", "uuid", "place_guess", "species_guess", "latitude", "longitude", "observed_on",
+	"time_zone", "quality_flags", "quality_grade", "duplicate_of", "geoprivacy"}
+
+// checkFormat returns an error unless format is one of formats.
+func checkFormat(format string, formats ...string) error {
+	for _, f := range formats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("format %q must be one of %s", format, strings.Join(formats, ", "))
+}
+
+// formatOf returns the format of a file by its extension, fallback when it has none known.
+func formatOf(path string, fallback string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return formatJSON
+	case ".ndjson", ".jsonl":
+		return formatNDJSON
+	case ".csv":
+		return formatCSV
+	}
+	return fallback
+}
+
+// entityRecord returns the csv record of an entity, quality flags are separated by semicolons.
+func entityRecord(entity model.Entity) []string {
+	var observedOn, duplicateOf string
+	if entity.ObservedOn.Valid {
+		observedOn = entity.ObservedOn.Time.Format("2006-01-02")
+	}
+	if entity.DuplicateOf != nil {
+		duplicateOf = strconv.Itoa(*entity.DuplicateOf)
+	}
+	return []string{strconv.Itoa(entity.Id), strconv.Itoa(entity.TaxonId), entity.Uuid.String(), entity.PlaceGuess,
+		entity.SpeciesGuess, entity.Latitude, entity.Longitude, observedOn, entity.TimeZone,
+		strings.Join(entity.QualityFlags, ";"), entity.QualityGrade, duplicateOf, entity.Geoprivacy}
+}
+
+// parseEntityRecord reverses entityRecord.
+func parseEntityRecord(record []string) (model.Entity, error) {
+	var entity model.Entity
+	if len(record) != len(entityHeader) {
+		return entity, fmt.Errorf("expected %d columns, found %d", len(entityHeader), len(record))
+	}
+	var err error
+	if entity.Id, err = strconv.Atoi(record[0]); err != nil {
+		return entity, fmt.Errorf("invalid id %q", record[0])
+	}
+	if entity.TaxonId, err = strconv.Atoi(record[1]); err != nil {
+		return entity, fmt.Errorf("invalid taxon_id %q", record[1])
+	}
+	if record[2] != "" {
+		if entity.Uuid, err = uuid.Parse(record[2]); err != nil {
+			return entity, fmt.Errorf("invalid uuid %q", record[2])
+		}
+	}
+	entity.PlaceGuess, entity.SpeciesGuess, entity.Latitude, entity.Longitude = record[3], record[4], record[5], record[6]
+	if entity.ObservedOn, err = parseDate(record[7]); err != nil {
+		return entity, err
+	}
+	entity.TimeZone = record[8]
+	if record[9] != "" {
+		entity.QualityFlags = strings.Split(record[9], ";")
+	}
+	entity.QualityGrade = record[10]
+	if record[11] != "" {
+		duplicateOf, err := strconv.Atoi(record[11])
+		if err != nil {
+			return entity, fmt.Errorf("invalid duplicate_of %q", record[11])
+		}
+		entity.DuplicateOf = &duplicateOf
+	}
+	entity.Geoprivacy = record[12]
+	return entity, nil
+}
+
+// writeEntities writes entities in any format.
+func writeEntities(w io.Writer, format string, entities []model.Entity) error {
+	switch format {
+	case formatJSON:
+		return writeJSON(w, entities)
+	case formatNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, entity := range entities {
+			if err := encoder.Encode(entity); err != nil {
+				return err
+			}
+		}
+		return nil
+	case formatCSV:
+		records := make([][]string, 0, len(entities)+1)
+		records = append(records, entityHeader)
+		for _, entity := range entities {
+			records = append(records, entityRecord(entity))
+		}
+		return csv.NewWriter(w).WriteAll(records)
+	}
+	rows := make([][]string, 0, len(entities))
+	for _, entity := range entities {
+		record := entityRecord(entity)
+		rows = append(rows, []string{record[0], record[1], record[4], record[7], record[3], record[5], record[6], record[10]})
+	}
+	return writeTable(w, []string{"ID", "TAXON", "SPECIES", "OBSERVED ON", "PLACE", "LATITUDE", "LONGITUDE", "GRADE"}, rows)
+}
+
+// readEntities reads entities written by writeEntities in any format but a table.
+func readEntities(r io.Reader, format string) ([]model.Entity, error) {
+	entities := []model.Entity{}
+	switch format {
+	case formatJSON:
+		if err := json.NewDecoder(r).Decode(&entities); err != nil {
+			return nil, fmt.Errorf("decoding json: %w", err)
+		}
+	case formatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1<<20)
+		for line := 1; scanner.Scan(); line++ {
+			if strings.TrimSpace(scanner.Text()) == "" {
+				continue
+			}
+			var entity model.Entity
+			if err := json.Unmarshal(scanner.Bytes(), &entity); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			entities = append(entities, entity)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	case formatCSV:
+		records, err := csv.NewReader(r).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("reading csv: %w", err)
+		}
+		for i, record := range records {
+			if i == 0 && len(record) != 0 && record[0] == entityHeader[0] {
+				continue // header
+			}
+			entity, err := parseEntityRecord(record)
+			if err != nil {
+				return nil, fmt.Errorf("record %d: %w", i+1, err)
+			}
+			entities = append(entities, entity)
+		}
+	default:
+		return nil, checkFormat(format, formatJSON, formatNDJSON, formatCSV)
+	}
+	return entities, nil
+}
+
+// writeTable writes rows aligned under the header.
+func writeTable(w io.Writer, header []string, rows [][]string) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(table, strings.Join(row, "\t"))
+	}
+	return table.Flush()
+}
+
+// writeRows writes rows as a table or csv.
+func writeRows(w io.Writer, format string, header []string, rows [][]string) error {
+	if format == formatCSV {
+		return csv.NewWriter(w).WriteAll(append([][]string{header}, rows...))
+	}
+	return writeTable(w, header, rows)
+}
+
+// writeJSON writes a value as indented json.
+func writeJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"mbcarruthers/helio/model"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// entity returns a monarch observation with the id.
+func entity(id int) model.Entity {
+	return model.Entity{
+		Id:           id,
+		TaxonId:      48662,
+		Uuid:         uuid.New(),
+		PlaceGuess:   "Gainesville, FL, USA",
+		SpeciesGuess: "Monarch",
+		Latitude:     "29.6516",
+		Longitude:    "-82.3248",
+		ObservedOn:   pgtype.Date{Time: time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, id), Valid: true},
+		TimeZone:     "Eastern Time (US & Canada)",
+		QualityGrade: model.GradeClean,
+		Geoprivacy:   model.GeoprivacyOpen,
+	}
+}
+
+func TestEntitiesRoundTrip(t *testing.T) {
+	merged := entity(2)
+	keep := 1
+	merged.DuplicateOf = &keep
+	merged.QualityFlags = []string{"coordinates_imprecise", "date_in_future"}
+	merged.QualityGrade = model.GradeFlagged
+	undated := entity(3)
+	undated.ObservedOn = pgtype.Date{}
+	undated.PlaceGuess = `Paynes Prairie, "La Chua" trail, FL`
+	entities := []model.Entity{entity(1), merged, undated}
+
+	for _, format := range []string{formatJSON, formatNDJSON, formatCSV} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeEntities(&buf, format, entities); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readEntities(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, entities) {
+				t.Errorf("readEntities() = %+v, want %+v", got, entities)
+			}
+		})
+	}
+}
+
+func TestReadEntitiesInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		in      string
+		wantErr string
+	}{
+		{"table", formatTable, "ID  TAXON\n1   48662\n", `format "table" must be one of json, ndjson, csv`},
+		{"json not an array", formatJSON, `{"id": 1}`, "decoding json"},
+		{"ndjson line", formatNDJSON, "{\"id\": 1}\n\n{\"id\": \n", "line 3"},
+		{"csv columns", formatCSV, "1,48662\n", "record 1: expected 13 columns, found 2"},
+		{"csv id", formatCSV, strings.Join(entityHeader, ",") + "\none,48662,,,,,,,,,,,\n", `record 2: invalid id "one"`},
+		{"csv date", formatCSV, "1,48662,,,,,,10/01/2021,,,,,\n", `record 1: date "10/01/2021" must be yyyy-mm-dd`},
+		{"csv duplicate_of", formatCSV, "1,48662,,,,,,,,,,first,\n", `record 1: invalid duplicate_of "first"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entities, err := readEntities(strings.NewReader(tt.in), tt.format)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("readEntities() = %v, %v, want %q", entities, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"observations.json", formatJSON},
+		{"observations.NDJSON", formatNDJSON},
+		{"observations.jsonl", formatNDJSON},
+		{"exports/observations.csv", formatCSV},
+		{"observations.txt", formatCSV}, // the fallback
+		{"-", formatCSV},
+	}
+	for _, tt := range tests {
+		if got := formatOf(tt.path, formatCSV); got != tt.want {
+			t.Errorf("formatOf(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeEntities(&buf, formatTable, []model.Entity{entity(1)}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "ID  TAXON  SPECIES") ||
+		!strings.HasPrefix(lines[1], "1   48662  Monarch  2021-10-02") {
+		t.Errorf("table = %q, want the header and the entity aligned", buf.String())
+	}
+}
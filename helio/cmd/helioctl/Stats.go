@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"mbcarruthers/helio/model"
+	"os"
+	"strconv"
+)
+
+// statGroupings are the groupings stats counts by, in the order they are printed.
+var statGroupings = []string{"quality_grade", "year", "geoprivacy", "taxon_id"}
+
+// statsCommand counts the observations matching a search by quality grade, year, geoprivacy and taxon.
+func statsCommand(args []string) error {
+	var database dbFlags
+	var search searchFlags
+	var format string
+	var top int
+	fs := newFlagSet("stats", "stats [flags]")
+	database.register(fs)
+	search.register(fs)
+	fs.StringVar(&format, "o", formatTable, "output format: table, json or csv")
+	fs.IntVar(&top, "top", 10, "taxa printed, most observed first, 0 prints every one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(format, formatTable, formatJSON, formatCSV); err != nil {
+		return err
+	}
+	query, err := search.query()
+	if err != nil {
+		return err
+	}
+	store, err := database.open()
+	if err != nil {
+		return err
+	}
+	defer closeStore(store)
+
+	total, err := store.CountEntities(query, background)
+	if err != nil {
+		return err
+	}
+	counts := map[string][]model.Count{}
+	for _, grouping := range statGroupings {
+		if counts[grouping], err = store.CountEntitiesBy(grouping, query, background); err != nil {
+			return err
+		}
+	}
+	if top > 0 && len(counts["taxon_id"]) > top {
+		counts["taxon_id"] = counts["taxon_id"][:top]
+	}
+
+	if format == formatJSON {
+		stats := map[string]any{"total": total}
+		for _, grouping := range statGroupings {
+			stats["by_"+grouping] = counts[grouping]
+		}
+		return writeJSON(os.Stdout, stats)
+	}
+	rows := [][]string{{"total", "", strconv.Itoa(total)}}
+	for _, grouping := range statGroupings {
+		for _, count := range counts[grouping] {
+			key := count.Key
+			if key == "" {
+				key = "(none)"
+			}
+			rows = append(rows, []string{grouping, key, strconv.Itoa(count.Count)})
+		}
+	}
+	if err = writeRows(os.Stdout, format, []string{"GROUPING", "KEY", "COUNT"}, rows); err != nil {
+		return fmt.Errorf("writing stats: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// Command helioctl operates helio from the command line. The entities commands read observations through the API,
+// honouring its authorization and geoprivacy, the other commands work on the database directly(DSN).
+//
+//	helioctl entities search --taxon-id 48662 --date1 2020-01-01 -o csv
+//	helioctl import observations.ndjson
+//	helioctl stats -o json
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+const usage = `helioctl operates helio.
+
+Usage:
+  helioctl <command> [flags]
+
+API commands(HELIO_URL, HELIO_TOKEN, HELIO_API_KEY):
+  entities get <id>...   Prints entities by id
+  entities list          Prints every entity
+  entities search        Prints the entities matching a search
+
+Database commands(DSN):
+  import <file>          Validates and imports entities from json, ndjson or csv, skipping stored ones
+  export                 Writes the entities matching a search as json, ndjson or csv
+  migrate                Brings the database schema up to date
+  seed                   Creates the database from data/monarch.json if it does not exist
+  stats                  Counts observations by quality grade, year, geoprivacy and taxon
+  dedupe                 Lists likely duplicates, merging them with --merge
+
+Run helioctl <command> -h for the flags of a command.
+`
+
+// commands are run with the arguments following their name.
+var commands = map[string]func(args []string) error{
+	"entities": entitiesCommand,
+	"import":   importCommand,
+	"export":   exportCommand,
+	"migrate":  migrateCommand,
+	"seed":     seedCommand,
+	"stats":    statsCommand,
+	"dedupe":   dedupeCommand,
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(os.Stderr, "helioctl: unknown command %q, one of %v\n", os.Args[1], names)
+		os.Exit(2)
+	}
+	if err := command(os.Args[2:]); errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "helioctl %s: %s\n", os.Args[1], err.Error())
+		os.Exit(1)
+	}
+}
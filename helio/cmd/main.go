@@ -3,81 +3,428 @@
 // API for retrieving Butterfly observation data from Florida through the years of 2012-2022
 // that is has been observed using the iNaturalist website/app (www.iNaturalist.com)
 //
-// Schemes: http
-// Host: localhost:3000
-// BasePath: /entities
-// Version: 1.0.0
+// The API is described by the OpenAPI 3.1 document at openapi/openapi.json, served at
+// http://localhost:8000/openapi.json and browsable at http://localhost:8000/docs.
+// Requests not conforming to it are refused.
 //
 // Contact:
 // <mbcarruthers@crimson.ua.edu> https://github.com/mbcarruthers
-//
-// Consumes:
-// - application/json
-//
-// Produces:
-// - application/json
-// swagger:meta
 package main
 
 import (
 	"context"
 	"fmt"
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"log"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"log/slog"
+	"mbcarruthers/config"
+	"mbcarruthers/helio/apikey"
+	"mbcarruthers/helio/auth"
+	"mbcarruthers/helio/cache"
 	"mbcarruthers/helio/dataservice/db"
+	"mbcarruthers/helio/geoprivacy"
+	"mbcarruthers/helio/graph"
+	"mbcarruthers/helio/openapi"
+	"mbcarruthers/helio/quality"
+	"mbcarruthers/helio/requestid"
 	"mbcarruthers/helio/routes"
-)
-
-const (
-	__port = 8000
+	"mbcarruthers/helio/rpc"
+	"mbcarruthers/helio/service"
+	"mbcarruthers/helio/validation"
+	"mbcarruthers/logging"
+	"mbcarruthers/metrics"
+	"mbcarruthers/tracing"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
 )
 
 var (
-	port = fmt.Sprintf(":%d", __port) // change to 8000 to use with react client
+	btrflydb      *db.DataStore
+	readCache     *cache.Cache
+	validator     *validation.Validator
+	policy        geoprivacy.Policy
+	authenticator *auth.Authenticator
+	oidc          *auth.OIDC
+	spec          *openapi.Document
+	graphLimits   = graph.DefaultLimits()
+	logLevel      *config.LevelVar
+	corsOrigins   *Cors
+	timeouts      routes.Timeouts
 )
 
-var (
-	btrflydb *db.DataStore
-)
+// routePolicy is the role required by each protected route, any route left out is public.
+var routePolicy = auth.Policy{
+	"POST /entities/":                 auth.Curator,
+	"PUT /entities/:id":               auth.Curator,
+	"DELETE /entities/:id":            auth.Admin,
+	"POST /entities/:id/restore":      auth.Admin,
+	"GET /entities/duplicates":        auth.Viewer,
+	"POST /entities/duplicates/merge": auth.Curator,
+	"GET /review/":                    auth.Viewer,
+	"POST /review/analyze":            auth.Admin,
+	"POST /review/:id/accept":         auth.Curator,
+	"POST /review/:id/reject":         auth.Curator,
+	"POST /apikeys/":                  auth.Admin,
+	"GET /apikeys/":                   auth.Admin,
+	"DELETE /apikeys/:id":             auth.Admin,
+	"GET /apikeys/:id/usage":          auth.Admin,
+	"GET /audit/":                     auth.Admin,
+	"GET /audit/export":               auth.Admin,
+}
+
+// setup connects to the database and builds everything the routes need from the configuration.
+func setup(cfg Config) {
+	btrflydb = db.NewDataStore(cfg.DSN, cfg.Database.StatementTimeout, db.NewBreaker(cfg.Database.BreakerThreshold, cfg.Database.BreakerCooldown, cfg.Database.BreakerMaxCooldown))
+	// reads of entities, searches and counts are kept by cache.backend, every mutation drops them
+	var err error
+	readCache, err = cache.Open(cfg.Cache.Backend, cfg.Cache.TTL, cfg.Cache.MaxEntries, cfg.Cache.RedisURL)
+	if err != nil {
+		logging.Fatal("Error opening the cache", "error", err)
+	}
+	btrflydb.UseCache(readCache)
+	corsOrigins = NewCors(cfg.CorsOrigins)
+	timeouts, _ = cfg.routeTimeouts() // checked by Config.Validate
+
+	// the settings were checked by Config.Validate, the files they name are only read here
+	mode, _ := validation.ParseMode(cfg.Validation.Mode)
+	rules, err := validation.LoadRules(cfg.Validation.Rules)
+	if err != nil {
+		logging.Fatal("Error loading validation rules", "error", err)
+	}
+	if validator, err = validation.NewValidator(rules, mode); err != nil {
+		logging.Fatal("Error creating validator", "error", err)
+	}
+	if policy, err = geoprivacy.LoadPolicy(cfg.GeoprivacyPolicy); err != nil {
+		logging.Fatal("Error loading geoprivacy policy", "error", err)
+	}
+	if spec, err = openapi.Load(); err != nil {
+		logging.Fatal("Error loading the OpenAPI document", "error", err)
+	}
+	graphLimits.MaxComplexity, graphLimits.MaxDepth = cfg.GraphQL.MaxComplexity, cfg.GraphQL.MaxDepth
+
+	// auth.jwks_url or auth.key_file(a PEM public key) validate bearer tokens
+	var keys auth.KeySource
+	if cfg.Auth.JWKSURL != "" {
+		keys = auth.NewJWKS(cfg.Auth.JWKSURL, 15*time.Minute)
+	} else if cfg.Auth.KeyFile != "" {
+		key, err := auth.LoadKeyFile(cfg.Auth.KeyFile)
+		if err != nil {
+			logging.Fatal("Error loading auth key file", "error", err)
+		}
+		keys = key
+	} else {
+		slog.Warn("No auth.jwks_url or auth.key_file set, every protected route will be refused")
+	}
+	authenticator = auth.NewAuthenticator(keys, cfg.Auth.Issuer, cfg.Auth.Audience)
 
-func init() {
-	btrflydb = db.NewDataStore(db.Defaultdb)
+	// oidc.issuer enables logging in through an OpenID Connect provider, sessions are signed by oidc.session_key_file
+	// (a PEM P-256 private key) or by a key generated at startup, which logs everyone out on restart
+	if cfg.OIDC.Issuer != "" {
+		var signer *auth.Signer
+		if cfg.OIDC.SessionKeyFile != "" {
+			signer, err = auth.LoadSigner(cfg.OIDC.SessionKeyFile, auth.SessionIssuer, auth.SessionIssuer)
+		} else {
+			signer, err = auth.NewSigner(auth.SessionIssuer, auth.SessionIssuer)
+		}
+		if err != nil {
+			logging.Fatal("Error creating session signer", "error", err)
+		}
+		roleMap, _ := auth.ParseRoleMap(cfg.OIDC.RoleMap)
+		sessions := auth.NewSessions(signer, 8*time.Hour, strings.HasPrefix(cfg.OIDC.RedirectURL, "https://"))
+		authenticator.UseSessions(sessions)
+		oidc = auth.NewOIDC(auth.OIDCConfig{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientId:     cfg.OIDC.ClientId,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			PostLoginURL: cfg.OIDC.PostLoginURL,
+			Scopes:       strings.Fields(cfg.OIDC.Scopes),
+			RolesClaim:   cfg.OIDC.RolesClaim,
+			RoleMap:      roleMap,
+		}, sessions)
+		// the provider is discovered now, and by a later login if it cannot be reached yet
+		if err = oidc.Discover(context.Background()); err != nil {
+			slog.Warn("OIDC discovery failed, logins will retry it", "error", err)
+		}
+	}
 }
 
 func main() {
-	defer func(btrflydb *db.DataStore, ctx context.Context) {
-		err := btrflydb.Close(ctx)
-		if err != nil {
-			log.Printf("database didnt close properly:%s \n", err.Error())
+	loader, err := config.NewLoader("helio", defaultConfig)
+	if err != nil {
+		logging.Fatal("Error reading the configuration settings", "error", err)
+	}
+	loader.Parse(os.Args[1:])
+	cfg, err := loader.Load()
+	if err != nil {
+		logging.Fatal("Error loading configuration", "error", err)
+	}
+	loader.Print(os.Stderr, cfg)
+	if loader.PrintOnly() {
+		return
+	}
+	if cfg.LogLevel != config.Debug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	// every line is logged as json, along with the request id of the request it was logged for
+	logLevel = config.NewLevelVar(cfg.LogLevel)
+	logging.Setup("helio", logLevel)
+	setup(cfg)
+	// requests and the statements they run are traced once tracing.exporter is set, see the tracing package
+	shutdownTracing, err := tracing.Setup("helio", cfg.Tracing)
+	if err != nil {
+		logging.Fatal("Error setting up tracing", "error", err)
+	}
+	// SIGHUP reloads cors_origins and log_level, any other setting needs a restart
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	loader.Watch(reloadCtx, cfg, func(next Config) {
+		logLevel.Set(next.LogLevel)
+		corsOrigins.SetOrigins(next.CorsOrigins)
+	})
+
+	r, app := newRouter(cfg)
+	for _, difference := range documentDrift(r.Routes()) {
+		slog.Warn("OpenAPI document differs from the routes", "difference", difference)
+	}
+	// /metrics adds the database and the observations to the request metrics, observations are counted every minute
+	prometheus.MustRegister(btrflydb.Collector(), service.NewObservationsCollector(btrflydb, time.Minute))
+	if readCache != nil {
+		prometheus.MustRegister(readCache.Collector())
+	}
+	// api keys and anonymous callers are rate limited in front of /entities, usage is written every 10 seconds
+	limitCtx, stopLimiter := context.WithCancel(context.Background())
+	limiterDone := make(chan struct{})
+	go func() {
+		app.limiter.Run(limitCtx, 10*time.Second)
+		close(limiterDone)
+	}()
+	// the database is connected to in the background, once it is reached data_file is imported into a new database
+	// and the quality analysis runs
+	connectCtx, stopConnect := context.WithCancel(context.Background())
+	go func() {
+		if err := btrflydb.Connect(cfg.Database.ConnectBackoff, cfg.Database.ConnectMaxBackoff, connectCtx); err != nil {
+			return
+		}
+		slog.Info("Database reached")
+		if err := app.entities.ImportFile(cfg.DataFile, connectCtx); err != nil {
+			slog.Error("Error importing data_file", "data_file", cfg.DataFile, "error", err)
 		}
-	}(btrflydb, context.Background())
-
-	r := gin.Default()
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"https://*", "http://", "*"},
-		AllowMethods:     []string{"GET", "PUT", "POST", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Accept", "Authorization", "Content-type", "X-CSRF-Token"},
-		ExposeHeaders:    []string{"Content-Length", "Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
-
-	entities := r.Group("/entities")
+		if report, err := app.review.RunAnalysis(connectCtx); err != nil {
+			slog.Error("Quality analysis failed", "error", err)
+		} else {
+			slog.Info("Quality analysis done", "flagged", report.Flagged, "analyzed", report.Analyzed)
+		}
+	}()
+
+	// the gRPC EntityService shares the entity service with the REST routes
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GrpcPort))
+	if err != nil {
+		logging.Fatal("Error listening at gRPC port", "port", cfg.GrpcPort, "error", err)
+	}
+	grpcServer := rpc.NewServer(app.entities, policy, authenticator, cfg.Timeouts.Default)
+	go func() {
+		slog.Info("gRPC Server live", "port", cfg.GrpcPort)
+		if err := grpcServer.Serve(listener); err != nil {
+			slog.Error("gRPC server stopped", "error", err)
+		}
+	}()
+	// every request runs under requestCtx, cancelled once the drain times out
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	server := &http.Server{
+		Addr:        fmt.Sprintf(":%d", cfg.Port),
+		Handler:     r,
+		BaseContext: func(net.Listener) context.Context { return requestCtx },
+		ErrorLog:    slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+	go func() {
+		slog.Info("Database Server live", "port", cfg.Port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logging.Fatal("Error running at port", "port", cfg.Port, "error", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	slog.Info("Server shutting down")
+	drain(cfg, server, grpcServer, app.health, cancelRequests)
+
+	// background work stops before the database closes, the limiter writes the usage it holds one last time
+	stopReload()
+	stopConnect()
+	stopLimiter()
+	<-limiterDone
+	if err := btrflydb.Close(context.Background()); err != nil {
+		slog.Error("database didnt close properly", "error", err)
+	}
+	// the spans of the last requests are exported before exiting
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(tracingCtx); err != nil {
+		slog.Error("traces didnt export properly", "error", err)
+	}
+	cancelTracing()
+	slog.Info("Server has shutdown properly")
+}
+
+// app is what the routes are served by, which main starts and stops along with them.
+type app struct {
+	health   *routes.HealthRouteHandler
+	limiter  *apikey.Limiter
+	entities *service.Entities
+	review   *routes.ReviewRouteHandler
+}
+
+// newRouter registers every route along with the middleware in front of them, once setup built what they need.
+func newRouter(cfg Config) (*gin.Engine, app) {
+	r := gin.New()
+	// client ips, limiting anonymous callers and recorded to the audit log, are only taken from X-Forwarded-For when
+	// the request came through a trusted proxy, anyone else could name any ip
+	_ = r.SetTrustedProxies(cfg.TrustedProxies) // checked by Config.Validate
+	r.Use(requestMetrics(metrics.NewHTTP()), requestTracing(), requestLogger(), recovery())
+	r.Use(requestid.Middleware())
+	// responses are compressed with zstd, brotli or gzip as the request accepts, once they hold compression.min_size
+	compression, _ := routes.NewCompression(cfg.Compression.Encodings, cfg.Compression.MinSize) // checked by Config.Validate
+	r.Use(compression.Middleware())
+	// every request runs under the timeout of its route, the statements it runs are cancelled along with it
+	r.Use(timeouts.Middleware())
+	r.Use(corsOrigins.Handler())
+	// curators and admins see the true coordinates of obscured and private observations
+	r.Use(authenticator.Authenticate(), auth.Authorize(routePolicy), auth.Grant(geoprivacy.TrustedKey, auth.Curator))
+	// requests to documented routes are validated once authorized, so callers without the role never learn the schema
+	r.Use(spec.Validate())
+	r.GET("/openapi.json", spec.SpecHandler)
+	r.GET("/docs", spec.DocsHandler)
+	// routes using the database answer 503 while its circuit breaker is open, /readyz tells load balancers the same.
+	// /healthz is liveness and /status reports the version, uptime and database latency
+	healthHandler := routes.NewHealthRouteHandler(btrflydb)
+	available := healthHandler.Available()
+	r.GET("/healthz", healthHandler.HealthzHandler)
+	r.GET("/readyz", healthHandler.ReadyHandler)
+	r.GET("/status", healthHandler.StatusHandler)
+	// /metrics serves the request metrics along with the collectors main registers
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// api keys and anonymous callers are rate limited in front of /entities, usage is written every 10 seconds
+	limiter := apikey.NewLimiter(btrflydb)
+	keyMiddleware := apikey.NewMiddleware(btrflydb, limiter, apikey.DefaultAnonymousLimits(), db.ErrNotFound)
+
+	detector := quality.DefaultDuplicateDetector()
+	entityService := service.NewEntities(btrflydb, validator) // every mutation of an entity is appended to observations.audit_log
+	btrflyHandler := routes.NewEntityRouteHandler(btrflydb, entityService, policy)
+	duplicateHandler := routes.NewDuplicateRouteHandler(btrflydb, entityService, detector)
+	// lists, searches and aggregates carry the dataset version as ETag and Last-Modified, so they can be revalidated
+	conditional := routes.NewConditional(btrflydb, cfg.Cache.MaxAge).Middleware()
+	entities := r.Group("/entities", available, keyMiddleware.Handler())
 	{
-		btrflyHandler := routes.NewEntityRouteHandler(btrflydb)
-		entities.POST("/", btrflyHandler.NewEntityHandler) // Note: All mutable operations will move to authorized
+		entities.POST("/", btrflyHandler.NewEntityHandler) // Note: All mutable operations are authorized through routePolicy
 		entities.GET("/:id", btrflyHandler.GetEntityById)
-		entities.GET("/", btrflyHandler.ListEntityHandler)
-		entities.PUT("/:id", btrflyHandler.UpdateEntityHandler)              // Note: All mutable operations will move to authorized
-		entities.DELETE("/:id", btrflyHandler.DeleteEntityHandler)           // Note: All mutable operations will move to authorized
-		entities.GET("/search", btrflyHandler.SearchEntitiesWithinDateRange) // Todo: Be able to get dates not within a string values.
+		entities.GET("/", conditional, btrflyHandler.ListEntityHandler)
+		entities.PUT("/:id", btrflyHandler.UpdateEntityHandler)
+		entities.DELETE("/:id", btrflyHandler.DeleteEntityHandler)
+		entities.POST("/:id/restore", btrflyHandler.RestoreEntityHandler)
+		entities.GET("/search", conditional, btrflyHandler.SearchEntitiesWithinDateRange) // Todo: Be able to get dates not within a string values.
+		entities.GET("/duplicates", duplicateHandler.ListDuplicatesHandler)
+		entities.POST("/duplicates/merge", duplicateHandler.MergeHandler)
+	}
+	// the front end asks /graphql for exactly the fields it needs, rate limited the same as /entities
+	graphHandler, err := graph.NewHandler(btrflydb, policy, graphLimits)
+	if err != nil {
+		logging.Fatal("Error building the GraphQL schema", "error", err)
+	}
+	r.GET("/graphql", available, keyMiddleware.Handler(), conditional, graphHandler.GraphQLHandler)
+	r.POST("/graphql", available, keyMiddleware.Handler(), graphHandler.GraphQLHandler)
+	reviewHandler := routes.NewReviewRouteHandler(btrflydb, quality.NewAnalyzer(validator, detector, 3, quality.DefaultTimeZones), policy)
+	review := r.Group("/review", available)
+	{
+		review.GET("/", reviewHandler.ListReviewHandler) // Note: All curator operations are authorized through routePolicy
+		review.POST("/analyze", reviewHandler.AnalyzeHandler)
+		review.POST("/:id/accept", reviewHandler.AcceptHandler)
+		review.POST("/:id/reject", reviewHandler.RejectHandler)
 	}
-	log.Printf("Database Server live at port %d \n", __port)
-	if err := r.Run(port); err != nil {
-		log.Fatalf("Error running at port %d\n%s",
-			__port,
-			err.Error())
+	keyHandler := routes.NewApiKeyRouteHandler(btrflydb, keyMiddleware)
+	apikeys := r.Group("/apikeys", available)
+	{
+		apikeys.POST("/", keyHandler.NewApiKeyHandler) // Note: Api keys are managed by admins only, see routePolicy
+		apikeys.GET("/", keyHandler.ListApiKeyHandler)
+		apikeys.DELETE("/:id", keyHandler.RevokeApiKeyHandler)
+		apikeys.GET("/:id/usage", keyHandler.UsageHandler)
+	}
+	auditHandler := routes.NewAuditRouteHandler(btrflydb)
+	auditGroup := r.Group("/audit", available)
+	{
+		auditGroup.GET("/", auditHandler.ListAuditHandler) // Note: The audit log is read by admins only, see routePolicy
+		auditGroup.GET("/export", auditHandler.ExportAuditHandler)
 	}
+	authGroup := r.Group("/auth")
+	{
+		authGroup.GET("/me", auth.MeHandler)
+		if oidc != nil {
+			authGroup.GET("/login", oidc.LoginHandler)
+			authGroup.GET("/callback", oidc.CallbackHandler)
+			authGroup.POST("/logout", oidc.LogoutHandler)
+		}
+	}
+	r.NoRoute(noRoute)
+	return r, app{health: healthHandler, limiter: limiter, entities: entityService, review: reviewHandler}
+}
 
+// drain stops helio taking new requests and waits for the ones in flight. Readiness fails first, for
+// shutdown.drain_delay, so load balancers stop sending requests before the listeners close. Requests still running
+// after shutdown.timeout are cancelled, along with their queries.
+func drain(cfg Config, server *http.Server, grpcServer *grpc.Server, health *routes.HealthRouteHandler, cancelRequests context.CancelFunc) {
+	health.Drain()
+	time.Sleep(cfg.Shutdown.DrainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+	defer cancel()
+	grpcDone := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcDone)
+	}()
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("Requests still running, cancelling them", "timeout", cfg.Shutdown.Timeout.String())
+		cancelRequests()
+		_ = server.Close()
+	}
+	select {
+	case <-grpcDone:
+	case <-ctx.Done():
+		slog.Warn("gRPC calls still running, stopping them", "timeout", cfg.Shutdown.Timeout.String())
+		grpcServer.Stop()
+	}
+	cancelRequests()
+}
+
+// documentDrift returns every difference between the OpenAPI document and the routes or the routePolicy.
+func documentDrift(routes gin.RoutesInfo) []string {
+	var differences []string
+	undocumented, unrouted := spec.Compare(routes)
+	for _, route := range undocumented {
+		differences = append(differences, "route "+route+" is missing from the document")
+	}
+	for _, route := range unrouted {
+		differences = append(differences, "operation "+route+" has no route")
+	}
+	roles := spec.Roles()
+	for route, role := range routePolicy {
+		method, path, _ := strings.Cut(route, " ")
+		key := method + " " + openapi.PathOf(path)
+		if roles[key] != string(role) {
+			differences = append(differences, fmt.Sprintf("route %s requires %q, the document says %q", key, role, roles[key]))
+		}
+		delete(roles, key)
+	}
+	for route, role := range roles {
+		differences = append(differences, fmt.Sprintf("operation %s requires %q, the routePolicy does not", route, role))
+	}
+	sort.Strings(differences)
+	return differences
 }
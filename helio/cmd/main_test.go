@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestDocumentDrift(t *testing.T) {
+	// the provider cannot be discovered, its routes are registered all the same
+	issuer := httptest.NewServer(http.NotFoundHandler())
+	defer issuer.Close()
+	cfg := defaultConfig()
+	cfg.OIDC.Issuer = issuer.URL
+	setup(cfg)
+	defer btrflydb.Close(context.Background())
+
+	r, _ := newRouter(cfg)
+	for _, difference := range documentDrift(r.Routes()) {
+		t.Errorf("the OpenAPI document differs from the routes, %s", difference)
+	}
+}
@@ -0,0 +1,213 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgtype"
+	"mbcarruthers/helio/cache"
+	"mbcarruthers/helio/model"
+	"strconv"
+)
+
+// groupings are the expressions entities can be counted by, keyed by name. Only these ever reach a statement.
+var groupings = map[string]string{
+	"taxon_id":      "taxon_id::STRING",
+	"year":          "extract(year FROM observed_on)::INT::STRING",
+	"month":         "date_trunc('month', observed_on)::DATE::STRING",
+	"quality_grade": "quality_grade",
+	"geoprivacy":    "geoprivacy",
+}
+
+// after adds the condition selecting entities past the cursor, in the order of build. Entities without an observed_on
+// are ordered first.
+func (q *queryBuilder) after(cursor *model.Cursor) *queryBuilder {
+	switch {
+	case cursor == nil:
+	case cursor.ObservedOn.Valid:
+		q.where("(observed_on, id) > (?, ?)", cursor.ObservedOn, cursor.Id)
+	default:
+		q.where("(observed_on IS NOT NULL OR id > ?)", cursor.Id)
+	}
+	return q
+}
+
+// SearchEntitiesPage returns at most limit entities matching the query past the cursor, a nil cursor starts at the beginning.
+func (d *DataStore) SearchEntitiesPage(query model.SearchQuery, cursor *model.Cursor, limit int, ctx context.Context) ([]model.Entity, error) {
+	return cache.Get(d.cache, "SearchEntitiesPage", []any{query, cursor, limit}, func(ctx context.Context) ([]model.Entity, error) {
+		return d.searchEntitiesPage(query, cursor, limit, ctx)
+	}, ctx)
+}
+
+// searchEntitiesPage reads from the database, see SearchEntitiesPage.
+func (d *DataStore) searchEntitiesPage(query model.SearchQuery, cursor *model.Cursor, limit int, ctx context.Context) ([]model.Entity, error) {
+	ctx, end := observe(ctx, "SearchEntitiesPage")
+	defer end()
+	q := searchBuilder(query).after(cursor)
+	rows, err := d.Conn.Query(ctx, q.build()+" LIMIT "+strconv.Itoa(limit), q.args...)
+	if err != nil {
+		logFailure(ctx, err, "executing paged search")
+		return nil, fmt.Errorf("err execute")
+	}
+	entities, err := collectEntities(rows)
+	if err != nil {
+		logFailure(ctx, err, "scanning through paged search results")
+		return nil, fmt.Errorf("error scanning entities")
+	}
+	return entities, nil
+}
+
+// GetEntitiesByIds returns the entities with the given ids, ids that are not found are left out.
+func (d *DataStore) GetEntitiesByIds(ids []int, ctx context.Context) ([]model.Entity, error) {
+	return cache.Get(d.cache, "GetEntitiesByIds", ids, func(ctx context.Context) ([]model.Entity, error) {
+		return d.getEntitiesByIds(ids, ctx)
+	}, ctx)
+}
+
+// getEntitiesByIds reads from the database, see GetEntitiesByIds.
+func (d *DataStore) getEntitiesByIds(ids []int, ctx context.Context) ([]model.Entity, error) {
+	ctx, end := observe(ctx, "GetEntitiesByIds")
+	defer end()
+	q := (&queryBuilder{}).where("id = ANY(?)", ids)
+	rows, err := d.Conn.Query(ctx, q.build(), q.args...)
+	if err != nil {
+		logFailure(ctx, err, "finding entities by id")
+		return nil, fmt.Errorf("err execute")
+	}
+	entities, err := collectEntities(rows)
+	if err != nil {
+		logFailure(ctx, err, "scanning through entities by id")
+		return nil, fmt.Errorf("error scanning entities")
+	}
+	return entities, nil
+}
+
+// CountEntities returns the number of entities matching the query.
+func (d *DataStore) CountEntities(query model.SearchQuery, ctx context.Context) (int, error) {
+	return cache.Get(d.cache, "CountEntities", query, func(ctx context.Context) (int, error) {
+		return d.countEntities(query, ctx)
+	}, ctx)
+}
+
+// countEntities reads from the database, see CountEntities.
+func (d *DataStore) countEntities(query model.SearchQuery, ctx context.Context) (int, error) {
+	ctx, end := observe(ctx, "CountEntities")
+	defer end()
+	q := searchBuilder(query)
+	var count int
+	if err := d.Conn.QueryRow(ctx, "SELECT count(*) FROM observations.fl_lepidoptera"+q.clause(), q.args...).Scan(&count); err != nil {
+		logFailure(ctx, err, "counting entities")
+		return 0, fmt.Errorf("err execute")
+	}
+	return count, nil
+}
+
+// CountEntitiesBy counts the entities matching the query by one of the groupings, largest count first.
+func (d *DataStore) CountEntitiesBy(grouping string, query model.SearchQuery, ctx context.Context) ([]model.Count, error) {
+	return cache.Get(d.cache, "CountEntitiesBy", []any{grouping, query}, func(ctx context.Context) ([]model.Count, error) {
+		return d.countEntitiesBy(grouping, query, ctx)
+	}, ctx)
+}
+
+// countEntitiesBy reads from the database, see CountEntitiesBy.
+func (d *DataStore) countEntitiesBy(grouping string, query model.SearchQuery, ctx context.Context) ([]model.Count, error) {
+	ctx, end := observe(ctx, "CountEntitiesBy")
+	defer end()
+	expression, ok := groupings[grouping]
+	if !ok {
+		return nil, fmt.Errorf("unknown grouping %q", grouping)
+	}
+	q := searchBuilder(query)
+	rows, err := d.Conn.Query(ctx, "SELECT "+expression+", count(*) FROM observations.fl_lepidoptera"+q.clause()+
+		" GROUP BY 1 ORDER BY 2 DESC, 1", q.args...)
+	if err != nil {
+		logFailure(ctx, err, "counting entities by %s", grouping)
+		return nil, fmt.Errorf("err execute")
+	}
+	defer rows.Close()
+	counts := []model.Count{}
+	for rows.Next() {
+		var count model.Count
+		if err = rows.Scan(&count.Key, &count.Count); err != nil {
+			logFailure(ctx, err, "scanning counts by %s", grouping)
+			return nil, fmt.Errorf("error scanning counts")
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// GetTaxa summarizes the entities of every taxon within ids that are published by a search, taxa without any are left out.
+func (d *DataStore) GetTaxa(ids []int, ctx context.Context) (map[int]model.Taxon, error) {
+	return cache.Get(d.cache, "GetTaxa", ids, func(ctx context.Context) (map[int]model.Taxon, error) {
+		return d.getTaxa(ids, ctx)
+	}, ctx)
+}
+
+// getTaxa reads from the database, see GetTaxa.
+func (d *DataStore) getTaxa(ids []int, ctx context.Context) (map[int]model.Taxon, error) {
+	ctx, end := observe(ctx, "GetTaxa")
+	defer end()
+	q := searchBuilder(model.SearchQuery{}).where("taxon_id = ANY(?)", ids)
+	rows, err := d.Conn.Query(ctx, "SELECT taxon_id, species_guess, count(*), min(observed_on), max(observed_on) "+
+		"FROM observations.fl_lepidoptera"+q.clause()+" GROUP BY 1, 2", q.args...)
+	if err != nil {
+		logFailure(ctx, err, "summarizing taxa")
+		return nil, fmt.Errorf("err execute")
+	}
+	defer rows.Close()
+	taxa, names := map[int]model.Taxon{}, map[int]int{} // names holds the count of each taxon's current name
+	for rows.Next() {
+		var id, count int
+		var name string
+		var first, last pgtype.Date
+		if err = rows.Scan(&id, &name, &count, &first, &last); err != nil {
+			logFailure(ctx, err, "scanning taxa")
+			return nil, fmt.Errorf("error scanning taxa")
+		}
+		taxon := taxa[id]
+		taxon.Id = id
+		taxon.Observations += count
+		if count > names[id] || (count == names[id] && name < taxon.Name) {
+			taxon.Name, names[id] = name, count
+		}
+		if !taxon.FirstObserved.Valid || first.Time.Before(taxon.FirstObserved.Time) {
+			taxon.FirstObserved = first
+		}
+		if !taxon.LastObserved.Valid || last.Time.After(taxon.LastObserved.Time) {
+			taxon.LastObserved = last
+		}
+		taxa[id] = taxon
+	}
+	return taxa, rows.Err()
+}
+
+// CountPlaces counts the entities matching the query by taxon, geoprivacy and place_guess.
+func (d *DataStore) CountPlaces(query model.SearchQuery, ctx context.Context) ([]model.PlaceCount, error) {
+	return cache.Get(d.cache, "CountPlaces", query, func(ctx context.Context) ([]model.PlaceCount, error) {
+		return d.countPlaces(query, ctx)
+	}, ctx)
+}
+
+// countPlaces reads from the database, see CountPlaces.
+func (d *DataStore) countPlaces(query model.SearchQuery, ctx context.Context) ([]model.PlaceCount, error) {
+	ctx, end := observe(ctx, "CountPlaces")
+	defer end()
+	q := searchBuilder(query)
+	rows, err := d.Conn.Query(ctx, "SELECT taxon_id, geoprivacy, place_guess, count(*) FROM observations.fl_lepidoptera"+
+		q.clause()+" GROUP BY 1, 2, 3", q.args...)
+	if err != nil {
+		logFailure(ctx, err, "counting places")
+		return nil, fmt.Errorf("err execute")
+	}
+	defer rows.Close()
+	places := []model.PlaceCount{}
+	for rows.Next() {
+		var place model.PlaceCount
+		if err = rows.Scan(&place.TaxonId, &place.Geoprivacy, &place.PlaceGuess, &place.Count); err != nil {
+			logFailure(ctx, err, "scanning places")
+			return nil, fmt.Errorf("error scanning places")
+		}
+		places = append(places, place)
+	}
+	return places, rows.Err()
+}
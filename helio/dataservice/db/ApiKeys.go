@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"mbcarruthers/helio/model"
+	"time"
+)
+
+const (
+	apiKeyColumns = "id,name,prefix,rate_per_second,burst,daily_quota,created_at,revoked_at"
+)
+
+func scanApiKey(row pgx.Row) (model.ApiKey, error) {
+	var key model.ApiKey
+	err := row.Scan(&key.Id, &key.Name, &key.Prefix, &key.RatePerSecond, &key.Burst, &key.DailyQuota, &key.CreatedAt, &key.RevokedAt)
+	return key, err
+}
+
+// InsertApiKey stores a new ApiKey along with the hash of the key.
+// Note: Made to be used with the ApiKeyRouteHandler
+func (d *DataStore) InsertApiKey(key model.ApiKey, hash []byte, ctx context.Context) (model.ApiKey, error) {
+	ctx, end := observe(ctx, "InsertApiKey")
+	defer end()
+	row := d.Conn.QueryRow(ctx, "INSERT INTO observations.api_keys(name,prefix,key_hash,rate_per_second,burst,daily_quota) "+
+		"VALUES($1,$2,$3,$4,$5,$6) RETURNING "+apiKeyColumns, key.Name, key.Prefix, hash, key.RatePerSecond, key.Burst, key.DailyQuota)
+	stored, err := scanApiKey(row)
+	if err != nil {
+		logFailure(ctx, err, "inserting api key %s", key.Name)
+		return model.ApiKey{}, fmt.Errorf("err execute")
+	}
+	return stored, nil
+}
+
+// ListApiKeys returns every ApiKey, revoked ones included.
+func (d *DataStore) ListApiKeys(ctx context.Context) ([]model.ApiKey, error) {
+	ctx, end := observe(ctx, "ListApiKeys")
+	defer end()
+	rows, err := d.Conn.Query(ctx, "SELECT "+apiKeyColumns+" FROM observations.api_keys ORDER BY created_at")
+	if err != nil {
+		logFailure(ctx, err, "listing api keys")
+		return nil, fmt.Errorf("err execute")
+	}
+	defer rows.Close()
+	keys := []model.ApiKey{}
+	for rows.Next() {
+		key, err := scanApiKey(rows)
+		if err != nil {
+			logFailure(ctx, err, "scanning api keys")
+			return nil, fmt.Errorf("error scanning api keys")
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// GetApiKeyByHash returns the ApiKey that is not revoked with the given key hash.
+func (d *DataStore) GetApiKeyByHash(hash []byte, ctx context.Context) (model.ApiKey, error) {
+	ctx, end := observe(ctx, "GetApiKeyByHash")
+	defer end()
+	key, err := scanApiKey(d.Conn.QueryRow(ctx, "SELECT "+apiKeyColumns+" FROM observations.api_keys WHERE key_hash = $1 AND revoked_at IS NULL", hash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.ApiKey{}, ErrNotFound
+	} else if err != nil {
+		logFailure(ctx, err, "finding api key")
+		return model.ApiKey{}, fmt.Errorf("err execute")
+	}
+	return key, nil
+}
+
+// RevokeApiKey revokes an ApiKey, it is kept along with its usage.
+func (d *DataStore) RevokeApiKey(id uuid.UUID, ctx context.Context) error {
+	ctx, end := observe(ctx, "RevokeApiKey")
+	defer end()
+	tag, err := d.Conn.Exec(ctx, "UPDATE observations.api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		logFailure(ctx, err, "revoking api key %s", id)
+		return fmt.Errorf("err execute")
+	} else if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddApiKeyUsage adds requests to an ApiKey's usage of the given day.
+func (d *DataStore) AddApiKeyUsage(id uuid.UUID, day time.Time, requests int64, ctx context.Context) error {
+	ctx, end := observe(ctx, "AddApiKeyUsage")
+	defer end()
+	_, err := d.Conn.Exec(ctx, "INSERT INTO observations.api_key_usage(key_id,day,requests) VALUES($1,$2,$3) "+
+		"ON CONFLICT (key_id,day) DO UPDATE SET requests = api_key_usage.requests + excluded.requests", id, day, requests)
+	if err != nil {
+		logFailure(ctx, err, "adding usage of api key %s", id)
+		return fmt.Errorf("err execute")
+	}
+	return nil
+}
+
+// GetApiKeyUsage returns the daily usage of an ApiKey, most recent day first.
+func (d *DataStore) GetApiKeyUsage(id uuid.UUID, ctx context.Context) ([]model.ApiKeyUsage, error) {
+	ctx, end := observe(ctx, "GetApiKeyUsage")
+	defer end()
+	rows, err := d.Conn.Query(ctx, "SELECT day,requests FROM observations.api_key_usage WHERE key_id = $1 ORDER BY day DESC", id)
+	if err != nil {
+		logFailure(ctx, err, "finding usage of api key %s", id)
+		return nil, fmt.Errorf("err execute")
+	}
+	defer rows.Close()
+	usage := []model.ApiKeyUsage{}
+	for rows.Next() {
+		var day time.Time
+		var requests int64
+		if err := rows.Scan(&day, &requests); err != nil {
+			logFailure(ctx, err, "scanning usage of api key %s", id)
+			return nil, fmt.Errorf("error scanning usage")
+		}
+		usage = append(usage, model.ApiKeyUsage{Day: day.Format("2006-01-02"), Requests: requests})
+	}
+	return usage, rows.Err()
+}
+
+// GetApiKeyUsageOn returns the number of requests made with an ApiKey on a single day.
+func (d *DataStore) GetApiKeyUsageOn(id uuid.UUID, day time.Time, ctx context.Context) (int64, error) {
+	ctx, end := observe(ctx, "GetApiKeyUsageOn")
+	defer end()
+	var requests int64
+	err := d.Conn.QueryRow(ctx, "SELECT requests FROM observations.api_key_usage WHERE key_id = $1 AND day = $2", id, day).Scan(&requests)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	} else if err != nil {
+		logFailure(ctx, err, "finding usage of api key %s", id)
+		return 0, fmt.Errorf("err execute")
+	}
+	return requests, nil
+}
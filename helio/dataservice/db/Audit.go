@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"mbcarruthers/helio/model"
+	"strconv"
+)
+
+const (
+	auditColumns = "id,at,action,entity_id,actor,request_id,client_ip,changes,before,after"
+)
+
+// Note: observations.audit_log is append-only, DataStore never updates or deletes an entry.
+
+func scanAuditEntry(row pgx.Row) (model.AuditEntry, error) {
+	var entry model.AuditEntry
+	var changes, before, after []byte
+	if err := row.Scan(&entry.Id, &entry.At, &entry.Action, &entry.EntityId, &entry.Actor, &entry.RequestId, &entry.ClientIp,
+		&changes, &before, &after); err != nil {
+		return entry, err
+	}
+	if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+		return entry, err
+	}
+	if before != nil {
+		entry.Before = &model.Entity{}
+		if err := json.Unmarshal(before, entry.Before); err != nil {
+			return entry, err
+		}
+	}
+	if after != nil {
+		entry.After = &model.Entity{}
+		if err := json.Unmarshal(after, entry.After); err != nil {
+			return entry, err
+		}
+	}
+	return entry, nil
+}
+
+// snapshot encodes an entity as json, a nil entity is stored as NULL.
+func snapshot(entity *model.Entity) ([]byte, error) {
+	if entity == nil {
+		return nil, nil
+	}
+	return json.Marshal(entity)
+}
+
+// insertAuditEntries appends entries to observations.audit_log within tx, so the entries of a mutation are committed
+// or rolled back along with it.
+func insertAuditEntries(tx pgx.Tx, entries []model.AuditEntry, ctx context.Context) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	batch := &pgx.Batch{}
+	for _, entry := range entries {
+		changes := entry.Changes
+		if changes == nil {
+			changes = []model.FieldChange{}
+		}
+		encodedChanges, err := json.Marshal(changes)
+		if err != nil {
+			return fmt.Errorf("err encoding changes")
+		}
+		before, err := snapshot(entry.Before)
+		if err != nil {
+			return fmt.Errorf("err encoding changes")
+		}
+		after, err := snapshot(entry.After)
+		if err != nil {
+			return fmt.Errorf("err encoding changes")
+		}
+		batch.Queue("INSERT INTO observations.audit_log(at,action,entity_id,actor,request_id,client_ip,changes,before,after) "+
+			"VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9)", entry.At, entry.Action, entry.EntityId, entry.Actor, entry.RequestId, entry.ClientIp,
+			encodedChanges, before, after)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		logFailure(ctx, err, "recording %d audit entries", len(entries))
+		return fmt.Errorf("err execute")
+	}
+	return nil
+}
+
+// auditBuilder turns a model.AuditQuery into a queryBuilder.
+func auditBuilder(query model.AuditQuery) *queryBuilder {
+	q := &queryBuilder{}
+	if query.EntityId != 0 {
+		q.where("entity_id = ?", query.EntityId)
+	}
+	if query.Action != "" {
+		q.where("action = ?", query.Action)
+	}
+	if query.Actor != "" {
+		q.where("actor = ?", query.Actor)
+	}
+	if query.RequestId != "" {
+		q.where("request_id = ?", query.RequestId)
+	}
+	if !query.Since.IsZero() {
+		q.where("at >= ?", query.Since)
+	}
+	if !query.Until.IsZero() {
+		q.where("at < ?", query.Until)
+	}
+	return q
+}
+
+// SearchAudit returns the entries of observations.audit_log matching the query, newest first.
+// A Limit of 0 returns every matching entry.
+// Route GET /audit?
+func (d *DataStore) SearchAudit(query model.AuditQuery, ctx context.Context) ([]model.AuditEntry, error) {
+	ctx, end := observe(ctx, "SearchAudit")
+	defer end()
+	q := auditBuilder(query)
+	statement := "SELECT " + auditColumns + " FROM observations.audit_log" + q.clause() + " ORDER BY at DESC, id DESC"
+	if query.Limit > 0 {
+		statement += " LIMIT " + strconv.Itoa(query.Limit)
+	}
+	if query.Offset > 0 {
+		statement += " OFFSET " + strconv.Itoa(query.Offset)
+	}
+	rows, err := d.Conn.Query(ctx, statement, q.args...)
+	if err != nil {
+		logFailure(ctx, err, "searching audit log")
+		return nil, fmt.Errorf("err execute")
+	}
+	defer rows.Close()
+	entries := []model.AuditEntry{}
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			logFailure(ctx, err, "scanning audit log")
+			return nil, fmt.Errorf("error scanning audit log")
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		logFailure(ctx, err, "scanning audit log")
+		return nil, fmt.Errorf("error scanning audit log")
+	}
+	return entries, nil
+}
+
+// LastDeleted returns the entity as it was when it was last deleted, and ErrNotFound if it never was.
+// Note: Made to restore a deleted entity
+func (d *DataStore) LastDeleted(id int, ctx context.Context) (model.Entity, error) {
+	ctx, end := observe(ctx, "LastDeleted")
+	defer end()
+	entries, err := d.SearchAudit(model.AuditQuery{EntityId: id, Action: model.AuditDelete, Limit: 1}, ctx)
+	if err != nil {
+		return model.Entity{}, err
+	}
+	if len(entries) == 0 || entries[0].Before == nil {
+		return model.Entity{}, ErrNotFound
+	}
+	return *entries[0].Before, nil
+}
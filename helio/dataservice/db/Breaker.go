@@ -0,0 +1,242 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var ErrUnavailable = errors.New("err database unavailable") // returned without reaching the database while the Breaker is open
+
+// State is the state of a Breaker.
+type State string
+
+const (
+	Closed   State = "closed"    // statements reach the database
+	Open     State = "open"      // statements fail right away with ErrUnavailable
+	HalfOpen State = "half-open" // the cooldown is over, the next statements decide whether to close or open again
+)
+
+// Breaker is a circuit breaker in front of the database. Threshold failures in a row open it for a cooldown, during
+// which every statement fails with ErrUnavailable instead of waiting on a database that is down. The cooldown doubles
+// every time it opens again without having closed, up to MaxCooldown.
+// Note: Only failures to reach the database count, an error returned by the database(a *pgconn.PgError) does not.
+type Breaker struct {
+	Threshold   int
+	Cooldown    time.Duration
+	MaxCooldown time.Duration
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	cooldown time.Duration
+	until    time.Time
+}
+
+// NewBreaker constructs a Breaker, open until the database is first reached.
+func NewBreaker(threshold int, cooldown time.Duration, maxCooldown time.Duration) *Breaker {
+	return &Breaker{
+		Threshold:   threshold,
+		Cooldown:    cooldown,
+		MaxCooldown: maxCooldown,
+		state:       Open,
+		cooldown:    cooldown,
+		until:       time.Now().Add(cooldown),
+	}
+}
+
+// Allow returns ErrUnavailable while the breaker is open.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != Open {
+		return nil
+	}
+	if time.Now().Before(b.until) {
+		return ErrUnavailable
+	}
+	b.state = HalfOpen
+	return nil
+}
+
+// Record records the outcome of a statement.
+func (b *Breaker) Record(err error) {
+	if !unreachable(err) {
+		b.mu.Lock()
+		if b.state != Closed {
+			slog.Info("Database reached, circuit breaker closed")
+		}
+		b.state, b.failures, b.cooldown = Closed, 0, b.Cooldown
+		b.mu.Unlock()
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == Open && !time.Now().Before(b.until) { // a Ping bypassing Allow once the cooldown is over
+		b.state = HalfOpen
+	}
+	switch {
+	case b.state == HalfOpen:
+		b.cooldown *= 2
+		if b.cooldown > b.MaxCooldown {
+			b.cooldown = b.MaxCooldown
+		}
+	case b.state == Closed && b.failures >= b.Threshold:
+		b.cooldown = b.Cooldown
+	default:
+		return
+	}
+	b.state, b.until = Open, time.Now().Add(b.cooldown)
+	slog.Error("Database unreachable, circuit breaker open", "cooldown", b.cooldown.String(), "error", err)
+}
+
+// State returns the state of the breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == Open && !time.Now().Before(b.until) {
+		return HalfOpen
+	}
+	return b.state
+}
+
+// RetryAfter returns how long the breaker stays open, 0 unless it is open.
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != Open {
+		return 0
+	}
+	if wait := time.Until(b.until); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// unreachable reports whether err means the database could not be reached or did not answer.
+func unreachable(err error) bool {
+	var pgErr *pgconn.PgError
+	switch {
+	case err == nil, errors.Is(err, pgx.ErrNoRows), errors.Is(err, pgx.ErrTxClosed), errors.Is(err, context.Canceled),
+		errors.Is(err, ErrUnavailable), errors.As(err, &pgErr):
+		return false
+	}
+	return true
+}
+
+// Conn runs statements on a connection pool, connected lazily, through the Breaker.
+type Conn struct {
+	pool    *pgxpool.Pool
+	breaker *Breaker
+}
+
+func (c *Conn) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if err := c.breaker.Allow(); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	tag, err := c.pool.Exec(ctx, sql, args...)
+	c.record(err, ctx)
+	return tag, err
+}
+
+func (c *Conn) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if err := c.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	rows, err := c.pool.Query(ctx, sql, args...)
+	c.record(err, ctx)
+	return rows, err
+}
+
+func (c *Conn) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if err := c.breaker.Allow(); err != nil {
+		return errRow{err}
+	}
+	return breakerRow{row: c.pool.QueryRow(ctx, sql, args...), conn: c, ctx: ctx}
+}
+
+func (c *Conn) Begin(ctx context.Context) (pgx.Tx, error) {
+	if err := c.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	tx, err := c.pool.Begin(ctx)
+	c.record(err, ctx)
+	return tx, err
+}
+
+// Ping reaches the database, bypassing the Breaker but recording the outcome to it.
+func (c *Conn) Ping(ctx context.Context) error {
+	err := c.pool.Ping(ctx)
+	c.breaker.Record(err)
+	return err
+}
+
+// record records the outcome of a statement to the Breaker. A statement failing once ctx is cancelled or past its
+// deadline says nothing about the database, unless no connection to it could be made at all.
+func (c *Conn) record(err error, ctx context.Context) {
+	if err != nil && ctx.Err() != nil && c.pool.Stat().TotalConns() > 0 {
+		return
+	}
+	c.breaker.Record(err)
+}
+
+// Stat returns the statistics of the connection pool.
+func (c *Conn) Stat() *pgxpool.Stat {
+	return c.pool.Stat()
+}
+
+// breakerRow records the outcome of QueryRow once it is scanned.
+type breakerRow struct {
+	row  pgx.Row
+	conn *Conn
+	ctx  context.Context
+}
+
+func (r breakerRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	r.conn.record(err, r.ctx)
+	return err
+}
+
+// errRow is a row that could not be queried.
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
+
+// Connect pings the database until it is reached or ctx is done, waiting twice as long after every failed attempt
+// from backoff up to maxBackoff. Statements do not need to wait for Connect, they connect on their own.
+func (d *DataStore) Connect(backoff time.Duration, maxBackoff time.Duration, ctx context.Context) error {
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := d.Conn.Ping(pingCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		slog.WarnContext(ctx, "Error connecting to database", "attempt", attempt, "retry_in", backoff.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("err connect: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// Breaker returns the circuit breaker in front of the database.
+func (d *DataStore) Breaker() *Breaker {
+	return d.Conn.breaker
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"testing"
+	"time"
+)
+
+var errRefused = errors.New("dial tcp 127.0.0.1:26257: connect: connection refused")
+
+// step is something happening to a Breaker.
+type step func(b *Breaker)
+
+var (
+	reached = func(b *Breaker) { b.Record(nil) }
+	failed  = func(b *Breaker) { b.Record(errRefused) }
+	// refused is the database answering with an error, which reached it
+	refused = func(b *Breaker) { b.Record(&pgconn.PgError{Code: "23505"}) }
+	// cooledDown ends the cooldown
+	cooledDown = func(b *Breaker) {
+		b.mu.Lock()
+		b.until = time.Now().Add(-time.Millisecond)
+		b.mu.Unlock()
+	}
+	allowed = func(b *Breaker) { _ = b.Allow() }
+)
+
+func TestBreaker(t *testing.T) {
+	tests := []struct {
+		name         string
+		steps        []step
+		want         State
+		wantCooldown time.Duration
+	}{
+		{"open until first reached", nil, Open, time.Second},
+		{"first reached", []step{reached}, Closed, time.Second},
+		{"failures under the threshold", []step{reached, failed, failed}, Closed, time.Second},
+		{"failures at the threshold", []step{reached, failed, failed, failed}, Open, time.Second},
+		{"failures not in a row", []step{reached, failed, failed, reached, failed, failed}, Closed, time.Second},
+		{"database errors", []step{reached, refused, refused, refused, refused}, Closed, time.Second},
+		{"cooled down", []step{reached, failed, failed, failed, cooledDown}, HalfOpen, time.Second},
+		{"half-open reached", []step{reached, failed, failed, failed, cooledDown, allowed, reached}, Closed, time.Second},
+		{"half-open failed", []step{reached, failed, failed, failed, cooledDown, allowed, failed}, Open, 2 * time.Second},
+		{"half-open failed again", []step{reached, failed, failed, failed, cooledDown, allowed, failed, cooledDown, allowed, failed},
+			Open, 4 * time.Second},
+		{"cooldown up to the max", []step{failed, cooledDown, allowed, failed, cooledDown, allowed, failed, cooledDown, allowed, failed,
+			cooledDown, allowed, failed}, Open, 10 * time.Second},
+		{"cooldown reset once reached", []step{failed, cooledDown, allowed, failed, cooledDown, allowed, reached, failed, failed, failed},
+			Open, time.Second},
+		// Ping bypasses Allow
+		{"ping failed once cooled down", []step{failed, cooledDown, failed}, Open, 2 * time.Second},
+		{"ping failed within the cooldown", []step{failed, failed, failed}, Open, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBreaker(3, time.Second, 10*time.Second)
+			for _, step := range tt.steps {
+				step(b)
+			}
+			if got := b.State(); got != tt.want {
+				t.Errorf("State() = %v, want %v", got, tt.want)
+			}
+			if b.cooldown != tt.wantCooldown {
+				t.Errorf("cooldown = %v, want %v", b.cooldown, tt.wantCooldown)
+			}
+			err, retryAfter := b.Allow(), b.RetryAfter()
+			if tt.want == Open && (!errors.Is(err, ErrUnavailable) || retryAfter <= 0 || retryAfter > tt.wantCooldown) {
+				t.Errorf("Allow(), RetryAfter() = %v, %v, want ErrUnavailable for at most %v", err, retryAfter, tt.wantCooldown)
+			}
+			if tt.want != Open && (err != nil || retryAfter != 0) {
+				t.Errorf("Allow(), RetryAfter() = %v, %v, want nil, 0", err, retryAfter)
+			}
+		})
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"no rows", pgx.ErrNoRows, false},
+		{"database error", fmt.Errorf("err execute: %w", &pgconn.PgError{Code: "40001"}), false},
+		{"cancelled", context.Canceled, false},
+		{"breaker open", ErrUnavailable, false},
+		{"connection refused", errRefused, true},
+		{"deadline exceeded", context.DeadlineExceeded, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unreachable(tt.err); got != tt.want {
+				t.Errorf("unreachable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
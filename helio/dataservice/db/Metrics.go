@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+var (
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "helio",
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Time taken by DataStore methods, by method.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"method"})
+	statementFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "helio",
+		Subsystem: "db",
+		Name:      "statement_failures_total",
+		Help:      "Statements that failed, by kind: error, timeout or cancelled.",
+	}, []string{"kind"})
+)
+
+// observe traces the DataStore method and records the time it takes, every method starting with
+//
+//	ctx, end := observe(ctx, "GetEntityById")
+//	defer end()
+func observe(ctx context.Context, method string) (context.Context, func()) {
+	start := time.Now()
+	ctx, span := startMethod(ctx, method)
+	return ctx, func() {
+		queryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		span.End()
+	}
+}
+
+// poolDesc describes a statistic of the connection pool.
+func poolDesc(name string, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName("helio", "db_pool", name), help, nil, nil)
+}
+
+var (
+	acquiredConns   = poolDesc("acquired_conns", "Connections in use.")
+	idleConns       = poolDesc("idle_conns", "Connections idle.")
+	totalConns      = poolDesc("total_conns", "Connections open or being opened.")
+	maxConns        = poolDesc("max_conns", "Most connections the pool opens.")
+	acquires        = poolDesc("acquires_total", "Connections acquired from the pool.")
+	acquireDuration = poolDesc("acquire_duration_seconds_total", "Time spent acquiring connections.")
+	emptyAcquires   = poolDesc("empty_acquires_total", "Acquires that waited for a connection, none being idle.")
+	canceledAcquire = poolDesc("canceled_acquires_total", "Acquires cancelled by their context.")
+	breakerState    = prometheus.NewDesc("helio_db_breaker_state", "State of the database circuit breaker, 1 for the current one.", []string{"state"}, nil)
+)
+
+// collector collects the metrics of a DataStore.
+type collector struct {
+	conn *Conn
+}
+
+// Collector returns the metrics of the DataStore: the duration of its methods, the failed statements, the statistics
+// of the connection pool and the state of the circuit breaker.
+func (d *DataStore) Collector() prometheus.Collector {
+	return collector{conn: d.Conn}
+}
+
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	queryDuration.Describe(ch)
+	statementFailures.Describe(ch)
+	for _, desc := range []*prometheus.Desc{acquiredConns, idleConns, totalConns, maxConns, acquires, acquireDuration, emptyAcquires, canceledAcquire, breakerState} {
+		ch <- desc
+	}
+}
+
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	queryDuration.Collect(ch)
+	statementFailures.Collect(ch)
+	stat := c.conn.Stat()
+	ch <- prometheus.MustNewConstMetric(acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(canceledAcquire, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	current := c.conn.breaker.State()
+	for _, state := range []State{Closed, Open, HalfOpen} {
+		value := 0.0
+		if state == current {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(breakerState, prometheus.GaugeValue, value, string(state))
+	}
+}
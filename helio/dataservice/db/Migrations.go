@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// migrations are idempotent statements bringing an existing observations.fl_lepidoptera up to date with the
+// table created by CreateAndInsert. They are run in order, new statements go at the end.
+var migrations = []string{
+	"ALTER TABLE observations.fl_lepidoptera ADD COLUMN IF NOT EXISTS quality_flags STRING[] NOT NULL DEFAULT '{}'",
+	"ALTER TABLE observations.fl_lepidoptera ADD COLUMN IF NOT EXISTS quality_grade STRING NOT NULL DEFAULT 'clean'",
+	"UPDATE observations.fl_lepidoptera SET quality_grade = 'flagged' WHERE quality_grade = 'clean' AND array_length(quality_flags, 1) > 0",
+	"ALTER TABLE observations.fl_lepidoptera ADD COLUMN IF NOT EXISTS duplicate_of INT8 NULL",
+	"ALTER TABLE observations.fl_lepidoptera ADD COLUMN IF NOT EXISTS geoprivacy STRING NOT NULL DEFAULT ''",
+	"CREATE TABLE IF NOT EXISTS observations.api_keys(" +
+		"id UUID PRIMARY KEY DEFAULT gen_random_uuid()," +
+		"name STRING NOT NULL," +
+		"prefix STRING NOT NULL," +
+		"key_hash BYTES UNIQUE NOT NULL," +
+		"rate_per_second FLOAT8 NOT NULL," +
+		"burst INT8 NOT NULL," +
+		"daily_quota INT8 NOT NULL DEFAULT 0," +
+		"created_at TIMESTAMPTZ NOT NULL DEFAULT now()," +
+		"revoked_at TIMESTAMPTZ NULL)",
+	"CREATE TABLE IF NOT EXISTS observations.api_key_usage(" +
+		"key_id UUID NOT NULL REFERENCES observations.api_keys(id)," +
+		"day DATE NOT NULL," +
+		"requests INT8 NOT NULL DEFAULT 0," +
+		"PRIMARY KEY (key_id, day))",
+	"CREATE TABLE IF NOT EXISTS observations.audit_log(" +
+		"id INT8 PRIMARY KEY DEFAULT unique_rowid()," +
+		"at TIMESTAMPTZ NOT NULL DEFAULT now()," +
+		"action STRING NOT NULL," +
+		"entity_id INT8 NOT NULL," +
+		"actor STRING NOT NULL," +
+		"request_id STRING NOT NULL DEFAULT ''," +
+		"client_ip STRING NOT NULL DEFAULT ''," +
+		"changes JSONB NOT NULL DEFAULT '[]'," +
+		"before JSONB NULL," +
+		"after JSONB NULL," +
+		"INDEX audit_log_entity_idx (entity_id, at DESC)," +
+		"INDEX audit_log_at_idx (at DESC))",
+	"CREATE TABLE IF NOT EXISTS observations.dataset_version(" +
+		"id INT8 PRIMARY KEY DEFAULT 1 CHECK (id = 1)," +
+		"version INT8 NOT NULL DEFAULT 0," +
+		"modified_at TIMESTAMPTZ NOT NULL DEFAULT now())",
+	"INSERT INTO observations.dataset_version(id) VALUES (1) ON CONFLICT (id) DO NOTHING",
+}
+
+// Migrate runs every migration against the database.
+func (d *DataStore) Migrate(ctx context.Context) error {
+	ctx, end := observe(ctx, "Migrate")
+	defer end()
+	for i, statement := range migrations {
+		if _, err := d.Conn.Exec(ctx, statement); err != nil {
+			logFailure(ctx, err, "running migration %d", i)
+			return fmt.Errorf("err migrating")
+		}
+	}
+	return nil
+}
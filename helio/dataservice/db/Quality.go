@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"mbcarruthers/helio/model"
+)
+
+// SaveQuality stores the quality flags and grade of every entity in a single transaction. The grade of an entity a
+// curator accepted or rejected is kept, even when it was reviewed after the entities were read.
+// Note: Made to be used after a quality analysis pass
+func (d *DataStore) SaveQuality(entities []model.Entity, ctx context.Context) error {
+	ctx, end := observe(ctx, "SaveQuality")
+	defer end()
+	tx, err := d.Conn.Begin(ctx)
+	if err != nil {
+		logFailure(ctx, err, "beginning quality update")
+		return fmt.Errorf("err execute")
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			logFailure(ctx, err, "rolling back quality update")
+		}
+	}(tx, ctx)
+
+	batch := &pgx.Batch{}
+	for _, entity := range entities {
+		batch.Queue("UPDATE observations.fl_lepidoptera SET quality_flags = $1, "+
+			"quality_grade = CASE WHEN quality_grade IN ($4, $5) THEN quality_grade ELSE $2 END WHERE id = $3",
+			qualityFlags(entity.QualityFlags), qualityGrade(entity), entity.Id, model.GradeAccepted, model.GradeRejected)
+	}
+	if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+		logFailure(ctx, err, "executing quality update")
+		return fmt.Errorf("err execute")
+	}
+	if err = bumpVersion(tx, ctx); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		logFailure(ctx, err, "commiting quality update")
+		return fmt.Errorf("could not persist data")
+	}
+	d.changed(ctx)
+	return nil
+}
+
+// SetQualityGrade sets the quality grade of a single entity, i.e. when a curator accepts or rejects it, along with
+// its audit entry.
+// Note: Made to be used with the ReviewRouteHandler
+func (d *DataStore) SetQualityGrade(id int, grade string, entry model.AuditEntry, ctx context.Context) error {
+	ctx, end := observe(ctx, "SetQualityGrade")
+	defer end()
+	tx, err := d.Conn.Begin(ctx)
+	if err != nil {
+		logFailure(ctx, err, "beginning review of %d", id)
+		return fmt.Errorf("err execute")
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			logFailure(ctx, err, "rolling back review of %d", id)
+		}
+	}(tx, ctx)
+	tag, err := tx.Exec(ctx, "UPDATE observations.fl_lepidoptera SET quality_grade = $1 WHERE id = $2", grade, id)
+	if err != nil {
+		logFailure(ctx, err, "setting quality grade of %d", id)
+		return fmt.Errorf("err execute")
+	} else if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if err = insertAuditEntries(tx, []model.AuditEntry{entry}, ctx); err != nil {
+		return err
+	}
+	if err = bumpVersion(tx, ctx); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		logFailure(ctx, err, "commiting review of %d", id)
+		return fmt.Errorf("could not persist data")
+	}
+	d.changed(ctx)
+	return nil
+}
+
+// MergeEntities links every duplicate to the entity being kept. Entities previously merged into one of the
+// duplicates are linked to the kept entity as well, so a duplicate_of never points at another duplicate.
+// Returns every relinked entity as it was before the merge, the audit entries entries returns for them are written in
+// the same transaction.
+// Note: Made to be used with the DuplicateRouteHandler
+func (d *DataStore) MergeEntities(keep int, duplicates []int, entries func(relinked []model.Entity) []model.AuditEntry, ctx context.Context) ([]model.Entity, error) {
+	ctx, end := observe(ctx, "MergeEntities")
+	defer end()
+	tx, err := d.Conn.Begin(ctx)
+	if err != nil {
+		logFailure(ctx, err, "beginning merge")
+		return nil, fmt.Errorf("err execute")
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			logFailure(ctx, err, "rolling back merge")
+		}
+	}(tx, ctx)
+
+	var kept int
+	if err = tx.QueryRow(ctx, "SELECT count(*) FROM observations.fl_lepidoptera WHERE id = $1 AND duplicate_of IS NULL", keep).Scan(&kept); err != nil {
+		logFailure(ctx, err, "finding %d to merge into", keep)
+		return nil, fmt.Errorf("err execute")
+	} else if kept == 0 {
+		return nil, ErrNotFound
+	}
+	rows, err := tx.Query(ctx, "SELECT "+entityColumns+" FROM observations.fl_lepidoptera "+
+		"WHERE (id = ANY($2) AND id != $1) OR duplicate_of = ANY($2) ORDER BY id", keep, duplicates)
+	if err != nil {
+		logFailure(ctx, err, "finding duplicates of %d", keep)
+		return nil, fmt.Errorf("err execute")
+	}
+	relinked, err := collectEntities(rows)
+	if err != nil {
+		logFailure(ctx, err, "scanning duplicates of %d", keep)
+		return nil, fmt.Errorf("error scanning entities")
+	}
+	tag, err := tx.Exec(ctx, "UPDATE observations.fl_lepidoptera SET duplicate_of = $1 WHERE id = ANY($2) AND id != $1", keep, duplicates)
+	if err != nil {
+		logFailure(ctx, err, "merging into %d", keep)
+		return nil, fmt.Errorf("err execute")
+	} else if tag.RowsAffected() != int64(len(duplicates)) {
+		return nil, ErrNotFound
+	}
+	if _, err = tx.Exec(ctx, "UPDATE observations.fl_lepidoptera SET duplicate_of = $1 WHERE duplicate_of = ANY($2)", keep, duplicates); err != nil {
+		logFailure(ctx, err, "relinking duplicates of %v", duplicates)
+		return nil, fmt.Errorf("err execute")
+	}
+	if err = insertAuditEntries(tx, entries(relinked), ctx); err != nil {
+		return nil, err
+	}
+	if err = bumpVersion(tx, ctx); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		logFailure(ctx, err, "commiting merge")
+		return nil, fmt.Errorf("could not persist data")
+	}
+	d.changed(ctx)
+	return relinked, nil
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"mbcarruthers/helio/cache"
+	"mbcarruthers/helio/model"
+	"strconv"
+	"strings"
+)
+
+// queryBuilder gathers the conditions of a WHERE clause along with their arguments.
+// Conditions are written with ? placeholders which are numbered($1, $2...) as they are added.
+type queryBuilder struct {
+	conditions []string
+	args       []any
+}
+
+// where adds a condition joined with AND to the ones before it.
+func (q *queryBuilder) where(condition string, args ...any) *queryBuilder {
+	for _, arg := range args {
+		q.args = append(q.args, arg)
+		condition = strings.Replace(condition, "?", "$"+strconv.Itoa(len(q.args)), 1)
+	}
+	q.conditions = append(q.conditions, condition)
+	return q
+}
+
+// clause returns the WHERE clause of every condition added, empty without any.
+func (q *queryBuilder) clause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// build returns the statement selecting entityColumns from observations.fl_lepidoptera with every condition added.
+func (q *queryBuilder) build() string {
+	return "SELECT " + entityColumns + " FROM observations.fl_lepidoptera" + q.clause() + " ORDER BY observed_on, id"
+}
+
+// searchBuilder turns a model.SearchQuery into a queryBuilder. Unless grades are asked for rejected entities are left out,
+// as well as entities merged into another unless merged is asked for.
+func searchBuilder(query model.SearchQuery) *queryBuilder {
+	q := &queryBuilder{}
+	if query.TaxonId != 0 {
+		q.where("taxon_id = ?", query.TaxonId)
+	}
+	date1, date2 := query.Date1, query.Date2
+	if date1.Valid && date2.Valid && date1.Time.After(date2.Time) {
+		date1, date2 = date2, date1
+	}
+	if date1.Valid {
+		q.where("observed_on >= ?", date1)
+	}
+	if date2.Valid {
+		q.where("observed_on <= ?", date2)
+	}
+	if grades := splitValues(query.QualityGrade); len(grades) != 0 {
+		q.where("quality_grade = ANY(?)", grades)
+	} else {
+		q.where("quality_grade != ?", model.GradeRejected)
+	}
+	if query.QualityFlag != "" {
+		q.where("? = ANY(quality_flags)", query.QualityFlag)
+	}
+	if query.Geoprivacy != "" {
+		q.where("geoprivacy = ?", query.Geoprivacy)
+	}
+	if !query.Merged {
+		q.where("duplicate_of IS NULL")
+	}
+	return q
+}
+
+// splitValues flattens repeated and comma separated query values.
+func splitValues(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				result = append(result, v)
+			}
+		}
+	}
+	return result
+}
+
+// SearchEntities returns every entity matching the query.
+// Route GET /entities/search?
+func (d *DataStore) SearchEntities(query model.SearchQuery, ctx context.Context) ([]model.Entity, error) {
+	return cache.Get(d.cache, "SearchEntities", query, func(ctx context.Context) ([]model.Entity, error) {
+		return d.searchEntities(query, ctx)
+	}, ctx)
+}
+
+// searchEntities reads from the database, see SearchEntities.
+func (d *DataStore) searchEntities(query model.SearchQuery, ctx context.Context) ([]model.Entity, error) {
+	ctx, end := observe(ctx, "SearchEntities")
+	defer end()
+	q := searchBuilder(query)
+	rows, err := d.Conn.Query(ctx, q.build(), q.args...)
+	if err != nil {
+		logFailure(ctx, err, "executing search")
+		return nil, fmt.Errorf("err execute")
+	}
+	entities, err := collectEntities(rows)
+	if err != nil {
+		logFailure(ctx, err, "scanning through search results")
+		return nil, fmt.Errorf("error scanning entities")
+	}
+	return entities, nil
+}
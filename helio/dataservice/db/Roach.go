@@ -2,50 +2,80 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
-	"log"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"log/slog"
+	"mbcarruthers/helio/cache"
 	"mbcarruthers/helio/model"
+	"mbcarruthers/logging"
 	"os"
+	"strconv"
+	"time"
 )
 
 var (
 	Defaultdb = os.Getenv("DSN") // Note: default database configuration. Nothing fancy, just for testing.
+
+	ErrNotFound = errors.New("err not found") // returned when the entity being operated on does not exist
+	ErrExists   = errors.New("err exists")    // returned by CreateAndInsert when the database was created before
 )
 
-// DataStore represents a Cochroachdb connection and facilitates operations surrounding it.
+// DataStore represents a Cochroachdb connection pool and facilitates operations surrounding it.
 type DataStore struct {
-	Conn *pgx.Conn
+	Conn  *Conn
+	cache *cache.Cache
 }
 
-// NewDataStore creates a new default Database Connection.
-func NewDataStore(dataConfig string) *DataStore {
-	config, err := pgx.ParseConfig(dataConfig)
+// NewDataStore creates a new default Database Connection pool, connected lazily. Statements go through the breaker,
+// see DataStore.Connect to wait for the database. The database cancels any statement running longer than
+// statementTimeout, 0 leaves it to the database's own setting.
+func NewDataStore(dataConfig string, statementTimeout time.Duration, breaker *Breaker) *DataStore {
+	config, err := pgxpool.ParseConfig(dataConfig)
 	if err != nil {
-		log.Fatalf("Error setting database configuration! %+v \n", err)
+		logging.Fatal("Error setting database configuration", "error", err)
 	}
 	// set a default name for the session
-	config.RuntimeParams["application_name"] = "$ helio"
+	config.ConnConfig.RuntimeParams["application_name"] = "$ helio"
+	config.ConnConfig.Tracer = statementTracer{}
+	if statementTimeout > 0 {
+		config.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(statementTimeout.Milliseconds(), 10)
+	}
 
-	conn, err := pgx.ConnectConfig(context.Background(), config) // Note: Might not be a bad place for a Circuit Breaker Pattern
+	pool, err := pgxpool.NewWithConfig(context.Background(), config) // Note: No connection is made until the first statement
 	if err != nil {
-		log.Fatalf("Error connecting to database! %+v \n", err)
+		logging.Fatal("Error creating database pool", "error", err)
 	}
 
 	return &DataStore{
-		Conn: conn,
+		Conn: &Conn{pool: pool, breaker: breaker},
 	}
 }
 
-// DataStore.Close() extends the pgx.Conn Close() function to be defered outside of the structure.
+// UseCache reads entities, searches and counts through c, invalidated by every mutation made through the DataStore.
+func (d *DataStore) UseCache(c *cache.Cache) {
+	d.cache = c
+}
+
+// DefaultBreaker opens after 5 failures in a row, for 1 second at first and for at most 30 seconds.
+func DefaultBreaker() *Breaker {
+	return NewBreaker(5, time.Second, 30*time.Second)
+}
+
+// DataStore.Close() closes the connection pool, to be defered outside of the structure.
 func (d *DataStore) Close(ctx context.Context) error {
-	return d.Conn.Close(ctx)
+	d.Conn.pool.Close()
+	return nil
 }
 
 // DataStore.CreateAndInsert() function to Create and Insert information into the a temporary database produced by docker-compose. For testing.
+// The database is migrated before the import, so the audit entries of the import are written along with it.
 // Note: specifically for testing called upon in the creation of a new EntityRouteHandler
-func (d *DataStore) CreateAndInsert(observations []model.Entity, ctx context.Context) error {
+func (d *DataStore) CreateAndInsert(observations []model.Entity, entries []model.AuditEntry, ctx context.Context) error {
+	ctx, end := observe(ctx, "CreateAndInsert")
+	defer end()
 	// preparedStatements is created to make creation + insertion a bit easier to read.
 	preparedStatements := map[string]string{
 		"database": "CREATE DATABASE observations",
@@ -58,80 +88,169 @@ func (d *DataStore) CreateAndInsert(observations []model.Entity, ctx context.Con
 			"latitude string NOT NULL," +
 			"longitude string NOT NULL," +
 			"observed_on DATE NOT NULL," +
-			"time_zone STRING NOT NULL);",
-	} // Todo: Fix the errors below to not expose SQL information
-	if _, err := d.Conn.Exec(ctx, preparedStatements["database"]); err != nil {
-		return fmt.Errorf("Error creating database\n %+v", err)
+			"time_zone STRING NOT NULL," +
+			"quality_flags STRING[] NOT NULL DEFAULT '{}'," +
+			"quality_grade STRING NOT NULL DEFAULT 'clean'," +
+			"duplicate_of INT8 NULL," +
+			"geoprivacy STRING NOT NULL DEFAULT '');",
+	}
+	if _, err := d.Conn.Exec(ctx, preparedStatements["database"]); isDuplicateDatabase(err) {
+		return ErrExists
+	} else if err != nil {
+		logFailure(ctx, err, "creating database")
+		return fmt.Errorf("err creating database")
 	} else if _, err = d.Conn.Exec(ctx, preparedStatements["table"]); err != nil {
-		return fmt.Errorf("Error creating table \n %+v\n", err)
+		logFailure(ctx, err, "creating table")
+		return fmt.Errorf("err creating table")
+	} else if err = d.Migrate(ctx); err != nil {
+		return err
 	} else {
-		log.Println("Database and table created")
+		slog.InfoContext(ctx, "Database and table created")
 		tx, err := d.Conn.Begin(ctx)
 
 		if err != nil {
-			return fmt.Errorf("Error beginning transaction\n $+v \n", err)
+			logFailure(ctx, err, "beginning import")
+			return fmt.Errorf("err execute")
 		}
 		// rollback if something Went wrong before commit
 		defer func(t pgx.Tx, c context.Context) {
 			if err := t.Rollback(c); err != nil && err != pgx.ErrTxClosed {
-				log.Printf("Error, Rolled Back. Connection Closed \n %+v\n", err)
+				logFailure(c, err, "rolling back import")
 			}
 		}(tx, ctx)
 		// insert items into database
 		for _, item := range observations { // Todo: Change the name 'item' to 'entity'
-			insertTransaction := fmt.Sprintf("INSERT INTO observations.fl_lepidoptera(id,taxon_id,uuid,place_guess,species_guess,latitude,longitude,observed_on,time_zone)" +
-				"VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9)")
-
-			_, err := tx.Exec(ctx, insertTransaction, item.Id, item.TaxonId, item.Uuid, item.PlaceGuess, item.SpeciesGuess, item.Latitude, item.Longitude, item.ObservedOn, item.TimeZone)
+			_, err := tx.Exec(ctx, insertStatement, item.Id, item.TaxonId, item.Uuid, item.PlaceGuess, item.SpeciesGuess, item.Latitude, item.Longitude, item.ObservedOn, item.TimeZone, qualityFlags(item.QualityFlags), qualityGrade(item), item.DuplicateOf, item.Geoprivacy)
 			if err != nil {
-				return fmt.Errorf("Error executing \n %v \n", err)
+				logFailure(ctx, err, "importing %d", item.Id)
+				return fmt.Errorf("err execute")
 			}
 		}
+		if err = insertAuditEntries(tx, entries, ctx); err != nil {
+			return err
+		}
+		if err = bumpVersion(tx, ctx); err != nil {
+			return err
+		}
 		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("Error committing insert operations\n %+v \n", err)
+			logFailure(ctx, err, "commiting import")
+			return fmt.Errorf("could not persist data")
 		}
+		d.changed(ctx)
 	}
 	return nil
 }
 
-// InsertNewEntity function to insert a new model.Entity into observations.fl_lepidoptera
+// InsertNewEntity function to insert a new model.Entity into observations.fl_lepidoptera, along with its audit entry
 // Note: Used within the EntityRouteHandler.NewEntityHandler
-func (d *DataStore) InsertNewEntity(entity model.Entity, ctx context.Context) error {
+func (d *DataStore) InsertNewEntity(entity model.Entity, entry model.AuditEntry, ctx context.Context) error {
+	ctx, end := observe(ctx, "InsertNewEntity")
+	defer end()
 	// Note:Upon insertion, even though UUID is NOT NULL, it will generate a zero value for uuid(000-000...).
 	tx, err := d.Conn.Begin(ctx)
 	if err != nil {
-		return err
+		logFailure(ctx, err, "beginning insert of %d", entity.Id)
+		return fmt.Errorf("err execute")
 	}
 	defer func(tx pgx.Tx, ctx context.Context) {
-		err := tx.Commit(ctx)
-		if err != nil && err != pgx.ErrTxClosed {
-			log.Println("Error Commiting change.")
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			logFailure(ctx, err, "rolling back insert of %d", entity.Id)
 		}
 	}(tx, ctx)
-	insertTransaction := fmt.Sprintf("INSERT INTO observations.fl_lepidoptera(id,taxon_id,uuid,place_guess,species_guess,latitude,longitude,observed_on,time_zone)" +
-		"VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9)")
-	_, err = tx.Exec(ctx, insertTransaction, entity.Id, entity.TaxonId, entity.Uuid, entity.PlaceGuess, entity.SpeciesGuess, entity.Latitude, entity.Longitude, entity.ObservedOn, entity.TimeZone)
+	_, err = tx.Exec(ctx, insertStatement, entity.Id, entity.TaxonId, entity.Uuid, entity.PlaceGuess, entity.SpeciesGuess, entity.Latitude, entity.Longitude, entity.ObservedOn, entity.TimeZone, qualityFlags(entity.QualityFlags), qualityGrade(entity), entity.DuplicateOf, entity.Geoprivacy)
 	if err != nil {
-		log.Printf("Err inserting element\n %s \n", err.Error())
+		logFailure(ctx, err, "inserting %d", entity.Id)
+		return fmt.Errorf("err execute")
+	}
+	if err = insertAuditEntries(tx, []model.AuditEntry{entry}, ctx); err != nil {
+		return err
+	}
+	if err = bumpVersion(tx, ctx); err != nil {
 		return err
 	}
 	if err = tx.Commit(ctx); err != nil {
-		log.Printf("Err commiting insert of %d\n %s \n",
-			entity.Id, err.Error())
+		logFailure(ctx, err, "commiting insert of %d", entity.Id)
 		return fmt.Errorf("CommitErr")
 	}
+	d.changed(ctx)
 	return nil
 }
 
+// InsertEntities inserts entities into an existing observations.fl_lepidoptera in a single transaction, leaving out any
+// whose id or uuid is stored already. The audit entries entries returns for the entities inserted are written in the
+// same transaction. Returns the entities inserted.
+// Note: Used by helioctl import
+func (d *DataStore) InsertEntities(entities []model.Entity, entries func(inserted []model.Entity) []model.AuditEntry, ctx context.Context) ([]model.Entity, error) {
+	ctx, end := observe(ctx, "InsertEntities")
+	defer end()
+	tx, err := d.Conn.Begin(ctx)
+	if err != nil {
+		logFailure(ctx, err, "beginning bulk insert")
+		return nil, fmt.Errorf("err execute")
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			logFailure(ctx, err, "rolling back bulk insert")
+		}
+	}(tx, ctx)
+
+	batch := &pgx.Batch{}
+	for _, entity := range entities {
+		batch.Queue(insertStatement+" ON CONFLICT DO NOTHING", entity.Id, entity.TaxonId, entity.Uuid, entity.PlaceGuess, entity.SpeciesGuess,
+			entity.Latitude, entity.Longitude, entity.ObservedOn, entity.TimeZone, qualityFlags(entity.QualityFlags), qualityGrade(entity),
+			entity.DuplicateOf, entity.Geoprivacy)
+	}
+	results := tx.SendBatch(ctx, batch)
+	inserted := make([]model.Entity, 0, len(entities))
+	for _, entity := range entities {
+		tag, err := results.Exec()
+		if err != nil {
+			_ = results.Close()
+			logFailure(ctx, err, "inserting %d", entity.Id)
+			return nil, fmt.Errorf("err execute")
+		}
+		if tag.RowsAffected() != 0 {
+			inserted = append(inserted, entity)
+		}
+	}
+	if err = results.Close(); err != nil {
+		logFailure(ctx, err, "executing bulk insert")
+		return nil, fmt.Errorf("err execute")
+	}
+	if err = insertAuditEntries(tx, entries(inserted), ctx); err != nil {
+		return nil, err
+	}
+	if err = bumpVersion(tx, ctx); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		logFailure(ctx, err, "commiting bulk insert")
+		return nil, fmt.Errorf("could not persist data")
+	}
+	d.changed(ctx)
+	return inserted, nil
+}
+
 // GetEntityById requests an entity by its observation id from the database.
 // Note: Used within the EntityRouteHandler.GetEntityById
 func (d *DataStore) GetEntityById(id int, ctx context.Context) (model.Entity, error) {
-	var entity model.Entity
-	selectStatement := fmt.Sprintf("SELECT (id,taxon_id,uuid,place_guess,species_guess,latitude,longitude,observed_on,time_zone)" +
-		"FROM observations.fl_lepidoptera WHERE id = $1")
-	if err := d.Conn.QueryRow(ctx, selectStatement, id).Scan(&entity); err != nil {
-		log.Printf("Error finding %d \n %s \n", id, err.Error())
-		return model.Entity{}, fmt.Errorf("err not found")
+	return cache.Get(d.cache, "GetEntityById", id, func(ctx context.Context) (model.Entity, error) {
+		return d.getEntityById(id, ctx)
+	}, ctx)
+}
+
+// getEntityById reads from the database, see GetEntityById.
+func (d *DataStore) getEntityById(id int, ctx context.Context) (model.Entity, error) {
+	ctx, end := observe(ctx, "GetEntityById")
+	defer end()
+	selectStatement := "SELECT " + entityColumns + " FROM observations.fl_lepidoptera WHERE id = $1"
+	if entity, err := scanEntity(d.Conn.QueryRow(ctx, selectStatement, id)); errors.Is(err, pgx.ErrNoRows) {
+		return model.Entity{}, ErrNotFound
+	} else if errors.Is(err, ErrUnavailable) {
+		return model.Entity{}, ErrUnavailable
+	} else if err != nil {
+		logFailure(ctx, err, "finding %d", id)
+		return model.Entity{}, fmt.Errorf("err execute")
 	} else {
 		return entity, nil
 	}
@@ -140,90 +259,103 @@ func (d *DataStore) GetEntityById(id int, ctx context.Context) (model.Entity, er
 // ListAllEntities requests all information within the database of observations.fl_lepidoptera
 // Note: Made primarily for EntityRouteHandler.ListEntityHandler
 func (d *DataStore) ListAllEntities(ctx context.Context) ([]model.Entity, error) {
-	selectStatement := "SELECT id,taxon_id,uuid,place_guess,species_guess,latitude,longitude,observed_on,time_zone FROM observations.fl_lepidoptera"
+	return cache.Get(d.cache, "ListAllEntities", nil, func(ctx context.Context) ([]model.Entity, error) {
+		return d.listAllEntities(ctx)
+	}, ctx)
+}
+
+// listAllEntities reads from the database, see ListAllEntities.
+func (d *DataStore) listAllEntities(ctx context.Context) ([]model.Entity, error) {
+	ctx, end := observe(ctx, "ListAllEntities")
+	defer end()
+	selectStatement := "SELECT " + entityColumns + " FROM observations.fl_lepidoptera"
 	rows, err := d.Conn.Query(ctx, selectStatement)
 	if err != nil {
-		log.Println("Error executing query for listing all elements\n %s\n",
-			err.Error())
+		logFailure(ctx, err, "executing query for listing all elements")
 		return nil, fmt.Errorf("err execute")
 	}
-	defer rows.Close()
-	entities := []model.Entity{}
-	for rows.Next() {
-		var entity model.Entity
-		if err := rows.Scan(&entity.Id, &entity.TaxonId, &entity.Uuid, &entity.PlaceGuess, &entity.SpeciesGuess, &entity.Latitude, &entity.Longitude, &entity.ObservedOn, &entity.TimeZone); err != nil {
-			log.Printf("Error Scanning through entities\n %s \n %s",
-				err.Error(),
-				rows.Err())
-			return nil, fmt.Errorf("error scanning entities")
-		} else {
-			entities = append(entities, entity)
-		}
+	entities, err := collectEntities(rows)
+	if err != nil {
+		logFailure(ctx, err, "scanning through entities")
+		return nil, fmt.Errorf("error scanning entities")
 	}
 	return entities, nil
 }
 
-// UpdateEntityById updates the database entry by id
+// UpdateEntityById updates the database entry by id, along with its audit entry
 // Note: Made to be used in UpdateEntityHandler
-func (d *DataStore) UpdateEntityById(id int, entity model.Entity, ctx context.Context) error {
+func (d *DataStore) UpdateEntityById(id int, entity model.Entity, entry model.AuditEntry, ctx context.Context) error {
+	ctx, end := observe(ctx, "UpdateEntityById")
+	defer end()
 	tx, err := d.Conn.Begin(ctx)
 	if err != nil {
-		log.Printf("Error Beginning update Query\n %s \n",
-			err.Error())
+		logFailure(ctx, err, "beginning update query")
 		return fmt.Errorf("err execute")
 	}
 
 	defer func(tx pgx.Tx, ctx context.Context) {
-		if err := tx.Commit(ctx); err != nil && err != pgx.ErrTxClosed {
-			log.Printf("Error Commiting.\n %+v \n",
-				err.Error())
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			logFailure(ctx, err, "rolling back update of %d", id)
 		}
 	}(tx, ctx)
 	tag, err := tx.Exec(ctx, "UPDATE observations.fl_lepidoptera SET "+
 		"place_guess = $1,species_guess= $2, latitude = $3, longitude = $4, observed_on = $5,"+
-		"time_zone = $6 WHERE id = $7", entity.PlaceGuess, entity.SpeciesGuess, entity.Latitude, entity.Longitude,
-		entity.ObservedOn, entity.TimeZone, id)
+		"time_zone = $6, quality_flags = $7, quality_grade = $8, geoprivacy = $9 WHERE id = $10", entity.PlaceGuess, entity.SpeciesGuess, entity.Latitude, entity.Longitude,
+		entity.ObservedOn, entity.TimeZone, qualityFlags(entity.QualityFlags), qualityGrade(entity), entity.Geoprivacy, id)
 	if err != nil {
-		log.Printf("Err executing Update \n %s \n", err.Error())
-		return fmt.Errorf("ErrExecute")
+		logFailure(ctx, err, "executing update")
+		return fmt.Errorf("err execute")
 	} else if tag.RowsAffected() == 0 {
-		return fmt.Errorf("Err not found")
+		return ErrNotFound
+	} else if err = insertAuditEntries(tx, []model.AuditEntry{entry}, ctx); err != nil {
+		return err
+	} else if err = bumpVersion(tx, ctx); err != nil {
+		return err
 	} else {
 		//return entity, tx.Commit(ctx) // <- what it was, should i keep it that way?
 		if err = tx.Commit(ctx); err != nil { // Note: Should this even happen?
-			log.Printf("Error commiting update\n %s \n",
-				err.Error())
+			logFailure(ctx, err, "commiting update")
 			return fmt.Errorf("could not persist data")
 		} else {
+			d.changed(ctx)
 			return nil
 		}
 	}
 }
 
-// DeleteEntity deletes an entity within the database by id but cross-references the id with the id in the request body
+// DeleteEntity deletes an entity within the database by id but cross-references the id with the id in the request body.
+// The entry, holding the entity as it was, is what LastDeleted restores it from.
 // Note: Made to be used with the DeleteEntityHandler
-func (d *DataStore) DeleteEntityById(id int, ctx context.Context) error {
+func (d *DataStore) DeleteEntityById(id int, entry model.AuditEntry, ctx context.Context) error {
+	ctx, end := observe(ctx, "DeleteEntityById")
+	defer end()
 	tx, err := d.Conn.Begin(ctx) // To conform to the name? or pass with model
 	if err != nil {              // and cross-reference the id to the model?
-		log.Printf("Error beginning deletion \n %s \n",
-			err.Error())
+		logFailure(ctx, err, "beginning deletion")
 		return fmt.Errorf("err connect")
 	}
 
 	defer func(tx pgx.Tx, ctx context.Context) {
-		if err := tx.Commit(ctx); err != nil && err != pgx.ErrTxClosed {
-			log.Println(err.Error())
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			logFailure(ctx, err, "rolling back deletion of %d", id)
 		}
 	}(tx, ctx)
 
 	if tag, err := tx.Exec(ctx, "DELETE FROM observations.fl_lepidoptera WHERE id = $1", id); err != nil {
-		log.Printf("Err deleting %d \n %s\n",
-			id, err.Error())
-		return err
+		logFailure(ctx, err, "deleting %d", id)
+		return fmt.Errorf("err execute")
 	} else if tag.RowsAffected() == 0 {
-		return fmt.Errorf("err not found")
+		return ErrNotFound
+	} else if err = insertAuditEntries(tx, []model.AuditEntry{entry}, ctx); err != nil {
+		return err
+	} else if err = bumpVersion(tx, ctx); err != nil {
+		return err
+	} else if err = tx.Commit(ctx); err != nil {
+		logFailure(ctx, err, "commiting deletion of %d", id)
+		return fmt.Errorf("could not persist data")
 	} else {
-		return tx.Commit(ctx)
+		d.changed(ctx)
+		return nil
 	}
 }
 
@@ -231,51 +363,56 @@ func (d *DataStore) DeleteEntityById(id int, ctx context.Context) error {
 // results of the entities with that taxon id value and a nil error or , on error, it returns nil and the error
 // Route GET /entities/search?
 func (d *DataStore) GetEntitiesByTaxonId(taxon int, ctx context.Context) ([]model.Entity, error) { // StoppingPoint- testing
-	queryStatement := fmt.Sprintf("SELECT id, taxon_id, uuid, place_guess, species_guess, latitude, longitude, observed_on, time_zone FROM observations.fl_lepidoptera WHERE taxon_id =$1")
-	if rows, err := d.Conn.Query(ctx, queryStatement, taxon); err != nil { // Note: Make sure to change error to
-		return nil, err
-	} else {
-		defer rows.Close()
-		var entities []model.Entity
-		for rows.Next() {
-			var entity model.Entity
-			if err := rows.Scan(&entity.Id, &entity.TaxonId, &entity.Uuid,
-				&entity.PlaceGuess, &entity.SpeciesGuess, &entity.Latitude,
-				&entity.Longitude, &entity.ObservedOn, &entity.TimeZone); err != nil {
-				return nil, err
-			} else {
-				entities = append(entities, entity)
-			}
-		}
-		return entities, nil
+	return cache.Get(d.cache, "GetEntitiesByTaxonId", taxon, func(ctx context.Context) ([]model.Entity, error) {
+		return d.getEntitiesByTaxonId(taxon, ctx)
+	}, ctx)
+}
+
+// getEntitiesByTaxonId reads from the database, see GetEntitiesByTaxonId.
+func (d *DataStore) getEntitiesByTaxonId(taxon int, ctx context.Context) ([]model.Entity, error) {
+	ctx, end := observe(ctx, "GetEntitiesByTaxonId")
+	defer end()
+	queryStatement := "SELECT " + entityColumns + " FROM observations.fl_lepidoptera WHERE taxon_id = $1"
+	rows, err := d.Conn.Query(ctx, queryStatement, taxon)
+	if err != nil {
+		logFailure(ctx, err, "finding taxon %d", taxon)
+		return nil, fmt.Errorf("err execute")
 	}
+	entities, err := collectEntities(rows)
+	if err != nil {
+		logFailure(ctx, err, "scanning taxon %d", taxon)
+		return nil, fmt.Errorf("error scanning entities")
+	}
+	return entities, nil
 }
 
 // GetEntitiesByTaxonIdWithinDateRange is a very long , and aptly named function to retrieve entities based on taxon_id(species) within
 // a set of date ranges
 // Route /entities/search?
 func (d *DataStore) GetEntitiesByTaxonIdWithinDateRange(taxon_id int, date_one pgtype.Date, date_two pgtype.Date, ctx context.Context) ([]model.Entity, error) {
-	log.Println("GetEntitiesByTaxonIdWithinDateRange called")
+	return cache.Get(d.cache, "GetEntitiesByTaxonIdWithinDateRange", []any{taxon_id, date_one, date_two}, func(ctx context.Context) ([]model.Entity, error) {
+		return d.getEntitiesByTaxonIdWithinDateRange(taxon_id, date_one, date_two, ctx)
+	}, ctx)
+}
+
+// getEntitiesByTaxonIdWithinDateRange reads from the database, see GetEntitiesByTaxonIdWithinDateRange.
+func (d *DataStore) getEntitiesByTaxonIdWithinDateRange(taxon_id int, date_one pgtype.Date, date_two pgtype.Date, ctx context.Context) ([]model.Entity, error) {
+	ctx, end := observe(ctx, "GetEntitiesByTaxonIdWithinDateRange")
+	defer end()
 	if date_one.Time.After(date_two.Time) {
 		date_two, date_one = date_one, date_two // Swap values just in case date_two is greater than date_one. I'm programming for me, so I'm preparing for idiocy
 	}
-	queryStatement := fmt.Sprintf("SELECT id,taxon_id, uuid, place_guess, species_guess, latitude, longitude, observed_on , time_zone FROM observations.fl_lepidoptera WHERE taxon_id = $1 AND observed_on BETWEEN $2 AND $3")
+	queryStatement := "SELECT " + entityColumns + " FROM observations.fl_lepidoptera WHERE taxon_id = $1 AND observed_on BETWEEN $2 AND $3"
 
 	rows, err := d.Conn.Query(ctx, queryStatement, taxon_id, date_one, date_two)
 	if err != nil {
-		return nil, err
+		logFailure(ctx, err, "finding taxon %d within dates", taxon_id)
+		return nil, fmt.Errorf("err execute")
 	}
-	defer rows.Close()
-	var entities []model.Entity
-	for rows.Next() {
-		var entity model.Entity
-		if err := rows.Scan(&entity.Id, &entity.TaxonId, &entity.Uuid,
-			&entity.PlaceGuess, &entity.SpeciesGuess, &entity.Latitude,
-			&entity.Longitude, &entity.ObservedOn, &entity.TimeZone); err != nil {
-			return nil, err
-		} else {
-			entities = append(entities, entity)
-		}
+	entities, err := collectEntities(rows)
+	if err != nil {
+		logFailure(ctx, err, "scanning taxon %d within dates", taxon_id)
+		return nil, fmt.Errorf("error scanning entities")
 	}
 	return entities, nil
 }
@@ -283,27 +420,29 @@ func (d *DataStore) GetEntitiesByTaxonIdWithinDateRange(taxon_id int, date_one p
 // GetEntitiesWithinRange queries all entities within a date range provided
 // Route /entities/search?
 func (d *DataStore) GetEntitiesWithinRange(date_one pgtype.Date, date_two pgtype.Date, ctx context.Context) ([]model.Entity, error) {
-	log.Println("GetEntitiesWithinRange invoked")
+	return cache.Get(d.cache, "GetEntitiesWithinRange", []any{date_one, date_two}, func(ctx context.Context) ([]model.Entity, error) {
+		return d.getEntitiesWithinRange(date_one, date_two, ctx)
+	}, ctx)
+}
+
+// getEntitiesWithinRange reads from the database, see GetEntitiesWithinRange.
+func (d *DataStore) getEntitiesWithinRange(date_one pgtype.Date, date_two pgtype.Date, ctx context.Context) ([]model.Entity, error) {
+	ctx, end := observe(ctx, "GetEntitiesWithinRange")
+	defer end()
 	if date_one.Time.After(date_two.Time) {
 		date_two, date_one = date_one, date_two // Swap values just in case date_two is greater than date_one. I'm programming for me, so I'm preparing for idiocy
 	}
-	queryStatement := fmt.Sprintf("SELECT id,taxon_id, uuid, place_guess, species_guess, latitude, longitude, observed_on , time_zone FROM observations.fl_lepidoptera WHERE observed_on BETWEEN $1 AND $2")
+	queryStatement := "SELECT " + entityColumns + " FROM observations.fl_lepidoptera WHERE observed_on BETWEEN $1 AND $2"
 
 	rows, err := d.Conn.Query(ctx, queryStatement, date_one, date_two)
 	if err != nil {
-		return nil, err
+		logFailure(ctx, err, "finding entities within dates")
+		return nil, fmt.Errorf("err execute")
 	}
-	defer rows.Close()
-	var entities []model.Entity
-	for rows.Next() {
-		var entity model.Entity
-		if err := rows.Scan(&entity.Id, &entity.TaxonId, &entity.Uuid,
-			&entity.PlaceGuess, &entity.SpeciesGuess, &entity.Latitude,
-			&entity.Longitude, &entity.ObservedOn, &entity.TimeZone); err != nil {
-			return nil, err
-		} else {
-			entities = append(entities, entity)
-		}
+	entities, err := collectEntities(rows)
+	if err != nil {
+		logFailure(ctx, err, "scanning entities within dates")
+		return nil, fmt.Errorf("error scanning entities")
 	}
 	return entities, nil
 }
@@ -311,23 +450,26 @@ func (d *DataStore) GetEntitiesWithinRange(date_one pgtype.Date, date_two pgtype
 // GetEntitiesWithinYear queries all given Entities from a given year
 // Todo: Need to do some testing on this one. And throw it into a route
 func (d *DataStore) GetEntitiesWithinYear(year pgtype.Date, ctx context.Context) ([]model.Entity, error) { // Note: Should consider changing datatype of year parameter
+	return cache.Get(d.cache, "GetEntitiesWithinYear", year, func(ctx context.Context) ([]model.Entity, error) {
+		return d.getEntitiesWithinYear(year, ctx)
+	}, ctx)
+}
+
+// getEntitiesWithinYear reads from the database, see GetEntitiesWithinYear.
+func (d *DataStore) getEntitiesWithinYear(year pgtype.Date, ctx context.Context) ([]model.Entity, error) {
+	ctx, end := observe(ctx, "GetEntitiesWithinYear")
+	defer end()
 	_year := year.Time.Year()
-	queryStatement := fmt.Sprintf("SELECT id, taxon_id, uuid, place_guess, species_guess, latitude, longitude, observed_on, time_zone FROM observations.fl_lepidoptera WHERE date_part('year',observed_on) = $1")
+	queryStatement := "SELECT " + entityColumns + " FROM observations.fl_lepidoptera WHERE date_part('year',observed_on) = $1"
 	rows, err := d.Conn.Query(ctx, queryStatement, _year) // query entities by year
 	if err != nil {
-		// return err if there is something wrong with query or database
-		return nil, err
+		logFailure(ctx, err, "finding entities within %d", _year)
+		return nil, fmt.Errorf("err execute")
 	}
-	defer rows.Close()
-	var entities []model.Entity
-
-	for rows.Next() { // loop through results from query
-		var entity model.Entity // scan entities into variable
-		if err := rows.Scan(&entity.Id, &entity.TaxonId, &entity.Uuid, &entity.PlaceGuess, &entity.SpeciesGuess, &entity.Latitude, &entity.Longitude, &entity.ObservedOn, &entity.TimeZone); err != nil {
-			return nil, err
-		} else {
-			entities = append(entities, entity) // append entities into slice
-		}
+	entities, err := collectEntities(rows)
+	if err != nil {
+		logFailure(ctx, err, "scanning entities within %d", _year)
+		return nil, fmt.Errorf("error scanning entities")
 	}
 	return entities, nil
 }
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"github.com/jackc/pgx/v5"
+	"mbcarruthers/helio/model"
+)
+
+const (
+	// entityColumns are the columns of observations.fl_lepidoptera in the order scanEntity expects them.
+	entityColumns = "id,taxon_id,uuid,place_guess,species_guess,latitude,longitude,observed_on,time_zone,quality_flags,quality_grade,duplicate_of,geoprivacy"
+
+	insertStatement = "INSERT INTO observations.fl_lepidoptera(" + entityColumns + ")" +
+		"VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)"
+)
+
+// scanEntity scans a single row selected with entityColumns into a model.Entity.
+func scanEntity(row pgx.Row) (model.Entity, error) {
+	var entity model.Entity
+	err := row.Scan(&entity.Id, &entity.TaxonId, &entity.Uuid,
+		&entity.PlaceGuess, &entity.SpeciesGuess, &entity.Latitude,
+		&entity.Longitude, &entity.ObservedOn, &entity.TimeZone, &entity.QualityFlags, &entity.QualityGrade, &entity.DuplicateOf, &entity.Geoprivacy)
+	return entity, err
+}
+
+// collectEntities scans every row selected with entityColumns and closes the rows.
+func collectEntities(rows pgx.Rows) ([]model.Entity, error) {
+	defer rows.Close()
+	entities := []model.Entity{}
+	for rows.Next() {
+		entity, err := scanEntity(rows)
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, entity)
+	}
+	return entities, rows.Err()
+}
+
+// qualityFlags makes sure a nil slice is stored as an empty array rather than NULL.
+func qualityFlags(flags []string) []string {
+	if flags == nil {
+		return []string{}
+	}
+	return flags
+}
+
+// qualityGrade returns the grade an entity is stored with, entities that were never graded get one from their flags.
+func qualityGrade(entity model.Entity) string {
+	if entity.QualityGrade == "" {
+		return model.GradeForFlags(entity.QualityFlags)
+	}
+	return entity.QualityGrade
+}
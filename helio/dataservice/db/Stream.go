@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"mbcarruthers/helio/model"
+)
+
+// StreamAllEntities calls each with every entity of observations.fl_lepidoptera as it is scanned, rather than holding
+// them all like ListAllEntities does. Rows are read from the database only as fast as each returns, so a slow
+// caller holds the statement open rather than growing helio. Streams are not cached.
+func (d *DataStore) StreamAllEntities(each func(model.Entity) error, ctx context.Context) error {
+	ctx, end := observe(ctx, "StreamAllEntities")
+	defer end()
+	return d.streamEntities("SELECT "+entityColumns+" FROM observations.fl_lepidoptera", nil, each, ctx)
+}
+
+// StreamSearchEntities calls each with every entity matching the query as it is scanned, see StreamAllEntities.
+func (d *DataStore) StreamSearchEntities(query model.SearchQuery, each func(model.Entity) error, ctx context.Context) error {
+	ctx, end := observe(ctx, "StreamSearchEntities")
+	defer end()
+	q := searchBuilder(query)
+	return d.streamEntities(q.build(), q.args, each, ctx)
+}
+
+// streamEntities runs a statement selecting entityColumns and calls each with every row. An error of each stops the
+// stream and is returned as it is.
+// A stream lasts as long as the client takes to read it, so the statement runs without the statement_timeout of the
+// session, in a transaction of its own. It is bounded by ctx alone.
+func (d *DataStore) streamEntities(statement string, args []any, each func(model.Entity) error, ctx context.Context) error {
+	tx, err := d.Conn.Begin(ctx)
+	if err != nil {
+		logFailure(ctx, err, "beginning streamed query")
+		return fmt.Errorf("err execute")
+	}
+	defer func(tx pgx.Tx, ctx context.Context) {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			logFailure(ctx, err, "rolling back streamed query")
+		}
+	}(tx, ctx)
+	if _, err = tx.Exec(ctx, "SET LOCAL statement_timeout = 0"); err != nil {
+		logFailure(ctx, err, "lifting the statement timeout of a stream")
+		return fmt.Errorf("err execute")
+	}
+	rows, err := tx.Query(ctx, statement, args...)
+	if err != nil {
+		logFailure(ctx, err, "executing streamed query")
+		return fmt.Errorf("err execute")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		entity, err := scanEntity(rows)
+		if err != nil {
+			logFailure(ctx, err, "scanning through streamed entities")
+			return fmt.Errorf("error scanning entities")
+		}
+		if err = each(entity); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		logFailure(ctx, err, "streaming entities")
+		return fmt.Errorf("err execute")
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+	"log/slog"
+)
+
+const (
+	queryCanceled     = "57014" // sqlstate of a statement cancelled by the database, i.e. past statement_timeout
+	duplicateDatabase = "42P04" // sqlstate of CREATE DATABASE when it exists
+)
+
+// TimedOut reports whether err is a statement running out of time, past either the deadline of its context or the
+// statement_timeout of the session.
+func TimedOut(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &pgErr) && pgErr.Code == queryCanceled)
+}
+
+// isDuplicateDatabase reports whether err is the database being created a second time.
+func isDuplicateDatabase(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == duplicateDatabase
+}
+
+// logFailure logs a statement that failed, format describing what it was doing, i.e. "finding %d". The error stays in
+// the log, callers return a vague one of their own so that nothing of the sql reaches clients.
+// A statement cancelled because the request went away, or that ran out of time, is logged as such and not as an error.
+func logFailure(ctx context.Context, err error, format string, args ...any) {
+	doing := fmt.Sprintf(format, args...)
+	switch {
+	case errors.Is(err, context.Canceled):
+		statementFailures.WithLabelValues("cancelled").Inc()
+		slog.InfoContext(ctx, "Statement cancelled, the request went away", "doing", doing)
+	case TimedOut(err):
+		statementFailures.WithLabelValues("timeout").Inc()
+		slog.WarnContext(ctx, "Statement timed out", "doing", doing, "error", err)
+	default:
+		statementFailures.WithLabelValues("error").Inc()
+		slog.ErrorContext(ctx, "Statement failed", "doing", doing, "error", err)
+	}
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"mbcarruthers/tracing"
+	"strings"
+)
+
+var tracer = tracing.Tracer("mbcarruthers/helio/dataservice/db")
+
+// methodKey holds the DataStore method a statement runs for.
+type methodKey struct{}
+
+// startMethod starts the span of the DataStore method, the statements it runs being its children.
+func startMethod(ctx context.Context, method string) (context.Context, trace.Span) {
+	ctx = context.WithValue(ctx, methodKey{}, method)
+	return tracer.Start(ctx, "db."+method, trace.WithAttributes(semconv.DBSystemCockroachdb))
+}
+
+// statementTracer traces every statement sent to the database, see pgx.QueryTracer and pgx.BatchTracer. A statement
+// is named after its operation and carries its sql, never its arguments, and the rows it returned or changed.
+type statementTracer struct{}
+
+func (statementTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = startStatement(ctx, data.SQL)
+	return ctx
+}
+
+func (statementTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	endStatement(trace.SpanFromContext(ctx), data.CommandTag.RowsAffected(), data.Err)
+}
+
+func (statementTracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	ctx, span := tracer.Start(ctx, "BATCH", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.DBSystemCockroachdb,
+		semconv.DBOperation("BATCH"),
+	))
+	if data.Batch != nil {
+		span.SetAttributes(attribute.Int("db.batch.size", data.Batch.Len()))
+	}
+	if method, ok := ctx.Value(methodKey{}).(string); ok {
+		span.SetAttributes(attribute.String("helio.db.method", method))
+	}
+	return ctx
+}
+
+// TraceBatchQuery is only called once the statement has run, its span covers the wait for its result.
+func (statementTracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	_, span := startStatement(ctx, data.SQL)
+	endStatement(span, data.CommandTag.RowsAffected(), data.Err)
+}
+
+func (statementTracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, "err batch")
+	}
+	span.End()
+}
+
+// startStatement starts the span of the statement sql.
+func startStatement(ctx context.Context, sql string) (context.Context, trace.Span) {
+	operation := operationOf(sql)
+	attributes := []attribute.KeyValue{
+		semconv.DBSystemCockroachdb,
+		semconv.DBStatement(sql),
+		semconv.DBOperation(operation),
+	}
+	if method, ok := ctx.Value(methodKey{}).(string); ok {
+		attributes = append(attributes, attribute.String("helio.db.method", method))
+	}
+	return tracer.Start(ctx, operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+}
+
+// endStatement ends the span of a statement having returned or changed rows. A statement timing out or cancelled
+// is recorded as such, along with the error.
+func endStatement(span trace.Span, rows int64, err error) {
+	span.SetAttributes(attribute.Int64("db.rows", rows))
+	if err != nil {
+		span.RecordError(err)
+		if TimedOut(err) {
+			span.SetStatus(codes.Error, "err timeout")
+		} else {
+			span.SetStatus(codes.Error, "err statement")
+		}
+	}
+	span.End()
+}
+
+// operationOf returns the first keyword of sql, i.e. SELECT.
+func operationOf(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "STATEMENT"
+	}
+	return strings.ToUpper(fields[0])
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"mbcarruthers/helio/model"
+	"time"
+)
+
+// DatasetVersion returns the version of the observations, kept by observations.dataset_version. It changes with
+// every mutation made through a DataStore, helioctl included.
+// Note: It is read from the database every time rather than through the cache, whose memory backend is kept by each
+// replica: a replica not seeing the mutations made through another would answer 304 to a stale ETag.
+func (d *DataStore) DatasetVersion(ctx context.Context) (model.DatasetVersion, error) {
+	ctx, end := observe(ctx, "DatasetVersion")
+	defer end()
+	var version model.DatasetVersion
+	if err := d.Conn.QueryRow(ctx, "SELECT version, modified_at FROM observations.dataset_version WHERE id = 1").Scan(&version.Version, &version.ModifiedAt); err != nil {
+		logFailure(ctx, err, "reading the dataset version")
+		return model.DatasetVersion{}, fmt.Errorf("err execute")
+	}
+	return version, nil
+}
+
+// bumpVersion moves the dataset version on within the transaction of a mutation, so the version moves on if and only
+// if the mutation is committed. An error fails the mutation, which is rolled back.
+func bumpVersion(tx pgx.Tx, ctx context.Context) error {
+	if _, err := tx.Exec(ctx, "UPDATE observations.dataset_version SET version = version + 1, modified_at = now() WHERE id = 1"); err != nil {
+		logFailure(ctx, err, "moving the dataset version on")
+		return fmt.Errorf("err execute")
+	}
+	return nil
+}
+
+// changed drops the results kept by the cache. Every mutation calls it once its changes, the version moved on by
+// bumpVersion included, are committed, and never when it failed or rolled back, which changed nothing.
+func (d *DataStore) changed(ctx context.Context) {
+	// Note: the mutation is done by now, the results must be dropped even though the request went away.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	d.cache.Invalidate(ctx)
+}
@@ -0,0 +1,293 @@
+// Package memory keeps entities and the audit log in memory, standing in for the database(db.DataStore) in tests
+// and local development. Searches filter and order the same as the database's.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"mbcarruthers/helio/dataservice/db"
+	"mbcarruthers/helio/model"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store holds entities by id along with the audit log.
+type Store struct {
+	mu       sync.RWMutex
+	created  bool
+	entities map[int]model.Entity
+	audit    []model.AuditEntry
+}
+
+// NewStore returns an empty Store, it is created by the first CreateAndInsert like the database.
+func NewStore() *Store {
+	return &Store{
+		entities: map[int]model.Entity{},
+	}
+}
+
+// Put stores the entities as they are, replacing any with the same id.
+func (s *Store) Put(entities ...model.Entity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.created = true
+	for _, entity := range entities {
+		s.entities[entity.Id] = stored(entity)
+	}
+}
+
+// stored returns the entity the way the database stores it.
+func stored(entity model.Entity) model.Entity {
+	if entity.QualityFlags == nil {
+		entity.QualityFlags = []string{}
+	}
+	if entity.QualityGrade == "" {
+		entity.QualityGrade = model.GradeForFlags(entity.QualityFlags)
+	}
+	entity.CoordinatesObscured = false
+	return entity
+}
+
+// CreateAndInsert stores the observations along with their audit entries unless the store was created before.
+func (s *Store) CreateAndInsert(observations []model.Entity, entries []model.AuditEntry, ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.created {
+		return db.ErrExists
+	}
+	s.created = true
+	for _, entity := range observations {
+		s.entities[entity.Id] = stored(entity)
+	}
+	s.record(entries)
+	return nil
+}
+
+// Migrate does nothing, the store is always up to date.
+func (s *Store) Migrate(ctx context.Context) error {
+	return nil
+}
+
+// GetEntityById returns the entity by id, and db.ErrNotFound if there is none.
+func (s *Store) GetEntityById(id int, ctx context.Context) (model.Entity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entity, ok := s.entities[id]
+	if !ok {
+		return model.Entity{}, db.ErrNotFound
+	}
+	return entity, nil
+}
+
+// ListAllEntities returns every entity ordered by id.
+func (s *Store) ListAllEntities(ctx context.Context) ([]model.Entity, error) {
+	return s.filter(func(model.Entity) bool { return true }, func(a, b model.Entity) bool { return a.Id < b.Id }), nil
+}
+
+// SearchEntities returns every entity matching the query.
+func (s *Store) SearchEntities(query model.SearchQuery, ctx context.Context) ([]model.Entity, error) {
+	return s.filter(matcher(query), before), nil
+}
+
+// StreamAllEntities calls each with every entity ordered by id, stopping at the first error it returns.
+func (s *Store) StreamAllEntities(each func(model.Entity) error, ctx context.Context) error {
+	entities, _ := s.ListAllEntities(ctx)
+	return stream(entities, each)
+}
+
+// StreamSearchEntities calls each with every entity matching the query, stopping at the first error it returns.
+func (s *Store) StreamSearchEntities(query model.SearchQuery, each func(model.Entity) error, ctx context.Context) error {
+	entities, _ := s.SearchEntities(query, ctx)
+	return stream(entities, each)
+}
+
+// stream calls each with every entity until it returns an error.
+func stream(entities []model.Entity, each func(model.Entity) error) error {
+	for _, entity := range entities {
+		if err := each(entity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SearchEntitiesPage returns at most limit entities matching the query past the cursor.
+func (s *Store) SearchEntitiesPage(query model.SearchQuery, cursor *model.Cursor, limit int, ctx context.Context) ([]model.Entity, error) {
+	matches := matcher(query)
+	entities := s.filter(func(entity model.Entity) bool {
+		return matches(entity) && (cursor == nil || before(model.Entity{ObservedOn: cursor.ObservedOn, Id: cursor.Id}, entity))
+	}, before)
+	if len(entities) > limit {
+		entities = entities[:limit]
+	}
+	return entities, nil
+}
+
+// InsertNewEntity stores a new entity along with its audit entry, refusing one whose id exists.
+func (s *Store) InsertNewEntity(entity model.Entity, entry model.AuditEntry, ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entities[entity.Id]; ok {
+		return fmt.Errorf("duplicate id %d", entity.Id)
+	}
+	s.entities[entity.Id] = stored(entity)
+	s.record([]model.AuditEntry{entry})
+	return nil
+}
+
+// UpdateEntityById stores the new values of an existing entity along with its audit entry, its taxon_id, uuid and
+// duplicate_of are kept.
+func (s *Store) UpdateEntityById(id int, entity model.Entity, entry model.AuditEntry, ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.entities[id]
+	if !ok {
+		return db.ErrNotFound
+	}
+	entity.Id, entity.TaxonId, entity.Uuid, entity.DuplicateOf = id, existing.TaxonId, existing.Uuid, existing.DuplicateOf
+	s.entities[id] = stored(entity)
+	s.record([]model.AuditEntry{entry})
+	return nil
+}
+
+// DeleteEntityById deletes an entity along with its audit entry, and returns db.ErrNotFound if there is none.
+func (s *Store) DeleteEntityById(id int, entry model.AuditEntry, ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entities[id]; !ok {
+		return db.ErrNotFound
+	}
+	delete(s.entities, id)
+	s.record([]model.AuditEntry{entry})
+	return nil
+}
+
+// MergeEntities links every duplicate to the entity being kept, along with the entities merged into one of the
+// duplicates before, and returns every relinked entity as it was before the merge. The audit entries entries returns
+// for them are stored along with the merge.
+func (s *Store) MergeEntities(keep int, duplicates []int, entries func(relinked []model.Entity) []model.AuditEntry, ctx context.Context) ([]model.Entity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if kept, ok := s.entities[keep]; !ok || kept.DuplicateOf != nil {
+		return nil, db.ErrNotFound
+	}
+	merging := map[int]bool{}
+	for _, id := range duplicates {
+		if _, ok := s.entities[id]; !ok || id == keep {
+			return nil, db.ErrNotFound
+		}
+		merging[id] = true
+	}
+	relinked := []model.Entity{}
+	for _, entity := range s.entities {
+		if merging[entity.Id] || (entity.DuplicateOf != nil && merging[*entity.DuplicateOf]) {
+			relinked = append(relinked, entity)
+		}
+	}
+	sort.Slice(relinked, func(i, j int) bool { return relinked[i].Id < relinked[j].Id })
+	for _, entity := range relinked {
+		merged := keep
+		entity.DuplicateOf = &merged
+		s.entities[entity.Id] = entity
+	}
+	s.record(entries(relinked))
+	return relinked, nil
+}
+
+// record appends entries to the audit log, s.mu is held by the mutation they belong to.
+func (s *Store) record(entries []model.AuditEntry) {
+	for _, entry := range entries {
+		entry.Id = int64(len(s.audit) + 1)
+		if entry.At.IsZero() {
+			entry.At = time.Now().UTC()
+		}
+		s.audit = append(s.audit, entry)
+	}
+}
+
+// AuditEntries returns the audit log, oldest first.
+func (s *Store) AuditEntries() []model.AuditEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]model.AuditEntry(nil), s.audit...)
+}
+
+// LastDeleted returns the entity as it was when it was last deleted, and db.ErrNotFound if it never was.
+func (s *Store) LastDeleted(id int, ctx context.Context) (model.Entity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.audit) - 1; i >= 0; i-- {
+		if entry := s.audit[i]; entry.EntityId == id && entry.Action == model.AuditDelete && entry.Before != nil {
+			return *entry.Before, nil
+		}
+	}
+	return model.Entity{}, db.ErrNotFound
+}
+
+// filter returns the entities matching, sorted by less.
+func (s *Store) filter(matches func(model.Entity) bool, less func(a, b model.Entity) bool) []model.Entity {
+	s.mu.RLock()
+	entities := []model.Entity{}
+	for _, entity := range s.entities {
+		if matches(entity) {
+			entities = append(entities, entity)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(entities, func(i, j int) bool { return less(entities[i], entities[j]) })
+	return entities
+}
+
+// before orders entities by observed_on then id, entities without an observed_on first, as the database does.
+func before(a, b model.Entity) bool {
+	if a.ObservedOn.Valid != b.ObservedOn.Valid {
+		return !a.ObservedOn.Valid
+	}
+	if !a.ObservedOn.Time.Equal(b.ObservedOn.Time) {
+		return a.ObservedOn.Time.Before(b.ObservedOn.Time)
+	}
+	return a.Id < b.Id
+}
+
+// matcher returns whether an entity matches the query, the same way the database's search does.
+func matcher(query model.SearchQuery) func(model.Entity) bool {
+	date1, date2 := query.Date1, query.Date2
+	if date1.Valid && date2.Valid && date1.Time.After(date2.Time) {
+		date1, date2 = date2, date1
+	}
+	var grades []string
+	for _, value := range query.QualityGrade {
+		for _, grade := range strings.Split(value, ",") {
+			if grade = strings.TrimSpace(grade); grade != "" {
+				grades = append(grades, grade)
+			}
+		}
+	}
+	return func(entity model.Entity) bool {
+		switch {
+		case query.TaxonId != 0 && entity.TaxonId != query.TaxonId:
+		case date1.Valid && (!entity.ObservedOn.Valid || entity.ObservedOn.Time.Before(date1.Time)):
+		case date2.Valid && (!entity.ObservedOn.Valid || entity.ObservedOn.Time.After(date2.Time)):
+		case len(grades) != 0 && !contains(grades, entity.QualityGrade):
+		case len(grades) == 0 && entity.QualityGrade == model.GradeRejected:
+		case query.QualityFlag != "" && !contains(entity.QualityFlags, query.QualityFlag):
+		case query.Geoprivacy != "" && entity.Geoprivacy != query.Geoprivacy:
+		case !query.Merged && entity.DuplicateOf != nil:
+		default:
+			return true
+		}
+		return false
+	}
+}
+
+// contains reports whether value is within values.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+// Package geoprivacy hides the true coordinates of sensitive observations(Entities) from the public.
+// An Entity is either open, obscured(coordinates generalized to a grid cell) or private(no coordinates),
+// set on the Entity itself or inherited from its taxon.
+package geoprivacy
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mbcarruthers/helio/model"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// TrustedKey is the gin.Context key set to true once a caller is allowed to see true coordinates.
+	TrustedKey = "geoprivacy.trusted"
+)
+
+// Policy decides the geoprivacy of entities that do not set one themselves.
+type Policy struct {
+	Default  string         `json:"default"`   // geoprivacy of any taxon not found within Taxa
+	Taxa     map[int]string `json:"taxa"`      // geoprivacy by taxon_id
+	CellSize float64        `json:"cell_size"` // degrees, obscured coordinates are moved to the center of their cell
+}
+
+// DefaultPolicy leaves every taxon open and obscures to 0.2 degree cells.
+func DefaultPolicy() Policy {
+	return Policy{
+		Default:  model.GeoprivacyOpen,
+		Taxa:     map[int]string{},
+		CellSize: 0.2,
+	}
+}
+
+// LoadPolicy reads a Policy from a json file, i.e. {"taxa": {"48662": "obscured"}}. Fields left out of the file keep
+// their DefaultPolicy value. An empty path returns the DefaultPolicy.
+func LoadPolicy(path string) (Policy, error) {
+	policy := DefaultPolicy()
+	if path != "" {
+		file, err := os.ReadFile(path)
+		if err != nil {
+			return policy, fmt.Errorf("reading geoprivacy policy: %w", err)
+		}
+		if err = json.Unmarshal(file, &policy); err != nil {
+			return policy, fmt.Errorf("parsing geoprivacy policy: %w", err)
+		}
+	}
+	if !model.ValidGeoprivacy(policy.Default) || policy.Default == "" {
+		return policy, fmt.Errorf("invalid default geoprivacy %q", policy.Default)
+	}
+	for taxon, level := range policy.Taxa {
+		if !model.ValidGeoprivacy(level) || level == "" {
+			return policy, fmt.Errorf("invalid geoprivacy %q for taxon %d", level, taxon)
+		}
+	}
+	if policy.CellSize <= 0 {
+		return policy, fmt.Errorf("cell_size must be greater than 0")
+	}
+	return policy, nil
+}
+
+// Level returns the geoprivacy an entity is published with.
+func (p Policy) Level(entity model.Entity) string {
+	if entity.Geoprivacy != "" {
+		return entity.Geoprivacy
+	}
+	if level, ok := p.Taxa[entity.TaxonId]; ok {
+		return level
+	}
+	return p.Default
+}
+
+// Public returns the entity as it may be shown to the public. Obscured entities have their coordinates moved to the
+// center of their grid cell, private entities have none. Both have their place_guess generalized.
+func (p Policy) Public(entity model.Entity) model.Entity {
+	switch p.Level(entity) {
+	case model.GeoprivacyObscured:
+		entity.Latitude = p.generalize(entity.Latitude)
+		entity.Longitude = p.generalize(entity.Longitude)
+		entity.PlaceGuess = generalizePlace(entity.PlaceGuess, 3)
+		entity.CoordinatesObscured = true
+	case model.GeoprivacyPrivate:
+		entity.Latitude, entity.Longitude = "", ""
+		entity.PlaceGuess = generalizePlace(entity.PlaceGuess, 2)
+		entity.CoordinatesObscured = true
+	}
+	return entity
+}
+
+// PublicAll applies Public to every entity.
+func (p Policy) PublicAll(entities []model.Entity) []model.Entity {
+	public := make([]model.Entity, 0, len(entities))
+	for _, entity := range entities {
+		public = append(public, p.Public(entity))
+	}
+	return public
+}
+
+// generalize moves a coordinate to the center of its cell. Coordinates that cannot be parsed are dropped.
+func (p Policy) generalize(coordinate string) string {
+	value, err := strconv.ParseFloat(strings.TrimSpace(coordinate), 64)
+	if err != nil {
+		return ""
+	}
+	center := math.Floor(value/p.CellSize)*p.CellSize + p.CellSize/2
+	return strconv.FormatFloat(center, 'f', 4, 64)
+}
+
+// generalizePlace keeps the last parts of a place_guess, i.e. "Wakulla County, FL, USA"
+func generalizePlace(place string, parts int) string {
+	split := strings.Split(place, ",")
+	if len(split) <= parts {
+		return place
+	}
+	return strings.TrimSpace(strings.Join(split[len(split)-parts:], ","))
+}
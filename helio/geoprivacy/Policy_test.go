@@ -0,0 +1,163 @@
+package geoprivacy
+
+import (
+	"mbcarruthers/helio/model"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// policy obscures monarchs(48662) and hides queens(51114), leaving every other taxon open.
+var policy = Policy{
+	Default:  model.GeoprivacyOpen,
+	Taxa:     map[int]string{48662: model.GeoprivacyObscured, 51114: model.GeoprivacyPrivate},
+	CellSize: 0.2,
+}
+
+// roost returns an observation of taxon at a roost, with geoprivacy set on the record itself.
+func roost(taxon int, geoprivacy string) model.Entity {
+	return model.Entity{
+		Id:         1,
+		TaxonId:    taxon,
+		PlaceGuess: "Bald Point State Park, Wakulla County, FL, USA",
+		Latitude:   "29.9318",
+		Longitude:  "-84.3397",
+		Geoprivacy: geoprivacy,
+	}
+}
+
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		name   string
+		entity model.Entity
+		want   string
+	}{
+		{"taxon open by default", roost(82792, ""), model.GeoprivacyOpen},
+		{"taxon obscured", roost(48662, ""), model.GeoprivacyObscured},
+		{"taxon private", roost(51114, ""), model.GeoprivacyPrivate},
+		{"record open within an obscured taxon", roost(48662, model.GeoprivacyOpen), model.GeoprivacyOpen},
+		{"record obscured within a private taxon", roost(51114, model.GeoprivacyObscured), model.GeoprivacyObscured},
+		{"record private within an open taxon", roost(82792, model.GeoprivacyPrivate), model.GeoprivacyPrivate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Level(tt.entity); got != tt.want {
+				t.Errorf("Level() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublic(t *testing.T) {
+	unparsable := roost(48662, "")
+	unparsable.Latitude = "north"
+	short := roost(51114, "")
+	short.PlaceGuess = "FL, USA"
+
+	tests := []struct {
+		name         string
+		policy       Policy
+		entity       model.Entity
+		wantLat      string
+		wantLon      string
+		wantPlace    string
+		wantObscured bool
+	}{
+		{"open", policy, roost(82792, ""), "29.9318", "-84.3397", "Bald Point State Park, Wakulla County, FL, USA", false},
+		{"obscured to the center of its cell", policy, roost(48662, ""), "29.9000", "-84.3000", "Wakulla County, FL, USA", true},
+		{"obscured to a larger cell", Policy{Default: model.GeoprivacyObscured, CellSize: 1}, roost(82792, ""),
+			"29.5000", "-84.5000", "Wakulla County, FL, USA", true},
+		{"obscured unparsable coordinate dropped", policy, unparsable, "", "-84.3000", "Wakulla County, FL, USA", true},
+		{"private coordinates removed", policy, roost(51114, ""), "", "", "FL, USA", true},
+		{"private short place kept", policy, short, "", "", "FL, USA", true},
+		{"record open overriding its taxon", policy, roost(48662, model.GeoprivacyOpen), "29.9318", "-84.3397",
+			"Bald Point State Park, Wakulla County, FL, USA", false},
+		{"record private overriding its taxon", policy, roost(82792, model.GeoprivacyPrivate), "", "", "FL, USA", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.Public(tt.entity)
+			if got.Latitude != tt.wantLat || got.Longitude != tt.wantLon {
+				t.Errorf("Public() coordinates = %q, %q, want %q, %q", got.Latitude, got.Longitude, tt.wantLat, tt.wantLon)
+			}
+			if got.PlaceGuess != tt.wantPlace {
+				t.Errorf("Public() place_guess = %q, want %q", got.PlaceGuess, tt.wantPlace)
+			}
+			if got.CoordinatesObscured != tt.wantObscured {
+				t.Errorf("Public() coordinates_obscured = %v, want %v", got.CoordinatesObscured, tt.wantObscured)
+			}
+			if got.Geoprivacy != tt.entity.Geoprivacy || got.Id != tt.entity.Id {
+				t.Errorf("Public() = %+v, want the rest of the entity untouched", got)
+			}
+		})
+	}
+}
+
+func TestPublicSameCell(t *testing.T) {
+	a, b := roost(48662, ""), roost(48662, "")
+	b.Latitude, b.Longitude = "29.8012", "-84.2011" // another roost within the same 0.2 degree cell
+	publicA, publicB := policy.Public(a), policy.Public(b)
+	if publicA.Latitude != publicB.Latitude || publicA.Longitude != publicB.Longitude {
+		t.Errorf("Public() = %s,%s and %s,%s, want the same cell", publicA.Latitude, publicA.Longitude, publicB.Latitude, publicB.Longitude)
+	}
+	entities := []model.Entity{a, roost(82792, "")}
+	public := policy.PublicAll(entities)
+	if len(public) != 2 || public[0].Latitude != publicA.Latitude || public[1].Latitude != "29.9318" {
+		t.Errorf("PublicAll() = %+v, want Public of every entity", public)
+	}
+	if entities[0].Latitude != "29.9318" {
+		t.Error("PublicAll() changed the entities it was given")
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string // "" loads no file
+		want    Policy
+		wantErr bool
+	}{
+		{"no file", "", DefaultPolicy(), false},
+		{"taxa", `{"taxa": {"48662": "obscured"}}`,
+			Policy{Default: model.GeoprivacyOpen, Taxa: map[int]string{48662: model.GeoprivacyObscured}, CellSize: 0.2}, false},
+		{"every field", `{"default": "private", "taxa": {"48662": "open"}, "cell_size": 0.5}`,
+			Policy{Default: model.GeoprivacyPrivate, Taxa: map[int]string{48662: model.GeoprivacyOpen}, CellSize: 0.5}, false},
+		{"invalid default", `{"default": "hidden"}`, Policy{}, true},
+		{"empty default", `{"default": ""}`, Policy{}, true},
+		{"invalid taxon level", `{"taxa": {"48662": "secret"}}`, Policy{}, true},
+		{"cell size zero", `{"cell_size": 0}`, Policy{}, true},
+		{"malformed", `{"taxa": [`, Policy{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := ""
+			if tt.file != "" {
+				path = filepath.Join(t.TempDir(), "geoprivacy.json")
+				if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := LoadPolicy(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("LoadPolicy() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPolicy() error = %v", err)
+			}
+			if got.Default != tt.want.Default || got.CellSize != tt.want.CellSize || len(got.Taxa) != len(tt.want.Taxa) {
+				t.Fatalf("LoadPolicy() = %+v, want %+v", got, tt.want)
+			}
+			for taxon, level := range tt.want.Taxa {
+				if got.Taxa[taxon] != level {
+					t.Errorf("LoadPolicy() = %+v, want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+	if _, err := LoadPolicy(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadPolicy() of a missing file = nil, want an error")
+	}
+}
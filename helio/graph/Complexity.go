@@ -0,0 +1,166 @@
+package graph
+
+import (
+	"fmt"
+	"github.com/graphql-go/graphql/language/ast"
+	"strconv"
+)
+
+const (
+	DefaultMaxComplexity = 10000 // GRAPHQL_MAX_COMPLEXITY overrides it
+	DefaultMaxDepth      = 10    // GRAPHQL_MAX_DEPTH overrides it
+)
+
+// Limits bound the queries a Handler runs.
+type Limits struct {
+	MaxComplexity int // estimated number of values a query resolves, see complexity
+	MaxDepth      int // deepest nesting of fields
+}
+
+// DefaultLimits returns the default limits.
+func DefaultLimits() Limits {
+	return Limits{MaxComplexity: DefaultMaxComplexity, MaxDepth: DefaultMaxDepth}
+}
+
+// pagedFields are the list fields sized by their first argument. The connections' edges and nodes are sized by the
+// connection itself.
+var pagedFields = map[string]bool{
+	"observations": true,
+	"taxa":         true,
+	"places":       true,
+	"byPlace":      true,
+}
+
+// countFields are the list fields of Aggregates that are not paged, their size is estimated.
+var countFields = map[string]int{
+	"byTaxon":        100,
+	"byYear":         20,
+	"byMonth":        240,
+	"byQualityGrade": 4,
+	"byGeoprivacy":   4,
+}
+
+// estimate walks the selections of an operation, costing every field 1 plus the cost of its own selections multiplied by
+// the number of items the field may return.
+type estimate struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	visiting  map[string]bool // fragments being walked, validation refuses cycles but the walk should not rely on it
+}
+
+// check returns an error when the operation of the document exceeds the limits. The operation is named by operationName
+// unless the document holds a single one.
+func (l Limits) check(document *ast.Document, operationName string, variables map[string]interface{}) error {
+	e := estimate{fragments: map[string]*ast.FragmentDefinition{}, variables: variables, visiting: map[string]bool{}}
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			e.fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		}
+	}
+	if operation == nil {
+		return nil // left for the executor to report
+	}
+	cost, depth := e.selections(operation.SelectionSet)
+	if depth > l.MaxDepth {
+		return fmt.Errorf("query depth %d exceeds the limit of %d", depth, l.MaxDepth)
+	}
+	if cost > l.MaxComplexity {
+		return fmt.Errorf("query complexity %d exceeds the limit of %d", cost, l.MaxComplexity)
+	}
+	return nil
+}
+
+// selections returns the cost and depth of a selection set.
+func (e estimate) selections(set *ast.SelectionSet) (cost int, depth int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var c, d int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			c, d = e.field(selection)
+		case *ast.InlineFragment:
+			c, d = e.selections(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			if fragment, ok := e.fragments[name]; ok && !e.visiting[name] {
+				e.visiting[name] = true
+				c, d = e.selections(fragment.SelectionSet)
+				delete(e.visiting, name)
+			}
+		}
+		cost = saturate(cost + c)
+		if d > depth {
+			depth = d
+		}
+	}
+	return cost, depth
+}
+
+// field returns the cost and depth of a field. Introspection fields cost 1 and are not walked, the schema is small.
+func (e estimate) field(field *ast.Field) (int, int) {
+	name := field.Name.Value
+	if len(name) > 1 && name[:2] == "__" {
+		return 1, 1
+	}
+	cost, depth := e.selections(field.SelectionSet)
+	return saturate(1 + saturate(e.size(field)*cost)), depth + 1
+}
+
+// size returns the number of items a field may return, 1 for fields that are not lists.
+func (e estimate) size(field *ast.Field) int {
+	size := e.first(field)
+	if size > MaxPageSize {
+		return MaxPageSize // refused by the resolver anyway
+	}
+	return size
+}
+
+// first returns the first argument of a list field, literal or variable.
+func (e estimate) first(field *ast.Field) int {
+	if size, ok := countFields[field.Name.Value]; ok {
+		return size
+	}
+	if !pagedFields[field.Name.Value] {
+		return 1
+	}
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			if first, err := strconv.Atoi(value.Value); err == nil && first > 0 {
+				return first
+			}
+		case *ast.Variable:
+			switch first := e.variables[value.Name.Value].(type) {
+			case float64:
+				if first > 0 {
+					return int(first)
+				}
+			case int:
+				if first > 0 {
+					return first
+				}
+			}
+		}
+	}
+	return DefaultPageSize
+}
+
+// saturate caps a cost so that multiplying costs cannot overflow.
+func saturate(cost int) int {
+	const max = 1 << 31
+	if cost > max || cost < 0 {
+		return max
+	}
+	return cost
+}
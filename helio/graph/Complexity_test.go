@@ -0,0 +1,114 @@
+package graph
+
+import (
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"strings"
+	"testing"
+)
+
+// parse returns the document of query.
+func parse(t *testing.T, query string) *ast.Document {
+	document, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(query)})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return document
+}
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		wantCost  int
+		wantDepth int
+	}{
+		// id 1, node 1+1, edges 1+2, observations 1+10*3
+		{"literal first", `{ observations(first: 10) { edges { node { id } } } }`, nil, 31, 4},
+		{"variable first", `query($n: Int) { observations(first: $n) { edges { node { id } } } }`,
+			map[string]interface{}{"n": float64(10)}, 31, 4},
+		{"variable first left out", `query($n: Int) { observations(first: $n) { edges { node { id } } } }`, nil, 151, 4},
+		{"first left out", `{ observations { edges { node { id } } } }`, nil, 151, 4},
+		{"first over the max", `{ observations(first: 100000) { edges { node { id } } } }`, nil, 1501, 4},
+		{"fragment spread", `{ observations(first: 10) { ...page } }
+			fragment page on ObservationConnection { edges { node { ...fields } } }
+			fragment fields on Observation { id }`, nil, 31, 4},
+		{"inline fragment", `{ observations(first: 10) { ... on ObservationConnection { edges { node { id } } } } }`, nil, 31, 4},
+		// nodes 1+1, observations 1+20*2, taxa 1+10*41
+		{"nested connections", `{ taxa(first: 10) { observations(first: 20) { nodes { id } } } }`, nil, 411, 4},
+		{"counted fields", `{ aggregates { byYear { count } } }`, nil, 22, 3},
+		{"introspection not walked", `{ __schema { types { name fields { name } } } }`, nil, 1, 1},
+		{"fragment cycle walked once", `{ observation(id: 1) { ...a } }
+			fragment a on Observation { duplicateOf { ...a } }`, nil, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document := parse(t, tt.query)
+			e := estimate{fragments: map[string]*ast.FragmentDefinition{}, variables: tt.variables, visiting: map[string]bool{}}
+			var operation *ast.OperationDefinition
+			for _, definition := range document.Definitions {
+				switch definition := definition.(type) {
+				case *ast.FragmentDefinition:
+					e.fragments[definition.Name.Value] = definition
+				case *ast.OperationDefinition:
+					operation = definition
+				}
+			}
+			cost, depth := e.selections(operation.SelectionSet)
+			if cost != tt.wantCost || depth != tt.wantDepth {
+				t.Errorf("selections() = %d, %d, want %d, %d", cost, depth, tt.wantCost, tt.wantDepth)
+			}
+		})
+	}
+}
+
+func TestLimitsCheck(t *testing.T) {
+	const nested = `query($n: Int) { taxa(first: 10) { observations(first: $n) { nodes { ...fields } } } }
+		fragment fields on Observation { id taxon { name } }`
+	tests := []struct {
+		name          string
+		limits        Limits
+		query         string
+		operationName string
+		variables     map[string]interface{}
+		wantErr       string // empty when the query is let through
+	}{
+		// fields 1+2, nodes 1+3, observations 1+20*4, taxa 1+10*81
+		{"at the complexity limit", Limits{MaxComplexity: 811, MaxDepth: 10}, nested, "",
+			map[string]interface{}{"n": float64(20)}, ""},
+		{"over the complexity limit", Limits{MaxComplexity: 810, MaxDepth: 10}, nested, "",
+			map[string]interface{}{"n": float64(20)}, "query complexity 811 exceeds the limit of 810"},
+		{"variable first over the limit", DefaultLimits(), nested, "",
+			map[string]interface{}{"n": float64(500)}, "query complexity 20011 exceeds the limit of 10000"},
+		{"at the depth limit", Limits{MaxComplexity: 10000, MaxDepth: 5}, nested, "",
+			map[string]interface{}{"n": float64(20)}, ""},
+		{"over the depth limit", Limits{MaxComplexity: 10000, MaxDepth: 4}, nested, "",
+			map[string]interface{}{"n": float64(20)}, "query depth 5 exceeds the limit of 4"},
+		{"depth of a fragment spread", Limits{MaxComplexity: 10000, MaxDepth: 3},
+			`{ observation(id: 1) { ...a } } fragment a on Observation { duplicateOf { taxon { name } } }`, "", nil,
+			"query depth 4 exceeds the limit of 3"},
+		{"named operation", Limits{MaxComplexity: 100, MaxDepth: 10},
+			`query Small { observation(id: 1) { id } } query Large { observations(first: 500) { nodes { id } } }`, "Small", nil, ""},
+		{"other named operation", Limits{MaxComplexity: 100, MaxDepth: 10},
+			`query Small { observation(id: 1) { id } } query Large { observations(first: 500) { nodes { id } } }`, "Large", nil,
+			"query complexity 1001 exceeds the limit of 100"},
+		{"unknown operation left to the executor", Limits{MaxComplexity: 1, MaxDepth: 1},
+			`query Large { observations(first: 500) { nodes { id } } }`, "Other", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.check(parse(t, tt.query), tt.operationName, tt.variables)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("check() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("check() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
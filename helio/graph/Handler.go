@@ -0,0 +1,90 @@
+package graph
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"mbcarruthers/helio/dataservice/db"
+	"mbcarruthers/helio/geoprivacy"
+	"net/http"
+)
+
+// Request is a GraphQL request, sent as a json body or as query parameters with variables encoded as json.
+type Request struct {
+	Query         string                 `json:"query" form:"query"`
+	OperationName string                 `json:"operationName" form:"operationName"`
+	Variables     map[string]interface{} `json:"variables" form:"-"`
+}
+
+// Handler runs GraphQL requests against the schema.
+type Handler struct {
+	schema   graphql.Schema
+	resolver *resolver
+	limits   Limits
+}
+
+// NewHandler builds the schema over the DataStore, sensitive coordinates are published according to the policy.
+func NewHandler(bfdb *db.DataStore, policy geoprivacy.Policy, limits Limits) (*Handler, error) {
+	r := &resolver{btrflydb: bfdb, policy: policy}
+	schema, err := newSchema(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{schema: schema, resolver: r, limits: limits}, nil
+}
+
+// failed writes errors raised before the request could run.
+func failed(c *gin.Context, errs ...gqlerrors.FormattedError) {
+	c.JSON(http.StatusBadRequest, &graphql.Result{Errors: errs})
+}
+
+// GraphQLHandler GET|POST /graphql
+// Runs a query, the json body or the query parameters hold the query, operationName and variables.
+// Responses:
+// 200 - {"data": ..., "errors": [...]} errors raised while resolving are listed along with the data resolved
+// 400 - {"errors": [...]} the query could not be parsed, is invalid or exceeds the depth or complexity limits
+func (h *Handler) GraphQLHandler(c *gin.Context) {
+	var request Request
+	if c.Request.Method == http.MethodGet {
+		request.Query = c.Query("query")
+		request.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				failed(c, gqlerrors.NewFormattedError("variables must be a json object"))
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&request); err != nil {
+		failed(c, gqlerrors.NewFormattedError("the body must be a json object holding the query"))
+		return
+	}
+	if request.Query == "" {
+		failed(c, gqlerrors.NewFormattedError("query is required"))
+		return
+	}
+
+	document, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(request.Query), Name: "GraphQL request"})})
+	if err != nil {
+		failed(c, gqlerrors.FormatError(err))
+		return
+	}
+	if validation := graphql.ValidateDocument(&h.schema, document, nil); !validation.IsValid {
+		failed(c, validation.Errors...)
+		return
+	}
+	if err = h.limits.check(document, request.OperationName, request.Variables); err != nil {
+		failed(c, gqlerrors.NewFormattedError(err.Error()))
+		return
+	}
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           document,
+		OperationName: request.OperationName,
+		Args:          request.Variables,
+		Context:       h.resolver.newRequest(c.Request.Context(), c.GetBool(geoprivacy.TrustedKey)),
+	})
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,67 @@
+package graph
+
+import (
+	"context"
+	"sync"
+)
+
+// Note: Resolvers return thunks rather than values, graphql-go resolves every field of a level before calling the
+// thunks of that level. Every key loaded across a level is pending by the time the first thunk is called, which then
+// fetches all of them at once instead of one query per field.
+
+// Loader batches the keys loaded within a single request and caches what was fetched for the rest of it.
+type Loader[K comparable, V any] struct {
+	fetch   func(keys []K, ctx context.Context) (map[K]V, error)
+	ctx     context.Context
+	mu      sync.Mutex
+	pending []K
+	results map[K]*result[V]
+}
+
+// result is the outcome of fetching a single key, found is false for keys the fetch left out.
+type result[V any] struct {
+	value V
+	found bool
+	err   error
+	done  bool
+}
+
+// NewLoader returns a Loader for a single request calling fetch with every pending key at once.
+func NewLoader[K comparable, V any](fetch func(keys []K, ctx context.Context) (map[K]V, error), ctx context.Context) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:   fetch,
+		ctx:     ctx,
+		results: map[K]*result[V]{},
+	}
+}
+
+// Load queues the key and returns a thunk returning its value, fetching it along with every other pending key.
+func (l *Loader[K, V]) Load(key K) func() (V, bool, error) {
+	l.mu.Lock()
+	if _, ok := l.results[key]; !ok {
+		l.results[key] = &result[V]{}
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+	return func() (V, bool, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if !l.results[key].done {
+			l.dispatch()
+		}
+		r := l.results[key]
+		return r.value, r.found, r.err
+	}
+}
+
+// dispatch fetches every pending key, the lock is held by the caller.
+func (l *Loader[K, V]) dispatch() {
+	keys := l.pending
+	l.pending = nil
+	values, err := l.fetch(keys, l.ctx)
+	for _, key := range keys {
+		r := l.results[key]
+		r.value, r.found = values[key]
+		r.err, r.done = err, true
+	}
+}
@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"github.com/graphql-go/graphql"
+	"mbcarruthers/helio/geoprivacy"
+	"mbcarruthers/helio/model"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// fetches records the keys of every fetch of a Loader.
+type fetches struct {
+	mu    sync.Mutex
+	calls [][]int
+}
+
+func (f *fetches) entities(ids []int, ctx context.Context) (map[int]model.Entity, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, append([]int(nil), ids...))
+	byId := map[int]model.Entity{}
+	for _, id := range ids {
+		if id < 100 { // 100 and over are not found
+			byId[id] = model.Entity{Id: id, TaxonId: 48662, SpeciesGuess: "Monarch"}
+		}
+	}
+	return byId, nil
+}
+
+func TestLoader(t *testing.T) {
+	f := &fetches{}
+	l := NewLoader(f.entities, context.Background())
+	thunks := map[int]func() (model.Entity, bool, error){}
+	for _, id := range []int{1, 2, 3, 2, 100} {
+		thunks[id] = l.Load(id)
+	}
+	for id, thunk := range thunks {
+		entity, found, err := thunk()
+		if err != nil || found != (id < 100) || (found && entity.Id != id) {
+			t.Errorf("Load(%d)() = %+v, %v, %v, want it found unless 100", id, entity, found, err)
+		}
+	}
+	if len(f.calls) != 1 || len(f.calls[0]) != 4 {
+		t.Fatalf("fetches = %v, want a single one of the 4 keys", f.calls)
+	}
+
+	// loaded keys are kept, a key loaded later is fetched on its own
+	if _, found, _ := l.Load(1)(); !found || len(f.calls) != 1 {
+		t.Errorf("fetches = %v after loading 1 again, want it kept", f.calls)
+	}
+	if _, found, _ := l.Load(4)(); !found || len(f.calls) != 2 || len(f.calls[1]) != 1 || f.calls[1][0] != 4 {
+		t.Errorf("fetches = %v after loading 4, want a second of 4 alone", f.calls)
+	}
+}
+
+func TestLoaderError(t *testing.T) {
+	errFetch := errors.New("err execute")
+	calls := 0
+	l := NewLoader(func(keys []int, ctx context.Context) (map[int]model.Entity, error) {
+		calls++
+		return nil, errFetch
+	}, context.Background())
+	first, second := l.Load(1), l.Load(2)
+	for i, thunk := range []func() (model.Entity, bool, error){first, second} {
+		if _, found, err := thunk(); found || !errors.Is(err, errFetch) {
+			t.Errorf("thunk %d = %v, %v, want %v", i, found, err, errFetch)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("fetches = %d, want 1", calls)
+	}
+}
+
+func TestLoaderBatchesLevel(t *testing.T) {
+	r := &resolver{policy: geoprivacy.Policy{Default: model.GeoprivacyOpen}}
+	schema, err := newSchema(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fetches{}
+	req := &request{trusted: true}
+	ctx := context.WithValue(context.Background(), requestKey, req)
+	req.entities = NewLoader(f.entities, ctx)
+
+	result := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `{ a: observation(id: 1) { id } b: observation(id: 2) { id } c: observation(id: 3) { id } d: observation(id: 100) { id } }`,
+		Context:       ctx,
+	})
+	if result.HasErrors() {
+		t.Fatal(result.Errors)
+	}
+	data := result.Data.(map[string]interface{})
+	for alias, want := range map[string]int{"a": 1, "b": 2, "c": 3} {
+		if got, ok := data[alias].(map[string]interface{}); !ok || got["id"] != want {
+			t.Errorf("%s = %v, want observation %d", alias, data[alias], want)
+		}
+	}
+	if data["d"] != nil {
+		t.Errorf("d = %v, want null", data["d"])
+	}
+	if len(f.calls) != 1 {
+		t.Fatalf("fetches = %v, want one for the level", f.calls)
+	}
+	sort.Ints(f.calls[0])
+	if got := f.calls[0]; len(got) != 4 || got[0] != 1 || got[3] != 100 {
+		t.Errorf("keys fetched = %v, want 1, 2, 3 and 100", got)
+	}
+}
@@ -0,0 +1,518 @@
+// Package graph serves observations, taxa, places and their aggregates over GraphQL. Filters and pagination map onto
+// the DataStore's search, fields referencing other entities or taxa are batched per request and queries are refused
+// before running once they grow too deep or too large.
+package graph
+
+import (
+	"context"
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/jackc/pgx/v5/pgtype"
+	"mbcarruthers/helio/dataservice/db"
+	"mbcarruthers/helio/geoprivacy"
+	"mbcarruthers/helio/model"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultPageSize = 50  // number of items of a list field when first is left out
+	MaxPageSize     = 500 // largest first allowed
+
+	dateLayout = "2006-01-02"
+)
+
+type contextKey int
+
+const requestKey contextKey = iota
+
+// request is the state of a single GraphQL request, carried by the context of every resolver.
+type request struct {
+	trusted  bool // the caller may see true coordinates
+	entities *Loader[int, model.Entity]
+	taxa     *Loader[int, model.Taxon]
+}
+
+// resolver resolves every field of the schema.
+type resolver struct {
+	btrflydb *db.DataStore
+	policy   geoprivacy.Policy
+}
+
+// newRequest returns the context of a request, trusted callers see true coordinates.
+func (r *resolver) newRequest(ctx context.Context, trusted bool) context.Context {
+	req := &request{trusted: trusted}
+	ctx = context.WithValue(ctx, requestKey, req)
+	req.entities = NewLoader(r.entitiesById, ctx)
+	req.taxa = NewLoader(r.btrflydb.GetTaxa, ctx)
+	return ctx
+}
+
+// requestOf returns the request of a resolver's context.
+func requestOf(ctx context.Context) *request {
+	if req, ok := ctx.Value(requestKey).(*request); ok {
+		return req
+	}
+	return &request{
+		entities: NewLoader(func([]int, context.Context) (map[int]model.Entity, error) { return nil, nil }, ctx),
+		taxa:     NewLoader(func([]int, context.Context) (map[int]model.Taxon, error) { return nil, nil }, ctx),
+	}
+}
+
+// entitiesById fetches the entities of the entity loader.
+func (r *resolver) entitiesById(ids []int, ctx context.Context) (map[int]model.Entity, error) {
+	entities, err := r.btrflydb.GetEntitiesByIds(ids, ctx)
+	if err != nil {
+		return nil, err
+	}
+	byId := make(map[int]model.Entity, len(entities))
+	for _, entity := range entities {
+		byId[entity.Id] = entity
+	}
+	return byId, nil
+}
+
+// public generalizes the coordinates of sensitive entities unless the caller is trusted.
+func (r *resolver) public(ctx context.Context, entities ...model.Entity) []model.Entity {
+	if requestOf(ctx).trusted {
+		return entities
+	}
+	return r.policy.PublicAll(entities)
+}
+
+// entityThunk loads an entity, resolving to null when it is not found.
+func (r *resolver) entityThunk(ctx context.Context, id int) func() (interface{}, error) {
+	load := requestOf(ctx).entities.Load(id)
+	return func() (interface{}, error) {
+		entity, found, err := load()
+		if err != nil || !found {
+			return nil, err
+		}
+		return r.public(ctx, entity)[0], nil
+	}
+}
+
+// taxonThunk loads a taxon, resolving to null when it has no published observations.
+func taxonThunk(ctx context.Context, id int) func() (interface{}, error) {
+	load := requestOf(ctx).taxa.Load(id)
+	return func() (interface{}, error) {
+		taxon, found, err := load()
+		if err != nil || !found {
+			return nil, err
+		}
+		return taxon, nil
+	}
+}
+
+// connection is a page of entities.
+type connection struct {
+	query       model.SearchQuery
+	entities    []model.Entity
+	hasNextPage bool
+}
+
+// edge is an entity along with its cursor.
+type edge struct {
+	cursor string
+	entity model.Entity
+}
+
+// pageSize returns the first argument, DefaultPageSize when left out.
+func pageSize(args map[string]interface{}) (int, error) {
+	first, ok := args["first"].(int)
+	if !ok {
+		return DefaultPageSize, nil
+	}
+	if first < 1 || first > MaxPageSize {
+		return 0, fmt.Errorf("first must be between 1 and %d", MaxPageSize)
+	}
+	return first, nil
+}
+
+// searchQuery turns the filter argument into a model.SearchQuery.
+func searchQuery(args map[string]interface{}) (model.SearchQuery, error) {
+	var query model.SearchQuery
+	filter, _ := args["filter"].(map[string]interface{})
+	query.TaxonId, _ = filter["taxonId"].(int)
+	query.Date1, _ = filter["date1"].(pgtype.Date)
+	query.Date2, _ = filter["date2"].(pgtype.Date)
+	if grades, ok := filter["qualityGrade"].([]interface{}); ok {
+		for _, grade := range grades {
+			if !model.ValidGrade(grade.(string)) {
+				return query, fmt.Errorf("invalid quality grade %q", grade)
+			}
+			query.QualityGrade = append(query.QualityGrade, grade.(string))
+		}
+	}
+	query.QualityFlag, _ = filter["qualityFlag"].(string)
+	query.Geoprivacy, _ = filter["geoprivacy"].(string)
+	if !model.ValidGeoprivacy(query.Geoprivacy) {
+		return query, fmt.Errorf("invalid geoprivacy %q", query.Geoprivacy)
+	}
+	query.Merged, _ = filter["merged"].(bool)
+	return query, nil
+}
+
+// page returns a page of the entities matching the query past the after argument.
+func (r *resolver) page(query model.SearchQuery, args map[string]interface{}, ctx context.Context) (*connection, error) {
+	first, err := pageSize(args)
+	if err != nil {
+		return nil, err
+	}
+	after, _ := args["after"].(string)
+	cursor, err := model.ParseCursor(after)
+	if err != nil {
+		return nil, err
+	}
+	entities, err := r.btrflydb.SearchEntitiesPage(query, cursor, first+1, ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn := &connection{query: query, hasNextPage: len(entities) > first}
+	if conn.hasNextPage {
+		entities = entities[:first]
+	}
+	conn.entities = r.public(ctx, entities...)
+	return conn, nil
+}
+
+// places counts the entities matching the query by place, largest first. The public only sees places the way the
+// geoprivacy policy publishes them, so the place_guess of sensitive entities is generalized before counting.
+func (r *resolver) places(query model.SearchQuery, first int, ctx context.Context) ([]model.Count, error) {
+	counts, err := r.btrflydb.CountPlaces(query, ctx)
+	if err != nil {
+		return nil, err
+	}
+	trusted := requestOf(ctx).trusted
+	byName := map[string]int{}
+	for _, count := range counts {
+		name := count.PlaceGuess
+		if !trusted {
+			name = r.policy.Public(model.Entity{TaxonId: count.TaxonId, Geoprivacy: count.Geoprivacy, PlaceGuess: name}).PlaceGuess
+		}
+		byName[name] += count.Count
+	}
+	places := make([]model.Count, 0, len(byName))
+	for name, count := range byName {
+		places = append(places, model.Count{Key: name, Count: count})
+	}
+	sort.Slice(places, func(i, j int) bool {
+		if places[i].Count != places[j].Count {
+			return places[i].Count > places[j].Count
+		}
+		return places[i].Key < places[j].Key
+	})
+	if len(places) > first {
+		places = places[:first]
+	}
+	return places, nil
+}
+
+// parseDate parses a yyyy-mm-dd date, nil when it is not one.
+func parseDate(value string) interface{} {
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil
+	}
+	return pgtype.Date{Time: date, Valid: true}
+}
+
+// dateType is a calendar date written yyyy-mm-dd.
+var dateType = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Date",
+	Description: "A calendar date, yyyy-mm-dd",
+	Serialize: func(value interface{}) interface{} {
+		if date, ok := value.(pgtype.Date); ok && date.Valid {
+			return date.Time.Format(dateLayout)
+		}
+		return nil
+	},
+	ParseValue: func(value interface{}) interface{} {
+		if s, ok := value.(string); ok {
+			return parseDate(s)
+		}
+		return nil
+	},
+	ParseLiteral: func(value ast.Value) interface{} {
+		if s, ok := value.(*ast.StringValue); ok {
+			return parseDate(s.Value)
+		}
+		return nil
+	},
+})
+
+// newSchema builds the schema resolved by r.
+func newSchema(r *resolver) (graphql.Schema, error) {
+	filterType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "ObservationFilter",
+		Description: "Filters observations the same way /entities/search does, fields left out do not filter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"taxonId":      &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"date1":        &graphql.InputObjectFieldConfig{Type: dateType, Description: "Observed on or after"},
+			"date2":        &graphql.InputObjectFieldConfig{Type: dateType, Description: "Observed on or before"},
+			"qualityGrade": &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String)), Description: "Rejected observations are left out unless asked for"},
+			"qualityFlag":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"geoprivacy":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"merged":       &graphql.InputObjectFieldConfig{Type: graphql.Boolean, Description: "Include observations merged into another"},
+		},
+	})
+	filterArg := &graphql.ArgumentConfig{Type: filterType}
+	firstArg := &graphql.ArgumentConfig{Type: graphql.Int, Description: fmt.Sprintf("At most %d, %d by default", MaxPageSize, DefaultPageSize)}
+	pageArgs := graphql.FieldConfigArgument{
+		"filter": filterArg,
+		"first":  firstArg,
+		"after":  &graphql.ArgumentConfig{Type: graphql.String, Description: "The endCursor of the previous page"},
+	}
+	countType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Count",
+		Fields: graphql.Fields{
+			"key":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"count": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+	placeType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Place",
+		Description: "A place_guess, generalized for sensitive observations unless the caller is trusted",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(model.Count).Key, nil
+			}},
+			"observationCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(model.Count).Count, nil
+			}},
+		},
+	})
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*connection).hasNextPage, nil
+			}},
+			"endCursor": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				conn := p.Source.(*connection)
+				if len(conn.entities) == 0 {
+					return nil, nil
+				}
+				return model.CursorOf(conn.entities[len(conn.entities)-1]).String(), nil
+			}},
+		},
+	})
+
+	var observationType, taxonType, connectionType *graphql.Object
+	entity := func(get func(model.Entity) interface{}) graphql.FieldResolveFn {
+		return func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(model.Entity)), nil
+		}
+	}
+	optional := func(value string) interface{} {
+		if value == "" {
+			return nil
+		}
+		return value
+	}
+	observationType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Observation",
+		Description: "An Entity, its coordinates and place are generalized for sensitive taxa unless the caller is trusted",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":           &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"taxonId":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"uuid":         &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: entity(func(e model.Entity) interface{} { return e.Uuid.String() })},
+				"placeGuess":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"speciesGuess": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"latitude":     &graphql.Field{Type: graphql.String, Resolve: entity(func(e model.Entity) interface{} { return optional(e.Latitude) })},
+				"longitude":    &graphql.Field{Type: graphql.String, Resolve: entity(func(e model.Entity) interface{} { return optional(e.Longitude) })},
+				"observedOn":   &graphql.Field{Type: dateType},
+				"timeZone":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"qualityFlags": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))), Resolve: entity(func(e model.Entity) interface{} {
+					if e.QualityFlags == nil {
+						return []string{}
+					}
+					return e.QualityFlags
+				})},
+				"qualityGrade":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"geoprivacy":          &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "The geoprivacy it is published with", Resolve: entity(func(e model.Entity) interface{} { return r.policy.Level(e) })},
+				"coordinatesObscured": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+				"duplicateOf": &graphql.Field{Type: observationType, Description: "The observation this one was merged into", Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if id := p.Source.(model.Entity).DuplicateOf; id != nil {
+						return r.entityThunk(p.Context, *id), nil
+					}
+					return nil, nil
+				}},
+				"taxon": &graphql.Field{Type: taxonType, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return taxonThunk(p.Context, p.Source.(model.Entity).TaxonId), nil
+				}},
+			}
+		}),
+	})
+	edgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ObservationEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(edge).cursor, nil
+			}},
+			"node": &graphql.Field{Type: graphql.NewNonNull(observationType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(edge).entity, nil
+			}},
+		},
+	})
+	connectionType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "ObservationConnection",
+		Description: "A page of observations ordered by observedOn then id",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edgeType))), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				conn := p.Source.(*connection)
+				edges := make([]edge, 0, len(conn.entities))
+				for _, entity := range conn.entities {
+					edges = append(edges, edge{cursor: model.CursorOf(entity).String(), entity: entity})
+				}
+				return edges, nil
+			}},
+			"nodes": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(observationType))), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*connection).entities, nil
+			}},
+			"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfoType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source, nil
+			}},
+			"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Every observation matching the filter", Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return r.btrflydb.CountEntities(p.Source.(*connection).query, p.Context)
+			}},
+		},
+	})
+	taxonType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Taxon",
+		Description: "A taxon summarized from its published observations, named by their most common species guess",
+		Fields: graphql.Fields{
+			"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"observationCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(model.Taxon).Observations, nil
+			}},
+			"firstObserved": &graphql.Field{Type: dateType},
+			"lastObserved":  &graphql.Field{Type: dateType},
+			"observations": &graphql.Field{Type: graphql.NewNonNull(connectionType), Args: pageArgs, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				query, err := searchQuery(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				query.TaxonId = p.Source.(model.Taxon).Id
+				return r.page(query, p.Args, p.Context)
+			}},
+		},
+	})
+	taxonCountType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TaxonCount",
+		Fields: graphql.Fields{
+			"taxon": &graphql.Field{Type: taxonType, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id, _ := strconv.Atoi(p.Source.(model.Count).Key)
+				return taxonThunk(p.Context, id), nil
+			}},
+			"count": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+	countsBy := func(grouping string) graphql.FieldResolveFn {
+		return func(p graphql.ResolveParams) (interface{}, error) {
+			return r.btrflydb.CountEntitiesBy(grouping, p.Source.(model.SearchQuery), p.Context)
+		}
+	}
+	countsType := graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(countType)))
+	aggregatesType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Aggregates",
+		Description: "Counts of the observations matching a filter, largest first",
+		Fields: graphql.Fields{
+			"total": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return r.btrflydb.CountEntities(p.Source.(model.SearchQuery), p.Context)
+			}},
+			"byTaxon":        &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(taxonCountType))), Resolve: countsBy("taxon_id")},
+			"byYear":         &graphql.Field{Type: countsType, Resolve: countsBy("year")},
+			"byMonth":        &graphql.Field{Type: countsType, Description: "Keyed by the first day of the month", Resolve: countsBy("month")},
+			"byQualityGrade": &graphql.Field{Type: countsType, Resolve: countsBy("quality_grade")},
+			"byGeoprivacy":   &graphql.Field{Type: countsType, Description: "Keyed by the geoprivacy stored, empty inherits it from the taxon", Resolve: countsBy("geoprivacy")},
+			"byPlace": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(placeType))), Args: graphql.FieldConfigArgument{"first": firstArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					first, err := pageSize(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					return r.places(p.Source.(model.SearchQuery), first, p.Context)
+				}},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"observation": &graphql.Field{Type: observationType, Args: graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return r.entityThunk(p.Context, p.Args["id"].(int)), nil
+				}},
+			"observations": &graphql.Field{Type: graphql.NewNonNull(connectionType), Args: pageArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					query, err := searchQuery(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					return r.page(query, p.Args, p.Context)
+				}},
+			"taxon": &graphql.Field{Type: taxonType, Args: graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return taxonThunk(p.Context, p.Args["id"].(int)), nil
+				}},
+			"taxa": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(taxonType))), Description: "Taxa of the observations matching the filter, most observed first",
+				Args: graphql.FieldConfigArgument{"filter": filterArg, "first": firstArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					query, err := searchQuery(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					first, err := pageSize(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					counts, err := r.btrflydb.CountEntitiesBy("taxon_id", query, p.Context)
+					if err != nil {
+						return nil, err
+					}
+					if len(counts) > first {
+						counts = counts[:first]
+					}
+					ids := make([]int, 0, len(counts))
+					for _, count := range counts {
+						id, _ := strconv.Atoi(count.Key)
+						ids = append(ids, id)
+					}
+					byId, err := r.btrflydb.GetTaxa(ids, p.Context)
+					if err != nil {
+						return nil, err
+					}
+					taxa := make([]model.Taxon, 0, len(ids))
+					for _, id := range ids {
+						if taxon, ok := byId[id]; ok {
+							taxa = append(taxa, taxon)
+						}
+					}
+					return taxa, nil
+				}},
+			"places": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(placeType))), Description: "Places of the observations matching the filter, most observed first",
+				Args: graphql.FieldConfigArgument{"filter": filterArg, "first": firstArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					query, err := searchQuery(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					first, err := pageSize(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					return r.places(query, first, p.Context)
+				}},
+			"aggregates": &graphql.Field{Type: graphql.NewNonNull(aggregatesType), Args: graphql.FieldConfigArgument{"filter": filterArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return searchQuery(p.Args)
+				}},
+		},
+	})
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
@@ -0,0 +1,101 @@
+package media
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"mbcarruthers/helio/model"
+	heliov1 "mbcarruthers/helio/proto/helio/v1"
+)
+
+// msgpack writes strings and binary apart, as every current MessagePack library expects.
+var msgpack = &codec.MsgpackHandle{WriteExt: true}
+
+// record is an entity as MessagePack and CBOR carry it, with the keys and values of its json. Both libraries take
+// the keys from the json tags.
+type record struct {
+	Id                  int      `json:"id"`
+	TaxonId             int      `json:"taxon_id"`
+	Uuid                string   `json:"uuid"`
+	PlaceGuess          string   `json:"place_guess"`
+	SpeciesGuess        string   `json:"species_guess"`
+	Latitude            string   `json:"latitude"`
+	Longitude           string   `json:"longitude"`
+	ObservedOn          *string  `json:"observed_on"` // yyyy-mm-dd, null when unknown
+	TimeZone            string   `json:"time_zone"`
+	QualityFlags        []string `json:"quality_flags"`
+	QualityGrade        string   `json:"quality_grade"`
+	DuplicateOf         *int     `json:"duplicate_of,omitempty"`
+	Geoprivacy          string   `json:"geoprivacy"`
+	CoordinatesObscured bool     `json:"coordinates_obscured,omitempty"`
+}
+
+func toRecord(entity model.Entity) record {
+	r := record{
+		Id:                  entity.Id,
+		TaxonId:             entity.TaxonId,
+		Uuid:                entity.Uuid.String(),
+		PlaceGuess:          entity.PlaceGuess,
+		SpeciesGuess:        entity.SpeciesGuess,
+		Latitude:            entity.Latitude,
+		Longitude:           entity.Longitude,
+		TimeZone:            entity.TimeZone,
+		QualityFlags:        entity.QualityFlags,
+		QualityGrade:        entity.QualityGrade,
+		DuplicateOf:         entity.DuplicateOf,
+		Geoprivacy:          entity.Geoprivacy,
+		CoordinatesObscured: entity.CoordinatesObscured,
+	}
+	if entity.ObservedOn.Valid {
+		observedOn := entity.ObservedOn.Time.Format(dateLayout)
+		r.ObservedOn = &observedOn
+	}
+	return r
+}
+
+// MarshalEntity encodes an entity as mediaType, one of Types.
+func MarshalEntity(mediaType string, entity model.Entity) ([]byte, error) {
+	switch mediaType {
+	case JSON:
+		return json.Marshal(entity)
+	case MsgPack:
+		return marshalMsgPack(toRecord(entity))
+	case CBOR:
+		return cbor.Marshal(toRecord(entity))
+	case Protobuf:
+		return proto.Marshal(ToProto(entity))
+	}
+	return nil, fmt.Errorf("err media type %q", mediaType)
+}
+
+// MarshalEntities encodes entities as mediaType, one of Types.
+func MarshalEntities(mediaType string, entities []model.Entity) ([]byte, error) {
+	switch mediaType {
+	case JSON:
+		return json.Marshal(entities)
+	case MsgPack, CBOR:
+		records := make([]record, len(entities))
+		for i, entity := range entities {
+			records[i] = toRecord(entity)
+		}
+		if mediaType == CBOR {
+			return cbor.Marshal(records)
+		}
+		return marshalMsgPack(records)
+	case Protobuf:
+		response := &heliov1.SearchEntitiesResponse{Entities: make([]*heliov1.Entity, len(entities))}
+		for i, entity := range entities {
+			response.Entities[i] = ToProto(entity)
+		}
+		return proto.Marshal(response)
+	}
+	return nil, fmt.Errorf("err media type %q", mediaType)
+}
+
+func marshalMsgPack(v any) ([]byte, error) {
+	var data []byte
+	err := codec.NewEncoderBytes(&data, msgpack).Encode(v)
+	return data, err
+}
@@ -0,0 +1,53 @@
+package media
+
+import (
+	"encoding/json"
+	"mbcarruthers/helio/model"
+	"os"
+	"testing"
+)
+
+// monarchs returns the entities of data/monarch.json, the ones helio is seeded with.
+func monarchs(b *testing.B) []model.Entity {
+	file, err := os.ReadFile("../data/monarch.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	entities := make([]model.Entity, 0)
+	if err = json.Unmarshal(file, &entities); err != nil {
+		b.Fatal(err)
+	}
+	return entities
+}
+
+// benchmarkEncode encodes the entities of data/monarch.json as mediaType, reporting the size of the encoding.
+func benchmarkEncode(b *testing.B, mediaType string) {
+	entities := monarchs(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	var size int
+	for i := 0; i < b.N; i++ {
+		data, err := MarshalEntities(mediaType, entities)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = len(data)
+	}
+	b.ReportMetric(float64(size), "bytes/response")
+}
+
+func BenchmarkEncodeJSON(b *testing.B) {
+	benchmarkEncode(b, JSON)
+}
+
+func BenchmarkEncodeMsgPack(b *testing.B) {
+	benchmarkEncode(b, MsgPack)
+}
+
+func BenchmarkEncodeCBOR(b *testing.B) {
+	benchmarkEncode(b, CBOR)
+}
+
+func BenchmarkEncodeProtobuf(b *testing.B) {
+	benchmarkEncode(b, Protobuf)
+}
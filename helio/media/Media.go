@@ -0,0 +1,76 @@
+// Package media encodes entities as json, MessagePack, CBOR or protobuf, whichever the Accept header of a request
+// prefers. MessagePack and CBOR carry the keys and values of the json, protobuf the messages of the gRPC API
+// (proto/helio/v1), a single entity as an Entity and lists as a SearchEntitiesResponse.
+package media
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+const (
+	JSON     = "application/json"
+	MsgPack  = "application/msgpack"
+	CBOR     = "application/cbor"
+	Protobuf = "application/x-protobuf"
+)
+
+// Types are the media types entities are encoded as, json first as it is answered on a tie.
+var Types = []string{JSON, MsgPack, CBOR, Protobuf}
+
+// Negotiate returns the type of Types the Accept header prefers. Anything else, or no Accept at all, is answered json
+// rather than 406 so that browsers and tools asking for */* or text/html keep working.
+func Negotiate(accept string) string {
+	if preferred, ok := Preferred(accept, Types...); ok {
+		return preferred
+	}
+	return JSON
+}
+
+// Preferred returns the offer with the highest q within an Accept or Accept-Encoding header, the first of offers on a
+// tie. It is false when the header accepts none of them, an empty header accepting the first.
+func Preferred(header string, offers ...string) (string, bool) {
+	if len(offers) == 0 {
+		return "", false
+	}
+	if strings.TrimSpace(header) == "" {
+		return offers[0], true
+	}
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := Quality(header, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// Quality returns the q an Accept or Accept-Encoding header gives offer, by the most specific range matching it:
+// the offer itself, type/* and then */* or *. It is 0 when no range matches.
+func Quality(header string, offer string) float64 {
+	q, specificity := 0.0, 0
+	for _, accepted := range strings.Split(header, ",") {
+		value, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		matched := 0
+		switch {
+		case value == offer:
+			matched = 3
+		case strings.HasSuffix(value, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(value, "*")):
+			matched = 2
+		case value == "*/*" || value == "*":
+			matched = 1
+		}
+		if matched <= specificity {
+			continue
+		}
+		specificity, q = matched, 1
+		if value, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			q = value
+		}
+	}
+	return q
+}
@@ -0,0 +1,36 @@
+package media
+
+import (
+	"mbcarruthers/helio/model"
+	heliov1 "mbcarruthers/helio/proto/helio/v1"
+)
+
+const (
+	dateLayout = "2006-01-02"
+)
+
+// ToProto converts an entity to its message, shared by the gRPC API and the protobuf responses of the REST routes.
+func ToProto(entity model.Entity) *heliov1.Entity {
+	message := &heliov1.Entity{
+		Id:                  int64(entity.Id),
+		TaxonId:             int64(entity.TaxonId),
+		Uuid:                entity.Uuid.String(),
+		PlaceGuess:          entity.PlaceGuess,
+		SpeciesGuess:        entity.SpeciesGuess,
+		Latitude:            entity.Latitude,
+		Longitude:           entity.Longitude,
+		TimeZone:            entity.TimeZone,
+		QualityFlags:        entity.QualityFlags,
+		QualityGrade:        entity.QualityGrade,
+		Geoprivacy:          entity.Geoprivacy,
+		CoordinatesObscured: entity.CoordinatesObscured,
+	}
+	if entity.ObservedOn.Valid {
+		message.ObservedOn = entity.ObservedOn.Time.Format(dateLayout)
+	}
+	if entity.DuplicateOf != nil {
+		duplicateOf := int64(*entity.DuplicateOf)
+		message.DuplicateOf = &duplicateOf
+	}
+	return message
+}
@@ -0,0 +1,27 @@
+package model
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+// Count is the number of entities sharing a key, i.e. a taxon_id, a year or a quality grade.
+type Count struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// Taxon summarizes the entities of a single taxon. Its name is the species_guess most of them share.
+type Taxon struct {
+	Id            int         `json:"id"`
+	Name          string      `json:"name"`
+	Observations  int         `json:"observations"`
+	FirstObserved pgtype.Date `json:"first_observed"`
+	LastObserved  pgtype.Date `json:"last_observed"`
+}
+
+// PlaceCount is the number of entities sharing a taxon, geoprivacy and place_guess. The taxon and geoprivacy are kept
+// so that the place can be generalized the way the geoprivacy policy publishes it.
+type PlaceCount struct {
+	TaxonId    int
+	Geoprivacy string
+	PlaceGuess string
+	Count      int
+}
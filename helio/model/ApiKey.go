@@ -0,0 +1,32 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// ApiKey is a partner's key to the observation API. Only a hash of the key itself is ever stored.
+type ApiKey struct {
+	Id            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	Prefix        string     `json:"prefix"`          // first characters of the key, to tell keys apart
+	RatePerSecond float64    `json:"rate_per_second"` // tokens added to the key's bucket every second
+	Burst         int        `json:"burst"`           // size of the key's bucket
+	DailyQuota    int64      `json:"daily_quota"`     // requests allowed per UTC day, 0 is unlimited
+	CreatedAt     time.Time  `json:"created_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+}
+
+// ApiKeyRequest is the body of a request issuing a new ApiKey.
+type ApiKeyRequest struct {
+	Name          string  `json:"name" binding:"required"`
+	RatePerSecond float64 `json:"rate_per_second" binding:"gt=0"`
+	Burst         int     `json:"burst" binding:"gt=0"`
+	DailyQuota    int64   `json:"daily_quota" binding:"gte=0"`
+}
+
+// ApiKeyUsage is the number of requests made with an ApiKey on a single day.
+type ApiKeyUsage struct {
+	Day      string `json:"day"` // yyyy-mm-dd
+	Requests int64  `json:"requests"`
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit actions, one for every kind of mutation of an Entity.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditMerge   = "merge"
+	AuditImport  = "import"
+	AuditRestore = "restore"
+	AuditReview  = "review" // a curator accepted or rejected the Entity
+)
+
+// FieldChange is the before and after value of a single field of an Entity.
+type FieldChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// AuditEntry records a single mutation of an Entity. Entries are only ever appended.
+type AuditEntry struct {
+	Id        int64         `json:"id"`
+	At        time.Time     `json:"at"`
+	Action    string        `json:"action"`
+	EntityId  int           `json:"entity_id"`
+	Actor     string        `json:"actor"`
+	RequestId string        `json:"request_id"`
+	ClientIp  string        `json:"client_ip"`
+	Changes   []FieldChange `json:"changes"`
+	Before    *Entity       `json:"before,omitempty"` // the whole Entity before the mutation, nil when created
+	After     *Entity       `json:"after,omitempty"`  // the whole Entity after the mutation, nil when deleted
+}
+
+// AuditQuery holds the optional filters of a search through the audit log.
+type AuditQuery struct {
+	EntityId  int       `form:"entity_id"`
+	Action    string    `form:"action"`
+	Actor     string    `form:"actor"`
+	RequestId string    `form:"request_id"`
+	Since     time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until     time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit     int       `form:"limit"`
+	Offset    int       `form:"offset"`
+}
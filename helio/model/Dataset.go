@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+// DatasetVersion changes along with the observations, every mutation adding one to Version and setting ModifiedAt.
+type DatasetVersion struct {
+	Version    int64     `json:"version"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
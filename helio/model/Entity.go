@@ -16,6 +16,12 @@ type Entity struct {
 	Longitude    string      `json:"longitude" form:"longitude"`
 	ObservedOn   pgtype.Date `json:"observed_on" form:"observed_on"`
 	TimeZone     string      `json:"time_zone" form:"time_zone"`
+	QualityFlags []string    `json:"quality_flags" form:"-"`
+	QualityGrade string      `json:"quality_grade" form:"-"`
+	DuplicateOf  *int        `json:"duplicate_of,omitempty" form:"-"` // id of the entity this one was merged into
+	Geoprivacy   string      `json:"geoprivacy" form:"geoprivacy"`    // empty inherits the geoprivacy of the taxon
+
+	CoordinatesObscured bool `json:"coordinates_obscured,omitempty" form:"-"` // set on responses whose coordinates were generalized or hidden
 }
 
 // EntityQuery is designed for gathering data about entities for search queries with either taxon_id, or in between to dates
@@ -30,3 +36,21 @@ type DateQuery struct {
 	Date1 pgtype.Date `json:"date1" form:"date1"`
 	Date2 pgtype.Date `json:"date2" form:"date2"`
 }
+
+// SearchQuery holds every optional filter of a search for entities. Zero values are left out of the search.
+// QualityGrade accepts repeated or comma separated values, i.e. quality_grade=clean,accepted
+type SearchQuery struct {
+	TaxonId      int         `json:"taxon_id" form:"taxon_id"`
+	Date1        pgtype.Date `json:"date1" form:"date1"`
+	Date2        pgtype.Date `json:"date2" form:"date2"`
+	QualityGrade []string    `json:"quality_grade" form:"quality_grade"`
+	QualityFlag  string      `json:"quality_flag" form:"quality_flag"`
+	Merged       bool        `json:"merged" form:"merged"` // include entities merged into another
+	Geoprivacy   string      `json:"geoprivacy" form:"geoprivacy"`
+}
+
+// MergeRequest names the entity to keep and the duplicates to link to it.
+type MergeRequest struct {
+	Keep       int   `json:"keep" binding:"required"`
+	Duplicates []int `json:"duplicates" binding:"required,min=1"`
+}
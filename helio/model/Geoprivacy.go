@@ -0,0 +1,17 @@
+package model
+
+// Geoprivacy levels of an Entity (geoprivacy). An empty geoprivacy inherits the level of the Entity's taxon.
+const (
+	GeoprivacyOpen     = "open"     // true coordinates are public
+	GeoprivacyObscured = "obscured" // coordinates are generalized to a grid cell for the public
+	GeoprivacyPrivate  = "private"  // coordinates are hidden from the public
+)
+
+// ValidGeoprivacy reports whether level is a geoprivacy level, or empty.
+func ValidGeoprivacy(level string) bool {
+	switch level {
+	case "", GeoprivacyOpen, GeoprivacyObscured, GeoprivacyPrivate:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgtype"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cursor is the position of an Entity within entities ordered by observed_on then id, used to page through them.
+type Cursor struct {
+	ObservedOn pgtype.Date
+	Id         int
+}
+
+// PageQuery pages through a search. A zero Limit and empty After leave the search unpaged.
+type PageQuery struct {
+	Limit int    `json:"limit" form:"limit"`
+	After string `json:"after" form:"after"` // the cursor of the last entity of the previous page
+}
+
+// CursorOf returns the cursor of an entity.
+func CursorOf(entity Entity) Cursor {
+	return Cursor{ObservedOn: entity.ObservedOn, Id: entity.Id}
+}
+
+// String encodes the cursor as an opaque url safe string.
+func (c Cursor) String() string {
+	var date string
+	if c.ObservedOn.Valid {
+		date = c.ObservedOn.Time.Format("2006-01-02")
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(date + "/" + strconv.Itoa(c.Id)))
+}
+
+// ParseCursor reverses Cursor.String, an empty string is a nil cursor.
+func ParseCursor(encoded string) (*Cursor, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	invalid := fmt.Errorf("invalid cursor %q", encoded)
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, invalid
+	}
+	date, id, ok := strings.Cut(string(decoded), "/")
+	if !ok {
+		return nil, invalid
+	}
+	cursor := &Cursor{}
+	if cursor.Id, err = strconv.Atoi(id); err != nil {
+		return nil, invalid
+	}
+	if date != "" {
+		observedOn, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, invalid
+		}
+		cursor.ObservedOn = pgtype.Date{Time: observedOn, Valid: true}
+	}
+	return cursor, nil
+}
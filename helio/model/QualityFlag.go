@@ -0,0 +1,68 @@
+package model
+
+// Quality flags are short codes stored alongside an Entity (quality_flags) describing anything questionable about
+// the observation. They are produced by the validation layer when it runs in warn mode and by the quality analysis pass.
+const (
+	FlagInvalidCoordinates = "invalid_coordinates" // latitude/longitude could not be parsed or are outside the allowed range
+	FlagOutsideRegion      = "outside_region"      // coordinates fall outside of the configured region geofence
+	FlagDateOutOfRange     = "date_out_of_range"   // observed_on is missing or outside of the allowed date bounds
+	FlagUnknownTaxon       = "unknown_taxon"       // taxon_id is not one of the known taxa
+	FlagMissingPrefix      = "missing_"            // prefix for a required field that was left empty, i.e. missing_species_guess
+	FlagSuspectedDuplicate = "suspected_duplicate" // another observation of the same taxon at the same place and date exists
+	FlagLowPrecision       = "low_precision"       // coordinates have too few decimal places to place the observation
+	FlagTimeZoneMismatch   = "time_zone_mismatch"  // time_zone does not belong to the region, observed_on may be shifted
+)
+
+// Quality grades describe where an Entity stands in the curator review queue (quality_grade).
+const (
+	GradeClean    = "clean"    // no quality flags
+	GradeFlagged  = "flagged"  // has quality flags and is waiting for a curator
+	GradeAccepted = "accepted" // flagged, but a curator accepted it
+	GradeRejected = "rejected" // a curator rejected it, it is left out of published searches
+)
+
+// ValidGrade reports whether grade is one of the quality grades.
+func ValidGrade(grade string) bool {
+	switch grade {
+	case GradeClean, GradeFlagged, GradeAccepted, GradeRejected:
+		return true
+	}
+	return false
+}
+
+// GradeForFlags returns the grade of an entity that has not been reviewed by a curator.
+func GradeForFlags(flags []string) string {
+	if len(flags) == 0 {
+		return GradeClean
+	}
+	return GradeFlagged
+}
+
+// Reviewed reports whether a curator has already accepted or rejected the Entity.
+func (e *Entity) Reviewed() bool {
+	return e.QualityGrade == GradeAccepted || e.QualityGrade == GradeRejected
+}
+
+// MissingFieldFlag returns the quality flag used for an empty required field.
+func MissingFieldFlag(field string) string {
+	return FlagMissingPrefix + field
+}
+
+// AddQualityFlags appends flags to the Entity's quality flags, skipping any that are already present.
+func (e *Entity) AddQualityFlags(flags ...string) {
+	for _, flag := range flags {
+		if !e.HasQualityFlag(flag) {
+			e.QualityFlags = append(e.QualityFlags, flag)
+		}
+	}
+}
+
+// HasQualityFlag reports whether the Entity carries the given quality flag.
+func (e *Entity) HasQualityFlag(flag string) bool {
+	for _, f := range e.QualityFlags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package openapi
+
+import (
+	"github.com/gin-gonic/gin"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// SpecHandler GET /openapi.json
+// Returns the OpenAPI document.
+// Responses:
+// 200 - Successful operation
+func (d *Document) SpecHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", d.raw)
+}
+
+// docsOperation is a single operation as shown on the docs page.
+type docsOperation struct {
+	Method    string
+	Path      string
+	Operation *Operation
+	Body      string
+	Responses []docsResponse
+}
+
+type docsResponse struct {
+	Status      string
+	Description string
+	Schema      string
+}
+
+// DocsHandler GET /docs
+// Returns a page documenting every operation of the OpenAPI document, rendered without any outside scripts.
+// Responses:
+// 200 - Successful operation
+func (d *Document) DocsHandler(c *gin.Context) {
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := docsTemplate.Execute(c.Writer, gin.H{"Info": d.Info, "Version": d.OpenAPI, "Operations": d.docsOperations()}); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// docsOperations returns every operation sorted by path and method.
+func (d *Document) docsOperations() []docsOperation {
+	methodOrder := map[string]int{"GET": 0, "POST": 1, "PUT": 2, "PATCH": 3, "DELETE": 4}
+	var operations []docsOperation
+	for path, item := range d.Paths {
+		for method, operation := range item.operations() {
+			entry := docsOperation{Method: method, Path: path, Operation: operation}
+			if operation.RequestBody != nil {
+				entry.Body = schemaName(operation.RequestBody.Content["application/json"].Schema)
+			}
+			for status, response := range operation.Responses {
+				var schemas []string
+				for contentType, media := range response.Content {
+					schemas = append(schemas, contentType+" "+schemaName(media.Schema))
+				}
+				sort.Strings(schemas)
+				entry.Responses = append(entry.Responses, docsResponse{Status: status, Description: response.Description, Schema: strings.Join(schemas, ", ")})
+			}
+			sort.Slice(entry.Responses, func(i, j int) bool { return entry.Responses[i].Status < entry.Responses[j].Status })
+			operations = append(operations, entry)
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		if operations[i].Path != operations[j].Path {
+			return operations[i].Path < operations[j].Path
+		}
+		return methodOrder[operations[i].Method] < methodOrder[operations[j].Method]
+	})
+	return operations
+}
+
+// schemaName describes a schema in a few words, i.e. Entity or array of Entity
+func schemaName(schema *Schema) string {
+	switch {
+	case schema == nil:
+		return ""
+	case schema.Ref != "":
+		return strings.TrimPrefix(schema.Ref, componentPrefix)
+	case schema.Type.has("array"):
+		return "array of " + schemaName(schema.Items)
+	default:
+		return strings.Join(schema.Type, " or ")
+	}
+}
+
+var docsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Info.Title}} {{.Info.Version}}</title>
+<style>
+body { font-family: sans-serif; margin: 2rem auto; max-width: 60rem; color: #222; }
+section { border: 1px solid #ddd; border-radius: 4px; margin: 1rem 0; padding: 0.5rem 1rem; }
+.method { display: inline-block; min-width: 4rem; font-weight: bold; }
+.role { float: right; color: #a33; }
+table { border-collapse: collapse; width: 100%; }
+td, th { border-bottom: 1px solid #eee; padding: 0.25rem; text-align: left; vertical-align: top; }
+code { background: #f5f5f5; }
+</style>
+</head>
+<body>
+<h1>{{.Info.Title}} <small>{{.Info.Version}}</small></h1>
+<p>{{.Info.Description}}</p>
+<p>OpenAPI {{.Version}} document: <a href="/openapi.json">/openapi.json</a></p>
+{{range .Operations}}
+<section id="{{.Operation.OperationId}}">
+<h3><span class="method">{{.Method}}</span> <code>{{.Path}}</code>{{if .Operation.Role}}<span class="role">{{.Operation.Role}}</span>{{end}}</h3>
+<p>{{.Operation.Summary}}{{if .Operation.Description}}. {{.Operation.Description}}{{end}}</p>
+{{if .Operation.Parameters}}<table>
+<tr><th>Parameter</th><th>In</th><th>Type</th><th>Description</th></tr>
+{{range .Operation.Parameters}}<tr><td><code>{{.Name}}</code>{{if .Required}} *{{end}}</td><td>{{.In}}</td><td>{{range .Schema.Type}}{{.}} {{end}}{{.Schema.Format}}</td><td>{{.Description}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Body}}<p>Body: <code>{{.Body}}</code></p>{{end}}
+<table>
+<tr><th>Status</th><th>Description</th><th>Content</th></tr>
+{{range .Responses}}<tr><td>{{.Status}}</td><td>{{.Description}}</td><td><code>{{.Schema}}</code></td></tr>
+{{end}}</table>
+</section>
+{{end}}
+</body>
+</html>
+`))
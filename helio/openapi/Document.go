@@ -0,0 +1,232 @@
+// Package openapi serves the OpenAPI document of helio(openapi.json, the single source of truth describing every
+// route) along with a docs page, and enforces it by validating requests against it.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	componentPrefix = "#/components/schemas/"
+)
+
+//go:embed openapi.json
+var document []byte
+
+// Document is the part of an OpenAPI 3.1 document needed to validate requests and render the docs page.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+
+	raw      []byte
+	patterns map[string]*regexp.Regexp // every pattern of the document, compiled once parsed
+}
+
+// Info describes the API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+// PathItem holds the operations of a single path.
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Put    *Operation `json:"put,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+	Patch  *Operation `json:"patch,omitempty"`
+}
+
+// operations returns the operations of the path by http method.
+func (p PathItem) operations() map[string]*Operation {
+	operations := map[string]*Operation{}
+	for method, operation := range map[string]*Operation{"GET": p.Get, "PUT": p.Put, "POST": p.Post, "DELETE": p.Delete, "PATCH": p.Patch} {
+		if operation != nil {
+			operations[method] = operation
+		}
+	}
+	return operations
+}
+
+// Operation is a single route.
+type Operation struct {
+	OperationId string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description"`
+	Tags        []string              `json:"tags"`
+	Parameters  []Parameter           `json:"parameters"`
+	RequestBody *RequestBody          `json:"requestBody"`
+	Responses   map[string]Response   `json:"responses"`
+	Role        string                `json:"x-role"` // role required by the route, see auth.Policy
+	Security    []map[string][]string `json:"security"`
+}
+
+// Parameter is a path or query parameter. Arrays are comma separated(style form, explode false).
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body of an operation, only application/json bodies are validated.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a single response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// MediaType holds the schema of a body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Load parses the embedded OpenAPI document.
+func Load() (*Document, error) {
+	return Parse(document)
+}
+
+// Parse parses an OpenAPI document, every $ref must point at one of its components.
+func Parse(raw []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("parsing openapi document: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version %q", doc.OpenAPI)
+	}
+	var schemaErr error
+	doc.patterns = map[string]*regexp.Regexp{}
+	doc.walk(func(schema *Schema) {
+		if schema.Ref != "" && doc.resolve(schema) == nil && schemaErr == nil {
+			schemaErr = fmt.Errorf("unresolved $ref %q", schema.Ref)
+		}
+		if schema.Pattern != "" {
+			pattern, err := regexp.Compile(schema.Pattern)
+			if err != nil && schemaErr == nil {
+				schemaErr = fmt.Errorf("invalid pattern %q: %w", schema.Pattern, err)
+			}
+			doc.patterns[schema.Pattern] = pattern
+		}
+	})
+	if schemaErr != nil {
+		return nil, schemaErr
+	}
+	doc.raw = raw
+	return &doc, nil
+}
+
+// walk calls fn with every schema of the document.
+func (d *Document) walk(fn func(*Schema)) {
+	for _, schema := range d.Components.Schemas {
+		schema.walk(fn)
+	}
+	for _, item := range d.Paths {
+		for _, operation := range item.operations() {
+			for _, parameter := range operation.Parameters {
+				parameter.Schema.walk(fn)
+			}
+			if operation.RequestBody != nil {
+				for _, media := range operation.RequestBody.Content {
+					media.Schema.walk(fn)
+				}
+			}
+			for _, response := range operation.Responses {
+				for _, media := range response.Content {
+					media.Schema.walk(fn)
+				}
+			}
+		}
+	}
+}
+
+// resolve returns the component a schema refers to, the schema itself if it is not a $ref, and nil if it is not found.
+func (d *Document) resolve(schema *Schema) *Schema {
+	for depth := 0; schema != nil && schema.Ref != "" && depth < 8; depth++ {
+		if !strings.HasPrefix(schema.Ref, componentPrefix) {
+			return nil
+		}
+		schema = d.Components.Schemas[strings.TrimPrefix(schema.Ref, componentPrefix)]
+	}
+	return schema
+}
+
+// PathOf turns a gin route, i.e. /entities/:id or /review/, into its OpenAPI path /entities/{id} or /review.
+func PathOf(route string) string {
+	parts := strings.Split(route, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	path := strings.Join(parts, "/")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+// operation returns the operation of a gin route, and nil if the route is not documented.
+func (d *Document) operation(method string, route string) *Operation {
+	item, ok := d.Paths[PathOf(route)]
+	if !ok {
+		return nil
+	}
+	return item.operations()[method]
+}
+
+// Compare checks the document against the routes registered with gin. It returns the routes missing from the
+// document and the documented operations without a route, both written as "METHOD /path".
+func (d *Document) Compare(routes gin.RoutesInfo) (undocumented []string, unrouted []string) {
+	routed := map[string]bool{}
+	for _, route := range routes {
+		if route.Method == "HEAD" || route.Method == "OPTIONS" {
+			continue
+		}
+		key := route.Method + " " + PathOf(route.Path)
+		routed[key] = true
+		if d.operation(route.Method, route.Path) == nil {
+			undocumented = append(undocumented, key)
+		}
+	}
+	for path, item := range d.Paths {
+		for method := range item.operations() {
+			if !routed[method+" "+path] {
+				unrouted = append(unrouted, method+" "+path)
+			}
+		}
+	}
+	sort.Strings(undocumented)
+	sort.Strings(unrouted)
+	return undocumented, unrouted
+}
+
+// Roles returns the role required by every protected operation(x-role) keyed by "METHOD /path".
+func (d *Document) Roles() map[string]string {
+	roles := map[string]string{}
+	for path, item := range d.Paths {
+		for method, operation := range item.operations() {
+			if operation.Role != "" {
+				roles[method+" "+path] = operation.Role
+			}
+		}
+	}
+	return roles
+}
@@ -0,0 +1,226 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of JSON Schema(2020-12, as used by OpenAPI 3.1) requests are validated against.
+// Keywords outside of this subset are documentation only.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 Types              `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Not                  *Schema            `json:"not,omitempty"`
+}
+
+// UnmarshalJSON accepts the boolean schemas true(anything) and false(nothing) as well as objects.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	switch strings.TrimSpace(string(data)) {
+	case "true":
+		*s = Schema{}
+		return nil
+	case "false":
+		*s = Schema{Not: &Schema{}}
+		return nil
+	}
+	type schema Schema // without the UnmarshalJSON method
+	return json.Unmarshal(data, (*schema)(s))
+}
+
+// Types is the type keyword, either a single type or a list of them, i.e. ["integer", "null"]
+type Types []string
+
+// UnmarshalJSON accepts a single type as well as a list.
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+func (t Types) has(name string) bool {
+	for _, value := range t {
+		if value == name {
+			return true
+		}
+	}
+	return false
+}
+
+// walk calls fn with the schema and every schema within it.
+func (s *Schema) walk(fn func(*Schema)) {
+	if s == nil {
+		return
+	}
+	fn(s)
+	for _, property := range s.Properties {
+		property.walk(fn)
+	}
+	s.AdditionalProperties.walk(fn)
+	s.Items.walk(fn)
+	s.Not.walk(fn)
+}
+
+// validate checks a value decoded with json.Decoder.UseNumber against the schema, appending a problem for every
+// keyword it breaks. at names the value within the request, i.e. body.observed_on
+func (d *Document) validate(schema *Schema, value any, at string, problems []string) []string {
+	schema = d.resolve(schema)
+	if schema == nil {
+		return problems
+	}
+	if schema.Not != nil && len(d.validate(schema.Not, value, at, nil)) == 0 {
+		return append(problems, at+" is not allowed")
+	}
+	if len(schema.Type) != 0 && !schema.Type.has(typeOf(value)) && !(schema.Type.has("number") && typeOf(value) == "integer") {
+		return append(problems, fmt.Sprintf("%s must be of type %s", at, strings.Join(schema.Type, " or ")))
+	}
+	if len(schema.Enum) != 0 && !inEnum(schema.Enum, value) {
+		return append(problems, fmt.Sprintf("%s must be one of %s", at, enumString(schema.Enum)))
+	}
+
+	switch v := value.(type) {
+	case string:
+		if schema.MinLength != nil && len([]rune(v)) < *schema.MinLength {
+			problems = append(problems, fmt.Sprintf("%s must be at least %d characters", at, *schema.MinLength))
+		}
+		if schema.MaxLength != nil && len([]rune(v)) > *schema.MaxLength {
+			problems = append(problems, fmt.Sprintf("%s must be at most %d characters", at, *schema.MaxLength))
+		}
+		if schema.Pattern != "" {
+			if pattern, ok := d.patterns[schema.Pattern]; ok && !pattern.MatchString(v) {
+				problems = append(problems, fmt.Sprintf("%s must match %s", at, schema.Pattern))
+			}
+		}
+		if !validFormat(schema.Format, v) {
+			problems = append(problems, fmt.Sprintf("%s must be a %s", at, schema.Format))
+		}
+	case json.Number:
+		n, _ := v.Float64()
+		if schema.Minimum != nil && n < *schema.Minimum {
+			problems = append(problems, fmt.Sprintf("%s must be at least %v", at, *schema.Minimum))
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			problems = append(problems, fmt.Sprintf("%s must be at most %v", at, *schema.Maximum))
+		}
+		if schema.ExclusiveMinimum != nil && n <= *schema.ExclusiveMinimum {
+			problems = append(problems, fmt.Sprintf("%s must be greater than %v", at, *schema.ExclusiveMinimum))
+		}
+		if schema.ExclusiveMaximum != nil && n >= *schema.ExclusiveMaximum {
+			problems = append(problems, fmt.Sprintf("%s must be less than %v", at, *schema.ExclusiveMaximum))
+		}
+	case []any:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			problems = append(problems, fmt.Sprintf("%s must have at least %d items", at, *schema.MinItems))
+		}
+		if schema.MaxItems != nil && len(v) > *schema.MaxItems {
+			problems = append(problems, fmt.Sprintf("%s must have at most %d items", at, *schema.MaxItems))
+		}
+		if schema.Items != nil {
+			for i, item := range v {
+				problems = d.validate(schema.Items, item, at+"["+strconv.Itoa(i)+"]", problems)
+			}
+		}
+	case map[string]any:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				problems = append(problems, at+"."+name+" is required")
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := schema.Properties[name]; ok {
+				problems = d.validate(property, v[name], at+"."+name, problems)
+			} else if schema.AdditionalProperties != nil {
+				problems = d.validate(schema.AdditionalProperties, v[name], at+"."+name, problems)
+			}
+		}
+	}
+	return problems
+}
+
+// typeOf returns the JSON Schema type of a decoded value.
+func typeOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if n, err := v.Float64(); err == nil && n == math.Trunc(n) && !strings.ContainsAny(v.String(), ".eE") {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
+
+func inEnum(enum []any, value any) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) && typeOf(value) != "object" && typeOf(value) != "array" {
+			return true
+		}
+	}
+	return false
+}
+
+func enumString(enum []any) string {
+	values := make([]string, 0, len(enum))
+	for _, value := range enum {
+		values = append(values, strconv.Quote(fmt.Sprint(value)))
+	}
+	return strings.Join(values, ", ")
+}
+
+// validFormat checks the formats helio relies on, any other format is documentation only.
+func validFormat(format string, value string) bool {
+	var err error
+	switch format {
+	case "date":
+		_, err = time.Parse("2006-01-02", value)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, value)
+	case "uuid":
+		_, err = uuid.Parse(value)
+	}
+	return err == nil
+}
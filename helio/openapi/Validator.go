@@ -0,0 +1,145 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxBody = 1 << 20 // bodies larger than 1MB are refused before being validated
+)
+
+// Validate is middleware refusing requests that do not conform to the document with 400, listing every problem found.
+// Path and query parameters are checked against their schemas, as are application/json bodies.
+// Routes left out of the document are not checked.
+func (d *Document) Validate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		operation := d.operation(c.Request.Method, c.FullPath())
+		if operation == nil {
+			c.Next()
+			return
+		}
+		problems := d.parameters(c, operation)
+		if operation.RequestBody != nil {
+			bodyProblems, status := d.body(c, operation.RequestBody)
+			if status != http.StatusOK {
+				c.AbortWithStatusJSON(status, gin.H{
+					"error":    "err request body",
+					"message":  "request does not conform to the OpenAPI document",
+					"problems": bodyProblems,
+				})
+				return
+			}
+			problems = append(problems, bodyProblems...)
+		}
+		if len(problems) != 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":    problems[0],
+				"message":  "request does not conform to the OpenAPI document",
+				"problems": problems,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// parameters checks the path and query parameters of the request.
+func (d *Document) parameters(c *gin.Context, operation *Operation) []string {
+	var problems []string
+	query := c.Request.URL.Query()
+	for _, parameter := range operation.Parameters {
+		var values []string
+		switch parameter.In {
+		case "path":
+			values = []string{c.Param(parameter.Name)}
+		case "query":
+			values = query[parameter.Name]
+		default:
+			continue
+		}
+		at := parameter.In + "." + parameter.Name
+		if len(values) == 0 || (len(values) == 1 && values[0] == "" && parameter.In == "path") {
+			if parameter.Required {
+				problems = append(problems, at+" is required")
+			}
+			continue
+		}
+		problems = d.validate(parameter.Schema, d.coerce(parameter.Schema, values), at, problems)
+	}
+	return problems
+}
+
+// coerce turns the raw values of a parameter into the value its schema describes. Arrays are comma separated
+// and may be repeated, values that cannot be converted are left as strings to fail validation.
+func (d *Document) coerce(schema *Schema, values []string) any {
+	schema = d.resolve(schema)
+	if schema == nil {
+		return values[0]
+	}
+	if schema.Type.has("array") {
+		items := []any{}
+		for _, value := range values {
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, d.coerce(schema.Items, []string{item}))
+				}
+			}
+		}
+		return items
+	}
+	value := values[0]
+	switch {
+	case schema.Type.has("integer") || schema.Type.has("number"):
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	case schema.Type.has("boolean"):
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// body checks an application/json body, restoring it for the handler. The status is 200 unless the body
+// could not be read at all.
+func (d *Document) body(c *gin.Context, requestBody *RequestBody) ([]string, int) {
+	media, ok := requestBody.Content["application/json"]
+	if !ok {
+		return nil, http.StatusOK
+	}
+	if contentType := c.GetHeader("Content-Type"); contentType != "" {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType != "application/json" {
+			return []string{"body must be application/json"}, http.StatusUnsupportedMediaType
+		}
+	}
+	raw, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBody+1))
+	if err != nil {
+		return []string{"body could not be read"}, http.StatusBadRequest
+	}
+	if len(raw) > maxBody {
+		return []string{"body is too large"}, http.StatusRequestEntityTooLarge
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+	if len(bytes.TrimSpace(raw)) == 0 {
+		if requestBody.Required {
+			return []string{"body is required"}, http.StatusOK
+		}
+		return nil, http.StatusOK
+	}
+
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err = decoder.Decode(&value); err != nil || decoder.More() {
+		return []string{"body is not valid json"}, http.StatusOK
+	}
+	return d.validate(media.Schema, value, "body", nil), http.StatusOK
+}
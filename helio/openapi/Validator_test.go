@@ -0,0 +1,204 @@
+package openapi
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testDocument describes a search by taxon, grade and page along with the creation of an entity.
+const testDocument = `{
+  "openapi": "3.1.0",
+  "info": {"title": "helio", "version": "test"},
+  "paths": {
+    "/entities/{id}": {
+      "get": {
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "integer", "minimum": 1}},
+          {"name": "taxon_id", "in": "query", "schema": {"type": "integer"}},
+          {"name": "all", "in": "query", "schema": {"type": "boolean"}},
+          {"name": "quality_grade", "in": "query", "schema": {"type": "array", "items": {"$ref": "#/components/schemas/Grade"}}},
+          {"name": "limit", "in": "query", "required": true, "schema": {"type": "integer", "maximum": 1000}}
+        ]
+      }
+    },
+    "/entities": {
+      "post": {
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/NewEntity"}}}
+        }
+      },
+      "put": {
+        "requestBody": {
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/NewEntity"}}}
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Grade": {"type": "string", "enum": ["clean", "flagged", "accepted", "rejected"]},
+      "NewEntity": {
+        "type": "object",
+        "required": ["species_guess"],
+        "properties": {
+          "species_guess": {"type": "string", "minLength": 1},
+          "taxon_id": {"type": "integer"}
+        }
+      }
+    }
+  }
+}`
+
+// newValidated returns a router validating requests against testDocument before answering 200.
+func newValidated(t *testing.T) *gin.Engine {
+	doc, err := Parse([]byte(testDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(doc.Validate())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/entities/:id", ok)
+	r.POST("/entities/", ok)
+	r.PUT("/entities/", ok)
+	r.GET("/undocumented", ok)
+	return r
+}
+
+// refusal is the body of a refused request.
+type refusal struct {
+	Error    string   `json:"error"`
+	Problems []string `json:"problems"`
+}
+
+func TestValidateParameters(t *testing.T) {
+	tests := []struct {
+		name         string
+		target       string
+		wantProblems []string // none when the request is let through
+	}{
+		{"valid", "/entities/1?limit=10&taxon_id=48662&all=true&quality_grade=clean,flagged", nil},
+		{"repeated array", "/entities/1?limit=10&quality_grade=clean&quality_grade=accepted", nil},
+		{"array with spaces", "/entities/1?limit=10&quality_grade=clean,%20flagged,", nil},
+		{"path not an integer", "/entities/one?limit=10", []string{"path.id must be of type integer"}},
+		{"path under the minimum", "/entities/0?limit=10", []string{"path.id must be at least 1"}},
+		{"query over the maximum", "/entities/1?limit=5000", []string{"query.limit must be at most 1000"}},
+		{"query not a boolean", "/entities/1?limit=10&all=yes", []string{"query.all must be of type boolean"}},
+		{"array item outside the enum", "/entities/1?limit=10&quality_grade=clean,spam",
+			[]string{`query.quality_grade[1] must be one of "clean", "flagged", "accepted", "rejected"`}},
+		{"required query missing", "/entities/1", []string{"query.limit is required"}},
+		{"every problem listed", "/entities/1?taxon_id=monarch",
+			[]string{"query.taxon_id must be of type integer", "query.limit is required"}},
+		{"undocumented route", "/undocumented?limit=nope", nil},
+	}
+	r := newValidated(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if tt.wantProblems == nil {
+				if recorder.Code != http.StatusOK {
+					t.Errorf("GET %s = %d %s, want 200", tt.target, recorder.Code, recorder.Body.String())
+				}
+				return
+			}
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("GET %s = %d, want 400", tt.target, recorder.Code)
+			}
+			var got refusal
+			if err := json.Unmarshal(recorder.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got.Problems, "; ") != strings.Join(tt.wantProblems, "; ") || got.Error != tt.wantProblems[0] {
+				t.Errorf("problems = %q with error %q, want %q", got.Problems, got.Error, tt.wantProblems)
+			}
+		})
+	}
+}
+
+func TestValidateBody(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		contentType  string
+		body         string
+		want         int
+		wantProblems []string
+	}{
+		{"valid", http.MethodPost, "application/json", `{"species_guess": "Monarch", "taxon_id": 48662}`, http.StatusOK, nil},
+		{"content type with charset", http.MethodPost, "application/json; charset=utf-8", `{"species_guess": "Monarch"}`, http.StatusOK, nil},
+		{"no content type", http.MethodPost, "", `{"species_guess": "Monarch"}`, http.StatusOK, nil},
+		{"not json", http.MethodPost, "text/plain", `species_guess=Monarch`, http.StatusUnsupportedMediaType,
+			[]string{"body must be application/json"}},
+		{"invalid content type", http.MethodPost, "application/", `{}`, http.StatusUnsupportedMediaType,
+			[]string{"body must be application/json"}},
+		{"too large", http.MethodPost, "application/json", `{"species_guess": "` + strings.Repeat("a", maxBody) + `"}`,
+			http.StatusRequestEntityTooLarge, []string{"body is too large"}},
+		{"required body missing", http.MethodPost, "application/json", "", http.StatusBadRequest, []string{"body is required"}},
+		{"required body blank", http.MethodPost, "application/json", " \n", http.StatusBadRequest, []string{"body is required"}},
+		{"optional body missing", http.MethodPut, "application/json", "", http.StatusOK, nil},
+		{"malformed", http.MethodPost, "application/json", `{"species_guess": `, http.StatusBadRequest,
+			[]string{"body is not valid json"}},
+		{"trailing values", http.MethodPost, "application/json", `{"species_guess": "Monarch"} {}`, http.StatusBadRequest,
+			[]string{"body is not valid json"}},
+		{"schema broken", http.MethodPost, "application/json", `{"species_guess": "", "taxon_id": 4.5}`, http.StatusBadRequest,
+			[]string{"body.species_guess must be at least 1 characters", "body.taxon_id must be of type integer"}},
+		{"required property missing", http.MethodPost, "application/json", `{"taxon_id": 48662}`, http.StatusBadRequest,
+			[]string{"body.species_guess is required"}},
+	}
+	r := newValidated(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, "/entities/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, request)
+			if recorder.Code != tt.want {
+				t.Fatalf("%s /entities/ = %d %s, want %d", tt.method, recorder.Code, recorder.Body.String(), tt.want)
+			}
+			if tt.wantProblems == nil {
+				return
+			}
+			var got refusal
+			if err := json.Unmarshal(recorder.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got.Problems, "; ") != strings.Join(tt.wantProblems, "; ") {
+				t.Errorf("problems = %q, want %q", got.Problems, tt.wantProblems)
+			}
+		})
+	}
+}
+
+func TestValidateBodyRestored(t *testing.T) {
+	doc, err := Parse([]byte(testDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(doc.Validate())
+	var got struct {
+		SpeciesGuess string `json:"species_guess"`
+	}
+	r.POST("/entities/", func(c *gin.Context) {
+		if err := c.ShouldBindJSON(&got); err != nil {
+			c.Status(http.StatusBadRequest)
+		}
+	})
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/entities/", strings.NewReader(`{"species_guess": "Monarch"}`)))
+	if recorder.Code != http.StatusOK || got.SpeciesGuess != "Monarch" {
+		t.Errorf("body read by the handler = %+v with %d, want the species_guess Monarch", got, recorder.Code)
+	}
+}
@@ -0,0 +1,402 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: helio/v1/entity.proto
+
+// helio.v1 mirrors the observation(Entity) routes of the REST API, see openapi/openapi.json
+
+package heliov1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	EntityService_GetEntity_FullMethodName         = "/helio.v1.EntityService/GetEntity"
+	EntityService_ListEntities_FullMethodName      = "/helio.v1.EntityService/ListEntities"
+	EntityService_SearchEntities_FullMethodName    = "/helio.v1.EntityService/SearchEntities"
+	EntityService_CreateEntity_FullMethodName      = "/helio.v1.EntityService/CreateEntity"
+	EntityService_UpdateEntity_FullMethodName      = "/helio.v1.EntityService/UpdateEntity"
+	EntityService_DeleteEntity_FullMethodName      = "/helio.v1.EntityService/DeleteEntity"
+	EntityService_StreamNewEntities_FullMethodName = "/helio.v1.EntityService/StreamNewEntities"
+)
+
+// EntityServiceClient is the client API for EntityService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type EntityServiceClient interface {
+	// GetEntity returns a single entity by id.
+	GetEntity(ctx context.Context, in *GetEntityRequest, opts ...grpc.CallOption) (*Entity, error)
+	// ListEntities streams the published entities, rejected and merged entities are left out.
+	ListEntities(ctx context.Context, in *ListEntitiesRequest, opts ...grpc.CallOption) (EntityService_ListEntitiesClient, error)
+	// SearchEntities returns every entity matching the search, rejected entities are left out unless asked for.
+	SearchEntities(ctx context.Context, in *SearchEntitiesRequest, opts ...grpc.CallOption) (*SearchEntitiesResponse, error)
+	// CreateEntity validates and stores a new entity. Requires the curator role.
+	CreateEntity(ctx context.Context, in *CreateEntityRequest, opts ...grpc.CallOption) (*Entity, error)
+	// UpdateEntity updates an existing entity, its taxon_id is never updated. Requires the curator role.
+	UpdateEntity(ctx context.Context, in *UpdateEntityRequest, opts ...grpc.CallOption) (*Entity, error)
+	// DeleteEntity deletes an entity. Requires the admin role.
+	DeleteEntity(ctx context.Context, in *DeleteEntityRequest, opts ...grpc.CallOption) (*DeleteEntityResponse, error)
+	// StreamNewEntities streams every entity created or restored from now on, through REST or gRPC.
+	StreamNewEntities(ctx context.Context, in *StreamNewEntitiesRequest, opts ...grpc.CallOption) (EntityService_StreamNewEntitiesClient, error)
+}
+
+type entityServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEntityServiceClient(cc grpc.ClientConnInterface) EntityServiceClient {
+	return &entityServiceClient{cc}
+}
+
+func (c *entityServiceClient) GetEntity(ctx context.Context, in *GetEntityRequest, opts ...grpc.CallOption) (*Entity, error) {
+	out := new(Entity)
+	err := c.cc.Invoke(ctx, EntityService_GetEntity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *entityServiceClient) ListEntities(ctx context.Context, in *ListEntitiesRequest, opts ...grpc.CallOption) (EntityService_ListEntitiesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &EntityService_ServiceDesc.Streams[0], EntityService_ListEntities_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &entityServiceListEntitiesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type EntityService_ListEntitiesClient interface {
+	Recv() (*Entity, error)
+	grpc.ClientStream
+}
+
+type entityServiceListEntitiesClient struct {
+	grpc.ClientStream
+}
+
+func (x *entityServiceListEntitiesClient) Recv() (*Entity, error) {
+	m := new(Entity)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *entityServiceClient) SearchEntities(ctx context.Context, in *SearchEntitiesRequest, opts ...grpc.CallOption) (*SearchEntitiesResponse, error) {
+	out := new(SearchEntitiesResponse)
+	err := c.cc.Invoke(ctx, EntityService_SearchEntities_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *entityServiceClient) CreateEntity(ctx context.Context, in *CreateEntityRequest, opts ...grpc.CallOption) (*Entity, error) {
+	out := new(Entity)
+	err := c.cc.Invoke(ctx, EntityService_CreateEntity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *entityServiceClient) UpdateEntity(ctx context.Context, in *UpdateEntityRequest, opts ...grpc.CallOption) (*Entity, error) {
+	out := new(Entity)
+	err := c.cc.Invoke(ctx, EntityService_UpdateEntity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *entityServiceClient) DeleteEntity(ctx context.Context, in *DeleteEntityRequest, opts ...grpc.CallOption) (*DeleteEntityResponse, error) {
+	out := new(DeleteEntityResponse)
+	err := c.cc.Invoke(ctx, EntityService_DeleteEntity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *entityServiceClient) StreamNewEntities(ctx context.Context, in *StreamNewEntitiesRequest, opts ...grpc.CallOption) (EntityService_StreamNewEntitiesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &EntityService_ServiceDesc.Streams[1], EntityService_StreamNewEntities_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &entityServiceStreamNewEntitiesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type EntityService_StreamNewEntitiesClient interface {
+	Recv() (*Entity, error)
+	grpc.ClientStream
+}
+
+type entityServiceStreamNewEntitiesClient struct {
+	grpc.ClientStream
+}
+
+func (x *entityServiceStreamNewEntitiesClient) Recv() (*Entity, error) {
+	m := new(Entity)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// EntityServiceServer is the server API for EntityService service.
+// All implementations must embed UnimplementedEntityServiceServer
+// for forward compatibility
+type EntityServiceServer interface {
+	// GetEntity returns a single entity by id.
+	GetEntity(context.Context, *GetEntityRequest) (*Entity, error)
+	// ListEntities streams the published entities, rejected and merged entities are left out.
+	ListEntities(*ListEntitiesRequest, EntityService_ListEntitiesServer) error
+	// SearchEntities returns every entity matching the search, rejected entities are left out unless asked for.
+	SearchEntities(context.Context, *SearchEntitiesRequest) (*SearchEntitiesResponse, error)
+	// CreateEntity validates and stores a new entity. Requires the curator role.
+	CreateEntity(context.Context, *CreateEntityRequest) (*Entity, error)
+	// UpdateEntity updates an existing entity, its taxon_id is never updated. Requires the curator role.
+	UpdateEntity(context.Context, *UpdateEntityRequest) (*Entity, error)
+	// DeleteEntity deletes an entity. Requires the admin role.
+	DeleteEntity(context.Context, *DeleteEntityRequest) (*DeleteEntityResponse, error)
+	// StreamNewEntities streams every entity created or restored from now on, through REST or gRPC.
+	StreamNewEntities(*StreamNewEntitiesRequest, EntityService_StreamNewEntitiesServer) error
+	mustEmbedUnimplementedEntityServiceServer()
+}
+
+// UnimplementedEntityServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedEntityServiceServer struct {
+}
+
+func (UnimplementedEntityServiceServer) GetEntity(context.Context, *GetEntityRequest) (*Entity, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEntity not implemented")
+}
+func (UnimplementedEntityServiceServer) ListEntities(*ListEntitiesRequest, EntityService_ListEntitiesServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListEntities not implemented")
+}
+func (UnimplementedEntityServiceServer) SearchEntities(context.Context, *SearchEntitiesRequest) (*SearchEntitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchEntities not implemented")
+}
+func (UnimplementedEntityServiceServer) CreateEntity(context.Context, *CreateEntityRequest) (*Entity, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateEntity not implemented")
+}
+func (UnimplementedEntityServiceServer) UpdateEntity(context.Context, *UpdateEntityRequest) (*Entity, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateEntity not implemented")
+}
+func (UnimplementedEntityServiceServer) DeleteEntity(context.Context, *DeleteEntityRequest) (*DeleteEntityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteEntity not implemented")
+}
+func (UnimplementedEntityServiceServer) StreamNewEntities(*StreamNewEntitiesRequest, EntityService_StreamNewEntitiesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamNewEntities not implemented")
+}
+func (UnimplementedEntityServiceServer) mustEmbedUnimplementedEntityServiceServer() {}
+
+// UnsafeEntityServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EntityServiceServer will
+// result in compilation errors.
+type UnsafeEntityServiceServer interface {
+	mustEmbedUnimplementedEntityServiceServer()
+}
+
+func RegisterEntityServiceServer(s grpc.ServiceRegistrar, srv EntityServiceServer) {
+	s.RegisterService(&EntityService_ServiceDesc, srv)
+}
+
+func _EntityService_GetEntity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEntityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EntityServiceServer).GetEntity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EntityService_GetEntity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EntityServiceServer).GetEntity(ctx, req.(*GetEntityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EntityService_ListEntities_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListEntitiesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EntityServiceServer).ListEntities(m, &entityServiceListEntitiesServer{stream})
+}
+
+type EntityService_ListEntitiesServer interface {
+	Send(*Entity) error
+	grpc.ServerStream
+}
+
+type entityServiceListEntitiesServer struct {
+	grpc.ServerStream
+}
+
+func (x *entityServiceListEntitiesServer) Send(m *Entity) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _EntityService_SearchEntities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchEntitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EntityServiceServer).SearchEntities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EntityService_SearchEntities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EntityServiceServer).SearchEntities(ctx, req.(*SearchEntitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EntityService_CreateEntity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateEntityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EntityServiceServer).CreateEntity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EntityService_CreateEntity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EntityServiceServer).CreateEntity(ctx, req.(*CreateEntityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EntityService_UpdateEntity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateEntityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EntityServiceServer).UpdateEntity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EntityService_UpdateEntity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EntityServiceServer).UpdateEntity(ctx, req.(*UpdateEntityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EntityService_DeleteEntity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteEntityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EntityServiceServer).DeleteEntity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EntityService_DeleteEntity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EntityServiceServer).DeleteEntity(ctx, req.(*DeleteEntityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EntityService_StreamNewEntities_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamNewEntitiesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EntityServiceServer).StreamNewEntities(m, &entityServiceStreamNewEntitiesServer{stream})
+}
+
+type EntityService_StreamNewEntitiesServer interface {
+	Send(*Entity) error
+	grpc.ServerStream
+}
+
+type entityServiceStreamNewEntitiesServer struct {
+	grpc.ServerStream
+}
+
+func (x *entityServiceStreamNewEntitiesServer) Send(m *Entity) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// EntityService_ServiceDesc is the grpc.ServiceDesc for EntityService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var EntityService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "helio.v1.EntityService",
+	HandlerType: (*EntityServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetEntity",
+			Handler:    _EntityService_GetEntity_Handler,
+		},
+		{
+			MethodName: "SearchEntities",
+			Handler:    _EntityService_SearchEntities_Handler,
+		},
+		{
+			MethodName: "CreateEntity",
+			Handler:    _EntityService_CreateEntity_Handler,
+		},
+		{
+			MethodName: "UpdateEntity",
+			Handler:    _EntityService_UpdateEntity_Handler,
+		},
+		{
+			MethodName: "DeleteEntity",
+			Handler:    _EntityService_DeleteEntity_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListEntities",
+			Handler:       _EntityService_ListEntities_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamNewEntities",
+			Handler:       _EntityService_StreamNewEntities_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "helio/v1/entity.proto",
+}
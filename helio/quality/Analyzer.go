@@ -0,0 +1,118 @@
+// Package quality provides the analysis pass computing the quality flags of every observation(Entity).
+package quality
+
+import (
+	"mbcarruthers/helio/model"
+	"mbcarruthers/helio/validation"
+	"strings"
+)
+
+// DefaultTimeZones are the time zones observations made in Florida are expected to be recorded in.
+var DefaultTimeZones = []string{
+	"Eastern Time (US & Canada)",
+	"Central Time (US & Canada)",
+	"America/New_York",
+	"America/Chicago",
+}
+
+// Analyzer computes the quality flags of entities.
+type Analyzer struct {
+	validator    *validation.Validator
+	detector     *DuplicateDetector
+	minPrecision int
+	timeZones    map[string]bool
+}
+
+// NewAnalyzer constructs an Analyzer. Coordinates with less than minPrecision decimal places are flagged
+// as well as time zones not found within timeZones. An empty timeZones disables the time zone check.
+// Entities within a pair found by the detector are flagged as suspected duplicates.
+func NewAnalyzer(validator *validation.Validator, detector *DuplicateDetector, minPrecision int, timeZones []string) *Analyzer {
+	zones := make(map[string]bool, len(timeZones))
+	for _, zone := range timeZones {
+		zones[zone] = true
+	}
+	return &Analyzer{
+		validator:    validator,
+		detector:     detector,
+		minPrecision: minPrecision,
+		timeZones:    zones,
+	}
+}
+
+// Report summarises an analysis pass.
+type Report struct {
+	Analyzed int            `json:"analyzed"`
+	Flagged  int            `json:"flagged"`
+	Flags    map[string]int `json:"flags"`
+}
+
+// Analyze recomputes the quality flags of every entity and grades the ones a curator has not reviewed yet.
+// The flags of an entity depend on the others(duplicates), so it should be given the whole table.
+func (a *Analyzer) Analyze(entities []model.Entity) ([]model.Entity, Report) {
+	report := Report{Analyzed: len(entities), Flags: map[string]int{}}
+	duplicates := a.suspectedDuplicates(entities)
+	analyzed := make([]model.Entity, 0, len(entities))
+	for _, entity := range entities {
+		entity.QualityFlags = a.Flags(entity)
+		if duplicates[entity.Id] {
+			entity.AddQualityFlags(model.FlagSuspectedDuplicate)
+		}
+		if !entity.Reviewed() {
+			entity.QualityGrade = model.GradeForFlags(entity.QualityFlags)
+		}
+		if len(entity.QualityFlags) != 0 {
+			report.Flagged++
+		}
+		for _, flag := range entity.QualityFlags {
+			report.Flags[flag]++
+		}
+		analyzed = append(analyzed, entity)
+	}
+	return analyzed, report
+}
+
+// Flags returns the flags of a single entity that do not depend on any other entity.
+func (a *Analyzer) Flags(entity model.Entity) []string {
+	flags := []string{}
+	for _, violation := range a.validator.Check(entity) {
+		if !contains(flags, violation.Flag) {
+			flags = append(flags, violation.Flag)
+		}
+	}
+	if a.minPrecision > 0 && entity.Latitude != "" && entity.Longitude != "" &&
+		(precision(entity.Latitude) < a.minPrecision || precision(entity.Longitude) < a.minPrecision) {
+		flags = append(flags, model.FlagLowPrecision)
+	}
+	if len(a.timeZones) != 0 && !a.timeZones[entity.TimeZone] {
+		flags = append(flags, model.FlagTimeZoneMismatch)
+	}
+	return flags
+}
+
+// suspectedDuplicates returns the ids of entities found within any of the detector's duplicate pairs.
+func (a *Analyzer) suspectedDuplicates(entities []model.Entity) map[int]bool {
+	duplicates := make(map[int]bool)
+	for _, pair := range a.detector.Candidates(entities) {
+		duplicates[pair.Id] = true
+		duplicates[pair.DuplicateId] = true
+	}
+	return duplicates
+}
+
+// precision returns the number of decimal places of a coordinate.
+func precision(coordinate string) int {
+	coordinate = strings.TrimSpace(coordinate)
+	if i := strings.IndexByte(coordinate, '.'); i >= 0 {
+		return len(coordinate) - i - 1
+	}
+	return 0
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
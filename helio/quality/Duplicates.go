@@ -0,0 +1,122 @@
+package quality
+
+import (
+	"math"
+	"mbcarruthers/helio/model"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	earthRadius = 6371000.0 // meters
+
+	// weights of each part of a DuplicatePair's score, they add up to 1
+	spatialWeight  = 0.45
+	temporalWeight = 0.35
+	taxonWeight    = 0.20
+)
+
+// DuplicatePair is a pair of entities that are likely the same observation.
+type DuplicatePair struct {
+	Id          int     `json:"id"`
+	DuplicateId int     `json:"duplicate_id"`
+	Score       float64 `json:"score"`      // 0 to 1, 1 being the same taxon at the same place and date
+	Distance    float64 `json:"distance"`   // meters
+	DaysApart   int     `json:"days_apart"` // days
+	SameTaxon   bool    `json:"same_taxon"`
+}
+
+// DuplicateDetector scores pairs of entities by their spatial and temporal proximity and taxon. Only entities of the
+// same taxon are ever duplicates: several species seen at the same roost on the same day are distinct observations.
+type DuplicateDetector struct {
+	MaxDistance float64 // meters, entities further apart are never duplicates
+	MaxDays     int     // entities observed further apart are never duplicates
+	MinScore    float64 // pairs scoring lower are not reported
+}
+
+// DefaultDuplicateDetector reports entities within 100 meters and a day of each other.
+func DefaultDuplicateDetector() *DuplicateDetector {
+	return &DuplicateDetector{
+		MaxDistance: 100,
+		MaxDays:     1,
+		MinScore:    0.8,
+	}
+}
+
+type located struct {
+	entity   model.Entity
+	lat, lon float64
+	day      time.Time
+}
+
+// Candidates returns every pair of entities scoring at least MinScore, highest score first.
+// Entities that were already merged into another are left out.
+func (d *DuplicateDetector) Candidates(entities []model.Entity) []DuplicatePair {
+	points := make([]located, 0, len(entities))
+	for _, entity := range entities {
+		if entity.DuplicateOf != nil || !entity.ObservedOn.Valid {
+			continue
+		}
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(entity.Latitude), 64)
+		lon, lonErr := strconv.ParseFloat(strings.TrimSpace(entity.Longitude), 64)
+		if latErr != nil || lonErr != nil {
+			continue
+		}
+		points = append(points, located{entity: entity, lat: lat, lon: lon, day: entity.ObservedOn.Time})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].day.Before(points[j].day) })
+
+	maxApart := time.Duration(d.MaxDays) * 24 * time.Hour
+	var pairs []DuplicatePair
+	for i := range points {
+		// points are sorted by date so only the ones within MaxDays after i need to be compared
+		for j := i + 1; j < len(points) && points[j].day.Sub(points[i].day) <= maxApart; j++ {
+			if pair, ok := d.score(points[i], points[j]); ok {
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Score > pairs[j].Score })
+	return pairs
+}
+
+func (d *DuplicateDetector) score(a, b located) (DuplicatePair, bool) {
+	if a.entity.TaxonId != b.entity.TaxonId {
+		return DuplicatePair{}, false
+	}
+	distance := haversine(a.lat, a.lon, b.lat, b.lon)
+	if distance > d.MaxDistance {
+		return DuplicatePair{}, false
+	}
+	days := int(math.Abs(b.day.Sub(a.day).Hours()) / 24)
+	pair := DuplicatePair{
+		Id:          a.entity.Id,
+		DuplicateId: b.entity.Id,
+		Distance:    math.Round(distance*10) / 10,
+		DaysApart:   days,
+		SameTaxon:   a.entity.TaxonId == b.entity.TaxonId,
+	}
+	spatial := 1.0
+	if d.MaxDistance > 0 {
+		spatial = 1 - distance/d.MaxDistance
+	}
+	temporal := 1 - float64(days)/float64(d.MaxDays+1)
+	pair.Score = spatialWeight*spatial + temporalWeight*temporal
+	if pair.SameTaxon {
+		pair.Score += taxonWeight
+	}
+	pair.Score = math.Round(pair.Score*1000) / 1000
+	return pair, pair.Score >= d.MinScore
+}
+
+// haversine returns the distance between two coordinates in meters.
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,122 @@
+package quality
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+	"mbcarruthers/helio/model"
+	"testing"
+	"time"
+)
+
+// observation returns an entity of taxon observed at lat, lon days after the first of October 2021.
+func observation(id int, taxon int, lat string, lon string, days int) model.Entity {
+	return model.Entity{
+		Id:         id,
+		TaxonId:    taxon,
+		Latitude:   lat,
+		Longitude:  lon,
+		ObservedOn: pgtype.Date{Time: time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, days), Valid: true},
+	}
+}
+
+func TestDuplicateScore(t *testing.T) {
+	detector := &DuplicateDetector{MaxDistance: 100, MaxDays: 1}
+	monarch := observation(1, 48662, "29.6516", "-82.3248", 0)
+	tests := []struct {
+		name         string
+		other        model.Entity
+		want         bool // whether they are paired at all
+		wantScore    float64
+		wantDistance float64
+		wantDays     int
+	}{
+		{"same taxon, place and day", observation(2, 48662, "29.6516", "-82.3248", 0), true, 1, 0, 0},
+		// several species at the same roost on the same day
+		{"other taxon at the same place and day", observation(2, 51114, "29.6516", "-82.3248", 0), false, 0, 0, 0},
+		{"a day apart", observation(2, 48662, "29.6516", "-82.3248", 1), true, 0.825, 0, 1},
+		{"50 meters apart", observation(2, 48662, "29.65205", "-82.3248", 0), true, 0.775, 50, 0},
+		{"50 meters and a day apart", observation(2, 48662, "29.65205", "-82.3248", 1), true, 0.6, 50, 1},
+		{"padded coordinates", observation(2, 48662, " 29.6516 ", " -82.3248", 0), true, 1, 0, 0},
+		{"beyond the max distance", observation(2, 48662, "29.6526", "-82.3248", 0), false, 0, 0, 0},
+		{"beyond the max days", observation(2, 48662, "29.6516", "-82.3248", 2), false, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := detector.Candidates([]model.Entity{monarch, tt.other})
+			if !tt.want {
+				if len(pairs) != 0 {
+					t.Errorf("Candidates() = %+v, want none", pairs)
+				}
+				return
+			}
+			if len(pairs) != 1 {
+				t.Fatalf("Candidates() = %+v, want a single pair", pairs)
+			}
+			pair := pairs[0]
+			if pair.Id != 1 || pair.DuplicateId != 2 {
+				t.Errorf("pair = %d and %d, want 1 and 2", pair.Id, pair.DuplicateId)
+			}
+			if pair.Score != tt.wantScore || pair.Distance != tt.wantDistance || pair.DaysApart != tt.wantDays {
+				t.Errorf("score, distance, days = %v, %v, %v, want %v, %v, %v",
+					pair.Score, pair.Distance, pair.DaysApart, tt.wantScore, tt.wantDistance, tt.wantDays)
+			}
+			if !pair.SameTaxon {
+				t.Error("SameTaxon = false, want true")
+			}
+		})
+	}
+}
+
+func TestDuplicateCandidates(t *testing.T) {
+	merged := observation(5, 48662, "29.6516", "-82.3248", 0)
+	keep := 1
+	merged.DuplicateOf = &keep
+	undated := observation(6, 48662, "29.6516", "-82.3248", 0)
+	undated.ObservedOn = pgtype.Date{}
+
+	tests := []struct {
+		name     string
+		entities []model.Entity
+		want     [][2]int // ids of the pairs, highest score first
+	}{
+		{"none", nil, nil},
+		{"highest score first", []model.Entity{
+			observation(1, 48662, "29.6516", "-82.3248", 0),
+			observation(2, 48662, "29.6516", "-82.3248", 1), // a day apart from 1 and 3, 0.825
+			observation(3, 48662, "29.6516", "-82.3248", 0), // same as 1, 1
+		}, [][2]int{{1, 3}, {1, 2}, {3, 2}}},
+		{"under the min score", []model.Entity{
+			observation(1, 48662, "29.6516", "-82.3248", 0),
+			observation(2, 48662, "29.65205", "-82.3248", 1),
+		}, nil},
+		{"other taxa at the same place and day", []model.Entity{
+			observation(1, 48662, "29.6516", "-82.3248", 0),
+			observation(2, 51114, "29.6516", "-82.3248", 0),
+			observation(3, 82792, "29.6516", "-82.3248", 0),
+		}, nil},
+		{"merged left out", []model.Entity{observation(1, 48662, "29.6516", "-82.3248", 0), merged}, nil},
+		{"undated left out", []model.Entity{observation(1, 48662, "29.6516", "-82.3248", 0), undated}, nil},
+		{"invalid coordinates left out", []model.Entity{
+			observation(1, 48662, "29.6516", "-82.3248", 0),
+			observation(2, 48662, "north", "-82.3248", 0),
+			observation(3, 48662, "29.6516", "", 0),
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := DefaultDuplicateDetector().Candidates(tt.entities)
+			got := make([][2]int, 0, len(pairs))
+			for _, pair := range pairs {
+				got = append(got, [2]int{pair.Id, pair.DuplicateId})
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Candidates() = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("Candidates() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
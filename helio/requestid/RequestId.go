@@ -0,0 +1,65 @@
+// Package requestid gives every request an id, taken from the X-Request-Id header or generated, and echoes it back.
+// The id is logged along with every line logged for the request, and added to every json error response as
+// request_id.
+package requestid
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/logging"
+	"net/http"
+	"strings"
+)
+
+const (
+	Header     = logging.Header
+	ContextKey = "requestid"
+)
+
+// Middleware sets the request id of every request.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := logging.NewRequestId(c.GetHeader(Header))
+		c.Set(ContextKey, id)
+		c.Request = c.Request.WithContext(logging.WithRequestId(c.Request.Context(), id))
+		c.Header(Header, id)
+		c.Writer = &errorWriter{ResponseWriter: c.Writer, id: id}
+		c.Next()
+	}
+}
+
+// Get returns the request id of the request, empty if Middleware did not run.
+func Get(c *gin.Context) string {
+	return c.GetString(ContextKey)
+}
+
+// errorWriter adds the request id to a json error response, an object written in one go as gin.Context.JSON does.
+type errorWriter struct {
+	gin.ResponseWriter
+	id string
+}
+
+func (w *errorWriter) Write(data []byte) (int, error) {
+	if w.Written() || w.Status() < http.StatusBadRequest || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		return w.ResponseWriter.Write(data)
+	}
+	body := bytes.TrimLeft(data, " \t\r\n")
+	if len(body) < 2 || body[0] != '{' {
+		return w.ResponseWriter.Write(data)
+	}
+	id, _ := json.Marshal(w.id)
+	field := append(append([]byte(`{"request_id":`), id...), ',')
+	if bytes.HasPrefix(bytes.TrimLeft(body[1:], " \t\r\n"), []byte("}")) {
+		field[len(field)-1] = '}'
+		body = bytes.TrimLeft(body[1:], " \t\r\n")
+	}
+	if _, err := w.ResponseWriter.Write(append(field, body[1:]...)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w *errorWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
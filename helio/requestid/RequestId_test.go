@@ -0,0 +1,96 @@
+package requestid
+
+import (
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/logging"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestErrorWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+		want    string
+	}{
+		{"json error", func(c *gin.Context) { c.JSON(http.StatusNotFound, gin.H{"error": "err not found"}) },
+			`{"request_id":"abc-123","error":"err not found"}`},
+		{"aborted", func(c *gin.Context) { c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "err forbidden"}) },
+			`{"request_id":"abc-123","error":"err forbidden"}`},
+		{"empty object", func(c *gin.Context) { c.JSON(http.StatusBadRequest, gin.H{}) }, `{"request_id":"abc-123"}`},
+		{"indented", func(c *gin.Context) { c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "err execute"}) },
+			"{\"request_id\":\"abc-123\",\n    \"error\": \"err execute\"\n}"},
+		{"indented empty object", func(c *gin.Context) {
+			c.Data(http.StatusConflict, "application/json; charset=utf-8", []byte(" {\n } "))
+		}, `{"request_id":"abc-123"} `},
+		{"success left alone", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"id": 1}) }, `{"id":1}`},
+		{"json array left alone", func(c *gin.Context) { c.JSON(http.StatusBadRequest, []string{"err"}) }, `["err"]`},
+		{"text left alone", func(c *gin.Context) { c.String(http.StatusNotFound, "{not json}") }, "{not json}"},
+		{"problem json left alone", func(c *gin.Context) {
+			c.Data(http.StatusBadRequest, "application/problem+json", []byte(`{"title":"bad"}`))
+		}, `{"title":"bad"}`},
+		// only the first write, holding the whole object, is changed
+		{"later writes left alone", func(c *gin.Context) {
+			c.Header("Content-Type", "application/json")
+			c.Status(http.StatusBadRequest)
+			_, _ = c.Writer.WriteString(`{"error":"err bad request"}`)
+			_, _ = c.Writer.WriteString("\n{}")
+		}, "{\"request_id\":\"abc-123\",\"error\":\"err bad request\"}\n{}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(Middleware())
+			r.GET("/", tt.handler)
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.Header.Set(Header, "abc-123")
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, request)
+			if got := recorder.Body.String(); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+			if got := recorder.Header().Get(Header); got != "abc-123" {
+				t.Errorf("%s = %q, want the incoming abc-123", Header, got)
+			}
+		})
+	}
+}
+
+func TestMiddlewareId(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		wantKept bool
+	}{
+		{"kept", "abc-123", true},
+		{"generated", "", false},
+		{"too long", strings.Repeat("a", 1000), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var id, logged string
+			r := gin.New()
+			r.Use(Middleware())
+			r.GET("/", func(c *gin.Context) {
+				id, logged = Get(c), logging.RequestId(c.Request.Context())
+				c.Status(http.StatusNoContent)
+			})
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				request.Header.Set(Header, tt.incoming)
+			}
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, request)
+			if id == "" || id != logged || recorder.Header().Get(Header) != id || (id == tt.incoming) != tt.wantKept {
+				t.Errorf("id = %q, logged with %q and echoed as %q, want the incoming %q kept %v", id, logged,
+					recorder.Header().Get(Header), tt.incoming, tt.wantKept)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package routes
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"mbcarruthers/helio/apikey"
+	"mbcarruthers/helio/dataservice/db"
+	"mbcarruthers/helio/model"
+	"net/http"
+)
+
+// ApiKeyRouteHandler manages the API keys issued to partners.
+type ApiKeyRouteHandler struct {
+	btrflydb   *db.DataStore
+	middleware *apikey.Middleware
+}
+
+// NewApiKeyRouteHandler constructs a new ApiKeyRouteHandler. Revoked keys are dropped from the middleware's cache.
+func NewApiKeyRouteHandler(bfdb *db.DataStore, middleware *apikey.Middleware) *ApiKeyRouteHandler {
+	return &ApiKeyRouteHandler{
+		btrflydb:   bfdb,
+		middleware: middleware,
+	}
+}
+
+// NewApiKeyHandler POST /apikeys
+// Issues a new API key. The key is only ever returned by this response, only its hash is stored.
+// Consumes - application/json {"name": "partner", "rate_per_second": 5, "burst": 20, "daily_quota": 10000}
+// Produces - application/json
+// Responses:
+// 201 - Successful operation. Returns the ApiKey along with the key
+// 400 - Invalid input
+// 500 - Internal database error
+func (a *ApiKeyRouteHandler) NewApiKeyHandler(c *gin.Context) {
+	var request model.ApiKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   err.Error(),
+			"message": "malformed request",
+		})
+		return
+	}
+	raw, hash, prefix, err := apikey.Generate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "err generating key",
+		})
+		return
+	}
+	key, err := a.btrflydb.InsertApiKey(model.ApiKey{
+		Name:          request.Name,
+		Prefix:        prefix,
+		RatePerSecond: request.RatePerSecond,
+		Burst:         request.Burst,
+		DailyQuota:    request.DailyQuota,
+	}, hash, c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   err.Error(),
+			"message": "error inserting api key",
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"key":     raw,
+		"api_key": key,
+	})
+}
+
+// ListApiKeyHandler GET /apikeys
+// Returns every API key issued, without the keys themselves.
+// Responses:
+// 200 - Successful operation
+// 500 - Internal database error
+func (a *ApiKeyRouteHandler) ListApiKeyHandler(c *gin.Context) {
+	keys, err := a.btrflydb.ListApiKeys(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeApiKeyHandler DELETE /apikeys/:id
+// Revokes an API key. Its usage is kept.
+// Responses:
+// 200 - Successful operation
+// 400 - Invalid id
+// 404 - API key not found or already revoked
+// 500 - Internal database error
+func (a *ApiKeyRouteHandler) RevokeApiKeyHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "err parsing: invalid id",
+		})
+		return
+	}
+	if err = a.btrflydb.RevokeApiKey(id, c.Request.Context()); errors.Is(err, db.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	a.middleware.Forget(id)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "api key revoked",
+	})
+}
+
+// UsageHandler GET /apikeys/:id/usage
+// Returns the number of requests made with an API key per day, most recent day first.
+// Usage is written every so often, the current day may lag behind by a few seconds.
+// Responses:
+// 200 - Successful operation
+// 400 - Invalid id
+// 500 - Internal database error
+func (a *ApiKeyRouteHandler) UsageHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "err parsing: invalid id",
+		})
+		return
+	}
+	usage, err := a.btrflydb.GetApiKeyUsage(id, c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
@@ -0,0 +1,146 @@
+package routes
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/helio/dataservice/db"
+	"mbcarruthers/helio/model"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// auditActions are the actions an AuditQuery may filter by.
+var auditActions = map[string]bool{
+	model.AuditCreate:  true,
+	model.AuditUpdate:  true,
+	model.AuditDelete:  true,
+	model.AuditMerge:   true,
+	model.AuditImport:  true,
+	model.AuditRestore: true,
+	model.AuditReview:  true,
+}
+
+// AuditRouteHandler manages reading and exporting the audit log.
+type AuditRouteHandler struct {
+	btrflydb *db.DataStore
+}
+
+// NewAuditRouteHandler constructs a new AuditRouteHandler.
+func NewAuditRouteHandler(bfdb *db.DataStore) *AuditRouteHandler {
+	return &AuditRouteHandler{
+		btrflydb: bfdb,
+	}
+}
+
+// bindAuditQuery binds and checks the audit filters of the request, responding with 400 if they are invalid.
+func bindAuditQuery(c *gin.Context) (model.AuditQuery, bool) {
+	var query model.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   err.Error(),
+			"message": "since and until are RFC 3339 timestamps, i.e. 2023-01-02T15:04:05Z",
+		})
+		return query, false
+	}
+	if query.Action != "" && !auditActions[query.Action] {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("unknown action %q", query.Action),
+		})
+		return query, false
+	}
+	if query.Limit < 0 || query.Offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "limit and offset must not be negative",
+		})
+		return query, false
+	}
+	return query, true
+}
+
+// ListAuditHandler GET /audit?entity_id=XXX&action=update&actor=...&request_id=...&since=...&until=...&limit=100&offset=0
+// Returns the entries of the audit log matching every filter given, newest first. since and until are RFC 3339 timestamps.
+// limit defaults to 100 and is at most 1000.
+// Produces - application/json
+// Responses:
+// 200 - Successful operation
+// 400 - Invalid filter
+// 500 - Internal database error
+func (a *AuditRouteHandler) ListAuditHandler(c *gin.Context) {
+	query, ok := bindAuditQuery(c)
+	if !ok {
+		return
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultAuditLimit
+	} else if query.Limit > maxAuditLimit {
+		query.Limit = maxAuditLimit
+	}
+	entries, err := a.btrflydb.SearchAudit(query, c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// ExportAuditHandler GET /audit/export?format=ndjson|csv
+// Exports every entry of the audit log matching the same filters as ListAuditHandler, newest first, as a download.
+// ndjson(default) writes an entry per line, csv writes the changes of an entry as a json column.
+// Produces - application/x-ndjson, text/csv
+// Responses:
+// 200 - Successful operation
+// 400 - Invalid filter or format
+// 500 - Internal database error
+func (a *AuditRouteHandler) ExportAuditHandler(c *gin.Context) {
+	format := c.DefaultQuery("format", "ndjson")
+	if format != "ndjson" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   fmt.Sprintf("unknown format %q", format),
+			"message": "format must be ndjson or csv",
+		})
+		return
+	}
+	query, ok := bindAuditQuery(c)
+	if !ok {
+		return
+	}
+	entries, err := a.btrflydb.SearchAudit(query, c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	filename := "audit-" + time.Now().UTC().Format("20060102T150405Z") + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		writer := csv.NewWriter(c.Writer)
+		_ = writer.Write([]string{"id", "at", "action", "entity_id", "actor", "request_id", "client_ip", "changes"})
+		for _, entry := range entries {
+			changes, _ := json.Marshal(entry.Changes)
+			_ = writer.Write([]string{strconv.FormatInt(entry.Id, 10), entry.At.UTC().Format(time.RFC3339Nano), entry.Action,
+				strconv.Itoa(entry.EntityId), entry.Actor, entry.RequestId, entry.ClientIp, string(changes)})
+		}
+		writer.Flush()
+		return
+	}
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+	for _, entry := range entries {
+		_ = encoder.Encode(entry)
+	}
+}
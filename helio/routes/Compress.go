@@ -0,0 +1,177 @@
+package routes
+
+import (
+	"compress/gzip"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"mbcarruthers/helio/media"
+	"net/http"
+	"sync"
+)
+
+const (
+	Zstd   = "zstd"
+	Brotli = "br"
+	Gzip   = "gzip"
+)
+
+// Encodings are the content codings responses are compressed with, fastest first.
+var Encodings = []string{Zstd, Brotli, Gzip}
+
+// encoder compresses a response, kept in a pool between responses.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoders pools the encoders of every encoding, they are costly to allocate and responses are many.
+var encoders = map[string]*sync.Pool{
+	Zstd: {New: func() any {
+		// Note: A single goroutine per encoder, responses are compressed concurrently already.
+		e, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return e
+	}},
+	Brotli: {New: func() any {
+		return brotli.NewWriterLevel(nil, 4) // brotli's default(11) is meant for static files, far too slow here
+	}},
+	Gzip: {New: func() any {
+		return gzip.NewWriter(nil)
+	}},
+}
+
+// Compression compresses responses with the encoding the Accept-Encoding header of a request prefers, of the ones
+// offered. Responses are held until they reach minSize, smaller ones are not worth compressing and go out as they are.
+type Compression struct {
+	encodings []string
+	minSize   int
+}
+
+// NewCompression constructs a new Compression offering encodings, of Encodings, in order of preference on a tie.
+// No encodings compresses nothing.
+func NewCompression(encodings []string, minSize int) (*Compression, error) {
+	for _, encoding := range encodings {
+		if _, ok := encoders[encoding]; !ok {
+			return nil, fmt.Errorf("err encoding %q, want one of %v", encoding, Encodings)
+		}
+	}
+	if minSize < 0 {
+		return nil, fmt.Errorf("err minimum size %d must not be negative", minSize)
+	}
+	return &Compression{encodings: encodings, minSize: minSize}, nil
+}
+
+// Middleware compresses the responses of every route. Streams are compressed as they are flushed.
+func (co *Compression) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(co.encodings) == 0 || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		addVary(c, "Accept-Encoding")
+		encoding, ok := media.Preferred(c.GetHeader("Accept-Encoding"), co.encodings...)
+		if !ok || c.GetHeader("Accept-Encoding") == "" {
+			c.Next()
+			return
+		}
+		w := &compressWriter{ResponseWriter: c.Writer, encoding: encoding, minSize: co.minSize}
+		c.Writer = w
+		defer w.close()
+		c.Next()
+	}
+}
+
+// compressWriter holds a response until it is known whether it is worth compressing, then writes it compressed or as
+// it is.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minSize  int
+	held     []byte
+	decided  bool
+	encoder  encoder // set once compressing
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+	w.held = append(w.held, data...)
+	if len(w.held) >= w.minSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written reports a response held as written, so that nothing writes another status along with it.
+func (w *compressWriter) Written() bool {
+	return len(w.held) > 0 || w.ResponseWriter.Written()
+}
+
+// WriteHeaderNow writes a response without a body as it is.
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Flush compresses whatever is held, a stream is worth compressing whatever its first flush holds.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(len(w.held) > 0)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide writes the response compressed from now on, when compress and the response can be, and writes what is held.
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	header := w.Header()
+	status := w.Status()
+	if compress && header.Get("Content-Encoding") == "" && status != http.StatusNoContent && status != http.StatusNotModified {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		w.encoder = encoders[w.encoding].Get().(encoder)
+		w.encoder.Reset(w.ResponseWriter)
+	}
+	held := w.held
+	w.held = nil
+	if len(held) == 0 {
+		return nil
+	}
+	if w.encoder != nil {
+		_, err := w.encoder.Write(held)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(held)
+	return err
+}
+
+// close writes what is still held, as it is since it stayed below minSize, or ends the compressed response.
+func (w *compressWriter) close() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+		w.encoder.Reset(nil)
+		encoders[w.encoding].Put(w.encoder)
+		w.encoder = nil
+	}
+}
@@ -0,0 +1,61 @@
+package routes
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/helio/media"
+	"mbcarruthers/helio/model"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// BenchmarkCompression answers the entities of data/monarch.json in every media type through the compression of the
+// routes, with every encoding and none, reporting the size of the responses.
+func BenchmarkCompression(b *testing.B) {
+	gin.SetMode(gin.ReleaseMode)
+	file, err := os.ReadFile("../data/monarch.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	entities := make([]model.Entity, 0)
+	if err = json.Unmarshal(file, &entities); err != nil {
+		b.Fatal(err)
+	}
+	compression, err := NewCompression(Encodings, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, mediaType := range media.Types {
+		router := gin.New()
+		router.Use(compression.Middleware())
+		router.GET("/entities/", func(c *gin.Context) {
+			data, err := media.MarshalEntities(mediaType, entities)
+			if err != nil {
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			c.Data(http.StatusOK, mediaType, data)
+		})
+		for _, encoding := range append([]string{"identity"}, Encodings...) {
+			b.Run(mediaType+"/"+encoding, func(b *testing.B) {
+				request := httptest.NewRequest(http.MethodGet, "/entities/", nil)
+				request.Header.Set("Accept-Encoding", encoding)
+				b.ReportAllocs()
+				var recorder *httptest.ResponseRecorder
+				for i := 0; i < b.N; i++ {
+					recorder = httptest.NewRecorder()
+					router.ServeHTTP(recorder, request)
+				}
+				if recorder.Code != http.StatusOK {
+					b.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+				}
+				if got := recorder.Header().Get("Content-Encoding"); encoding != "identity" && got != encoding {
+					b.Fatalf("Content-Encoding = %q, want %q", got, encoding)
+				}
+				b.ReportMetric(float64(recorder.Body.Len()), "bytes/response")
+			})
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/helio/media"
+	"mbcarruthers/helio/model"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DatasetVersions tells the version of the observations, implemented by db.DataStore.
+type DatasetVersions interface {
+	DatasetVersion(ctx context.Context) (model.DatasetVersion, error)
+}
+
+// Conditional lets browsers and the CDN revalidate lists, searches and aggregates rather than download them again.
+// Responses carry a weak ETag and the Last-Modified of the dataset version, which moves on with every mutation, and
+// a request whose If-None-Match or If-Modified-Since still holds answers 304 before anything is read.
+type Conditional struct {
+	versions DatasetVersions
+	maxAge   time.Duration
+}
+
+// NewConditional constructs a new Conditional. Callers seeing obscured coordinates may keep responses for maxAge, 0
+// revalidating every time. Trusted callers see true coordinates, so their responses are private and always revalidated.
+func NewConditional(versions DatasetVersions, maxAge time.Duration) *Conditional {
+	return &Conditional{versions: versions, maxAge: maxAge}
+}
+
+// Middleware sets the validators and Cache-Control of a GET route, put it after authentication so that trusted
+// callers are known. Without the dataset version the route answers without them.
+func (co *Conditional) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		version, err := co.versions.DatasetVersion(c.Request.Context())
+		if err != nil {
+			c.Next()
+			return
+		}
+		etag := weakETag(version, variant(c))
+		modified := version.ModifiedAt.UTC().Truncate(time.Second)
+		header := c.Writer.Header()
+		header.Set("ETag", etag)
+		header.Set("Last-Modified", modified.Format(http.TimeFormat))
+		header.Set("Cache-Control", co.cacheControl(c))
+		header.Add("Vary", "Accept, Authorization, Cookie")
+		if notModified(c.Request, etag, modified) {
+			c.AbortWithStatus(http.StatusNotModified)
+			return
+		}
+		c.Writer = &conditionalWriter{ResponseWriter: c.Writer}
+		c.Next()
+	}
+}
+
+// variant is what a response depends on besides its url and the dataset: whether the caller sees true coordinates,
+// and whether it is streamed or else the media type it is encoded as. json is left out, its ETags predate the others.
+func variant(c *gin.Context) string {
+	v := "public"
+	if trusted(c) {
+		v = "trusted"
+	}
+	if wantsStream(c) {
+		v += "-ndjson"
+	} else if mediaType := media.Negotiate(c.GetHeader("Accept")); mediaType != media.JSON {
+		v += "-" + strings.TrimPrefix(strings.TrimPrefix(mediaType, "application/"), "x-")
+	}
+	return v
+}
+
+// weakETag identifies the responses of a dataset version to callers of a variant. Responses are only semantically
+// equal, i.e. entities may come in another order or with other whitespace, hence weak.
+func weakETag(version model.DatasetVersion, variant string) string {
+	return fmt.Sprintf(`W/"%d-%x-%s"`, version.Version, version.ModifiedAt.UnixNano(), variant)
+}
+
+func (co *Conditional) cacheControl(c *gin.Context) string {
+	if trusted(c) {
+		return "private, no-cache"
+	} else if co.maxAge <= 0 {
+		return "public, no-cache"
+	}
+	return fmt.Sprintf("public, max-age=%d", int(co.maxAge.Seconds()))
+}
+
+// notModified reports whether the request already holds the response, by If-None-Match, or by If-Modified-Since when
+// it has no If-None-Match. ETags are compared weakly.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !modified.After(since)
+}
+
+// conditionalWriter drops the validators of a response that is not a 200, so that errors are neither revalidated nor
+// kept by caches.
+type conditionalWriter struct {
+	gin.ResponseWriter
+}
+
+func (w *conditionalWriter) WriteHeader(code int) {
+	if code != http.StatusOK && !w.Written() {
+		w.Header().Del("ETag")
+		w.Header().Del("Last-Modified")
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,215 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/helio/geoprivacy"
+	"mbcarruthers/helio/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// versions is a dataset at a fixed version, unreadable when err is set.
+type versions struct {
+	version model.DatasetVersion
+	err     error
+}
+
+func (v *versions) DatasetVersion(ctx context.Context) (model.DatasetVersion, error) {
+	return v.version, v.err
+}
+
+var modifiedAt = time.Date(2023, 5, 1, 12, 30, 15, 500, time.UTC)
+
+// newConditional returns a router answering GET /entities/ with 200, or status when the query holds one, behind
+// Conditional. The caller is trusted when the request has a X-Trusted header.
+func newConditional(v *versions, maxAge time.Duration) (*gin.Engine, *int) {
+	served := 0
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if c.GetHeader("X-Trusted") != "" {
+			c.Set(geoprivacy.TrustedKey, true)
+		}
+	})
+	r.Use(NewConditional(v, maxAge).Middleware())
+	r.GET("/entities/", func(c *gin.Context) {
+		served++
+		if c.Query("status") == "500" {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "err execute"})
+			return
+		}
+		c.JSON(http.StatusOK, []model.Entity{})
+	})
+	return r, &served
+}
+
+func TestConditionalValidators(t *testing.T) {
+	v := &versions{version: model.DatasetVersion{Version: 7, ModifiedAt: modifiedAt}}
+	r, _ := newConditional(v, time.Minute)
+	tests := []struct {
+		name             string
+		target           string
+		headers          map[string]string
+		wantETag         string
+		wantCacheControl string
+	}{
+		{"anonymous", "/entities/", nil, `W/"7-%x-public"`, "public, max-age=60"},
+		{"trusted", "/entities/", map[string]string{"X-Trusted": "1"}, `W/"7-%x-trusted"`, "private, no-cache"},
+		{"streamed", "/entities/?stream=true", nil, `W/"7-%x-public-ndjson"`, "public, max-age=60"},
+		{"ndjson accepted", "/entities/", map[string]string{"Accept": "application/x-ndjson"}, `W/"7-%x-public-ndjson"`,
+			"public, max-age=60"},
+		{"trusted msgpack", "/entities/", map[string]string{"X-Trusted": "1", "Accept": "application/msgpack"},
+			`W/"7-%x-trusted-msgpack"`, "private, no-cache"},
+		{"protobuf", "/entities/", map[string]string{"Accept": "application/x-protobuf"}, `W/"7-%x-public-protobuf"`,
+			"public, max-age=60"},
+		{"json", "/entities/", map[string]string{"Accept": "application/json"}, `W/"7-%x-public"`, "public, max-age=60"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			for name, value := range tt.headers {
+				request.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, request)
+			header := recorder.Header()
+			wantETag := fmt.Sprintf(tt.wantETag, modifiedAt.UnixNano())
+			if recorder.Code != http.StatusOK || header.Get("ETag") != wantETag {
+				t.Errorf("GET %s = %d with ETag %s, want 200 with %s", tt.target, recorder.Code, header.Get("ETag"), wantETag)
+			}
+			if got := header.Get("Cache-Control"); got != tt.wantCacheControl {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.wantCacheControl)
+			}
+			if got := header.Get("Last-Modified"); got != "Mon, 01 May 2023 12:30:15 GMT" {
+				t.Errorf("Last-Modified = %q, want the version truncated to the second", got)
+			}
+			if got := header.Values("Vary"); len(got) != 1 || got[0] != "Accept, Authorization, Cookie" {
+				t.Errorf("Vary = %q, want Accept, Authorization, Cookie", got)
+			}
+		})
+	}
+}
+
+func TestConditionalNotModified(t *testing.T) {
+	v := &versions{version: model.DatasetVersion{Version: 7, ModifiedAt: modifiedAt}}
+	public, trusted := weakETag(v.version, "public"), weakETag(v.version, "trusted")
+	stale := weakETag(model.DatasetVersion{Version: 6, ModifiedAt: modifiedAt.Add(-time.Hour)}, "public")
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"no validators", nil, http.StatusOK},
+		{"etag matches", map[string]string{"If-None-Match": public}, http.StatusNotModified},
+		{"strong form of the etag", map[string]string{"If-None-Match": strings.TrimPrefix(public, "W/")}, http.StatusNotModified},
+		{"among several", map[string]string{"If-None-Match": stale + ", " + public}, http.StatusNotModified},
+		{"any", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"stale etag", map[string]string{"If-None-Match": stale}, http.StatusOK},
+		{"etag of another variant", map[string]string{"If-None-Match": trusted}, http.StatusOK},
+		{"trusted etag of a trusted caller", map[string]string{"If-None-Match": trusted, "X-Trusted": "1"}, http.StatusNotModified},
+		{"public etag of a trusted caller", map[string]string{"If-None-Match": public, "X-Trusted": "1"}, http.StatusOK},
+		{"modified since", map[string]string{"If-Modified-Since": "Mon, 01 May 2023 12:00:00 GMT"}, http.StatusOK},
+		{"not modified since", map[string]string{"If-Modified-Since": "Mon, 01 May 2023 12:30:15 GMT"}, http.StatusNotModified},
+		{"not modified since later", map[string]string{"If-Modified-Since": "Tue, 02 May 2023 00:00:00 GMT"}, http.StatusNotModified},
+		{"invalid date", map[string]string{"If-Modified-Since": "yesterday"}, http.StatusOK},
+		// If-Modified-Since is ignored along with If-None-Match
+		{"stale etag, not modified since", map[string]string{"If-None-Match": stale,
+			"If-Modified-Since": "Tue, 02 May 2023 00:00:00 GMT"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, served := newConditional(v, 0)
+			request := httptest.NewRequest(http.MethodGet, "/entities/", nil)
+			for name, value := range tt.headers {
+				request.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, request)
+			if recorder.Code != tt.want {
+				t.Fatalf("GET /entities/ = %d, want %d", recorder.Code, tt.want)
+			}
+			if tt.want == http.StatusNotModified {
+				if *served != 0 || recorder.Body.Len() != 0 {
+					t.Errorf("handler ran %d times and answered %q, want 304 before anything is read", *served, recorder.Body.String())
+				}
+				if recorder.Header().Get("ETag") == "" || recorder.Header().Get("Cache-Control") == "" {
+					t.Errorf("304 headers = %v, want the ETag and Cache-Control", recorder.Header())
+				}
+			}
+		})
+	}
+}
+
+func TestConditionalCacheControl(t *testing.T) {
+	v := &versions{version: model.DatasetVersion{Version: 7, ModifiedAt: modifiedAt}}
+	tests := []struct {
+		name    string
+		maxAge  time.Duration
+		trusted bool
+		target  string
+		want    string
+	}{
+		{"public", 5 * time.Minute, false, "/entities/", "public, max-age=300"},
+		{"public revalidated", 0, false, "/entities/", "public, no-cache"},
+		{"trusted", 5 * time.Minute, true, "/entities/", "private, no-cache"},
+		{"error", 5 * time.Minute, false, "/entities/?status=500", "no-store"},
+		{"trusted error", 5 * time.Minute, true, "/entities/?status=500", "no-store"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := newConditional(v, tt.maxAge)
+			request := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.trusted {
+				request.Header.Set("X-Trusted", "1")
+			}
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, request)
+			if got := recorder.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.want)
+			}
+			if recorder.Code != http.StatusOK && (recorder.Header().Get("ETag") != "" || recorder.Header().Get("Last-Modified") != "") {
+				t.Errorf("error headers = %v, want no validators", recorder.Header())
+			}
+		})
+	}
+}
+
+func TestConditionalVersionUnreadable(t *testing.T) {
+	v := &versions{err: errors.New("err execute")}
+	r, served := newConditional(v, time.Minute)
+	request := httptest.NewRequest(http.MethodGet, "/entities/", nil)
+	request.Header.Set("If-None-Match", "*")
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK || *served != 1 || recorder.Header().Get("ETag") != "" || recorder.Header().Get("Cache-Control") != "" {
+		t.Errorf("GET /entities/ = %d with %v, want 200 answered without validators", recorder.Code, recorder.Header())
+	}
+}
+
+func TestWeakETag(t *testing.T) {
+	version := model.DatasetVersion{Version: 7, ModifiedAt: modifiedAt}
+	later := model.DatasetVersion{Version: 8, ModifiedAt: modifiedAt.Add(time.Second)}
+	// the same version restored, i.e. a database recreated, differs by its time
+	recreated := model.DatasetVersion{Version: 7, ModifiedAt: modifiedAt.Add(time.Nanosecond)}
+	etags := map[string]bool{}
+	for _, etag := range []string{
+		weakETag(version, "public"), weakETag(version, "trusted"), weakETag(version, "public-ndjson"),
+		weakETag(later, "public"), weakETag(recreated, "public"),
+	} {
+		if !strings.HasPrefix(etag, `W/"`) || !strings.HasSuffix(etag, `"`) {
+			t.Errorf("weakETag() = %s, want a weak ETag", etag)
+		}
+		if etags[etag] {
+			t.Errorf("weakETag() = %s twice, want every version and variant to differ", etag)
+		}
+		etags[etag] = true
+	}
+	if weakETag(version, "public") != weakETag(version, "public") {
+		t.Error("weakETag() of the same version and variant differ, want them equal")
+	}
+}
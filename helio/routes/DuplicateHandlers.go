@@ -0,0 +1,107 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/helio/audit"
+	"mbcarruthers/helio/dataservice/db"
+	"mbcarruthers/helio/model"
+	"mbcarruthers/helio/quality"
+	"mbcarruthers/helio/service"
+	"net/http"
+	"strconv"
+)
+
+// DuplicateRouteHandler manages the detection and merging of duplicate entities.
+type DuplicateRouteHandler struct {
+	btrflydb *db.DataStore
+	entities *service.Entities
+	detector *quality.DuplicateDetector
+}
+
+// NewDuplicateRouteHandler constructs a new DuplicateRouteHandler, merges go through entities.
+func NewDuplicateRouteHandler(bfdb *db.DataStore, entities *service.Entities, detector *quality.DuplicateDetector) *DuplicateRouteHandler {
+	return &DuplicateRouteHandler{
+		btrflydb: bfdb,
+		entities: entities,
+		detector: detector,
+	}
+}
+
+// ListDuplicatesHandler GET /entities/duplicates?min_score=0.8
+// Returns the candidate pairs of duplicate entities scored by spatial and temporal proximity and taxon, highest score first.
+// min_score is an optional number between 0 and 1 overriding the detector's minimum score.
+// Produces - application/json
+// Responses:
+// 200 - Successful operation
+// 400 - Invalid min_score
+// 500 - Internal database error
+func (d *DuplicateRouteHandler) ListDuplicatesHandler(c *gin.Context) {
+	detector := *d.detector
+	if minScore := c.Query("min_score"); minScore != "" {
+		score, err := strconv.ParseFloat(minScore, 64)
+		if err != nil || score < 0 || score > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "min_score must be a number between 0 and 1",
+			})
+			return
+		}
+		detector.MinScore = score
+	}
+	entities, err := d.btrflydb.ListAllEntities(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	pairs := detector.Candidates(entities)
+	if pairs == nil {
+		pairs = []quality.DuplicatePair{}
+	}
+	c.JSON(http.StatusOK, pairs)
+}
+
+// MergeHandler POST /entities/duplicates/merge
+// Keeps a single entity and links the duplicates to it. Merged entities are left out of searches.
+// Consumes - application/json {"keep": 105781710, "duplicates": [105781701]}
+// Produces - application/json
+// Responses:
+// 200 - Successful operation
+// 400 - Invalid input / The kept entity is one of the duplicates
+// 404 - The kept entity or one of the duplicates was not found
+// 500 - Internal database error
+func (d *DuplicateRouteHandler) MergeHandler(c *gin.Context) {
+	var merge model.MergeRequest
+	if err := c.ShouldBindJSON(&merge); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   err.Error(),
+			"message": "malformed request",
+		})
+		return
+	}
+	merged, err := d.entities.Merge(merge.Keep, merge.Duplicates, audit.CallerOf(c), c.Request.Context())
+	if errors.Is(err, service.ErrInvalidMerge) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   err.Error(),
+			"message": "the kept entity must not be one of the duplicates",
+		})
+		return
+	} else if errors.Is(err, db.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   err.Error(),
+			"message": "kept entity or duplicate not found",
+		})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   err.Error(),
+			"message": "error merging entities",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("merged %d observations into %d", len(merged), merge.Keep),
+	})
+}
@@ -2,44 +2,93 @@
 package routes
 
 import (
-	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+	"mbcarruthers/helio/audit"
+	"mbcarruthers/helio/auth"
 	"mbcarruthers/helio/dataservice/db"
+	"mbcarruthers/helio/geoprivacy"
 	"mbcarruthers/helio/model"
+	"mbcarruthers/helio/service"
+	"mbcarruthers/helio/validation"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 )
 
-//Note: Crud operations are more than likely not necessary except by an admin. Therefore they will be by
-// by authorization in the future. They really are not needed, I really wanted to as a means to get familiar
+//Note: Crud operations are more than likely not necessary except by an admin. Therefore mutating operations
+// require the curator(create, update) or admin(delete) role, see the auth package. They really are not needed, I really wanted to as a means to get familiar
 // with the jackc/pgx Postgres library  as well as weed out any issues I will encounter with the database layout and application layer.
 
 //Note: any mutating operations must include a body with the same identification number as within the url
 // as a means to make sure the correct information is being modified.
-// Todo: Write OpenAPI compliant documentation (https://ogen.dev/docs/intro/) <- try that.. anything but Swagger
+// Note: Every route is documented by openapi/openapi.json, a route added here is added there as well.
+
+const (
+	defaultPageSize = 100  // entities of a page of /entities/search when only after is given
+	maxPageSize     = 1000 // largest limit of /entities/search
+)
 
 // EntityRouteHandler struct manages routes surrounding a particular entity
 type EntityRouteHandler struct {
 	btrflydb *db.DataStore
+	entities *service.Entities
+	policy   geoprivacy.Policy
 }
 
 // NewEntityRouteHandler constructs a new EntityRouteHandler with a lepidoptera database (and until all functions are made to work with the database-a btrfly array)
-func NewEntityRouteHandler(bfdb *db.DataStore) *EntityRouteHandler {
-	entities := make([]model.Entity, 0)
-	file, _ := os.ReadFile("data/monarch.json")
-	_ = json.Unmarshal([]byte(file), &entities)
+// Entities are published according to the geoprivacy policy.
+// Note: data/monarch.json is imported once the database is reached, see service.Entities.ImportFile
+func NewEntityRouteHandler(bfdb *db.DataStore, entities *service.Entities, policy geoprivacy.Policy) *EntityRouteHandler {
+	handler := NewEntityRoutes(entities, policy)
+	handler.btrflydb = bfdb
+	return handler
+}
 
-	// Note: Comment Out / Uncomment following 3 lines if the database does/doesn't need to be created.It will just err & continue,however
-	if err := bfdb.CreateAndInsert(entities, context.Background()); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "CreateAndInsertError \n %+v\n", err)
-	}
+// NewEntityRoutes constructs an EntityRouteHandler serving the entity service as it is, without importing anything.
+// Used by tests serving an in-memory store, see client/heliotest.
+func NewEntityRoutes(entities *service.Entities, policy geoprivacy.Policy) *EntityRouteHandler {
 	return &EntityRouteHandler{
-		btrflydb: bfdb,
+		entities: entities,
+		policy:   policy,
+	}
+}
+
+// entityFailed responds to an error of the entity service with the matching status.
+func entityFailed(c *gin.Context, err error, message string) {
+	var verr *validation.Error
+	switch {
+	case errors.As(err, &verr):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      err.Error(),
+			"message":    "entity failed validation",
+			"violations": verr.Violations,
+		})
+	case errors.Is(err, service.ErrInvalidGeoprivacy):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   err.Error(),
+			"message": "geoprivacy must be open, obscured, private or empty",
+		})
+	case errors.Is(err, db.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, service.ErrExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   err.Error(),
+			"message": "entity exists",
+		})
+	case errors.Is(err, db.ErrUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   err.Error(),
+			"message": "the database is unavailable, try again later",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   err.Error(),
+			"message": message,
+		})
 	}
 }
 
@@ -48,7 +97,7 @@ func NewEntityRouteHandler(bfdb *db.DataStore) *EntityRouteHandler {
 // Produces and Consumes - application/json
 // Responses:
 // 200 - Successful Operation
-// 400 - Invalid Input
+// 400 - Invalid Input / Entity failed validation(strict mode)
 // 500 - Error inserting Entity into database
 func (e *EntityRouteHandler) NewEntityHandler(c *gin.Context) {
 	var btrfly model.Entity
@@ -59,16 +108,12 @@ func (e *EntityRouteHandler) NewEntityHandler(c *gin.Context) {
 		})
 		return
 	} else {
-		btrfly.Id = 555555                                                              // Note: Represents a dummy value. Will not be allowed once this is in authorized & a permanent database.
-		btrfly.Uuid, _ = uuid.FromBytes([]byte("00000000-0000-0000-0000-000000000000")) // Note: It will be created anyway by crdb may as well make it concrete.
-		if err := e.btrflydb.InsertNewEntity(btrfly, context.Background()); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   err.Error(),
-				"message": "error inserting element",
-			})
+		created, err := e.entities.Create(btrfly, audit.CallerOf(c), c.Request.Context())
+		if err != nil {
+			entityFailed(c, err, "error inserting element")
 			return
 		}
-		c.JSON(http.StatusOK, btrfly)
+		c.JSON(http.StatusOK, created)
 	}
 }
 
@@ -79,6 +124,8 @@ func (e *EntityRouteHandler) NewEntityHandler(c *gin.Context) {
 // 200 - Successful Operation
 // 400 - Invalid input
 // 404 - Entity Not Found
+// 500 - Internal Database Error
+// 503 - The database is unavailable
 func (e *EntityRouteHandler) GetEntityById(c *gin.Context) {
 	id, err := strconv.Atoi(strings.ReplaceAll(c.Param("id"), " ", "")) // remove any spaces left by accident
 	if err != nil {
@@ -88,30 +135,66 @@ func (e *EntityRouteHandler) GetEntityById(c *gin.Context) {
 		})
 		return
 	}
-	entity, err := e.btrflydb.GetEntityById(id, context.Background())
+	entity, err := e.entities.Get(id, c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": err.Error(),
-		})
+		entityFailed(c, err, "error reading the entity")
 		return
 	}
-	c.JSON(http.StatusOK, entity)
+	respondEntity(c, e.policy, http.StatusOK, entity)
 }
 
-// ListEntityHandler GET /entities
-// Returns a collection of Entities within the database.
-// Produces and Consumes - application/json
+// ListEntityHandler GET /entities?all=true
+// Returns the published Entities within the database, rejected entities and entities merged into another are left
+// out the same as a search without filters. Curators may ask for every entity with all=true.
+// Produces and Consumes - application/json, or application/x-ndjson streamed when asked for, see streamEntities
 // Responses:
-// 200 - Successful operation. Returns all entities within the database.
+// 200 - Successful operation. Returns the published entities, or all entities within the database.
+// 400 - Invalid all
+// 401 - all=true without authentication
+// 403 - all=true without the curator role
 // 500 - Internal Database Error
 func (e *EntityRouteHandler) ListEntityHandler(c *gin.Context) {
-	if entities, err := e.btrflydb.ListAllEntities(context.Background()); err != nil {
+	all, err := strconv.ParseBool(c.DefaultQuery("all", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "err parsing: all must be true or false",
+		})
+		return
+	}
+	if identity, ok := auth.GetIdentity(c); all && !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "err unauthorized",
+			"message": "authentication required",
+		})
+		return
+	} else if all && !identity.Role.Allows(auth.Curator) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "err forbidden",
+			"message": "requires the curator role",
+		})
+		return
+	}
+	query := &model.SearchQuery{} // the defaults of a search leave rejected and merged entities out
+	if all {
+		query = nil
+	}
+	if wantsStream(c) {
+		e.streamEntities(c, query)
+		return
+	}
+	var entities []model.Entity
+	if query == nil {
+		entities, err = e.entities.List(c.Request.Context())
+	} else {
+		entities, err = e.entities.Search(*query, c.Request.Context())
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
 		return
 	} else {
-		c.JSON(http.StatusOK, entities)
+		respondEntities(c, e.policy, http.StatusOK, entities)
 		return
 	}
 }
@@ -123,7 +206,8 @@ func (e *EntityRouteHandler) ListEntityHandler(c *gin.Context) {
 // Produces and Consumes - application/json
 // Returns:
 // 200 - Successful operation. Returns update Entity
-// 400 - Invalid Input
+// 400 - Invalid Input / Entity failed validation(strict mode)
+// 404 - Entity Not Found
 // 500 - Internal database error
 func (e *EntityRouteHandler) UpdateEntityHandler(c *gin.Context) {
 	id, err := strconv.Atoi(strings.ReplaceAll(c.Param("id"), " ", "")) // remove any spaces left by accident
@@ -142,11 +226,9 @@ func (e *EntityRouteHandler) UpdateEntityHandler(c *gin.Context) {
 		return
 	}
 
-	if err := e.btrflydb.UpdateEntityById(id, btrfly, context.Background()); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   err.Error(),
-			"message": "error updating",
-		})
+	// taxon_id is not updated, the entity is validated against the stored one
+	if _, err := e.entities.Update(id, btrfly, audit.CallerOf(c), c.Request.Context()); err != nil {
+		entityFailed(c, err, "error updating")
 		return
 	} else {
 		c.JSON(http.StatusOK, gin.H{
@@ -162,6 +244,7 @@ func (e *EntityRouteHandler) UpdateEntityHandler(c *gin.Context) {
 // responses:
 // 200 - Successful Operation
 // 400 - Invalid input / No Request body(todo:Remove that condition and change function signature of crdb function to just an integer)
+// 404 - Entity Not Found
 // 500 - Database error
 func (e *EntityRouteHandler) DeleteEntityHandler(c *gin.Context) {
 	id, err := strconv.Atoi(strings.ReplaceAll(c.Param("id"), " ", "")) // in case any space is accidentally left in postman
@@ -172,10 +255,9 @@ func (e *EntityRouteHandler) DeleteEntityHandler(c *gin.Context) {
 		})
 		return
 	}
-	if err = e.btrflydb.DeleteEntityById(id, context.Background()); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+	// the deleted entity is kept within the audit log, see RestoreEntityHandler
+	if err = e.entities.Delete(id, audit.CallerOf(c), c.Request.Context()); err != nil {
+		entityFailed(c, err, "error deleting")
 		return
 	} else {
 		c.JSON(http.StatusOK, gin.H{
@@ -185,6 +267,31 @@ func (e *EntityRouteHandler) DeleteEntityHandler(c *gin.Context) {
 	}
 }
 
+// RestoreEntityHandler POST /entities/:id/restore
+// Restores a deleted Entity as it was when it was last deleted, taken from the audit log.
+// Produces - application/json
+// Responses:
+// 200 - Successful operation. Returns the restored Entity
+// 400 - Invalid input / Entity failed validation(strict mode)
+// 404 - The Entity was never deleted
+// 409 - The Entity exists
+// 500 - Internal database error
+func (e *EntityRouteHandler) RestoreEntityHandler(c *gin.Context) {
+	id, err := strconv.Atoi(strings.ReplaceAll(c.Param("id"), " ", ""))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "err parsing: invalid syntax",
+		})
+		return
+	}
+	restored, err := e.entities.Restore(id, audit.CallerOf(c), c.Request.Context())
+	if err != nil {
+		entityFailed(c, err, "error restoring element")
+		return
+	}
+	respondEntity(c, e.policy, http.StatusOK, restored)
+}
+
 // hope to be Route /entities/search?taxon_id=XXX&date1=yyyy-mm-dd&date2=yyyy-mm-dd
 // TestEntitySearchHandler is a temporary name for a handler that searches for two dates or a taxon_id
 // Note: Dear god. It works fine but don't use it anymore.
@@ -201,28 +308,28 @@ func (e *EntityRouteHandler) SearchEntityTaxonIdAndDateRange(c *gin.Context) { /
 	if queryEntity.Date1.Valid && queryEntity.Date2.Valid {
 		// if there is a taxonId
 		if queryEntity.TaxonId != 0 {
-			if entities, err := e.btrflydb.GetEntitiesByTaxonIdWithinDateRange(queryEntity.TaxonId, queryEntity.Date1, queryEntity.Date2, context.Background()); err != nil {
+			if entities, err := e.btrflydb.GetEntitiesByTaxonIdWithinDateRange(queryEntity.TaxonId, queryEntity.Date1, queryEntity.Date2, c.Request.Context()); err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // return as error if the query falls through
 				return
 			} else {
-				c.JSON(http.StatusOK, entities) // return entities by taxon_id within date range if not
+				respondEntities(c, e.policy, http.StatusOK, entities) // return entities by taxon_id within date range if not
 				return
 			}
 		} else {
-			entities, err := e.btrflydb.GetEntitiesWithinRange(queryEntity.Date1, queryEntity.Date2, context.Background())
+			entities, err := e.btrflydb.GetEntitiesWithinRange(queryEntity.Date1, queryEntity.Date2, c.Request.Context())
 			if err != nil {
 				// if any entities(regardless of taxon) cannot be found within a range
 				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "message": "could not get items within range"})
 				return
 			} else {
 				// return entities(regardless of taxon_id) of that particular range
-				c.JSON(http.StatusOK, entities)
+				respondEntities(c, e.policy, http.StatusOK, entities)
 				return
 			}
 		}
 	}
 	if queryEntity.TaxonId != 0 {
-		entities, err := e.btrflydb.GetEntitiesByTaxonId(queryEntity.TaxonId, context.Background())
+		entities, err := e.btrflydb.GetEntitiesByTaxonId(queryEntity.TaxonId, c.Request.Context())
 		if err != nil {
 			// returns not found(404) if the data cannot be found
 			c.JSON(http.StatusNotFound, gin.H{
@@ -231,17 +338,24 @@ func (e *EntityRouteHandler) SearchEntityTaxonIdAndDateRange(c *gin.Context) { /
 			return
 		} else {
 			// returns entities by taxon_id on a successful operation
-			c.JSON(http.StatusOK, entities)
+			respondEntities(c, e.policy, http.StatusOK, entities)
 			return
 		}
 	}
 }
 
 // SearchEntitiesWithinDateRange - search entities within a given date range.
-// Route /entities/search?date1=yyyy-mm-dd&date2=yyyy-mm-dd
+// Route /entities/search?date1=yyyy-mm-dd&date2=yyyy-mm-dd&taxon_id=XXX&quality_grade=clean,accepted
+// Every parameter is optional. Rejected entities are left out unless asked for with quality_grade.
+// limit and after page through the search, the Link header holds the url of the next page(rel="next") until the last one.
+// An unpaged search is streamed as application/x-ndjson when asked for, see streamEntities.
 func (e *EntityRouteHandler) SearchEntitiesWithinDateRange(c *gin.Context) {
-	var dateQuery model.DateQuery
-	err := c.ShouldBindQuery(&dateQuery)
+	var searchQuery model.SearchQuery
+	var page model.PageQuery
+	err := c.ShouldBindQuery(&searchQuery)
+	if err == nil {
+		err = c.ShouldBindQuery(&page)
+	}
 	if err != nil {
 		// if there is an error in formatting
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -249,13 +363,56 @@ func (e *EntityRouteHandler) SearchEntitiesWithinDateRange(c *gin.Context) {
 		})
 		return
 	}
-	// get Entities Within Date range
-	if entities, err := e.btrflydb.GetEntitiesWithinRange(dateQuery.Date1, dateQuery.Date2, context.Background()); err != nil {
+	if page.Limit != 0 || page.After != "" {
+		e.searchPage(c, searchQuery, page)
+		return
+	} else if wantsStream(c) {
+		e.streamEntities(c, &searchQuery)
+		return
+	}
+	// get Entities matching the search
+	if entities, err := e.entities.Search(searchQuery, c.Request.Context()); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"err": err.Error(),
 		})
 		return
 	} else {
-		c.JSON(http.StatusOK, entities)
+		respondEntities(c, e.policy, http.StatusOK, entities)
+	}
+}
+
+// searchPage responds with a page of the search, linking to the next one.
+func (e *EntityRouteHandler) searchPage(c *gin.Context, searchQuery model.SearchQuery, page model.PageQuery) {
+	if page.Limit < 0 || page.Limit > maxPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("limit must be between 1 and %d", maxPageSize),
+		})
+		return
+	} else if page.Limit == 0 {
+		page.Limit = defaultPageSize
+	}
+	cursor, err := model.ParseCursor(page.After)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   err.Error(),
+			"message": "after must be the cursor of a previous page",
+		})
+		return
+	}
+	entities, next, err := e.entities.SearchPage(searchQuery, cursor, page.Limit, c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	if next != nil {
+		link := *c.Request.URL
+		values := link.Query()
+		values.Set("limit", strconv.Itoa(page.Limit))
+		values.Set("after", next.String())
+		link.RawQuery = values.Encode()
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="next"`, link.RequestURI()))
 	}
+	respondEntities(c, e.policy, http.StatusOK, entities)
 }
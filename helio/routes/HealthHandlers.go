@@ -0,0 +1,127 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"math"
+	"mbcarruthers/health"
+	"mbcarruthers/helio/dataservice/db"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// HealthRouteHandler tells whether helio is alive and able to serve requests, which depends on the database and on
+// whether helio is shutting down.
+type HealthRouteHandler struct {
+	btrflydb *db.DataStore
+	checker  *health.Checker
+	draining atomic.Bool
+}
+
+// NewHealthRouteHandler constructs a new HealthRouteHandler, the database being its one dependency.
+func NewHealthRouteHandler(bfdb *db.DataStore) *HealthRouteHandler {
+	h := &HealthRouteHandler{
+		btrflydb: bfdb,
+		checker:  health.NewChecker("helio", 2*time.Second),
+	}
+	h.checker.Add("database", h.pingDatabase)
+	return h
+}
+
+// pingDatabase is the check of the database. It is not pinged while the circuit breaker is open, so that probes do
+// not hold back its cooldown, and the errors do not tell where the database is.
+func (h *HealthRouteHandler) pingDatabase(ctx context.Context) error {
+	if h.btrflydb.Breaker().RetryAfter() > 0 {
+		return db.ErrUnavailable
+	}
+	if err := h.btrflydb.Conn.Ping(ctx); db.TimedOut(err) {
+		return errors.New("err timeout")
+	} else if err != nil {
+		return errors.New("err unreachable")
+	}
+	return nil
+}
+
+// Drain fails readiness from now on, as helio is shutting down.
+func (h *HealthRouteHandler) Drain() {
+	h.draining.Store(true)
+}
+
+// setRetryAfter sets Retry-After in whole seconds, at least 1.
+func setRetryAfter(c *gin.Context, retryAfter time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(retryAfter.Seconds())))))
+}
+
+// unavailable responds with 503 and a Retry-After.
+func unavailable(c *gin.Context, retryAfter time.Duration) {
+	setRetryAfter(c, retryAfter)
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"error":   db.ErrUnavailable.Error(),
+		"message": "the database is unavailable, retry after Retry-After seconds",
+	})
+}
+
+// Available refuses requests with 503 while the database circuit breaker is open, rather than letting each of them
+// fail on its own. Put it in front of every route using the database.
+func (h *HealthRouteHandler) Available() gin.HandlerFunc {
+	breaker := h.btrflydb.Breaker()
+	return func(c *gin.Context) {
+		if retryAfter := breaker.RetryAfter(); retryAfter > 0 {
+			unavailable(c, retryAfter)
+			return
+		}
+		c.Next()
+	}
+}
+
+// HealthzHandler GET /healthz
+// Liveness, helio answers as long as it serves HTTP whatever the state of the database.
+// Responses: 200 - alive
+func (h *HealthRouteHandler) HealthzHandler(c *gin.Context) {
+	h.checker.HealthzHandler(c.Writer, c.Request)
+}
+
+// ReadyHandler GET /readyz
+// Reports whether helio is ready to serve requests, i.e. whether the database answers a ping and helio is not
+// shutting down. The database is not pinged while its circuit breaker is open, a ping reaching it closes the breaker.
+// Responses: 200 - ready
+// 503 - helio is draining, or the database is unavailable, retry after Retry-After seconds
+func (h *HealthRouteHandler) ReadyHandler(c *gin.Context) {
+	breaker := h.btrflydb.Breaker()
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":   "draining",
+			"database": breaker.State(),
+		})
+		return
+	}
+	dependencies := h.checker.Check(c.Request.Context())
+	if status := health.Readiness(dependencies); status != health.Ready {
+		setRetryAfter(c, breaker.RetryAfter())
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":       status,
+			"database":     breaker.State(),
+			"dependencies": dependencies,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":       health.Ready,
+		"database":     breaker.State(),
+		"dependencies": dependencies,
+	})
+}
+
+// StatusHandler GET /status
+// Returns the version, commit and uptime of helio along with the latency of the database, as a page to browsers.
+// Responses: 200 - the status, whether or not helio is ready
+func (h *HealthRouteHandler) StatusHandler(c *gin.Context) {
+	status := h.checker.Status(c.Request.Context())
+	if h.draining.Load() {
+		status.Status = "draining"
+	}
+	health.WriteStatus(c.Writer, c.Request, status)
+}
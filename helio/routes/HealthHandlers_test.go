@@ -0,0 +1,83 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/health"
+	"mbcarruthers/helio/dataservice/db"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadyHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		cooldown       time.Duration // of the breaker, open until the database is first reached
+		draining       bool
+		want           string
+		wantDatabase   db.State
+		wantError      string // of the database dependency
+		wantRetryAfter string
+	}{
+		{"breaker open", time.Minute, false, health.Unavailable, db.Open, db.ErrUnavailable.Error(), "60"},
+		// the ping fails to reach the database, opening the breaker again
+		{"database unreachable", time.Millisecond, false, health.Unavailable, db.Open, "err unreachable", "1"},
+		{"draining", time.Minute, true, "draining", db.Open, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// nothing listens on port 1, the pool connects on the first statement
+			store := db.NewDataStore("postgresql://helio@127.0.0.1:1/helio?connect_timeout=1", 0, db.NewBreaker(1, tt.cooldown, tt.cooldown))
+			defer store.Close(context.Background())
+			h := NewHealthRouteHandler(store)
+			if tt.draining {
+				h.Drain()
+			}
+			time.Sleep(2 * time.Millisecond) // a cooldown of a millisecond is over
+
+			r := gin.New()
+			r.GET("/readyz", h.ReadyHandler)
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			var body struct {
+				Status       string              `json:"status"`
+				Database     db.State            `json:"database"`
+				Dependencies []health.Dependency `json:"dependencies"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if recorder.Code != http.StatusServiceUnavailable || body.Status != tt.want || body.Database != tt.wantDatabase {
+				t.Errorf("GET /readyz = %d %s, want 503 %s with the breaker %s", recorder.Code, recorder.Body.String(), tt.want, tt.wantDatabase)
+			}
+			if got := recorder.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+			if tt.wantError == "" {
+				if len(body.Dependencies) != 0 {
+					t.Errorf("dependencies = %+v, want none checked while draining", body.Dependencies)
+				}
+				return
+			}
+			if len(body.Dependencies) != 1 || body.Dependencies[0].Status != health.Down || body.Dependencies[0].Error != tt.wantError {
+				t.Errorf("dependencies = %+v, want the database down with %q", body.Dependencies, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	store := db.NewDataStore("postgresql://helio@127.0.0.1:1/helio", 0, db.NewBreaker(1, time.Minute, time.Minute))
+	defer store.Close(context.Background())
+	h := NewHealthRouteHandler(store)
+	r := gin.New()
+	r.GET("/entities/", h.Available(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/entities/", nil))
+	if recorder.Code != http.StatusServiceUnavailable || recorder.Header().Get("Retry-After") != "60" {
+		t.Errorf("GET /entities/ = %d with Retry-After %q, want 503 with 60", recorder.Code, recorder.Header().Get("Retry-After"))
+	}
+}
@@ -0,0 +1,79 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"mbcarruthers/helio/geoprivacy"
+	"mbcarruthers/helio/media"
+	"mbcarruthers/helio/model"
+	"net/http"
+	"strings"
+)
+
+// Note: Every response carrying entities goes through respondEntities/respondEntity so that sensitive coordinates
+// never reach the public, whatever the format of the response.
+
+// trusted reports whether the caller may see true coordinates.
+func trusted(c *gin.Context) bool {
+	return c.GetBool(geoprivacy.TrustedKey)
+}
+
+// respondEntities writes the entities as the media type the caller accepts, see media.Negotiate, generalizing the
+// coordinates of sensitive entities unless the caller is trusted.
+func respondEntities(c *gin.Context, policy geoprivacy.Policy, status int, entities []model.Entity) {
+	if !trusted(c) {
+		entities = policy.PublicAll(entities)
+	}
+	mediaType := negotiate(c)
+	if mediaType == media.JSON {
+		c.JSON(status, entities)
+		return
+	}
+	data, err := media.MarshalEntities(mediaType, entities)
+	respondEncoded(c, status, mediaType, data, err)
+}
+
+// respondEntity writes a single entity the same way respondEntities does.
+func respondEntity(c *gin.Context, policy geoprivacy.Policy, status int, entity model.Entity) {
+	if !trusted(c) {
+		entity = policy.Public(entity)
+	}
+	mediaType := negotiate(c)
+	if mediaType == media.JSON {
+		c.JSON(status, entity)
+		return
+	}
+	data, err := media.MarshalEntity(mediaType, entity)
+	respondEncoded(c, status, mediaType, data, err)
+}
+
+// negotiate returns the media type entities are written as, adding Accept to Vary.
+func negotiate(c *gin.Context) string {
+	addVary(c, "Accept")
+	return media.Negotiate(c.GetHeader("Accept"))
+}
+
+// respondEncoded writes entities encoded as mediaType. Errors are still answered as json.
+func respondEncoded(c *gin.Context, status int, mediaType string, data []byte, err error) {
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error encoding entities", "media_type", mediaType, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "err encoding",
+		})
+		return
+	}
+	c.Data(status, mediaType, data)
+}
+
+// addVary adds field to the Vary header of the response, unless it is there already.
+func addVary(c *gin.Context, field string) {
+	header := c.Writer.Header()
+	for _, value := range header.Values("Vary") {
+		for _, existing := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), field) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", field)
+}
@@ -0,0 +1,67 @@
+package routes
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/helio/geoprivacy"
+	"mbcarruthers/helio/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestRespondEntitiesGeoprivacy(t *testing.T) {
+	policy := geoprivacy.Policy{
+		Default:  model.GeoprivacyOpen,
+		Taxa:     map[int]string{48662: model.GeoprivacyObscured},
+		CellSize: 0.2,
+	}
+	entity := func(geoprivacy string) model.Entity {
+		return model.Entity{Id: 1, TaxonId: 48662, Latitude: "29.9318", Longitude: "-84.3397", Geoprivacy: geoprivacy}
+	}
+	tests := []struct {
+		name    string
+		entity  model.Entity
+		trusted bool
+		wantLat string
+	}{
+		{"open", entity(model.GeoprivacyOpen), false, "29.9318"},
+		{"obscured", entity(""), false, "29.9000"},
+		{"private", entity(model.GeoprivacyPrivate), false, ""},
+		{"obscured to a trusted caller", entity(""), true, "29.9318"},
+		{"private to a trusted caller", entity(model.GeoprivacyPrivate), true, "29.9318"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, list := range []bool{false, true} {
+				recorder := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(recorder)
+				c.Request = httptest.NewRequest(http.MethodGet, "/entities/", nil)
+				if tt.trusted {
+					c.Set(geoprivacy.TrustedKey, true)
+				}
+				var got model.Entity
+				if list {
+					respondEntities(c, policy, http.StatusOK, []model.Entity{tt.entity})
+					var entities []model.Entity
+					if err := json.Unmarshal(recorder.Body.Bytes(), &entities); err != nil || len(entities) != 1 {
+						t.Fatalf("respondEntities() = %s, want a single entity", recorder.Body.String())
+					}
+					got = entities[0]
+				} else {
+					respondEntity(c, policy, http.StatusOK, tt.entity)
+					if err := json.Unmarshal(recorder.Body.Bytes(), &got); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if got.Latitude != tt.wantLat {
+					t.Errorf("latitude of the response(list %v) = %q, want %q", list, got.Latitude, tt.wantLat)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/helio/audit"
+	"mbcarruthers/helio/dataservice/db"
+	"mbcarruthers/helio/geoprivacy"
+	"mbcarruthers/helio/model"
+	"mbcarruthers/helio/quality"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ReviewRouteHandler manages the curator review queue of flagged entities.
+type ReviewRouteHandler struct {
+	btrflydb *db.DataStore
+	analyzer *quality.Analyzer
+	policy   geoprivacy.Policy
+}
+
+// NewReviewRouteHandler constructs a new ReviewRouteHandler. Accepted and rejected entities are recorded to the audit log.
+func NewReviewRouteHandler(bfdb *db.DataStore, analyzer *quality.Analyzer, policy geoprivacy.Policy) *ReviewRouteHandler {
+	return &ReviewRouteHandler{
+		btrflydb: bfdb,
+		analyzer: analyzer,
+		policy:   policy,
+	}
+}
+
+// RunAnalysis runs the quality analysis pass over every entity within observations.fl_lepidoptera and stores the result.
+func (r *ReviewRouteHandler) RunAnalysis(ctx context.Context) (quality.Report, error) {
+	entities, err := r.btrflydb.ListAllEntities(ctx)
+	if err != nil {
+		return quality.Report{}, err
+	}
+	analyzed, report := r.analyzer.Analyze(entities)
+	if err = r.btrflydb.SaveQuality(analyzed, ctx); err != nil {
+		return quality.Report{}, err
+	}
+	return report, nil
+}
+
+// AnalyzeHandler POST /review/analyze
+// Recomputes the quality flags of every entity. Grades set by a curator are kept.
+// Produces - application/json
+// Responses:
+// 200 - Successful operation. Returns a report of the flags found
+// 500 - Internal database error
+func (r *ReviewRouteHandler) AnalyzeHandler(c *gin.Context) {
+	report, err := r.RunAnalysis(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   err.Error(),
+			"message": "error analyzing entities",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ListReviewHandler GET /review?quality_grade=flagged&quality_flag=outside_region
+// Returns the entities in the review queue. Without a quality_grade the flagged(unreviewed) entities are returned.
+// Produces - application/json
+// Responses:
+// 200 - Successful operation
+// 400 - Invalid quality grade
+// 500 - Internal database error
+func (r *ReviewRouteHandler) ListReviewHandler(c *gin.Context) {
+	var query model.SearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if len(query.QualityGrade) == 0 {
+		query.QualityGrade = []string{model.GradeFlagged}
+	}
+	for _, grade := range strings.Split(strings.Join(query.QualityGrade, ","), ",") {
+		if !model.ValidGrade(grade) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("unknown quality grade %q", grade),
+			})
+			return
+		}
+	}
+	entities, err := r.btrflydb.SearchEntities(query, c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	respondEntities(c, r.policy, http.StatusOK, entities)
+}
+
+// AcceptHandler POST /review/:id/accept
+// Marks a flagged entity as accepted by a curator.
+// Responses:
+// 200 - Successful operation
+// 400 - Invalid input
+// 404 - Entity Not Found
+// 500 - Internal database error
+func (r *ReviewRouteHandler) AcceptHandler(c *gin.Context) {
+	r.setGrade(c, model.GradeAccepted)
+}
+
+// RejectHandler POST /review/:id/reject
+// Marks an entity as rejected by a curator, rejected entities are left out of published searches.
+// Responses:
+// 200 - Successful operation
+// 400 - Invalid input
+// 404 - Entity Not Found
+// 500 - Internal database error
+func (r *ReviewRouteHandler) RejectHandler(c *gin.Context) {
+	r.setGrade(c, model.GradeRejected)
+}
+
+func (r *ReviewRouteHandler) setGrade(c *gin.Context, grade string) {
+	id, err := strconv.Atoi(strings.ReplaceAll(c.Param("id"), " ", ""))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "err parsing: invalid syntax",
+		})
+		return
+	}
+	existing, err := r.btrflydb.GetEntityById(id, c.Request.Context())
+	if err != nil {
+		entityFailed(c, err, "error reading the entity")
+		return
+	}
+	graded := existing
+	graded.QualityGrade = grade
+	entry := audit.Entry(c, model.AuditReview, id, &existing, &graded)
+	if err = r.btrflydb.SetQualityGrade(id, grade, entry, c.Request.Context()); errors.Is(err, db.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("observation %d %s", id, grade),
+	})
+}
@@ -0,0 +1,69 @@
+package routes
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/helio/media"
+	"mbcarruthers/helio/model"
+	"mbcarruthers/helio/requestid"
+	"net/http"
+	"strconv"
+)
+
+const (
+	ndjson     = "application/x-ndjson"
+	flushEvery = 100 // entities written between flushes of a stream
+)
+
+// wantsStream reports whether the caller asked for entities one json object per line, with stream=true or an Accept
+// preferring application/x-ndjson to application/json.
+func wantsStream(c *gin.Context) bool {
+	if stream, err := strconv.ParseBool(c.Query("stream")); err == nil && stream {
+		return true
+	}
+	accept := c.GetHeader("Accept")
+	return media.Quality(accept, ndjson) > media.Quality(accept, media.JSON)
+}
+
+// streamEntities writes the entities of the query, every entity with a nil one, as ndjson while they are read from the
+// database. Nothing is held but the entity being written: writing blocks once the client stops reading, which stops
+// the rows being read in turn. Sensitive coordinates are generalized unless the caller is trusted.
+// The status is only written along with the first entity, so a query failing right away still answers 500. A stream
+// failing later can only end with a line of its own, the one line holding an error key.
+func (e *EntityRouteHandler) streamEntities(c *gin.Context, query *model.SearchQuery) {
+	public := !trusted(c)
+	encoder := json.NewEncoder(c.Writer)
+	written := 0
+	err := e.entities.Stream(query, func(entity model.Entity) error {
+		if written == 0 {
+			c.Header("Content-Type", ndjson)
+			c.Status(http.StatusOK)
+		}
+		if public {
+			entity = e.policy.Public(entity)
+		}
+		if err := encoder.Encode(entity); err != nil {
+			return err // the client went away
+		}
+		if written++; written%flushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	}, c.Request.Context())
+	switch {
+	case err != nil && written == 0:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+	case err != nil:
+		if c.Request.Context().Err() == nil {
+			_ = encoder.Encode(gin.H{"error": err.Error(), "request_id": requestid.Get(c)})
+		}
+	case written == 0:
+		c.Header("Content-Type", ndjson)
+		c.Status(http.StatusOK)
+		c.Writer.WriteHeaderNow()
+	default:
+		c.Writer.Flush()
+	}
+}
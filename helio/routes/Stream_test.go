@@ -0,0 +1,216 @@
+package routes
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/helio/dataservice/memory"
+	"mbcarruthers/helio/geoprivacy"
+	"mbcarruthers/helio/model"
+	"mbcarruthers/helio/requestid"
+	"mbcarruthers/helio/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// streaming is a memory store whose streams wait delay before every entity, and fail once failAfter entities are
+// streamed when fails is set.
+type streaming struct {
+	*memory.Store
+	delay     time.Duration
+	fails     bool
+	failAfter int
+}
+
+func (s *streaming) StreamSearchEntities(query model.SearchQuery, each func(model.Entity) error, ctx context.Context) error {
+	entities, _ := s.SearchEntities(query, ctx)
+	for i, entity := range entities {
+		if s.fails && i == s.failAfter {
+			return errors.New("err execute")
+		}
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err := each(entity); err != nil {
+			return err
+		}
+	}
+	if s.fails {
+		return errors.New("err execute")
+	}
+	return nil
+}
+
+// newStreaming returns a router answering GET /entities/ from store behind the request id and the route timeout,
+// every route having timeout. The caller is trusted when the request has a X-Trusted header.
+func newStreaming(store *streaming, timeout time.Duration) *gin.Engine {
+	policy := geoprivacy.Policy{
+		Default:  model.GeoprivacyOpen,
+		Taxa:     map[int]string{48662: model.GeoprivacyObscured},
+		CellSize: 0.2,
+	}
+	timeouts, _ := ParseTimeouts(timeout, nil)
+	r := gin.New()
+	r.Use(requestid.Middleware(), timeouts.Middleware(), func(c *gin.Context) {
+		if c.GetHeader("X-Trusted") != "" {
+			c.Set(geoprivacy.TrustedKey, true)
+		}
+	})
+	r.GET("/entities/", NewEntityRoutes(service.NewEntities(store, nil), policy).ListEntityHandler)
+	return r
+}
+
+// monarchs returns a store of n entities of the monarch, a taxon obscured by default.
+func monarchs(n int) *memory.Store {
+	store := memory.NewStore()
+	for id := 1; id <= n; id++ {
+		store.Put(model.Entity{Id: id, TaxonId: 48662, Latitude: "29.9318", Longitude: "-84.3397"})
+	}
+	return store
+}
+
+// lines returns the json objects of an ndjson body.
+func lines(t *testing.T, body string) []map[string]interface{} {
+	var objects []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var object map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &object); err != nil {
+			t.Fatalf("line %q is not a json object: %v", scanner.Text(), err)
+		}
+		objects = append(objects, object)
+	}
+	return objects
+}
+
+func TestWantsStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		accept string
+		want   bool
+	}{
+		{"neither", "/entities/", "", false},
+		{"stream", "/entities/?stream=true", "", true},
+		{"stream 1", "/entities/?stream=1", "", true},
+		{"stream false", "/entities/?stream=false", "", false},
+		{"stream invalid", "/entities/?stream=yes", "", false},
+		{"ndjson", "/entities/", "application/x-ndjson", true},
+		{"ndjson preferred", "/entities/", "application/x-ndjson, application/json;q=0.5", true},
+		{"json preferred", "/entities/", "application/json, application/x-ndjson;q=0.5", false},
+		{"tie", "/entities/", "application/json, application/x-ndjson", false},
+		{"anything", "/entities/", "*/*", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, tt.target, nil)
+			c.Request.Header.Set("Accept", tt.accept)
+			if got := wantsStream(c); got != tt.want {
+				t.Errorf("wantsStream() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamEntities(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		headers     map[string]string
+		entities    int
+		fails       bool
+		failAfter   int
+		want        int
+		wantLines   int
+		wantLat     string // of every entity
+		wantFailure bool   // the last line is the error of the stream
+	}{
+		{"stream", "/entities/?stream=true", nil, 3, false, 0, http.StatusOK, 3, "29.9000", false},
+		{"ndjson accepted", "/entities/", map[string]string{"Accept": ndjson}, 3, false, 0, http.StatusOK, 3, "29.9000", false},
+		{"trusted", "/entities/?stream=true", map[string]string{"X-Trusted": "1"}, 3, false, 0, http.StatusOK, 3, "29.9318", false},
+		{"empty", "/entities/?stream=true", nil, 0, false, 0, http.StatusOK, 0, "", false},
+		{"more than a flush", "/entities/?stream=true", nil, flushEvery + 1, false, 0, http.StatusOK, flushEvery + 1, "29.9000", false},
+		{"failing once started", "/entities/?stream=true", nil, 3, true, 2, http.StatusOK, 3, "29.9000", true},
+		{"failing at the end", "/entities/?stream=true", nil, 3, true, 3, http.StatusOK, 4, "29.9000", true},
+		// nothing is written yet, so the stream still answers with a status
+		{"failing right away", "/entities/?stream=true", nil, 3, true, 0, http.StatusInternalServerError, 0, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newStreaming(&streaming{Store: monarchs(tt.entities), fails: tt.fails, failAfter: tt.failAfter}, time.Minute)
+			request := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			request.Header.Set(requestid.Header, "abc-123")
+			for name, value := range tt.headers {
+				request.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, request)
+			if tt.want != http.StatusOK {
+				var body map[string]interface{}
+				if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || recorder.Code != tt.want || body["error"] != "err execute" {
+					t.Errorf("GET %s = %d %s, want %d with the error", tt.target, recorder.Code, recorder.Body.String(), tt.want)
+				}
+				return
+			}
+			if recorder.Code != tt.want || recorder.Header().Get("Content-Type") != ndjson {
+				t.Fatalf("GET %s = %d %s, want %d %s", tt.target, recorder.Code, recorder.Header().Get("Content-Type"), tt.want, ndjson)
+			}
+			objects := lines(t, recorder.Body.String())
+			if len(objects) != tt.wantLines {
+				t.Fatalf("GET %s = %d lines, want %d", tt.target, len(objects), tt.wantLines)
+			}
+			entities := objects
+			if tt.wantFailure {
+				last := objects[len(objects)-1]
+				if len(last) != 2 || last["error"] != "err execute" || last["request_id"] != "abc-123" {
+					t.Errorf("last line = %v, want the error with the request id alone", last)
+				}
+				entities = objects[:len(objects)-1]
+			}
+			for _, entity := range entities {
+				if entity["latitude"] != tt.wantLat || entity["error"] != nil {
+					t.Errorf("entity = %v, want the latitude %s", entity, tt.wantLat)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamIdleTimeout(t *testing.T) {
+	tests := []struct {
+		name      string
+		delay     time.Duration // before every entity
+		want      int
+		wantLines int
+	}{
+		// six entities 15ms apart take longer than the timeout of the route, but none waits for it
+		{"making progress", 15 * time.Millisecond, http.StatusOK, 6},
+		{"idle", 100 * time.Millisecond, http.StatusGatewayTimeout, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newStreaming(&streaming{Store: monarchs(6), delay: tt.delay}, 50*time.Millisecond)
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/entities/?stream=true", nil))
+			if recorder.Code != tt.want {
+				t.Fatalf("GET /entities/?stream=true = %d %s, want %d", recorder.Code, recorder.Body.String(), tt.want)
+			}
+			objects := lines(t, recorder.Body.String())
+			if tt.want != http.StatusOK {
+				if len(objects) != 1 || objects[0]["error"] != "err timeout" {
+					t.Errorf("GET /entities/?stream=true = %s, want the timeout error", recorder.Body.String())
+				}
+			} else if len(objects) != tt.wantLines {
+				t.Errorf("GET /entities/?stream=true = %d lines, want %d", len(objects), tt.wantLines)
+			}
+		})
+	}
+}
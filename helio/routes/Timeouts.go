@@ -0,0 +1,172 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Timeouts bounds the time a request has by its route, i.e. "GET /audit/export". Once it is up the context of the
+// request is done, which cancels the statements it is running.
+type Timeouts struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+// ParseTimeouts parses routes given as "METHOD /path=duration", i.e. "GET /audit/export=2m", every other route
+// having fallback.
+func ParseTimeouts(fallback time.Duration, routes []string) (Timeouts, error) {
+	if fallback <= 0 {
+		return Timeouts{}, fmt.Errorf("err timeout %s must be positive", fallback)
+	}
+	timeouts := Timeouts{Default: fallback, Routes: make(map[string]time.Duration, len(routes))}
+	for _, entry := range routes {
+		route, value, found := strings.Cut(entry, "=")
+		method, path, isRoute := strings.Cut(strings.TrimSpace(route), " ")
+		if !found || !isRoute || method != strings.ToUpper(method) || !strings.HasPrefix(path, "/") {
+			return Timeouts{}, fmt.Errorf("err route timeout %q, want \"METHOD /path=duration\"", entry)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || timeout <= 0 {
+			return Timeouts{}, fmt.Errorf("err route timeout %q, want a positive duration", entry)
+		}
+		timeouts.Routes[method+" "+path] = timeout
+	}
+	return timeouts, nil
+}
+
+// For returns the timeout of route.
+func (t Timeouts) For(route string) time.Duration {
+	if timeout, ok := t.Routes[route]; ok {
+		return timeout
+	}
+	return t.Default
+}
+
+// Middleware runs every request under the timeout of its route. A request running out of time answers 504 in place of
+// whatever error its handler made of it. Requests running out of time, or cancelled before they completed because the
+// client went away, are logged as such.
+// A stream, see wantsStream, may take as long as it needs: the timeout of its route only bounds the time between two
+// of its writes, so it runs out of time once neither the database nor the client makes progress.
+func (t Timeouts) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		timeout := t.For(route)
+		writer := &timeoutWriter{ResponseWriter: c.Writer}
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if wantsStream(c) {
+			writer.idle = withIdleTimeout(c.Request.Context(), timeout)
+			ctx, cancel = writer.idle, writer.idle.stop
+		} else {
+			ctx, cancel = context.WithTimeout(c.Request.Context(), timeout)
+		}
+		defer cancel()
+		writer.ctx = ctx
+		c.Request = c.Request.WithContext(ctx)
+		c.Writer = writer
+		c.Next()
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			slog.WarnContext(ctx, "Timed out", "route", route, "timeout", timeout.String())
+		case context.Canceled:
+			slog.InfoContext(ctx, "Cancelled before it completed", "route", route)
+		}
+	}
+}
+
+// idleContext is done once it has been idle for timeout, or its parent is done. Being idle for timeout it reports
+// context.DeadlineExceeded, like a context past its deadline.
+type idleContext struct {
+	context.Context
+	cancel  context.CancelFunc
+	timer   *time.Timer
+	timeout time.Duration
+	expired atomic.Bool
+}
+
+func withIdleTimeout(parent context.Context, timeout time.Duration) *idleContext {
+	ctx, cancel := context.WithCancel(parent)
+	idle := &idleContext{Context: ctx, cancel: cancel, timeout: timeout}
+	idle.timer = time.AfterFunc(timeout, func() {
+		idle.expired.Store(true)
+		cancel()
+	})
+	return idle
+}
+
+func (c *idleContext) Err() error {
+	err := c.Context.Err()
+	if err != nil && c.expired.Load() {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// progress starts the timeout over, unless it is already up.
+func (c *idleContext) progress() {
+	if !c.expired.Load() {
+		c.timer.Reset(c.timeout)
+	}
+}
+
+func (c *idleContext) stop() {
+	c.timer.Stop()
+	c.cancel()
+}
+
+// timeoutWriter replaces an error response written once ctx is past its deadline with 504. Every write of a stream is
+// progress of its idle timeout.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx      context.Context
+	idle     *idleContext // of a stream, nil otherwise
+	timedOut bool
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if code >= http.StatusBadRequest && !w.Written() && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.timedOut = true
+		code = http.StatusGatewayTimeout
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	if !w.timedOut {
+		n, err := w.ResponseWriter.Write(data)
+		if w.idle != nil && err == nil {
+			w.idle.progress()
+		}
+		return n, err
+	}
+	if !w.Written() {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		body, _ := json.Marshal(gin.H{
+			"error":   "err timeout",
+			"message": "the request ran out of time",
+		})
+		if _, err := w.ResponseWriter.Write(body); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil // the handler's own response is dropped
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Flush() {
+	w.ResponseWriter.Flush()
+	if w.idle != nil {
+		w.idle.progress()
+	}
+}
@@ -0,0 +1,143 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback time.Duration
+		routes   []string
+		want     map[string]time.Duration // by route, along with GET /entities/ falling back
+		wantErr  string
+	}{
+		{"fallback only", 30 * time.Second, nil, map[string]time.Duration{"GET /entities/": 30 * time.Second}, ""},
+		{"routes", 30 * time.Second, []string{"GET /audit/export=2m", " POST /review/analyze = 90s "},
+			map[string]time.Duration{"GET /audit/export": 2 * time.Minute, "POST /review/analyze": 90 * time.Second,
+				"GET /entities/": 30 * time.Second}, ""},
+		{"fallback not positive", 0, nil, nil, "err timeout 0s must be positive"},
+		{"no method", 30 * time.Second, []string{"/audit/export=2m"}, nil, `err route timeout "/audit/export=2m"`},
+		{"lower case method", 30 * time.Second, []string{"get /audit/export=2m"}, nil, `err route timeout "get /audit/export=2m"`},
+		{"relative path", 30 * time.Second, []string{"GET audit/export=2m"}, nil, `err route timeout "GET audit/export=2m"`},
+		{"no duration", 30 * time.Second, []string{"GET /audit/export"}, nil, `err route timeout "GET /audit/export"`},
+		{"not a duration", 30 * time.Second, []string{"GET /audit/export=long"}, nil, "want a positive duration"},
+		{"negative duration", 30 * time.Second, []string{"GET /audit/export=-1m"}, nil, "want a positive duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeouts, err := ParseTimeouts(tt.fallback, tt.routes)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseTimeouts() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for route, want := range tt.want {
+				if got := timeouts.For(route); got != want {
+					t.Errorf("For(%q) = %v, want %v", route, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeoutsMiddleware(t *testing.T) {
+	// late waits for the deadline of the request before answering with respond
+	late := func(respond func(c *gin.Context)) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			<-c.Request.Context().Done()
+			respond(c)
+		}
+	}
+	tests := []struct {
+		name        string
+		handler     gin.HandlerFunc
+		cancel      bool // the client goes away rather than the request running out of time
+		want        int
+		wantTimeout bool // the body is the timeout error rather than the handler's
+	}{
+		{"in time", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"id": 1}) }, false, http.StatusOK, false},
+		{"error in time", func(c *gin.Context) { c.JSON(http.StatusNotFound, gin.H{"error": "err not found"}) }, false,
+			http.StatusNotFound, false},
+		{"server error once timed out", late(func(c *gin.Context) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "err execute"})
+		}), false, http.StatusGatewayTimeout, true},
+		{"unavailable once timed out", late(func(c *gin.Context) {
+			c.String(http.StatusServiceUnavailable, "database unreachable")
+		}), false, http.StatusGatewayTimeout, true},
+		{"aborted once timed out", late(func(c *gin.Context) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "err bad request"})
+		}), false, http.StatusGatewayTimeout, true},
+		{"success once timed out", late(func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"id": 1}) }), false, http.StatusOK, false},
+		{"written before timing out", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+			c.Writer.WriteHeaderNow()
+			<-c.Request.Context().Done()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "err execute"})
+		}, false, http.StatusOK, false},
+		{"cancelled by the client", late(func(c *gin.Context) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "err execute"})
+		}), true, http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeouts, err := ParseTimeouts(time.Minute, []string{"GET /entities/:id=20ms"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := gin.New()
+			r.Use(timeouts.Middleware())
+			r.GET("/entities/:id", tt.handler)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				time.AfterFunc(10*time.Millisecond, cancel)
+			}
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/entities/1", nil).WithContext(ctx))
+			if recorder.Code != tt.want {
+				t.Errorf("GET /entities/1 = %d %s, want %d", recorder.Code, recorder.Body.String(), tt.want)
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); tt.wantTimeout && (err != nil || body["error"] != "err timeout") {
+				t.Errorf("GET /entities/1 body = %s, want the timeout error alone", recorder.Body.String())
+			} else if !tt.wantTimeout && body["error"] == "err timeout" {
+				t.Errorf("GET /entities/1 body = %s, want the handler's own", recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestTimeoutsMiddlewareRoute(t *testing.T) {
+	timeouts, err := ParseTimeouts(20*time.Millisecond, []string{"GET /audit/export=1m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(timeouts.Middleware())
+	deadline := func(c *gin.Context) {
+		at, _ := c.Request.Context().Deadline()
+		c.String(http.StatusOK, time.Until(at).Round(10*time.Second).String())
+	}
+	r.GET("/audit/export", deadline)
+	r.GET("/entities/:id", deadline)
+	for target, want := range map[string]string{"/audit/export": "1m0s", "/entities/1": "0s"} {
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		if recorder.Body.String() != want {
+			t.Errorf("deadline of GET %s = %s, want about %s", target, recorder.Body.String(), want)
+		}
+	}
+}
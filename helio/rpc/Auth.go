@@ -0,0 +1,130 @@
+package rpc
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"mbcarruthers/helio/audit"
+	"mbcarruthers/helio/auth"
+	heliov1 "mbcarruthers/helio/proto/helio/v1"
+	"mbcarruthers/logging"
+	"net"
+	"strings"
+)
+
+type contextKey int
+
+const (
+	identityKey contextKey = iota
+)
+
+// MethodPolicy is the role required by each protected rpc, the same as the matching REST routes. Any rpc left out is public.
+var MethodPolicy = map[string]auth.Role{
+	heliov1.EntityService_CreateEntity_FullMethodName: auth.Curator,
+	heliov1.EntityService_UpdateEntity_FullMethodName: auth.Curator,
+	heliov1.EntityService_DeleteEntity_FullMethodName: auth.Admin,
+}
+
+// authenticate verifies the bearer token within the authorization metadata, if there is one, and enforces the policy.
+// Returns the context carrying the caller's Identity and request id.
+func authenticate(ctx context.Context, method string, authenticator *auth.Authenticator, policy map[string]auth.Role) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	requestId := logging.NewRequestId(first(md, "x-request-id"))
+	ctx = logging.WithRequestId(ctx, requestId)
+	_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestId))
+
+	if header := first(md, "authorization"); header != "" {
+		scheme, token, _ := strings.Cut(header, " ")
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return ctx, status.Error(codes.Unauthenticated, "authorization must be a bearer token")
+		}
+		identity, err := authenticator.Verify(strings.TrimSpace(token))
+		if err != nil {
+			return ctx, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		ctx = context.WithValue(ctx, identityKey, identity)
+	}
+
+	required, ok := policy[method]
+	if !ok {
+		return ctx, nil
+	}
+	identity, ok := IdentityFrom(ctx)
+	if !ok {
+		return ctx, status.Error(codes.Unauthenticated, "authentication required")
+	}
+	if !identity.Role.Allows(required) {
+		return ctx, status.Error(codes.PermissionDenied, "requires the "+string(required)+" role")
+	}
+	return ctx, nil
+}
+
+// UnaryAuth is an interceptor authenticating unary rpcs against the policy.
+func UnaryAuth(authenticator *auth.Authenticator, policy map[string]auth.Role) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, info.FullMethod, authenticator, policy)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuth is an interceptor authenticating streaming rpcs against the policy.
+func StreamAuth(authenticator *auth.Authenticator, policy map[string]auth.Role) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(stream.Context(), info.FullMethod, authenticator, policy)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// contextStream replaces the context of a grpc.ServerStream.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// IdentityFrom returns the caller's Identity and false for anonymous callers.
+func IdentityFrom(ctx context.Context) (auth.Identity, bool) {
+	identity, ok := ctx.Value(identityKey).(auth.Identity)
+	return identity, ok
+}
+
+// trusted reports whether the caller may see true coordinates, the same as auth.Grant(geoprivacy.TrustedKey, auth.Curator)
+func trusted(ctx context.Context) bool {
+	identity, ok := IdentityFrom(ctx)
+	return ok && identity.Role.Allows(auth.Curator)
+}
+
+// callerOf returns the audit.Caller of the rpc.
+func callerOf(ctx context.Context) audit.Caller {
+	caller := audit.Caller{Actor: audit.Anonymous}
+	if identity, ok := IdentityFrom(ctx); ok {
+		caller.Actor = audit.IdentityActor(identity)
+	}
+	caller.RequestId = logging.RequestId(ctx)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		caller.ClientIp = p.Addr.String()
+		if host, _, err := net.SplitHostPort(caller.ClientIp); err == nil {
+			caller.ClientIp = host
+		}
+	}
+	return caller
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) != 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package rpc
+
+import (
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"mbcarruthers/helio/model"
+	heliov1 "mbcarruthers/helio/proto/helio/v1"
+	"time"
+)
+
+const (
+	dateLayout = "2006-01-02"
+)
+
+// fromProto converts a message to an entity. Fields set only by helio(quality, duplicates) are left out.
+func fromProto(message *heliov1.Entity) (model.Entity, error) {
+	entity := model.Entity{
+		Id:           int(message.GetId()),
+		TaxonId:      int(message.GetTaxonId()),
+		PlaceGuess:   message.GetPlaceGuess(),
+		SpeciesGuess: message.GetSpeciesGuess(),
+		Latitude:     message.GetLatitude(),
+		Longitude:    message.GetLongitude(),
+		TimeZone:     message.GetTimeZone(),
+		Geoprivacy:   message.GetGeoprivacy(),
+	}
+	if message.GetUuid() != "" {
+		id, err := uuid.Parse(message.GetUuid())
+		if err != nil {
+			return entity, err
+		}
+		entity.Uuid = id
+	}
+	date, err := parseDate(message.GetObservedOn())
+	if err != nil {
+		return entity, err
+	}
+	entity.ObservedOn = date
+	return entity, nil
+}
+
+// parseDate parses a yyyy-mm-dd date, an empty date is not valid.
+func parseDate(value string) (pgtype.Date, error) {
+	if value == "" {
+		return pgtype.Date{}, nil
+	}
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return pgtype.Date{}, err
+	}
+	return pgtype.Date{Time: t, Valid: true}, nil
+}
@@ -0,0 +1,182 @@
+// Package rpc serves the gRPC EntityService(proto/helio/v1/entity.proto) on top of the same entity service as the
+// REST routes.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"mbcarruthers/helio/auth"
+	"mbcarruthers/helio/dataservice/db"
+	"mbcarruthers/helio/geoprivacy"
+	"mbcarruthers/helio/media"
+	"mbcarruthers/helio/model"
+	heliov1 "mbcarruthers/helio/proto/helio/v1"
+	"mbcarruthers/helio/service"
+	"mbcarruthers/helio/validation"
+	"time"
+)
+
+// EntityServer implements heliov1.EntityServiceServer.
+type EntityServer struct {
+	heliov1.UnimplementedEntityServiceServer
+	entities *service.Entities
+	policy   geoprivacy.Policy
+}
+
+// NewServer constructs a grpc.Server serving the EntityService along with reflection. Callers are authenticated by the
+// authenticator and authorized against MethodPolicy, entities are published according to the geoprivacy policy.
+// Unary rpcs have at most timeout.
+func NewServer(entities *service.Entities, policy geoprivacy.Policy, authenticator *auth.Authenticator, timeout time.Duration) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryAuth(authenticator, MethodPolicy), UnaryTimeout(timeout)),
+		grpc.StreamInterceptor(StreamAuth(authenticator, MethodPolicy)),
+	)
+	heliov1.RegisterEntityServiceServer(server, &EntityServer{entities: entities, policy: policy})
+	reflection.Register(server)
+	return server
+}
+
+// public returns the entity as the caller may see it.
+func (s *EntityServer) public(ctx context.Context, entity model.Entity) *heliov1.Entity {
+	if !trusted(ctx) {
+		entity = s.policy.Public(entity)
+	}
+	return media.ToProto(entity)
+}
+
+// GetEntity returns a single entity by id.
+func (s *EntityServer) GetEntity(ctx context.Context, req *heliov1.GetEntityRequest) (*heliov1.Entity, error) {
+	entity, err := s.entities.Get(int(req.GetId()), ctx)
+	if err != nil {
+		return nil, statusOf(err, ctx)
+	}
+	return s.public(ctx, entity), nil
+}
+
+// ListEntities streams the published entities, the same as GET /entities. SearchEntities finds the others.
+func (s *EntityServer) ListEntities(_ *heliov1.ListEntitiesRequest, stream heliov1.EntityService_ListEntitiesServer) error {
+	entities, err := s.entities.Search(model.SearchQuery{}, stream.Context())
+	if err != nil {
+		return statusOf(err, stream.Context())
+	}
+	for _, entity := range entities {
+		if err = stream.Send(s.public(stream.Context(), entity)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SearchEntities returns every entity matching the search.
+func (s *EntityServer) SearchEntities(ctx context.Context, req *heliov1.SearchEntitiesRequest) (*heliov1.SearchEntitiesResponse, error) {
+	query := model.SearchQuery{
+		TaxonId:      int(req.GetTaxonId()),
+		QualityGrade: req.GetQualityGrade(),
+		QualityFlag:  req.GetQualityFlag(),
+		Merged:       req.GetMerged(),
+		Geoprivacy:   req.GetGeoprivacy(),
+	}
+	var err error
+	if query.Date1, err = parseDate(req.GetDate1()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "date1 must be yyyy-mm-dd")
+	}
+	if query.Date2, err = parseDate(req.GetDate2()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "date2 must be yyyy-mm-dd")
+	}
+	for _, grade := range query.QualityGrade {
+		if !model.ValidGrade(grade) {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown quality grade %q", grade)
+		}
+	}
+	entities, err := s.entities.Search(query, ctx)
+	if err != nil {
+		return nil, statusOf(err, ctx)
+	}
+	response := &heliov1.SearchEntitiesResponse{Entities: make([]*heliov1.Entity, 0, len(entities))}
+	for _, entity := range entities {
+		response.Entities = append(response.Entities, s.public(ctx, entity))
+	}
+	return response, nil
+}
+
+// CreateEntity validates and stores a new entity.
+func (s *EntityServer) CreateEntity(ctx context.Context, req *heliov1.CreateEntityRequest) (*heliov1.Entity, error) {
+	entity, err := fromProto(req.GetEntity())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "malformed entity: "+err.Error())
+	}
+	created, err := s.entities.Create(entity, callerOf(ctx), ctx)
+	if err != nil {
+		return nil, statusOf(err, ctx)
+	}
+	return media.ToProto(created), nil
+}
+
+// UpdateEntity updates an existing entity.
+func (s *EntityServer) UpdateEntity(ctx context.Context, req *heliov1.UpdateEntityRequest) (*heliov1.Entity, error) {
+	entity, err := fromProto(req.GetEntity())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "malformed entity: "+err.Error())
+	}
+	updated, err := s.entities.Update(int(req.GetId()), entity, callerOf(ctx), ctx)
+	if err != nil {
+		return nil, statusOf(err, ctx)
+	}
+	return media.ToProto(updated), nil
+}
+
+// DeleteEntity deletes an entity.
+func (s *EntityServer) DeleteEntity(ctx context.Context, req *heliov1.DeleteEntityRequest) (*heliov1.DeleteEntityResponse, error) {
+	if err := s.entities.Delete(int(req.GetId()), callerOf(ctx), ctx); err != nil {
+		return nil, statusOf(err, ctx)
+	}
+	return &heliov1.DeleteEntityResponse{}, nil
+}
+
+// StreamNewEntities streams every entity created or restored until the caller goes away.
+func (s *EntityServer) StreamNewEntities(req *heliov1.StreamNewEntitiesRequest, stream heliov1.EntityService_StreamNewEntitiesServer) error {
+	ctx := stream.Context()
+	for entity := range s.entities.Subscribe(ctx) {
+		if req.GetTaxonId() != 0 && int64(entity.TaxonId) != req.GetTaxonId() {
+			continue
+		}
+		if err := stream.Send(s.public(ctx, entity)); err != nil {
+			return err
+		}
+	}
+	return status.FromContextError(ctx.Err()).Err()
+}
+
+// statusOf maps an error of the entity service to a gRPC status. Validation errors carry their violations.
+func statusOf(err error, ctx context.Context) error {
+	var verr *validation.Error
+	switch {
+	case errors.As(err, &verr):
+		st := status.New(codes.InvalidArgument, err.Error())
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(verr.Violations))
+		for _, violation := range verr.Violations {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: violation.Field, Description: violation.Message})
+		}
+		if detailed, derr := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); derr == nil {
+			st = detailed
+		}
+		return st.Err()
+	case errors.Is(err, service.ErrInvalidGeoprivacy):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, db.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		slog.ErrorContext(ctx, "Error serving rpc", "error", err)
+		return status.Error(codes.Internal, "err internal")
+	}
+}
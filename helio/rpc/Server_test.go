@@ -0,0 +1,232 @@
+package rpc
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"mbcarruthers/helio/auth"
+	"mbcarruthers/helio/dataservice/memory"
+	"mbcarruthers/helio/geoprivacy"
+	"mbcarruthers/helio/model"
+	heliov1 "mbcarruthers/helio/proto/helio/v1"
+	"mbcarruthers/helio/service"
+	"mbcarruthers/helio/validation"
+	"net"
+	"testing"
+	"time"
+)
+
+// policy obscures monarchs(48662), leaving every other taxon open.
+var policy = geoprivacy.Policy{
+	Default:  model.GeoprivacyOpen,
+	Taxa:     map[int]string{48662: model.GeoprivacyObscured},
+	CellSize: 0.2,
+}
+
+// roost returns an observation of a monarch at a roost, obscured by the policy.
+func roost() model.Entity {
+	return model.Entity{
+		Id:           1,
+		TaxonId:      48662,
+		PlaceGuess:   "Bald Point State Park, Wakulla County, FL, USA",
+		SpeciesGuess: "Monarch",
+		Latitude:     "29.9318",
+		Longitude:    "-84.3397",
+		ObservedOn:   pgtype.Date{Time: time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+		TimeZone:     "Eastern Time (US & Canada)",
+	}
+}
+
+// testServer serves the EntityService over an in-memory connection, holding the roost.
+type testServer struct {
+	client heliov1.EntityServiceClient
+	store  *memory.Store
+	signer *auth.Signer
+}
+
+func newTestServer(t *testing.T) *testServer {
+	signer, err := auth.NewSigner("helio", "helio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator, err := validation.NewValidator(validation.DefaultRules(), validation.Warn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := memory.NewStore()
+	store.Put(roost())
+	server := NewServer(service.NewEntities(store, validator), policy, auth.NewAuthenticator(signer.Verifier(), "helio", "helio"), time.Second)
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return &testServer{client: heliov1.NewEntityServiceClient(conn), store: store, signer: signer}
+}
+
+// as returns a context calling with the authorization metadata of the role, anonymously when it is empty.
+func (s *testServer) as(t *testing.T, role auth.Role) context.Context {
+	if role == "" {
+		return context.Background()
+	}
+	token, err := s.signer.Mint("1234", time.Hour, role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestAuth(t *testing.T) {
+	create := func(ctx context.Context, client heliov1.EntityServiceClient) error {
+		_, err := client.CreateEntity(ctx, &heliov1.CreateEntityRequest{Entity: &heliov1.Entity{
+			TaxonId: 48662, SpeciesGuess: "Monarch", PlaceGuess: "Gainesville, FL, USA", Latitude: "29.6516",
+			Longitude: "-82.3248", ObservedOn: "2021-10-02", TimeZone: "Eastern Time (US & Canada)",
+		}})
+		return err
+	}
+	remove := func(ctx context.Context, client heliov1.EntityServiceClient) error {
+		_, err := client.DeleteEntity(ctx, &heliov1.DeleteEntityRequest{Id: 1})
+		return err
+	}
+	get := func(ctx context.Context, client heliov1.EntityServiceClient) error {
+		_, err := client.GetEntity(ctx, &heliov1.GetEntityRequest{Id: 1})
+		return err
+	}
+	tests := []struct {
+		name  string
+		role  auth.Role
+		call  func(ctx context.Context, client heliov1.EntityServiceClient) error
+		want  codes.Code
+		audit string // action recorded to the audit log, if any
+	}{
+		{"public rpc anonymous", "", get, codes.OK, ""},
+		{"create anonymous", "", create, codes.Unauthenticated, ""},
+		{"create as viewer", auth.Viewer, create, codes.PermissionDenied, ""},
+		{"create as curator", auth.Curator, create, codes.OK, model.AuditCreate},
+		{"create as admin", auth.Admin, create, codes.OK, model.AuditCreate},
+		{"delete as curator", auth.Curator, remove, codes.PermissionDenied, ""},
+		{"delete as admin", auth.Admin, remove, codes.OK, model.AuditDelete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			err := tt.call(s.as(t, tt.role), s.client)
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("code = %v (%v), want %v", got, err, tt.want)
+			}
+			entries := s.store.AuditEntries()
+			if tt.audit == "" {
+				if len(entries) != 0 {
+					t.Errorf("audit log = %+v, want nothing recorded", entries)
+				}
+				return
+			}
+			if len(entries) != 1 || entries[0].Action != tt.audit || entries[0].Actor != string(tt.role)+":1234" {
+				t.Errorf("audit log = %+v, want %s by %s:1234", entries, tt.audit, tt.role)
+			}
+		})
+	}
+}
+
+func TestAuthMetadata(t *testing.T) {
+	s := newTestServer(t)
+	tests := []struct {
+		name          string
+		authorization string
+		public        bool // GetEntity rather than DeleteEntity
+		want          codes.Code
+	}{
+		{"not a bearer token", "Basic dXNlcjpwYXNz", false, codes.Unauthenticated},
+		{"empty bearer token", "Bearer ", false, codes.Unauthenticated},
+		{"invalid token", "Bearer not.a.token", false, codes.Unauthenticated},
+		// a token is verified even when the rpc is public
+		{"invalid token on a public rpc", "Bearer not.a.token", true, codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", tt.authorization)
+			var err error
+			if tt.public {
+				_, err = s.client.GetEntity(ctx, &heliov1.GetEntityRequest{Id: 1})
+			} else {
+				_, err = s.client.DeleteEntity(ctx, &heliov1.DeleteEntityRequest{Id: 1})
+			}
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("code = %v (%v), want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "abc-123")
+	if _, err := s.client.GetEntity(ctx, &heliov1.GetEntityRequest{Id: 1}, grpc.Header(&header)); err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get("x-request-id"); len(got) != 1 || got[0] != "abc-123" {
+		t.Errorf("x-request-id = %v, want the caller's abc-123", got)
+	}
+}
+
+func TestGeoprivacy(t *testing.T) {
+	tests := []struct {
+		name         string
+		role         auth.Role
+		wantObscured bool
+	}{
+		{"anonymous", "", true},
+		{"viewer", auth.Viewer, true},
+		{"curator", auth.Curator, false},
+		{"admin", auth.Admin, false},
+	}
+	s := newTestServer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := s.as(t, tt.role)
+			var got []*heliov1.Entity
+			entity, err := s.client.GetEntity(ctx, &heliov1.GetEntityRequest{Id: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, entity)
+			search, err := s.client.SearchEntities(ctx, &heliov1.SearchEntitiesRequest{TaxonId: 48662})
+			if err != nil || len(search.GetEntities()) != 1 {
+				t.Fatalf("SearchEntities() = %v, %v, want the roost", search, err)
+			}
+			got = append(got, search.GetEntities()[0])
+			stream, err := s.client.ListEntities(ctx, &heliov1.ListEntitiesRequest{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for {
+				entity, err := stream.Recv()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, entity)
+			}
+			if len(got) != 3 {
+				t.Fatalf("entities = %d, want one of every rpc", len(got))
+			}
+			for i, entity := range got {
+				trueCoordinates := entity.GetLatitude() == "29.9318" && entity.GetLongitude() == "-84.3397"
+				if entity.GetCoordinatesObscured() != tt.wantObscured || trueCoordinates == tt.wantObscured {
+					t.Errorf("rpc %d coordinates = %s, %s obscured %v, want obscured %v", i, entity.GetLatitude(),
+						entity.GetLongitude(), entity.GetCoordinatesObscured(), tt.wantObscured)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package rpc
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"time"
+)
+
+// UnaryTimeout is an interceptor bounding unary rpcs to timeout, or to the deadline of the caller if it is sooner.
+// Note: Streaming rpcs last as long as the caller keeps them open and are not bounded.
+func UnaryTimeout(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
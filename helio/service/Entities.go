@@ -0,0 +1,316 @@
+// Package service holds the operations on observations(Entities) shared by the REST routes and the gRPC EntityService,
+// so validation, review and the audit log behave the same whichever way an Entity is changed.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"mbcarruthers/helio/audit"
+	"mbcarruthers/helio/dataservice/db"
+	"mbcarruthers/helio/model"
+	"mbcarruthers/helio/validation"
+	"os"
+	"sync"
+)
+
+const (
+	subscriberBuffer = 64 // new entities held for a slow subscriber before they are dropped
+)
+
+var (
+	ErrInvalidGeoprivacy = errors.New("err invalid geoprivacy") // the entity's geoprivacy is not a geoprivacy level
+	ErrExists            = errors.New("err exists")             // the entity being restored exists
+	ErrInvalidMerge      = errors.New("err invalid merge")      // the merge keeps one of its duplicates or has none
+)
+
+// Store stores entities, *db.DataStore is the database and memory.Store keeps them in memory for tests.
+// Lookups of an entity that does not exist return db.ErrNotFound.
+type Store interface {
+	CreateAndInsert(observations []model.Entity, entries []model.AuditEntry, ctx context.Context) error
+	Migrate(ctx context.Context) error
+	GetEntityById(id int, ctx context.Context) (model.Entity, error)
+	ListAllEntities(ctx context.Context) ([]model.Entity, error)
+	SearchEntities(query model.SearchQuery, ctx context.Context) ([]model.Entity, error)
+	SearchEntitiesPage(query model.SearchQuery, cursor *model.Cursor, limit int, ctx context.Context) ([]model.Entity, error)
+	StreamAllEntities(each func(model.Entity) error, ctx context.Context) error
+	StreamSearchEntities(query model.SearchQuery, each func(model.Entity) error, ctx context.Context) error
+	InsertNewEntity(entity model.Entity, entry model.AuditEntry, ctx context.Context) error
+	UpdateEntityById(id int, entity model.Entity, entry model.AuditEntry, ctx context.Context) error
+	DeleteEntityById(id int, entry model.AuditEntry, ctx context.Context) error
+	LastDeleted(id int, ctx context.Context) (model.Entity, error)
+	MergeEntities(keep int, duplicates []int, entries func(relinked []model.Entity) []model.AuditEntry, ctx context.Context) ([]model.Entity, error)
+}
+
+// Entities creates, reads, updates, deletes and restores entities, recording every change to the audit log and
+// telling subscribers about every new entity. The Store writes the audit entries of a change along with it, a change
+// failing to be recorded is not made at all.
+type Entities struct {
+	btrflydb  Store
+	validator *validation.Validator
+
+	mu          sync.Mutex
+	subscribers map[chan model.Entity]struct{}
+}
+
+// NewEntities constructs Entities.
+func NewEntities(bfdb Store, validator *validation.Validator) *Entities {
+	return &Entities{
+		btrflydb:    bfdb,
+		validator:   validator,
+		subscribers: map[chan model.Entity]struct{}{},
+	}
+}
+
+// Import creates the database from entities if it does not exist yet. Every entity goes through the validator,
+// in strict mode entities breaking a rule are left out of the import. The database is migrated either way.
+func (e *Entities) Import(entities []model.Entity, ctx context.Context) {
+	valid := make([]model.Entity, 0, len(entities))
+	for _, entity := range entities {
+		if err := e.validator.Apply(&entity); err != nil {
+			slog.WarnContext(ctx, "Skipping import of an invalid entity", "entity_id", entity.Id, "error", err)
+			continue
+		}
+		valid = append(valid, entity)
+	}
+
+	entries := make([]model.AuditEntry, 0, len(valid))
+	for i := range valid {
+		entries = append(entries, audit.System(model.AuditImport, valid[i].Id, nil, &valid[i]))
+	}
+	if err := e.btrflydb.CreateAndInsert(valid, entries, ctx); errors.Is(err, db.ErrExists) {
+		slog.InfoContext(ctx, "Database exists, nothing imported")
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error importing entities", "error", err)
+	}
+	if err := e.btrflydb.Migrate(ctx); err != nil {
+		slog.ErrorContext(ctx, "Error migrating the database", "error", err)
+	}
+}
+
+// ImportFile imports the json array of entities at path(data/monarch.json) through Import.
+// Note: the database is only created and imported into if it does not exist yet. It will just err & continue,however
+func (e *Entities) ImportFile(path string, ctx context.Context) error {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	observations := make([]model.Entity, 0)
+	if err = json.Unmarshal(file, &observations); err != nil {
+		return fmt.Errorf("decoding %s: %w", path, err)
+	}
+	e.Import(observations, ctx)
+	return nil
+}
+
+// Get returns the entity by id, and db.ErrNotFound if there is none.
+func (e *Entities) Get(id int, ctx context.Context) (model.Entity, error) {
+	return e.btrflydb.GetEntityById(id, ctx)
+}
+
+// List returns every entity.
+func (e *Entities) List(ctx context.Context) ([]model.Entity, error) {
+	return e.btrflydb.ListAllEntities(ctx)
+}
+
+// Search returns every entity matching the query.
+func (e *Entities) Search(query model.SearchQuery, ctx context.Context) ([]model.Entity, error) {
+	return e.btrflydb.SearchEntities(query, ctx)
+}
+
+// Stream calls each with every entity as it is read, a nil query streaming every entity like List and any other
+// the entities matching it like Search. An error of each stops the stream and is returned.
+func (e *Entities) Stream(query *model.SearchQuery, each func(model.Entity) error, ctx context.Context) error {
+	if query == nil {
+		return e.btrflydb.StreamAllEntities(each, ctx)
+	}
+	return e.btrflydb.StreamSearchEntities(*query, each, ctx)
+}
+
+// SearchPage returns at most limit entities matching the query past the cursor, along with the cursor of the next page
+// which is nil on the last one.
+func (e *Entities) SearchPage(query model.SearchQuery, cursor *model.Cursor, limit int, ctx context.Context) ([]model.Entity, *model.Cursor, error) {
+	entities, err := e.btrflydb.SearchEntitiesPage(query, cursor, limit+1, ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(entities) <= limit {
+		return entities, nil, nil
+	}
+	entities = entities[:limit]
+	next := model.CursorOf(entities[limit-1])
+	return entities, &next, nil
+}
+
+// checkGeoprivacy returns ErrInvalidGeoprivacy if the entity's geoprivacy is not a geoprivacy level.
+func checkGeoprivacy(entity model.Entity) error {
+	if model.ValidGeoprivacy(entity.Geoprivacy) {
+		return nil
+	}
+	return fmt.Errorf("%w %q, geoprivacy must be open, obscured, private or empty", ErrInvalidGeoprivacy, entity.Geoprivacy)
+}
+
+// Create validates and stores a new entity. Entities breaking a rule in strict mode are refused with a *validation.Error.
+func (e *Entities) Create(entity model.Entity, caller audit.Caller, ctx context.Context) (model.Entity, error) {
+	entity.Id = 555555                                                              // Note: Represents a dummy value. Will not be allowed once this is in authorized & a permanent database.
+	entity.Uuid, _ = uuid.FromBytes([]byte("00000000-0000-0000-0000-000000000000")) // Note: It will be created anyway by crdb may as well make it concrete.
+	entity.QualityFlags, entity.QualityGrade, entity.DuplicateOf = nil, "", nil     // Note: Flags are only ever set by the validator and analysis
+	if err := checkGeoprivacy(entity); err != nil {
+		return model.Entity{}, err
+	}
+	if err := e.validator.Apply(&entity); err != nil {
+		return model.Entity{}, err
+	}
+	if err := e.btrflydb.InsertNewEntity(entity, caller.Entry(model.AuditCreate, entity.Id, nil, &entity), ctx); err != nil {
+		return model.Entity{}, err
+	}
+	e.publish(entity)
+	return entity, nil
+}
+
+// Update validates and stores the new values of an existing entity. Its taxon_id, uuid and duplicate_of are never
+// updated and an empty geoprivacy keeps the stored one. The rules are checked again, the quality flags set by analysis
+// and a curator's grade are kept.
+func (e *Entities) Update(id int, entity model.Entity, caller audit.Caller, ctx context.Context) (model.Entity, error) {
+	existing, err := e.btrflydb.GetEntityById(id, ctx)
+	if err != nil {
+		return model.Entity{}, err
+	}
+	entity.Id, entity.TaxonId = id, existing.TaxonId
+	entity.Uuid, entity.DuplicateOf = existing.Uuid, existing.DuplicateOf
+	if entity.Geoprivacy == "" {
+		entity.Geoprivacy = existing.Geoprivacy
+	}
+	if err = checkGeoprivacy(entity); err != nil {
+		return model.Entity{}, err
+	}
+	if err = e.recheck(&entity, existing); err != nil {
+		return model.Entity{}, err
+	}
+	if err = e.btrflydb.UpdateEntityById(id, entity, caller.Entry(model.AuditUpdate, id, &existing, &entity), ctx); err != nil {
+		return model.Entity{}, err
+	}
+	return entity, nil
+}
+
+// recheck checks the entity against the rules again. It keeps the quality flags of the stored entity other than the
+// rules' along with the grade a curator gave it, and is graded from its flags otherwise, the same as it is stored.
+func (e *Entities) recheck(entity *model.Entity, stored model.Entity) error {
+	entity.QualityFlags, entity.QualityGrade = nil, ""
+	for _, flag := range stored.QualityFlags {
+		if !validation.RuleFlag(flag) {
+			entity.AddQualityFlags(flag)
+		}
+	}
+	if err := e.validator.Apply(entity); err != nil {
+		return err
+	}
+	if stored.Reviewed() {
+		entity.QualityGrade = stored.QualityGrade
+	} else {
+		entity.QualityGrade = model.GradeForFlags(entity.QualityFlags)
+	}
+	return nil
+}
+
+// Delete deletes an entity, it is kept within the audit log so that it can be restored.
+func (e *Entities) Delete(id int, caller audit.Caller, ctx context.Context) error {
+	existing, err := e.btrflydb.GetEntityById(id, ctx)
+	if err != nil {
+		return err
+	}
+	return e.btrflydb.DeleteEntityById(id, caller.Entry(model.AuditDelete, id, &existing, nil), ctx)
+}
+
+// Restore restores a deleted entity as it was when it was last deleted, checked against the rules again like an
+// update. It returns ErrExists if the entity exists and db.ErrNotFound if it was never deleted.
+func (e *Entities) Restore(id int, caller audit.Caller, ctx context.Context) (model.Entity, error) {
+	if _, err := e.btrflydb.GetEntityById(id, ctx); err == nil {
+		return model.Entity{}, ErrExists
+	} else if !errors.Is(err, db.ErrNotFound) {
+		return model.Entity{}, err
+	}
+	deleted, err := e.btrflydb.LastDeleted(id, ctx)
+	if err != nil {
+		return model.Entity{}, err
+	}
+	restored := deleted
+	if err = checkGeoprivacy(restored); err != nil {
+		return model.Entity{}, err
+	}
+	if err = e.recheck(&restored, deleted); err != nil {
+		return model.Entity{}, err
+	}
+	if err = e.btrflydb.InsertNewEntity(restored, caller.Entry(model.AuditRestore, id, nil, &restored), ctx); err != nil {
+		return model.Entity{}, err
+	}
+	e.publish(restored)
+	return restored, nil
+}
+
+// Merge links the duplicates to the entity kept, along with every entity merged into one of them before, and returns
+// the entities merged as they are now. Repeated duplicates are merged once. It returns ErrInvalidMerge if the entity
+// kept is one of the duplicates or there are none, and db.ErrNotFound if any of them does not exist.
+func (e *Entities) Merge(keep int, duplicates []int, caller audit.Caller, ctx context.Context) ([]model.Entity, error) {
+	unique, seen := make([]int, 0, len(duplicates)), map[int]bool{}
+	for _, id := range duplicates {
+		if id == keep {
+			return nil, fmt.Errorf("%w, %d is both kept and a duplicate", ErrInvalidMerge, keep)
+		}
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, fmt.Errorf("%w, there are no duplicates", ErrInvalidMerge)
+	}
+	var merged []model.Entity
+	_, err := e.btrflydb.MergeEntities(keep, unique, func(relinked []model.Entity) []model.AuditEntry {
+		merged = make([]model.Entity, len(relinked))
+		entries := make([]model.AuditEntry, len(relinked))
+		for i := range relinked {
+			merged[i] = relinked[i]
+			merged[i].DuplicateOf = &keep
+			entries[i] = caller.Entry(model.AuditMerge, merged[i].Id, &relinked[i], &merged[i])
+		}
+		return entries
+	}, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// Subscribe returns a channel receiving every entity created or restored until ctx is done, when it is closed.
+// A subscriber falling too far behind misses entities rather than holding up everyone else.
+func (e *Entities) Subscribe(ctx context.Context) <-chan model.Entity {
+	ch := make(chan model.Entity, subscriberBuffer)
+	e.mu.Lock()
+	e.subscribers[ch] = struct{}{}
+	e.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		e.mu.Lock()
+		delete(e.subscribers, ch)
+		e.mu.Unlock()
+		close(ch)
+	}()
+	return ch
+}
+
+// publish hands the entity to every subscriber.
+func (e *Entities) publish(entity model.Entity) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for ch := range e.subscribers {
+		select {
+		case ch <- entity:
+		default:
+			slog.Warn("Subscriber fell behind, dropped new entity", "entity_id", entity.Id)
+		}
+	}
+}
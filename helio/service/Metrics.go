@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"log/slog"
+	"mbcarruthers/helio/model"
+	"sync"
+	"time"
+)
+
+// Counter counts entities, implemented by db.DataStore.
+type Counter interface {
+	CountEntities(query model.SearchQuery, ctx context.Context) (int, error)
+	CountEntitiesBy(grouping string, query model.SearchQuery, ctx context.Context) ([]model.Count, error)
+}
+
+var (
+	observationsDesc        = prometheus.NewDesc("helio_observations", "Observations in the database.", nil, nil)
+	observationsTaxonDesc   = prometheus.NewDesc("helio_observations_by_taxon", "Observations in the database, by taxon_id.", []string{"taxon_id"}, nil)
+	observationsGradeDesc   = prometheus.NewDesc("helio_observations_by_quality_grade", "Observations in the database, by quality_grade.", []string{"quality_grade"}, nil)
+	observationsCountedDesc = prometheus.NewDesc("helio_observations_counted_timestamp_seconds", "When the observations were last counted.", nil, nil)
+)
+
+// ObservationsCollector collects the observations in the database, in total, by taxon and by quality grade. They are
+// counted again at most every interval, scrapes in between get the last counts. Nothing is collected until they
+// have been counted once.
+type ObservationsCollector struct {
+	store    Counter
+	interval time.Duration
+
+	mu      sync.Mutex
+	tried   time.Time // last attempt, successful or not, so a database that is down is not asked on every scrape
+	counted time.Time
+	total   int
+	byTaxon []model.Count
+	byGrade []model.Count
+}
+
+// NewObservationsCollector constructs an ObservationsCollector.
+func NewObservationsCollector(store Counter, interval time.Duration) *ObservationsCollector {
+	return &ObservationsCollector{store: store, interval: interval}
+}
+
+func (o *ObservationsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- observationsDesc
+	ch <- observationsTaxonDesc
+	ch <- observationsGradeDesc
+	ch <- observationsCountedDesc
+}
+
+func (o *ObservationsCollector) Collect(ch chan<- prometheus.Metric) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if time.Since(o.tried) >= o.interval {
+		o.tried = time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		o.count(ctx)
+		cancel()
+	}
+	if o.counted.IsZero() {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(observationsDesc, prometheus.GaugeValue, float64(o.total))
+	for _, count := range o.byTaxon {
+		ch <- prometheus.MustNewConstMetric(observationsTaxonDesc, prometheus.GaugeValue, float64(count.Count), count.Key)
+	}
+	for _, count := range o.byGrade {
+		ch <- prometheus.MustNewConstMetric(observationsGradeDesc, prometheus.GaugeValue, float64(count.Count), count.Key)
+	}
+	ch <- prometheus.MustNewConstMetric(observationsCountedDesc, prometheus.GaugeValue, float64(o.counted.Unix()))
+}
+
+// count counts the observations again, keeping the last counts if it fails.
+func (o *ObservationsCollector) count(ctx context.Context) {
+	total, err := o.store.CountEntities(model.SearchQuery{}, ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error counting observations for metrics", "error", err)
+		return
+	}
+	byTaxon, err := o.store.CountEntitiesBy("taxon_id", model.SearchQuery{}, ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error counting observations by taxon for metrics", "error", err)
+		return
+	}
+	byGrade, err := o.store.CountEntitiesBy("quality_grade", model.SearchQuery{}, ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error counting observations by quality grade for metrics", "error", err)
+		return
+	}
+	o.total, o.byTaxon, o.byGrade, o.counted = total, byTaxon, byGrade, time.Now()
+}
@@ -0,0 +1,299 @@
+// Package validation provides the domain rules an observation(Entity) has to satisfy before it is stored.
+// Rules are configurable through a json file and a Validator either rejects(strict) or flags(warn) an Entity
+// that breaks them.
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"mbcarruthers/helio/model"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout = "2006-01-02"
+)
+
+// Mode decides what happens to an Entity that breaks a rule.
+type Mode string
+
+const (
+	Strict Mode = "strict" // reject the Entity
+	Warn   Mode = "warn"   // store the Entity with the broken rules as quality flags
+)
+
+// ParseMode turns a configuration string into a Mode. An empty string is treated as Warn.
+func ParseMode(mode string) (Mode, error) {
+	switch Mode(strings.ToLower(strings.TrimSpace(mode))) {
+	case Strict:
+		return Strict, nil
+	case Warn, "":
+		return Warn, nil
+	default:
+		return "", fmt.Errorf("unknown validation mode %q", mode)
+	}
+}
+
+// Point is a longitude/latitude pair used to describe the region geofence.
+type Point struct {
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+}
+
+// Rules is the configurable set of rules an Entity is checked against.
+// Zero values disable a rule, i.e. an empty Region or KnownTaxa are not checked.
+type Rules struct {
+	MinLatitude    float64  `json:"min_latitude"`
+	MaxLatitude    float64  `json:"max_latitude"`
+	MinLongitude   float64  `json:"min_longitude"`
+	MaxLongitude   float64  `json:"max_longitude"`
+	Region         []Point  `json:"region"`
+	MinDate        string   `json:"min_date"` // yyyy-mm-dd
+	MaxDate        string   `json:"max_date"` // yyyy-mm-dd, empty means today
+	KnownTaxa      []int    `json:"known_taxa"`
+	RequiredFields []string `json:"required_fields"` // json names of the Entity fields that may not be empty
+}
+
+// Florida is a rough polygon around the state of Florida, with a little room for coastal observations.
+var Florida = []Point{
+	{-87.65, 31.01}, {-85.00, 31.01}, {-84.86, 30.72}, {-82.22, 30.58}, {-81.40, 30.75},
+	{-80.90, 29.80}, {-80.20, 28.40}, {-79.90, 26.80}, {-79.95, 25.20}, {-80.30, 24.40},
+	{-81.90, 24.35}, {-83.10, 24.50}, {-82.60, 26.20}, {-83.00, 27.50}, {-83.00, 28.80},
+	{-83.90, 29.70}, {-84.60, 29.60}, {-85.50, 29.50}, {-86.50, 30.20}, {-87.65, 30.20},
+}
+
+// DefaultRules returns the rules for the Florida lepidoptera observations.
+func DefaultRules() Rules {
+	return Rules{
+		MinLatitude:    -90,
+		MaxLatitude:    90,
+		MinLongitude:   -180,
+		MaxLongitude:   180,
+		Region:         Florida,
+		MinDate:        "1900-01-01",
+		KnownTaxa:      []int{48662, 235550}, // Monarch, Danaus plexippus plexippus
+		RequiredFields: []string{"place_guess", "species_guess", "latitude", "longitude", "observed_on", "time_zone"},
+	}
+}
+
+// LoadRules reads Rules from a json file. Any field left out of the file keeps its DefaultRules value.
+// An empty path returns the DefaultRules.
+func LoadRules(path string) (Rules, error) {
+	rules := DefaultRules()
+	if path == "" {
+		return rules, nil
+	}
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return rules, fmt.Errorf("reading validation rules: %w", err)
+	}
+	if err = json.Unmarshal(file, &rules); err != nil {
+		return rules, fmt.Errorf("parsing validation rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Violation describes a single broken rule.
+type Violation struct {
+	Field   string `json:"field"`
+	Flag    string `json:"flag"`
+	Message string `json:"message"`
+}
+
+// Error is returned by Validator.Apply in strict mode and carries every rule the Entity broke.
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Validator checks entities against a set of Rules.
+type Validator struct {
+	rules   Rules
+	mode    Mode
+	minDate time.Time
+	maxDate time.Time // zero means today
+	taxa    map[int]bool
+}
+
+// NewValidator constructs a Validator, returning an error if the rules cannot be understood.
+func NewValidator(rules Rules, mode Mode) (*Validator, error) {
+	v := &Validator{
+		rules: rules,
+		mode:  mode,
+		taxa:  make(map[int]bool, len(rules.KnownTaxa)),
+	}
+	var err error
+	if rules.MinDate != "" {
+		if v.minDate, err = time.Parse(dateLayout, rules.MinDate); err != nil {
+			return nil, fmt.Errorf("invalid min_date %q", rules.MinDate)
+		}
+	}
+	if rules.MaxDate != "" {
+		if v.maxDate, err = time.Parse(dateLayout, rules.MaxDate); err != nil {
+			return nil, fmt.Errorf("invalid max_date %q", rules.MaxDate)
+		}
+	}
+	if len(rules.Region) != 0 && len(rules.Region) < 3 {
+		return nil, fmt.Errorf("region needs at least 3 points, got %d", len(rules.Region))
+	}
+	for _, taxon := range rules.KnownTaxa {
+		v.taxa[taxon] = true
+	}
+	for _, field := range rules.RequiredFields {
+		if _, ok := fieldValue(model.Entity{}, field); !ok {
+			return nil, fmt.Errorf("unknown required field %q", field)
+		}
+	}
+	return v, nil
+}
+
+// Mode returns the mode the Validator runs in.
+func (v *Validator) Mode() Mode {
+	return v.mode
+}
+
+// Check returns every rule the entity breaks, or nil if it breaks none.
+func (v *Validator) Check(entity model.Entity) []Violation {
+	var violations []Violation
+	for _, field := range v.rules.RequiredFields {
+		if value, _ := fieldValue(entity, field); strings.TrimSpace(value) == "" {
+			violations = append(violations, Violation{
+				Field:   field,
+				Flag:    model.MissingFieldFlag(field),
+				Message: fmt.Sprintf("%s must not be empty", field),
+			})
+		}
+	}
+	violations = append(violations, v.checkCoordinates(entity)...)
+	violations = append(violations, v.checkDate(entity)...)
+	if len(v.taxa) != 0 && !v.taxa[entity.TaxonId] {
+		violations = append(violations, Violation{
+			Field:   "taxon_id",
+			Flag:    model.FlagUnknownTaxon,
+			Message: fmt.Sprintf("taxon_id %d is not a known taxon", entity.TaxonId),
+		})
+	}
+	return violations
+}
+
+// RuleFlag reports whether flag is one of the flags the rules set, rather than analysis(package quality).
+func RuleFlag(flag string) bool {
+	switch flag {
+	case model.FlagInvalidCoordinates, model.FlagOutsideRegion, model.FlagDateOutOfRange, model.FlagUnknownTaxon:
+		return true
+	}
+	return strings.HasPrefix(flag, model.FlagMissingPrefix)
+}
+
+// Apply checks the entity and acts on it according to the Validator's Mode. In strict mode any violation is
+// returned as an *Error. In warn mode the violations are added to the entity's quality flags and nil is returned.
+func (v *Validator) Apply(entity *model.Entity) error {
+	violations := v.Check(*entity)
+	if len(violations) == 0 {
+		return nil
+	}
+	if v.mode == Strict {
+		return &Error{Violations: violations}
+	}
+	for _, violation := range violations {
+		entity.AddQualityFlags(violation.Flag)
+	}
+	return nil
+}
+
+func (v *Validator) checkCoordinates(entity model.Entity) []Violation {
+	if entity.Latitude == "" || entity.Longitude == "" {
+		return nil // reported by the required fields rule
+	}
+	lat, latErr := strconv.ParseFloat(strings.TrimSpace(entity.Latitude), 64)
+	lon, lonErr := strconv.ParseFloat(strings.TrimSpace(entity.Longitude), 64)
+	if latErr != nil || lonErr != nil {
+		return []Violation{{
+			Field:   "latitude,longitude",
+			Flag:    model.FlagInvalidCoordinates,
+			Message: fmt.Sprintf("coordinates (%q, %q) are not numbers", entity.Latitude, entity.Longitude),
+		}}
+	}
+	if lat < v.rules.MinLatitude || lat > v.rules.MaxLatitude || lon < v.rules.MinLongitude || lon > v.rules.MaxLongitude {
+		return []Violation{{
+			Field:   "latitude,longitude",
+			Flag:    model.FlagInvalidCoordinates,
+			Message: fmt.Sprintf("coordinates (%g, %g) are out of range", lat, lon),
+		}}
+	}
+	if len(v.rules.Region) != 0 && !InRegion(v.rules.Region, lon, lat) {
+		return []Violation{{
+			Field:   "latitude,longitude",
+			Flag:    model.FlagOutsideRegion,
+			Message: fmt.Sprintf("coordinates (%g, %g) are outside of the region", lat, lon),
+		}}
+	}
+	return nil
+}
+
+func (v *Validator) checkDate(entity model.Entity) []Violation {
+	if !entity.ObservedOn.Valid {
+		return nil // reported by the required fields rule
+	}
+	maxDate := v.maxDate
+	if maxDate.IsZero() {
+		maxDate = time.Now().UTC()
+	}
+	observed := entity.ObservedOn.Time
+	if (!v.minDate.IsZero() && observed.Before(v.minDate)) || observed.After(maxDate) {
+		return []Violation{{
+			Field:   "observed_on",
+			Flag:    model.FlagDateOutOfRange,
+			Message: fmt.Sprintf("observed_on %s is outside of the allowed dates", observed.Format(dateLayout)),
+		}}
+	}
+	return nil
+}
+
+// InRegion reports whether the point lies within the polygon using ray casting.
+func InRegion(polygon []Point, lon, lat float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		pi, pj := polygon[i], polygon[j]
+		if (pi.Latitude > lat) != (pj.Latitude > lat) &&
+			lon < (pj.Longitude-pi.Longitude)*(lat-pi.Latitude)/(pj.Latitude-pi.Latitude)+pi.Longitude {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// fieldValue returns the string value of an Entity field by its json name, and false if there is no such field.
+func fieldValue(entity model.Entity, field string) (string, bool) {
+	switch field {
+	case "place_guess":
+		return entity.PlaceGuess, true
+	case "species_guess":
+		return entity.SpeciesGuess, true
+	case "latitude":
+		return entity.Latitude, true
+	case "longitude":
+		return entity.Longitude, true
+	case "time_zone":
+		return entity.TimeZone, true
+	case "observed_on":
+		if !entity.ObservedOn.Valid {
+			return "", true
+		}
+		return entity.ObservedOn.Time.Format(dateLayout), true
+	case "uuid":
+		return entity.Uuid.String(), true
+	default:
+		return "", false
+	}
+}
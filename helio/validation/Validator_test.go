@@ -0,0 +1,222 @@
+package validation
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"mbcarruthers/helio/model"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// square is a 10 by 10 degree region, simple enough to reason about its boundary.
+var square = []Point{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+
+func date(value string) pgtype.Date {
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		panic(err)
+	}
+	return pgtype.Date{Time: t, Valid: true}
+}
+
+// valid returns an entity breaking none of the DefaultRules.
+func valid() model.Entity {
+	return model.Entity{
+		Id:           1,
+		TaxonId:      48662,
+		Uuid:         uuid.New(),
+		PlaceGuess:   "Gainesville, FL, USA",
+		SpeciesGuess: "Monarch",
+		Latitude:     "29.6516",
+		Longitude:    "-82.3248",
+		ObservedOn:   date("2021-10-01"),
+		TimeZone:     "Eastern Time (US & Canada)",
+	}
+}
+
+func TestInRegion(t *testing.T) {
+	tests := []struct {
+		name     string
+		lon, lat float64
+		want     bool
+	}{
+		{"center", 5, 5, true},
+		{"near a corner inside", 0.001, 9.999, true},
+		{"left of the region", -1, 5, false},
+		{"right of the region", 11, 5, false},
+		{"above the region", 5, 11, false},
+		{"below the region", 5, -1, false},
+		// Note: Boundaries are half-open, the left and bottom edges are inside and the right and top ones are not, so
+		// a point on the edge shared by two regions is within exactly one of them.
+		{"on the left edge", 0, 5, true},
+		{"on the bottom edge", 5, 0, true},
+		{"on the right edge", 10, 5, false},
+		{"on the top edge", 5, 10, false},
+		{"on the top right corner", 10, 10, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InRegion(square, tt.lon, tt.lat); got != tt.want {
+				t.Errorf("InRegion(%g, %g) = %v, want %v", tt.lon, tt.lat, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInRegionFlorida(t *testing.T) {
+	tests := []struct {
+		name     string
+		lon, lat float64
+		want     bool
+	}{
+		{"Gainesville", -82.3248, 29.6516, true},
+		{"Miami", -80.1918, 25.7617, true},
+		{"Key West", -81.7800, 24.5551, true},
+		{"Pensacola", -87.2169, 30.4213, true},
+		{"Atlanta", -84.3880, 33.7490, false},
+		{"Havana", -82.3666, 23.1136, false},
+		{"Gulf of Mexico", -85.0, 27.0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InRegion(Florida, tt.lon, tt.lat); got != tt.want {
+				t.Errorf("InRegion(%g, %g) = %v, want %v", tt.lon, tt.lat, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	rules := DefaultRules()
+	rules.MaxDate = "2023-12-31"
+	validator, err := NewValidator(rules, Warn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		change func(entity *model.Entity)
+		want   []string // flags of the violations, in order
+	}{
+		{"valid", func(entity *model.Entity) {}, nil},
+		{"missing species guess", func(entity *model.Entity) { entity.SpeciesGuess = "" }, []string{"missing_species_guess"}},
+		{"blank place guess", func(entity *model.Entity) { entity.PlaceGuess = "  " }, []string{"missing_place_guess"}},
+		{"missing observed on", func(entity *model.Entity) { entity.ObservedOn = pgtype.Date{} }, []string{"missing_observed_on"}},
+		{"missing coordinates", func(entity *model.Entity) { entity.Latitude, entity.Longitude = "", "" },
+			[]string{"missing_latitude", "missing_longitude"}},
+		{"coordinates not numbers", func(entity *model.Entity) { entity.Latitude = "north" }, []string{model.FlagInvalidCoordinates}},
+		{"latitude out of range", func(entity *model.Entity) { entity.Latitude = "91" }, []string{model.FlagInvalidCoordinates}},
+		{"longitude out of range", func(entity *model.Entity) { entity.Longitude = "-180.5" }, []string{model.FlagInvalidCoordinates}},
+		{"outside of florida", func(entity *model.Entity) { entity.Latitude, entity.Longitude = "33.7490", "-84.3880" },
+			[]string{model.FlagOutsideRegion}},
+		{"before min date", func(entity *model.Entity) { entity.ObservedOn = date("1899-12-31") }, []string{model.FlagDateOutOfRange}},
+		{"on min date", func(entity *model.Entity) { entity.ObservedOn = date("1900-01-01") }, nil},
+		{"on max date", func(entity *model.Entity) { entity.ObservedOn = date("2023-12-31") }, nil},
+		{"after max date", func(entity *model.Entity) { entity.ObservedOn = date("2024-01-01") }, []string{model.FlagDateOutOfRange}},
+		{"unknown taxon", func(entity *model.Entity) { entity.TaxonId = 1 }, []string{model.FlagUnknownTaxon}},
+		{"several rules", func(entity *model.Entity) { entity.TimeZone, entity.TaxonId = "", 1 },
+			[]string{"missing_time_zone", model.FlagUnknownTaxon}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entity := valid()
+			tt.change(&entity)
+			var got []string
+			for _, violation := range validator.Check(entity) {
+				got = append(got, violation.Flag)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() flags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckFutureDate(t *testing.T) {
+	validator, err := NewValidator(DefaultRules(), Warn) // without max_date nothing may be observed after today
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity := valid()
+	entity.ObservedOn = pgtype.Date{Time: time.Now().UTC().AddDate(0, 0, 2), Valid: true}
+	if violations := validator.Check(entity); len(violations) != 1 || violations[0].Flag != model.FlagDateOutOfRange {
+		t.Errorf("Check() = %v, want a single %s", violations, model.FlagDateOutOfRange)
+	}
+}
+
+func TestApply(t *testing.T) {
+	broken := valid()
+	broken.SpeciesGuess = ""
+
+	strict, err := NewValidator(DefaultRules(), Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity := broken
+	var validationErr *Error
+	if err := strict.Apply(&entity); !errors.As(err, &validationErr) || len(validationErr.Violations) != 1 {
+		t.Errorf("strict Apply() = %v, want an *Error with a single violation", err)
+	}
+	if len(entity.QualityFlags) != 0 {
+		t.Errorf("strict Apply() flagged %v, want the entity left as it is", entity.QualityFlags)
+	}
+
+	warn, err := NewValidator(DefaultRules(), Warn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity = broken
+	entity.QualityFlags = []string{"missing_species_guess"}
+	if err := warn.Apply(&entity); err != nil {
+		t.Errorf("warn Apply() = %v, want nil", err)
+	}
+	if want := []string{"missing_species_guess"}; !reflect.DeepEqual(entity.QualityFlags, want) {
+		t.Errorf("warn Apply() flags = %v, want %v without duplicates", entity.QualityFlags, want)
+	}
+}
+
+func TestNewValidator(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(rules *Rules)
+		wantErr bool
+	}{
+		{"defaults", func(rules *Rules) {}, false},
+		{"bad min date", func(rules *Rules) { rules.MinDate = "01/01/1900" }, true},
+		{"bad max date", func(rules *Rules) { rules.MaxDate = "2023-13-01" }, true},
+		{"region of two points", func(rules *Rules) { rules.Region = square[:2] }, true},
+		{"no region", func(rules *Rules) { rules.Region = nil }, false},
+		{"unknown required field", func(rules *Rules) { rules.RequiredFields = []string{"color"} }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := DefaultRules()
+			tt.change(&rules)
+			if _, err := NewValidator(rules, Warn); (err != nil) != tt.wantErr {
+				t.Errorf("NewValidator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    Mode
+		wantErr bool
+	}{
+		{"strict", Strict, false},
+		{" STRICT ", Strict, false},
+		{"warn", Warn, false},
+		{"", Warn, false},
+		{"lenient", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMode(tt.mode)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseMode(%q) = %q, %v, want %q, error %v", tt.mode, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
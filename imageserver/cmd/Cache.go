@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "imageserver_cache_requests_total",
+		Help: "Images read through the cache, by result: hit or miss.",
+	}, []string{"result"})
+	bytesServed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "imageserver_bytes_served_total",
+		Help: "Bytes of images served, by asset.",
+	}, []string{"asset"})
+)
+
+func init() {
+	prometheus.MustRegister(cacheRequests, bytesServed)
+}
+
+// ImageCache keeps the images served in memory, reading a file again once its size or modification time changes.
+// Note: The assets are a handful of images, nothing is ever evicted.
+type ImageCache struct {
+	mu     sync.Mutex
+	images map[string]cachedImage
+}
+
+// cachedImage is the content of a file as of modTime.
+type cachedImage struct {
+	modTime time.Time
+	size    int64
+	data    []byte
+}
+
+// NewImageCache constructs an empty ImageCache.
+func NewImageCache() *ImageCache {
+	return &ImageCache{images: map[string]cachedImage{}}
+}
+
+// Read returns the content of filename, from memory unless the file changed.
+func (i *ImageCache) Read(filename string) ([]byte, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	i.mu.Lock()
+	cached, ok := i.images[filename]
+	i.mu.Unlock()
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		cacheRequests.WithLabelValues("hit").Inc()
+		return cached.data, nil
+	}
+	cacheRequests.WithLabelValues("miss").Inc()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	i.mu.Lock()
+	i.images[filename] = cachedImage{modTime: info.ModTime(), size: int64(len(data)), data: data}
+	i.mu.Unlock()
+	return data, nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"mbcarruthers/config"
+	"mbcarruthers/tracing"
+)
+
+// Config is the configuration of the image server, loaded from IMAGESERVER_CONFIG(or -config), the environment and
+// flags. log_level is reloaded on SIGHUP.
+type Config struct {
+	Port      int          `config:"port" env:"PORT" usage:"HTTP port"`
+	AssetsDir string       `config:"assets_dir" env:"ASSETS_DIR" usage:"directory of the images served"`
+	PublicURL string       `config:"public_url" env:"PUBLIC_URL" usage:"url the images are reached at, logged at startup, http://image-server:<port> if not set"`
+	LogLevel  config.Level `config:"log_level" env:"LOG_LEVEL" reload:"true" usage:"debug, info, warn or error, every image served is logged at debug"`
+
+	Tracing tracing.Settings `config:"tracing"`
+}
+
+// defaultConfig is the configuration the image server runs with when nothing is set.
+func defaultConfig() Config {
+	return Config{
+		Port:      8025,
+		AssetsDir: "assets/",
+		LogLevel:  config.Info,
+		Tracing:   tracing.Defaults(),
+	}
+}
+
+// Validate checks every setting before the server starts.
+func (c *Config) Validate() error {
+	var problems config.Problems
+	problems.Port("port", c.Port)
+	problems.Dir("assets_dir", c.AssetsDir)
+	if err := c.Tracing.Validate(); err != nil {
+		problems.Add("tracing: %s", err.Error())
+	}
+	return problems.Err()
+}
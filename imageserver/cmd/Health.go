@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mbcarruthers/health"
+	"net/http"
+	"os"
+	"time"
+)
+
+// NewHealthChecker constructs the health.Checker of the image server, the assets directory being its one dependency.
+func NewHealthChecker(assetsDir string) *health.Checker {
+	checker := health.NewChecker("imageserver", 2*time.Second)
+	checker.Add("assets", assetsCheck(assetsDir))
+	return checker
+}
+
+// assetsCheck checks that the assets directory is still there, readable and not empty.
+func assetsCheck(directoryname string) health.Check {
+	return func(ctx context.Context) error {
+		dir, err := os.Open(directoryname)
+		if err != nil {
+			return err
+		}
+		defer dir.Close()
+		if _, err = dir.ReadDir(1); err == io.EOF {
+			return fmt.Errorf("err no images in %s", directoryname)
+		}
+		return err
+	}
+}
+
+// HandleHealth serves /healthz, /readyz and /status.
+func (i *ImageHandler) HandleHealth(checker *health.Checker) {
+	i.HandleFunc("/healthz", onlyGet(checker.HealthzHandler))
+	i.HandleFunc("/readyz", onlyGet(checker.ReadyzHandler))
+	i.HandleFunc("/status", onlyGet(checker.StatusHandler))
+}
+
+// onlyGet refuses every method but GET and HEAD.
+func onlyGet(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeError(w, r, http.StatusMethodNotAllowed, "err method not allowed")
+			return
+		}
+		handler(w, r)
+	}
+}
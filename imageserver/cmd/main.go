@@ -1,21 +1,26 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"mbcarruthers/config"
+	"mbcarruthers/logging"
+	"mbcarruthers/metrics"
+	"mbcarruthers/tracing"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
-)
-
-const (
-	__port = 8025
+	"time"
 )
 
 var (
-	port = fmt.Sprintf(":%d", __port)
+	logLevel *config.LevelVar
+	images   = NewImageCache()
 )
 
 type ImageFunc func(w http.ResponseWriter, r *http.Request)
@@ -30,63 +35,123 @@ func NewImageHandler() *ImageHandler {
 	}
 }
 
-func ServeImage(imagefile string) ImageFunc {
+func ServeImage(directoryname string, imagefile string) ImageFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		filename := filepath.Join("assets/", imagefile)
-		res, err := os.ReadFile(filename)
+		filename := filepath.Join(directoryname, imagefile)
+		res, err := images.Read(filename)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Error reading image", "image", imagefile, "error", err)
+			writeError(w, r, http.StatusInternalServerError, "err reading image")
 			return
 		}
-		_, err = w.Write(res)
+		n, err := w.Write(res)
+		bytesServed.WithLabelValues(imagefile).Add(float64(n))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			slog.WarnContext(r.Context(), "Error serving image", "image", imagefile, "error", err)
 			return
 		}
+		slog.DebugContext(r.Context(), "Served image", "image", imagefile, "client", r.RemoteAddr)
 		return
 	}
 }
 
+// notFound answers requests for anything but an image, /metrics and the health routes.
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusNotFound, "err not found")
+}
+
+// writeError answers with a json error, carrying the request id so the request can be found in the logs.
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message, "request_id": logging.RequestId(r.Context())})
+}
+
 func CreateImageFileMap(directoryname string) map[string]ImageFunc {
 	filemap := make(map[string]ImageFunc)
 	items, err := os.ReadDir(directoryname)
 	if err != nil {
-		log.Fatalf(err.Error())
+		logging.Fatal("Error reading assets_dir", "error", err)
 		return nil
 	}
 	for _, item := range items {
 		urlPath := "/" + item.Name()
-		filemap[urlPath] = ServeImage(item.Name())
+		filemap[urlPath] = ServeImage(directoryname, item.Name())
 	}
 	return filemap
 }
 
 func main() {
-	imageMap := CreateImageFileMap("assets/")
+	loader, err := config.NewLoader("imageserver", defaultConfig)
+	if err != nil {
+		logging.Fatal("Error reading the configuration settings", "error", err)
+	}
+	loader.Parse(os.Args[1:])
+	cfg, err := loader.Load()
+	if err != nil {
+		logging.Fatal("Error loading configuration", "error", err)
+	}
+	loader.Print(os.Stderr, cfg)
+	if loader.PrintOnly() {
+		return
+	}
+	// every line is logged as json, along with the request id of the request it was logged for
+	logLevel = config.NewLevelVar(cfg.LogLevel)
+	logging.Setup("imageserver", logLevel)
+	shutdownTracing, err := tracing.Setup("imageserver", cfg.Tracing)
+	if err != nil {
+		logging.Fatal("Error setting up tracing", "error", err)
+	}
+	// SIGHUP reloads log_level, any other setting needs a restart
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	defer stopReload()
+	loader.Watch(reloadCtx, cfg, func(next Config) {
+		logLevel.Set(next.LogLevel)
+	})
+
+	imageMap := CreateImageFileMap(cfg.AssetsDir)
 	imageHandle := NewImageHandler()
+	imageHandle.HandleHealth(NewHealthChecker(cfg.AssetsDir))
+	imageHandle.Handle("/metrics", metrics.Handler())
 
 	for k, v := range imageMap {
 		imageHandle.HandleFunc(k, v)
 	}
+	imageHandle.HandleFunc("/", notFound)
+	// every request is recorded and traced by its route, the path of an image or unmatched, and given a request id
+	route := func(r *http.Request) string {
+		_, pattern := imageHandle.Handler(r)
+		if pattern == "/" {
+			return ""
+		}
+		return pattern
+	}
 	server := &http.Server{
-		Addr:    port,
-		Handler: imageHandle,
+		Addr:     fmt.Sprintf(":%d", cfg.Port),
+		Handler:  metrics.NewHTTP().Middleware(tracing.Middleware(logging.Middleware(imageHandle), route), route),
+		ErrorLog: slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
 	}
 	go func() {
-		log.Printf("We are live at port:%d \n", __port)
-		log.Println("Available Image Urls")
+		slog.Info("We are live", "port", cfg.Port)
+		publicURL := strings.TrimSuffix(cfg.PublicURL, "/")
+		if publicURL == "" {
+			publicURL = fmt.Sprintf("http://image-server:%d", cfg.Port)
+		}
 		for _url, _ := range imageMap {
-			log.Printf("http://image-server:%d%s \n",
-				__port, _url)
+			slog.Info("Available Image Url", "url", publicURL+_url)
 		}
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("There was an error listening to the server:%s \n",
-				err.Error())
+			logging.Fatal("There was an error listening to the server", "error", err)
 		}
 	}()
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	<-quit
-	log.Println("image server closed.")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("traces didnt export properly", "error", err)
+	}
+	slog.Info("image server closed.")
 }
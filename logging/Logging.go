@@ -0,0 +1,60 @@
+// Package logging logs helio, imageserver and webServer as json lines through log/slog, one object per line:
+//
+//	{"time":"…","level":"INFO","msg":"request","service":"helio","request_id":"…","trace_id":"…","status":200,…}
+//
+// Every line logged with the context of a request carries its request id, and its trace id once it is traced, so
+// the lines of a request can be found from the X-Request-Id of its response. Setup makes the logger the default of
+// slog and of the log package.
+package logging
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// New returns the logger of service, writing json lines to w for messages at level and above. level may change while
+// it is in use, i.e. a config.LevelVar reloaded on SIGHUP.
+func New(service string, level slog.Leveler, w io.Writer) *slog.Logger {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+	return slog.New(contextHandler{handler}).With("service", service)
+}
+
+// Setup makes the logger of service, writing to stderr, the default one. Lines still written through the log
+// package, i.e. by dependencies, are logged at info.
+func Setup(service string, level slog.Leveler) *slog.Logger {
+	logger := New(service, level, os.Stderr)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// Fatal logs msg at error and exits, in place of log.Fatalf.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// contextHandler adds the request id and the trace id held by the context of every record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestId(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,44 @@
+package logging
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+// Header holds the request id, taken from the request when it has one and echoed in the response.
+const Header = "X-Request-Id"
+
+const maxLength = 128 // longer incoming ids are replaced
+
+// requestIdKey holds the request id in a context.
+type requestIdKey struct{}
+
+// NewRequestId returns incoming, the id a request came with, or a new id when it has none or a longer one than
+// anybody needs.
+func NewRequestId(incoming string) string {
+	if incoming == "" || len(incoming) > maxLength {
+		return uuid.NewString()
+	}
+	return incoming
+}
+
+// WithRequestId returns ctx holding the request id, logged along with every line logged with it.
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, id)
+}
+
+// RequestId returns the request id held by ctx, empty if it holds none.
+func RequestId(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+// Middleware gives every request served by next a request id, echoed in the X-Request-Id of its response.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := NewRequestId(r.Header.Get(Header))
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(WithRequestId(r.Context(), id)))
+	})
+}
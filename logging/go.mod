@@ -0,0 +1,10 @@
+module mbcarruthers/logging
+
+go 1.21
+
+require (
+	github.com/google/uuid v1.3.0
+	go.opentelemetry.io/otel/trace v1.16.0
+)
+
+require go.opentelemetry.io/otel v1.16.0 // indirect
@@ -0,0 +1,91 @@
+// Package metrics exposes the metrics of helio, imageserver and webServer at /metrics in the Prometheus text format.
+// Every service counts and times its HTTP requests by method, route and status(the RED metrics):
+//
+//	http_requests_total{method,route,status}
+//	http_request_duration_seconds{method,route,status}
+//	http_requests_in_flight
+//
+// along with the Go runtime and process metrics. Metrics of its own are registered with prometheus.DefaultRegisterer.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Unmatched is the route of requests matching no route, so that unknown paths do not each get their own series.
+const Unmatched = "unmatched"
+
+// HTTP records the requests served.
+type HTTP struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+}
+
+// NewHTTP constructs and registers HTTP.
+func NewHTTP() *HTTP {
+	h := &HTTP{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken serving HTTP requests, by method, route and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "HTTP requests being served.",
+		}),
+	}
+	prometheus.MustRegister(h.requests, h.duration, h.inFlight)
+	return h
+}
+
+// Start records a request coming in, the function returned records it once served.
+func (h *HTTP) Start() func(method string, route string, status int) {
+	start := time.Now()
+	h.inFlight.Inc()
+	return func(method string, route string, status int) {
+		h.inFlight.Dec()
+		if route == "" {
+			route = Unmatched
+		}
+		labels := prometheus.Labels{"method": method, "route": route, "status": strconv.Itoa(status)}
+		h.requests.With(labels).Inc()
+		h.duration.With(labels).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Middleware records the requests served by next, route returning the route of a request("" when none matches).
+func (h *HTTP) Middleware(next http.Handler, route func(r *http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		done := h.Start()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			done(r.Method, route(r), recorder.status)
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// statusRecorder remembers the status written.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Handler serves every metric registered with prometheus.DefaultRegisterer.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
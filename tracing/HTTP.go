@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"context"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// Unmatched is the route of requests matching no route, so that unknown paths do not each get a span name.
+const Unmatched = "unmatched"
+
+const instrumentation = "mbcarruthers/tracing"
+
+// StartServer starts the span of the request r served at route, continuing the trace of its traceparent header.
+// The request is served with the context returned.
+func StartServer(r *http.Request, route string) (context.Context, trace.Span) {
+	if route == "" {
+		route = Unmatched
+	}
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	return otel.Tracer(instrumentation).Start(ctx, r.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPMethod(r.Method),
+			semconv.HTTPRoute(route),
+			semconv.HTTPTarget(r.URL.RequestURI()),
+			semconv.HTTPScheme(scheme(r)),
+			semconv.HTTPUserAgent(r.UserAgent()),
+			attribute.String("net.peer.addr", r.RemoteAddr),
+		),
+	)
+}
+
+// SetRoute renames the span of the request r once its route is known to be route.
+func SetRoute(span trace.Span, r *http.Request, route string) {
+	span.SetName(r.Method + " " + route)
+	span.SetAttributes(semconv.HTTPRoute(route))
+}
+
+// EndServer ends the span of a request answered with status, a server error marking the span as failed.
+func EndServer(span trace.Span, status int) {
+	span.SetAttributes(semconv.HTTPStatusCode(status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	span.End()
+}
+
+// Middleware traces the requests served by next, route returning the route of a request("" when none matches).
+func Middleware(next http.Handler, route func(r *http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := StartServer(r, route(r))
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			EndServer(span, recorder.status)
+		}()
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+	})
+}
+
+// Transport traces the requests sent through base, passing the trace on in their traceparent header. A nil base is
+// http.DefaultTransport.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
+
+// statusRecorder remembers the status written.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// scheme returns the scheme r was received over.
+func scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,128 @@
+// Package tracing traces requests from webServer through helio down to its SQL statements with OpenTelemetry.
+// Every service calls Setup with its Settings, which installs the tracer provider and the W3C trace-context
+// propagator, so a traceparent header coming in continues the trace and requests going out carry it on:
+//
+//	none    traces nothing, the default
+//	otlp    exports to an OpenTelemetry collector over gRPC, i.e. the collector of Jaeger or Tempo
+//	stdout  prints every span as json, for local runs
+//	file    appends every span as json to a file, for local runs
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"mbcarruthers/health"
+	"os"
+)
+
+const (
+	None   = "none"
+	OTLP   = "otlp"
+	Stdout = "stdout"
+	File   = "file"
+)
+
+// Settings of the traces of a service, a section of its configuration.
+type Settings struct {
+	Exporter    string  `config:"exporter" env:"OTEL_TRACES_EXPORTER" usage:"none, otlp, stdout or file"`
+	Endpoint    string  `config:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" usage:"host:port of the OpenTelemetry collector, for otlp"`
+	Insecure    bool    `config:"insecure" env:"OTEL_EXPORTER_OTLP_INSECURE" usage:"export to the collector without TLS, for otlp"`
+	File        string  `config:"file" env:"TRACES_FILE" usage:"file spans are appended to, for file"`
+	SampleRatio float64 `config:"sample_ratio" env:"OTEL_TRACES_SAMPLER_ARG" usage:"share of new traces sampled, 0 to 1, traces started upstream follow their caller"`
+}
+
+// Defaults returns the Settings of a service tracing nothing.
+func Defaults() Settings {
+	return Settings{
+		Exporter:    None,
+		Endpoint:    "localhost:4317",
+		SampleRatio: 1,
+	}
+}
+
+// Validate checks the settings.
+func (s Settings) Validate() error {
+	switch s.Exporter {
+	case None, OTLP, Stdout:
+	case File:
+		if s.File == "" {
+			return fmt.Errorf("file is required by the file exporter")
+		}
+	default:
+		return fmt.Errorf("exporter must be none, otlp, stdout or file, not %q", s.Exporter)
+	}
+	if s.Exporter == OTLP && s.Endpoint == "" {
+		return fmt.Errorf("endpoint is required by the otlp exporter")
+	}
+	if s.SampleRatio < 0 || s.SampleRatio > 1 {
+		return fmt.Errorf("sample_ratio must be between 0 and 1")
+	}
+	return nil
+}
+
+// Setup installs the tracer provider of service and the propagator of W3C trace-context and baggage. The propagator
+// is installed whatever the exporter, so a service tracing nothing still passes the traces of others on. shutdown
+// flushes the spans not yet exported, before the service exits.
+func Setup(service string, settings Settings) (shutdown func(ctx context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	exporter, closer, err := newExporter(settings)
+	if err != nil || exporter == nil {
+		return func(ctx context.Context) error { return nil }, err
+	}
+	version, _ := health.Build()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(settings.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(service),
+			semconv.ServiceVersion(version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			_ = closer.Close()
+		}
+		return err
+	}, nil
+}
+
+// newExporter returns the exporter of settings, nil when nothing is traced, and the file it writes to if any.
+func newExporter(settings Settings) (sdktrace.SpanExporter, io.Closer, error) {
+	switch settings.Exporter {
+	case OTLP:
+		options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(settings.Endpoint)}
+		if settings.Insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		// Note: the client connects in the background, spans are dropped while the collector is unreachable.
+		exporter, err := otlptracegrpc.New(context.Background(), options...)
+		return exporter, nil, err
+	case Stdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		return exporter, nil, err
+	case File:
+		f, err := os.OpenFile(settings.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("err opening %s\n %s", settings.File, err.Error())
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		return exporter, f, err
+	}
+	return nil, nil, nil
+}
+
+// Tracer returns the tracer of the instrumentation name, i.e. mbcarruthers/helio/dataservice/db.
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
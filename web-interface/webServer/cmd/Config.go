@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/config"
+	"mbcarruthers/metrics"
+	"mbcarruthers/tracing"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Config is the configuration of the web server, loaded from WEBSERVER_CONFIG(or -config), the environment and
+// flags. log_level is reloaded on SIGHUP.
+type Config struct {
+	Port            int           `config:"port" env:"PORT" usage:"HTTP port"`
+	ClientDir       string        `config:"client_dir" env:"CLIENT_DIR" usage:"directory of the built front end(zebrafalter)"`
+	ShutdownTimeout time.Duration `config:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" usage:"time given to requests in flight on shutdown"`
+	LogLevel        config.Level  `config:"log_level" env:"LOG_LEVEL" reload:"true" usage:"debug, info, warn or error, requests are logged at debug and info"`
+	HelioURL        string        `config:"helio_url" env:"HELIO_URL" usage:"url of helio, /entities is forwarded to it and the readiness of the web server depends on its /readyz"`
+
+	Tracing tracing.Settings `config:"tracing"`
+}
+
+// defaultConfig is the configuration the web server runs with when nothing is set.
+func defaultConfig() Config {
+	return Config{
+		Port:            3000,
+		ClientDir:       "./client",
+		ShutdownTimeout: 750 * time.Millisecond,
+		LogLevel:        config.Info,
+		HelioURL:        "http://helio:8000",
+		Tracing:         tracing.Defaults(),
+	}
+}
+
+// Validate checks every setting before the server starts.
+func (c *Config) Validate() error {
+	var problems config.Problems
+	problems.Port("port", c.Port)
+	problems.Dir("client_dir", c.ClientDir)
+	problems.Positive("shutdown_timeout", int64(c.ShutdownTimeout))
+	if u, err := url.Parse(c.HelioURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems.Add("helio_url must be an http(s) url, not %q", c.HelioURL)
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		problems.Add("tracing: %s", err.Error())
+	}
+	return problems.Err()
+}
+
+// requestMetrics records every request by method, route and status, files of the front end under the route static.
+func requestMetrics(m *metrics.HTTP) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		done := m.Start()
+		c.Next()
+		route := c.FullPath()
+		if route == "" && c.Writer.Status() != http.StatusNotFound {
+			route = "static"
+		}
+		done(c.Request.Method, route, c.Writer.Status())
+	}
+}
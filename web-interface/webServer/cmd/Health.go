@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"mbcarruthers/health"
+	"mbcarruthers/tracing"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NewHealthChecker constructs the health.Checker of the web server, helio being its one dependency.
+func NewHealthChecker(helioURL string) *health.Checker {
+	checker := health.NewChecker("webServer", 2*time.Second)
+	checker.Add("helio", upstreamCheck(strings.TrimSuffix(helioURL, "/")+"/readyz"))
+	return checker
+}
+
+// upstreamCheck checks that readyURL answers 200, the check being part of the trace of the readiness request.
+func upstreamCheck(readyURL string) health.Check {
+	client := &http.Client{Transport: tracing.Transport(nil)}
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, readyURL, nil)
+		if err != nil {
+			return err
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("err %s answered %s", readyURL, res.Status)
+		}
+		return nil
+	}
+}
+
+// healthRoutes serves /healthz, /readyz and /status.
+func healthRoutes(r gin.IRoutes, checker *health.Checker) {
+	r.GET("/healthz", gin.WrapF(checker.HealthzHandler))
+	r.GET("/readyz", gin.WrapF(checker.ReadyzHandler))
+	r.GET("/status", gin.WrapF(checker.StatusHandler))
+}
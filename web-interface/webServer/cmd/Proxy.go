@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"mbcarruthers/tracing"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// helioProxy forwards requests to helio, passing the trace of every request on so that a search made from the map
+// is traced from the web server down to the statements helio runs. helioURL has passed Config.Validate.
+func helioProxy(helioURL string) gin.HandlerFunc {
+	target, _ := url.Parse(helioURL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = tracing.Transport(nil)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		slog.ErrorContext(r.Context(), "Error forwarding to helio", "method", r.Method, "path", r.URL.Path, "error", err)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadGateway)
+		_ = json.NewEncoder(w).Encode(errorBody(r, "err upstream", "helio could not be reached"))
+	}
+	return gin.WrapH(proxy)
+}
+
+// requestTracing traces every request by method and route, continuing the trace of its traceparent header. Files of
+// the front end are traced under the route static, like requestMetrics records them.
+func requestTracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := tracing.StartServer(c.Request, c.FullPath())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		if c.FullPath() == "" && c.Writer.Status() != http.StatusNotFound {
+			tracing.SetRoute(span, c.Request, "static")
+		}
+		tracing.EndServer(span, c.Writer.Status())
+	}
+}
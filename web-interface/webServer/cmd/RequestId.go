@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"mbcarruthers/logging"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// requestId gives every request an id, taken from its X-Request-Id or generated, and echoes it in the response. The
+// id is passed on to helio by helioProxy, so the lines both log for a search can be found together.
+func requestId() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := logging.NewRequestId(c.GetHeader(logging.Header))
+		c.Request.Header.Set(logging.Header, id)
+		c.Request = c.Request.WithContext(logging.WithRequestId(c.Request.Context(), id))
+		c.Header(logging.Header, id)
+		c.Next()
+	}
+}
+
+// requestLogger logs every request once served, at info, and at error when it failed with a server error. Files of
+// the front end are logged under the route static, like requestMetrics records them.
+func requestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" && c.Writer.Status() != http.StatusNotFound {
+			route = "static"
+		}
+		logging.Request(c.Request.Context(), c.Request, route, c.Writer.Status(), c.Writer.Size(), start)
+	}
+}
+
+// recovery answers 500 to a request whose handler panicked, logging the panic and its stack.
+func recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, err any) {
+		slog.ErrorContext(c.Request.Context(), "Panic serving request", "panic", err, "stack", string(debug.Stack()))
+		c.AbortWithStatusJSON(http.StatusInternalServerError, errorBody(c.Request, "err internal", ""))
+	})
+}
+
+// noRoute answers 404 to a request matching no route nor any file of the front end.
+func noRoute(c *gin.Context) {
+	c.JSON(http.StatusNotFound, errorBody(c.Request, "err not found", "no route matches the request"))
+}
+
+// errorBody returns the json body of an error, carrying the request id so the request can be found in the logs.
+func errorBody(r *http.Request, err string, message string) gin.H {
+	body := gin.H{
+		"error":      err,
+		"request_id": logging.RequestId(r.Context()),
+	}
+	if message != "" {
+		body["message"] = message
+	}
+	return body
+}
@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"github.com/gin-gonic/contrib/static"
 	"github.com/gin-gonic/gin"
-	"log"
+	"log/slog"
+	"mbcarruthers/config"
+	"mbcarruthers/logging"
+	"mbcarruthers/metrics"
+	"mbcarruthers/tracing"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,17 +17,45 @@ import (
 	"time"
 )
 
-const (
-	__port = 3000
-)
-
-var (
-	port = fmt.Sprintf(":%d", __port)
-)
-
 func main() {
-	r := gin.Default()
-	r.Use(static.Serve("/", static.LocalFile("./client", true)))
+	loader, err := config.NewLoader("webServer", defaultConfig)
+	if err != nil {
+		logging.Fatal("Error reading the configuration settings", "error", err)
+	}
+	loader.Parse(os.Args[1:])
+	cfg, err := loader.Load()
+	if err != nil {
+		logging.Fatal("Error loading configuration", "error", err)
+	}
+	loader.Print(os.Stderr, cfg)
+	if loader.PrintOnly() {
+		return
+	}
+	if cfg.LogLevel != config.Debug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	// every line is logged as json, along with the request id of the request it was logged for
+	logLevel := config.NewLevelVar(cfg.LogLevel)
+	logging.Setup("webServer", logLevel)
+	shutdownTracing, err := tracing.Setup("webServer", cfg.Tracing)
+	if err != nil {
+		logging.Fatal("Error setting up tracing", "error", err)
+	}
+	// SIGHUP reloads log_level, any other setting needs a restart
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	defer stopReload()
+	loader.Watch(reloadCtx, cfg, func(next Config) {
+		logLevel.Set(next.LogLevel)
+	})
+
+	r := gin.New()
+	r.Use(requestMetrics(metrics.NewHTTP()), requestTracing(), requestId(), requestLogger(), recovery())
+	r.Use(static.Serve("/", static.LocalFile(cfg.ClientDir, true)))
+	healthRoutes(r, NewHealthChecker(cfg.HelioURL))
+	// the front end searches through the web server, so that its requests reach helio as part of their trace
+	r.Any("/entities/*path", helioProxy(cfg.HelioURL))
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	r.NoRoute(noRoute)
 
 	r.GET("/", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -34,33 +66,37 @@ func main() {
 	})
 
 	server := &http.Server{
-		Addr:    port,
-		Handler: r,
+		Addr:     fmt.Sprintf(":%d", cfg.Port),
+		Handler:  r,
+		ErrorLog: slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
 	}
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("server listening err: %s \n",
-				err.Error())
+			logging.Fatal("server listening err", "error", err)
 		}
 	}()
 
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	<-quit
-	log.Println("Server shutting down")
+	slog.Info("Server shutting down")
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*750)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("webServer shutdown err: %s \n",
-			err.Error())
+		logging.Fatal("webServer shutdown err", "error", err)
 	}
 
 	select {
 	case <-ctx.Done():
 		break;
 	}
-	log.Println("Web Server has shutdown properly")
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelTracing()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		slog.Error("traces didnt export properly", "error", err)
+	}
+	slog.Info("Web Server has shutdown properly")
 }
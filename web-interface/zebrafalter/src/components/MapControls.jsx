@@ -14,7 +14,7 @@ const MapControls = (props) => {
         let d1 = date1.value;
         let d2 = date2.value; // Todo: Just pass date1.value & date2.value to the url
         console.log(d1,d2);
-        fetch(`\/\/\localhost:8000/entities/search?date1="${d1}"&date2="${d2}"`)
+        fetch(`/entities/search?date1="${d1}"&date2="${d2}"&quality_grade=clean,accepted`) // only reviewed or unflagged observations are published
             .then((res) => res.json())
             .then((entities) => {
                 if(entities.length !== 0) {